@@ -6,6 +6,10 @@ angular.module('todoApp', [])
     todoList.editTodo = {};
     todoList.deleteTodo = {};
     todoList.editDue = "";
+    todoList.tag = "";
+    todoList.tags = [];
+    todoList.list = undefined;
+    todoList.lists = [];
     showWorking();
     $http.get('/user-info')
       .then(function (response) {
@@ -13,13 +17,257 @@ angular.module('todoApp', [])
         hideWorking();
       })
       .catch(hideWorking);
-    showWorking();
-    $http.get('/list')
-      .then(function (response) {
-        todoList.todos = response.data;
-        hideWorking();
-      })
-      .catch(hideWorking);
+    todoList.load = function () {
+      showWorking();
+      var config = {
+        params: {
+          "tag": todoList.tag || undefined,
+          "list": todoList.list
+        }
+      };
+      return $http.get('/list', config)
+        .then(function (response) {
+          todoList.todos = response.data || [];
+          if (!todoList.tag && todoList.list === undefined) {
+            todoList.tags = collectTags(todoList.todos);
+          }
+          hideWorking();
+        })
+        .catch(hideWorking);
+    };
+
+    function collectTags(todos) {
+      var tags = [];
+      angular.forEach(todos, function (todo) {
+        angular.forEach(todo.Tags, function (tag) {
+          if (tags.indexOf(tag) < 0) {
+            tags.push(tag);
+          }
+        });
+      });
+      return tags.sort();
+    }
+
+    todoList.filterTag = function (tag) {
+      todoList.tag = tag;
+      todoList.load();
+    };
+
+    todoList.loadLists = function () {
+      return $http.get('/lists')
+        .then(function (response) {
+          todoList.lists = response.data || [];
+        });
+    };
+    todoList.loadLists();
+
+    // The due dates are shown in the time zone of the setting, the browser may be elsewhere
+    todoList.setting = {};
+    todoList.timeZones = ["Asia/Bangkok", "Asia/Tokyo", "Asia/Singapore", "Asia/Kolkata", "Europe/London",
+      "Europe/Berlin", "America/New_York", "America/Los_Angeles", "Australia/Sydney", "UTC"];
+    addTimeZone(browserTimeZone());
+
+    function browserTimeZone() {
+      try {
+        return Intl.DateTimeFormat().resolvedOptions().timeZone;
+      } catch (e) {
+        return undefined;
+      }
+    }
+
+    function addTimeZone(timeZone) {
+      if (timeZone && todoList.timeZones.indexOf(timeZone) < 0) {
+        todoList.timeZones.push(timeZone);
+        todoList.timeZones.sort();
+      }
+    }
+
+    todoList.loadSetting = function () {
+      return $http.get('/setting')
+        .then(function (response) {
+          todoList.setting = response.data;
+          addTimeZone(todoList.setting.TimeZone);
+        });
+    };
+    todoList.loadSetting();
+
+    todoList.saveSetting = function () {
+      showWorking();
+      var data = {
+        "TimeZone": todoList.setting.TimeZone
+      };
+      $http.post('/setting', data)
+        .then(todoList.load)
+        .catch(hideWorking);
+    };
+
+    // The reminder setting is edited in a modal, the weekdays are numbered from 0 for Sunday
+    todoList.weekdays = ["Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"];
+    todoList.reminder = {};
+
+    todoList.toReminder = function () {
+      var setting = todoList.setting;
+      var days = setting.RemindDays ? setting.RemindDays.split(",") : [];
+      todoList.reminder = {
+        "RemindTimes": setting.RemindTimes,
+        "Days": todoList.weekdays.map(function (name, day) {
+          return days.length == 0 || days.indexOf(String(day)) >= 0;
+        }),
+        "QuietStart": setting.QuietStart,
+        "QuietEnd": setting.QuietEnd,
+        "HideCompleted": setting.HideCompleted,
+        "OnlyOverdue": setting.OnlyOverdue
+      };
+      todoList.reminderError = "";
+      todoList.reminderSaved = false;
+    };
+
+    todoList.saveReminder = function () {
+      var reminder = todoList.reminder;
+      var days = [];
+      angular.forEach(reminder.Days, function (on, day) {
+        if (on) {
+          days.push(day);
+        }
+      });
+      todoList.reminderError = "";
+      todoList.reminderSaved = false;
+      showWorking();
+      var data = {
+        "RemindTimes": (reminder.RemindTimes || "").replace(/\s+/g, ""),
+        // Every day or none is the default
+        "RemindDays": days.length == 7 ? "" : days.join(","),
+        "QuietStart": reminder.QuietStart || "",
+        "QuietEnd": reminder.QuietEnd || "",
+        "HideCompleted": !!reminder.HideCompleted,
+        "OnlyOverdue": !!reminder.OnlyOverdue
+      };
+      $http.post('/setting', data)
+        .then(todoList.loadSetting)
+        .then(function () {
+          todoList.reminderSaved = true;
+          hideWorking();
+        })
+        .catch(function (response) {
+          todoList.reminderError = response.data;
+          hideWorking();
+        });
+    };
+
+    todoList.findList = function (id) {
+      var found = {};
+      angular.forEach(todoList.lists, function (list) {
+        if (list.ID == id) {
+          found = list;
+        }
+      });
+      return found;
+    };
+
+    todoList.listName = function (id) {
+      return todoList.findList(id).Name || "";
+    };
+
+    todoList.filterList = function (id) {
+      todoList.list = id;
+      todoList.load();
+    };
+
+    todoList.createList = function () {
+      if (!todoList.newList) {
+        return;
+      }
+      showWorking();
+      var data = {
+        "Name": todoList.newList
+      };
+      todoList.newList = "";
+      $http.post('/create-list', data)
+        .then(todoList.loadLists)
+        .then(hideWorking)
+        .catch(hideWorking);
+    };
+
+    todoList.renameList = function (id) {
+      var name = window.prompt("List name", todoList.listName(id));
+      if (!name) {
+        return;
+      }
+      showWorking();
+      var data = {
+        "ID": id,
+        "Name": name
+      };
+      $http.post('/rename-list', data)
+        .then(todoList.loadLists)
+        .then(hideWorking)
+        .catch(hideWorking);
+    };
+
+    todoList.shareList = function (id) {
+      showWorking();
+      var data = {
+        "ID": id
+      };
+      $http.post('/share-list', data)
+        .then(function (response) {
+          hideWorking();
+          var invite = "join " + response.data.InviteCode;
+          if (response.data.InviteURL) {
+            invite = response.data.InviteURL;
+          }
+          window.prompt("Send this to your friends to share " + todoList.listName(id), invite);
+        })
+        .catch(hideWorking);
+    };
+
+    todoList.joinList = function () {
+      if (!todoList.inviteCode) {
+        return;
+      }
+      showWorking();
+      var data = {
+        "InviteCode": todoList.inviteCode
+      };
+      todoList.inviteCode = "";
+      $http.post('/join-list', data)
+        .then(function (response) {
+          todoList.list = response.data.ID;
+          return todoList.loadLists();
+        })
+        .then(todoList.load)
+        .catch(hideWorking);
+    };
+
+    todoList.leaveList = function (id) {
+      showWorking();
+      var data = {
+        "ID": id
+      };
+      $http.post('/leave-list', data)
+        .then(function () {
+          todoList.list = undefined;
+          return todoList.loadLists();
+        })
+        .then(todoList.load)
+        .catch(hideWorking);
+    };
+
+    todoList.deleteList = function (id) {
+      showWorking();
+      var data = {
+        "ID": id
+      };
+      $http.post('/delete-list', data)
+        .then(function () {
+          // The todos of the list are moved to the default list
+          todoList.list = undefined;
+          return todoList.loadLists();
+        })
+        .then(todoList.load)
+        .catch(hideWorking);
+    };
+    todoList.load();
 
     todoList.remaining = function () {
       var count = 0;
@@ -57,10 +305,103 @@ angular.module('todoApp', [])
         "Done": status
       };
       $http.post('/done', data)
+        .then(function () {
+          hideWorking();
+          // The next occurrence of a repeating task is created by the server
+          if (status && todoList.isRepeat(id)) {
+            todoList.load();
+          }
+        })
+        .catch(hideWorking);
+    };
+
+    todoList.isRepeat = function (id) {
+      var repeat = false;
+      angular.forEach(todoList.todos, function (todo) {
+        if (todo.ID == id && todo.Repeat) {
+          repeat = true;
+        }
+      });
+      return repeat;
+    };
+
+    todoList.progress = function (todo) {
+      if (!todo.Steps || todo.Steps.length == 0) {
+        return "";
+      }
+      var done = 0;
+      angular.forEach(todo.Steps, function (step) {
+        done += step.Done ? 1 : 0;
+      });
+      return done + "/" + todo.Steps.length;
+    };
+
+    todoList.addStep = function (todo) {
+      if (!todoList.newStep) {
+        return;
+      }
+      showWorking();
+      var data = {
+        "TodoID": todo.ID,
+        "Task": todoList.newStep
+      };
+      todoList.newStep = "";
+      $http.post('/add-step', data)
+        .then(todoList.load)
+        .then(function () {
+          // Keep editing the reloaded todo
+          angular.forEach(todoList.todos, function (reloaded) {
+            if (reloaded.ID == todo.ID) {
+              todoList.editTodo = reloaded;
+            }
+          });
+        })
+        .catch(hideWorking);
+    };
+
+    todoList.setStepDone = function (id, status) {
+      showWorking();
+      var data = {
+        "ID": id,
+        "Done": status
+      };
+      $http.post('/done-step', data)
         .then(hideWorking)
         .catch(hideWorking);
     };
 
+    todoList.moveStep = function (todo, index, offset) {
+      var target = index + offset;
+      if (target < 0 || target >= todo.Steps.length) {
+        return;
+      }
+      var step = todo.Steps.splice(index, 1)[0];
+      todo.Steps.splice(target, 0, step);
+      showWorking();
+      var data = {
+        "TodoID": todo.ID,
+        "StepIDs": todo.Steps.map(function (step) {
+          return step.ID;
+        })
+      };
+      $http.post('/reorder-steps', data)
+        .then(hideWorking)
+        .catch(hideWorking);
+    };
+
+    todoList.deleteStep = function (todo, index) {
+      showWorking();
+      var data = {
+        "ID": todo.Steps[index].ID
+      };
+      $http.post('/delete-step', data)
+        .then(function () {
+          todo.Steps.splice(index, 1);
+          hideWorking();
+        })
+        .catch(hideWorking);
+    };
+
     todoList.setPin = function (id, status) {
       showWorking();
       var data = {
@@ -72,11 +413,49 @@ angular.module('todoApp', [])
         .catch(hideWorking);
     };
 
+    todoList.isSnoozed = function (todo) {
+      return !!todo.Snoozed && moment(todo.Snoozed).isAfter(moment());
+    };
+
+    // Hides the task from the digests and the alerts for some hours, its due date stays
+    todoList.snooze = function (todo, hours) {
+      showWorking();
+      // In the time zone of the user like the due dates
+      var offset = moment.parseZone(todo.Due).utcOffset();
+      var data = {
+        "ID": todo.ID,
+        "Snoozed": moment().utcOffset(offset).add(hours, 'hours').startOf('minute').format()
+      };
+      $http.post('/snooze', data)
+        .then(function () {
+          todo.Snoozed = data.Snoozed;
+          hideWorking();
+        })
+        .catch(hideWorking);
+    };
+
+    todoList.wake = function (todo) {
+      showWorking();
+      var data = {
+        "ID": todo.ID,
+        "Snoozed": null
+      };
+      $http.post('/snooze', data)
+        .then(function () {
+          todo.Snoozed = null;
+          hideWorking();
+        })
+        .catch(hideWorking);
+    };
+
     function sortByDue(tasks) {
       return tasks.sort(function (a, b) {
-        if (a.Due < b.Due) {
+        // The offsets may differ around daylight saving
+        var aDue = moment(a.Due).valueOf();
+        var bDue = moment(b.Due).valueOf();
+        if (aDue < bDue) {
           return -1;
-        } else if (a.Due > b.Due) {
+        } else if (aDue > bDue) {
           return 1;
         }
         return 0;
@@ -84,7 +463,9 @@ angular.module('todoApp', [])
     }
 
     todoList.formatDate = function (date) {
-      var dateString = moment(date).calendar(Date.now(), {
+      var due = moment.parseZone(date);
+      var now = moment().utcOffset(due.utcOffset());
+      var dateString = due.calendar(now, {
         sameDay: '[Today] [at] H:mm',
         nextDay: '[Tomorrow] [at] H:mm',
         nextWeek: 'dddd [at] H:mm',
@@ -103,30 +484,67 @@ angular.module('todoApp', [])
     }
 
     function formatDateInput(date) {
-      return moment(date).format('YYYY-MM-DD[T]HH:mm');
+      return moment.parseZone(date).format('YYYY-MM-DD[T]HH:mm');
     };
 
+    function formatTagsInput(tags) {
+      return (tags || []).map(function (tag) {
+        return "#" + tag;
+      }).join(" ");
+    }
+
+    function parseTagsInput(text) {
+      var tags = [];
+      angular.forEach((text || "").split(/[\s,]+/), function (tag) {
+        tag = tag.replace(/^#+/, "").toLowerCase();
+        if (tag && tags.indexOf(tag) < 0) {
+          tags.push(tag);
+        }
+      });
+      return tags.sort();
+    }
+
     todoList.toEdit = function (todo) {
       todoList.editTodo = todo;
       todoList.editDue = formatDateInput(todo.Due);
       $("#task-input").val(todoList.editTodo.Task);
       $("#due-input").val(todoList.editDue);
+      $("#tags-input").val(formatTagsInput(todoList.editTodo.Tags));
+      $("#list-input").val(String(todoList.editTodo.ListID || 0));
     };
 
     todoList.edit = function () {
       showWorking();
       todoList.editTodo.Task = $("#task-input").val();
       todoList.editDue = $("#due-input").val();
-      todoList.editTodo.Due = moment(todoList.editDue).format('YYYY-MM-DD[T]HH:mm:ssZ');
+      // The server reads the clock in the time zone of the setting, the offset is only for the local copy
+      var offset = moment.parseZone(todoList.editTodo.Due).utcOffset();
+      todoList.editTodo.Due = moment.utc(todoList.editDue).utcOffset(offset, true).format('YYYY-MM-DD[T]HH:mm:ssZ');
+      todoList.editTodo.Tags = parseTagsInput($("#tags-input").val());
+      var listID = parseInt($("#list-input").val() || "0", 10);
+      var moved = listID != (todoList.editTodo.ListID || 0);
+      todoList.editTodo.ListID = listID;
       var data = {
         "ID": todoList.editTodo.ID,
         "Task": todoList.editTodo.Task,
-        "Due": todoList.editTodo.Due
+        "Due": todoList.editTodo.Due,
+        "Repeat": todoList.editTodo.Repeat,
+        "Tags": todoList.editTodo.Tags,
+        "ListID": todoList.editTodo.ListID
       };
       $http.post('/edit', data)
         .then(function () {
           todoList.updateEditTodoLocal(todoList.editTodo);
+          angular.forEach(todoList.editTodo.Tags, function (tag) {
+            if (todoList.tags.indexOf(tag) < 0) {
+              todoList.tags.push(tag);
+              todoList.tags.sort();
+            }
+          });
           hideWorking();
+          if (moved && todoList.list !== undefined) {
+            todoList.load();
+          }
         })
         .catch(hideWorking);
     };
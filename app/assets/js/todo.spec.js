@@ -9,6 +9,17 @@ describe('todoApp', function () {
         $controller = _$controller_;
         $rootScope = _$rootScope_;
         $httpBackend = $injector.get('$httpBackend')
+        $httpBackend.when('GET', '/list?tag=home')
+            .respond([
+                {
+                    "Id": 6,
+                    "Task": "Task 6",
+                    "Done": false,
+                    "Pin": false,
+                    "Due": "2018-11-12T12:27:00+07:00",
+                    "Tags": ["home"]
+                }
+            ]);
         var reqHandler = $httpBackend.when('GET', '/list')
             .respond([
                 {
@@ -51,10 +62,41 @@ describe('todoApp', function () {
             .respond();
         $httpBackend.when('POST', '/done')
             .respond();
+        $httpBackend.when('POST', '/snooze')
+            .respond();
         $httpBackend.when('POST', '/edit')
             .respond();
         $httpBackend.when('POST', '/delete')
             .respond();
+        $httpBackend.when('POST', '/add-step')
+            .respond();
+        $httpBackend.when('POST', '/done-step')
+            .respond();
+        $httpBackend.when('POST', '/reorder-steps')
+            .respond();
+        $httpBackend.when('POST', '/delete-step')
+            .respond();
+        $httpBackend.when('GET', '/lists')
+            .respond([
+                { "ID": 0, "Name": "Inbox" },
+                { "ID": 1, "Name": "Shopping" }
+            ]);
+        $httpBackend.when('GET', '/list?list=1')
+            .respond([]);
+        $httpBackend.when('POST', '/create-list')
+            .respond();
+        $httpBackend.when('POST', '/delete-list')
+            .respond();
+        $httpBackend.when('POST', '/share-list')
+            .respond({ "InviteCode": "K3XQ7M2A", "InviteURL": "" });
+        $httpBackend.when('POST', '/join-list')
+            .respond({ "ID": 1, "Name": "Shopping", "Joined": true });
+        $httpBackend.when('POST', '/leave-list')
+            .respond();
+        $httpBackend.when('GET', '/setting')
+            .respond({ "UserID": "user id", "Language": "", "TimeZone": "Asia/Bangkok" });
+        $httpBackend.when('POST', '/setting')
+            .respond();
         $httpBackend.when('GET', '/user-info')
             .respond({
                 "oauthPicture": "oauthPicture",
@@ -103,6 +145,31 @@ describe('todoApp', function () {
             });
         });
 
+        describe('snooze(todo, hours)', function () {
+            it('shoud post to /snooze and show the task snoozed', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                var todo = todoList.todos[0];
+                $httpBackend.expectPOST('/snooze');
+                todoList.snooze(todo, 1);
+                $httpBackend.flush();
+                expect(todoList.isSnoozed(todo)).toBe(true);
+            });
+        });
+
+        describe('wake(todo)', function () {
+            it('shoud post to /snooze without a time', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                var todo = todoList.todos[0];
+                todo.Snoozed = moment().add(1, 'hours').format();
+                $httpBackend.expectPOST('/snooze', { "ID": todo.ID, "Snoozed": null });
+                todoList.wake(todo);
+                $httpBackend.flush();
+                expect(todoList.isSnoozed(todo)).toBe(false);
+            });
+        });
+
         describe('setDone(id)', function () {
             it('shoud post to /done', function () {
                 var todoList = $controller('TodoListController', { $scope: $rootScope });
@@ -113,6 +180,234 @@ describe('todoApp', function () {
             });
         });
 
+        describe('setDone(id, status) for a repeating task', function () {
+            it('shoud reload /list to show the next occurrence', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.todos[1].ID = 2;
+                todoList.todos[1].Repeat = 'FREQ=DAILY';
+                $httpBackend.expectPOST('/done');
+                $httpBackend.expectGET('/list');
+                todoList.setDone(2, true);
+                $httpBackend.flush();
+            });
+        });
+
+        describe('progress(todo)', function () {
+            it('shoud return done steps of all steps', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                var todo = {
+                    Steps: [{ ID: 1, Done: true }, { ID: 2, Done: false }, { ID: 3, Done: false }]
+                };
+                expect(todoList.progress(todo)).toEqual("1/3");
+            });
+            it('shoud return blank without steps', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                expect(todoList.progress({ Steps: null })).toEqual("");
+            });
+        });
+
+        describe('addStep(todo)', function () {
+            it('shoud post to /add-step and reload /list', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.newStep = "Step 1";
+                $httpBackend.expectPOST('/add-step', { "TodoID": 1, "Task": "Step 1" });
+                $httpBackend.expectGET('/list');
+                todoList.addStep({ ID: 1 });
+                $httpBackend.flush();
+            });
+        });
+
+        describe('moveStep(todo, index, offset)', function () {
+            it('shoud post the new order to /reorder-steps', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                var todo = {
+                    ID: 1,
+                    Steps: [{ ID: 1 }, { ID: 2 }, { ID: 3 }]
+                };
+                $httpBackend.expectPOST('/reorder-steps', { "TodoID": 1, "StepIDs": [2, 1, 3] });
+                todoList.moveStep(todo, 1, -1);
+                $httpBackend.flush();
+            });
+        });
+
+        describe('deleteStep(todo, index)', function () {
+            it('shoud post to /delete-step and remove the step', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                var todo = {
+                    ID: 1,
+                    Steps: [{ ID: 1 }, { ID: 2 }]
+                };
+                $httpBackend.expectPOST('/delete-step', { "ID": 1 });
+                todoList.deleteStep(todo, 0);
+                $httpBackend.flush();
+                expect(todo.Steps).toEqual([{ ID: 2 }]);
+            });
+        });
+
+        describe('filterTag(tag)', function () {
+            it('shoud get /list filtered by the tag and keep all tags selectable', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.tags = ["home", "work"];
+                $httpBackend.expectGET('/list?tag=home');
+                todoList.filterTag("home");
+                $httpBackend.flush();
+                expect(todoList.todos.length).toEqual(1);
+                expect(todoList.tags).toEqual(["home", "work"]);
+            });
+            it('shoud get /list without a tag', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.tag = "home";
+                $httpBackend.expectGET('/list');
+                todoList.filterTag("");
+                $httpBackend.flush();
+                expect(todoList.todos.length).toEqual(5);
+            });
+        });
+
+        describe('filterList(id)', function () {
+            it('shoud get /list of the list', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                expect(todoList.listName(1)).toEqual("Shopping");
+                $httpBackend.expectGET('/list?list=1');
+                todoList.filterList(1);
+                $httpBackend.flush();
+                expect(todoList.todos.length).toEqual(0);
+            });
+        });
+
+        describe('createList()', function () {
+            it('shoud post to /create-list and reload /lists', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.newList = "Work";
+                $httpBackend.expectPOST('/create-list', { "Name": "Work" });
+                $httpBackend.expectGET('/lists');
+                todoList.createList();
+                $httpBackend.flush();
+            });
+        });
+
+        describe('shareList(id)', function () {
+            it('shoud show the invite code', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                spyOn(window, 'prompt');
+                $httpBackend.expectPOST('/share-list', { "ID": 1 });
+                todoList.shareList(1);
+                $httpBackend.flush();
+                expect(window.prompt).toHaveBeenCalledWith("Send this to your friends to share Shopping", "join K3XQ7M2A");
+            });
+        });
+
+        describe('joinList()', function () {
+            it('shoud post to /join-list and show the list', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.inviteCode = "K3XQ7M2A";
+                $httpBackend.expectPOST('/join-list', { "InviteCode": "K3XQ7M2A" });
+                $httpBackend.expectGET('/lists');
+                $httpBackend.expectGET('/list?list=1');
+                todoList.joinList();
+                $httpBackend.flush();
+                expect(todoList.list).toEqual(1);
+            });
+        });
+
+        describe('leaveList(id)', function () {
+            it('shoud post to /leave-list and show all todos', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.list = 1;
+                $httpBackend.expectPOST('/leave-list', { "ID": 1 });
+                $httpBackend.expectGET('/lists');
+                $httpBackend.expectGET('/list');
+                todoList.leaveList(1);
+                $httpBackend.flush();
+                expect(todoList.list).toBeUndefined();
+            });
+        });
+
+        describe('deleteList(id)', function () {
+            it('shoud post to /delete-list and show all todos', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.list = 1;
+                $httpBackend.expectPOST('/delete-list', { "ID": 1 });
+                $httpBackend.expectGET('/lists');
+                $httpBackend.expectGET('/list');
+                todoList.deleteList(1);
+                $httpBackend.flush();
+                expect(todoList.list).toBeUndefined();
+            });
+        });
+
+        describe('loadSetting()', function () {
+            it('shoud get /setting and offer its time zone', function () {
+                $httpBackend.expectGET('/setting');
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                expect(todoList.setting.TimeZone).toEqual("Asia/Bangkok");
+                expect(todoList.timeZones).toContain("Asia/Bangkok");
+            });
+        });
+
+        describe('saveSetting()', function () {
+            it('shoud post the time zone to /setting and reload /list', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.setting.TimeZone = "Europe/Berlin";
+                $httpBackend.expectPOST('/setting', { "TimeZone": "Europe/Berlin" });
+                $httpBackend.expectGET('/list');
+                todoList.saveSetting();
+                $httpBackend.flush();
+            });
+        });
+
+        describe('saveReminder()', function () {
+            it('shoud post the reminder setting to /setting and reload it', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                todoList.toReminder();
+                expect(todoList.reminder.Days).toEqual([true, true, true, true, true, true, true]);
+                todoList.reminder.RemindTimes = "08:00, 18:30";
+                todoList.reminder.Days[0] = false;
+                todoList.reminder.Days[6] = false;
+                todoList.reminder.QuietStart = "22:00";
+                todoList.reminder.QuietEnd = "07:00";
+                todoList.reminder.OnlyOverdue = true;
+                $httpBackend.expectPOST('/setting', {
+                    "RemindTimes": "08:00,18:30",
+                    "RemindDays": "1,2,3,4,5",
+                    "QuietStart": "22:00",
+                    "QuietEnd": "07:00",
+                    "HideCompleted": false,
+                    "OnlyOverdue": true
+                });
+                $httpBackend.expectGET('/setting');
+                todoList.saveReminder();
+                $httpBackend.flush();
+                expect(todoList.reminderSaved).toBe(true);
+            });
+        });
+
+        describe('formatDate(date)', function () {
+            it('shoud show the clock of the time zone of the due date', function () {
+                var todoList = $controller('TodoListController', { $scope: $rootScope });
+                $httpBackend.flush();
+                expect(todoList.formatDate("1980-11-18T23:30:00+01:00")).toEqual("Tue 18 Nov 80 at 23:30");
+                expect(todoList.formatDate("1980-11-18T23:30:00+07:00")).toEqual("Tue 18 Nov 80 at 23:30");
+            });
+        });
+
         describe('remaining()', function () {
             it('shoud return number of remaining tasks', function () {
                 var todoList = $controller('TodoListController', { $scope: $rootScope });
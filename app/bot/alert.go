@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/choobot/choo-todo-bot/app/scheduler"
+)
+
+var errLead = errors.New("Wrong lead time")
+
+// DefaultAlertLeads are the lead times of the users who have set none
+var DefaultAlertLeads = []time.Duration{15 * time.Minute}
+
+const (
+	// maxAlertLead is a week, longer leads are what the digest is for
+	maxAlertLead = 7 * 24 * time.Hour
+	// alertHorizon is how far ahead the alerts are stored as pending
+	alertHorizon = 24 * time.Hour
+	// alertRetention is how long the sent alerts are kept
+	alertRetention = 7 * 24 * time.Hour
+	// alertTick is how often the alerts are sent, they are 5 minutes late at most
+	alertTick = "*/5 * * * *"
+	// maxAlertAttempts is how many failed pushes an alert or an overdue notice gets, like to a user who blocked the bot
+	maxAlertAttempts = 3
+)
+
+// AlertJob sends the alerts of the todos before they are due, see Alert
+func (this *TodoBot) AlertJob() scheduler.Job {
+	tick, _ := scheduler.ParseSchedule(alertTick)
+	return scheduler.Job{
+		Name:     "alert",
+		Schedule: tick,
+		Run: func(from time.Time, to time.Time) error {
+			return this.Alert(to)
+		},
+	}
+}
+
+type alertKey struct {
+	userID string
+	todoID int
+}
+
+// DueAlert is a todo to alert now, its alert is claimed until it is pushed
+type DueAlert struct {
+	AlertID int
+	UserID  string
+	Todo    model.Todo
+	// bot is in the language and the time zone of the user
+	bot *TodoBot
+}
+
+// Alert pushes the alerts whose time has come, each one is marked sent once pushed or released for the next run
+func (this *TodoBot) Alert(now time.Time) error {
+	alerts, err := this.DueAlerts(now)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, alert := range alerts {
+		wg.Add(1)
+		//Fork for massive API calls
+		go func(alert DueAlert) {
+			defer wg.Done()
+			err := this.PushMessage(alert.UserID, alert.bot.AlertMessage(now, alert.Todo))
+			this.deliver(alert.AlertID, now, err)
+		}(alert)
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliver marks the claimed alert sent after its push, or releases it after a failed one so the next run pushes it again
+func (this *TodoBot) deliver(alertID int, now time.Time, pushErr error) {
+	var err error
+	if pushErr != nil {
+		err = this.AlertModel.ReleaseAlert(alertID)
+	} else {
+		err = this.AlertModel.SendAlert(alertID, now)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func userIDsOf(userTodos map[string][]model.Todo) []string {
+	userIDs := []string{}
+	for userID := range userTodos {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs
+}
+
+// userBots are the bots of the users by user, see For, with their settings loaded at once
+func (this *TodoBot) userBots(userIDs []string) (map[string]*TodoBot, error) {
+	settings, err := this.TodoModel.Settings(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	bots := map[string]*TodoBot{}
+	for userID, setting := range settings {
+		bots[userID] = this.with(setting, "")
+	}
+	return bots, nil
+}
+
+// DueAlerts schedules the alerts of the next day and returns the ones to push now,
+// each one is claimed before it is returned so no instance pushes it twice
+func (this *TodoBot) DueAlerts(now time.Time) ([]DueAlert, error) {
+	// Only the todos which may have an alert by the horizon or held back by the quiet hours, the other pending ones are stale
+	userTodos, err := this.TodoModel.DueTodos(now.Add(-alertHorizon), now.Add(alertHorizon+maxAlertLead))
+	if err != nil {
+		return nil, err
+	}
+	bots, err := this.userBots(userIDsOf(userTodos))
+	if err != nil {
+		return nil, err
+	}
+	userLeads, err := this.AlertModel.AlertLeadsByUser(userIDsOf(userTodos))
+	if err != nil {
+		return nil, err
+	}
+	todos := map[alertKey]model.Todo{}
+	alerts := []model.Alert{}
+	for userID, list := range userTodos {
+		for _, todo := range list {
+			todos[alertKey{userID, todo.ID}] = todo
+			for _, lead := range alertLeads(userLeads[userID], todo.ID) {
+				at := todo.Due.Add(-lead)
+				// Like DueEscalations only the recent ones, the older ones may have been deleted after they were sent
+				if at.After(now.Add(alertHorizon)) || !at.After(now.Add(-alertHorizon)) {
+					continue
+				}
+				alerts = append(alerts, model.Alert{UserID: userID, TodoID: todo.ID, Due: todo.Due, Lead: lead})
+			}
+		}
+	}
+	if err := this.AlertModel.ScheduleAlerts(alerts); err != nil {
+		return nil, err
+	}
+	pending, err := this.AlertModel.PendingAlerts(now)
+	if err != nil {
+		return nil, err
+	}
+	due := []DueAlert{}
+	alerted := map[alertKey]bool{}
+	for _, alert := range pending {
+		if alert.Lead < 0 {
+			// A notice after the due date, see DueEscalations
+			continue
+		}
+		key := alertKey{alert.UserID, alert.TodoID}
+		todo, ok := todos[key]
+		if ok && bots[alert.UserID].isQuiet(now) {
+			// Left pending until the quiet hours end
+			continue
+		}
+		if ok && todo.IsSnoozed(now) {
+			// Left pending until the todo wakes up
+			continue
+		}
+		claimed, err := this.AlertModel.ClaimAlert(alert.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			// Another instance is pushing it
+			continue
+		}
+		// Done, moved or deleted since it was scheduled, or several leads have passed at once,
+		// like for a todo created shortly before it is due, and the shortest one is enough
+		skip := !ok || !todo.Due.Truncate(time.Second).Equal(alert.Due) || alerted[key]
+		// Due already, only an alert held back by the quiet hours which have just ended is pushed, as a was due one
+		if !skip && !todo.Due.After(now) {
+			skip = !bots[alert.UserID].isDeferred(alert.Due.Add(-alert.Lead), now.Add(-alertHorizon), now)
+		}
+		if skip || alert.Attempts >= maxAlertAttempts {
+			if err := this.AlertModel.SendAlert(alert.ID, now); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		alerted[key] = true
+		due = append(due, DueAlert{AlertID: alert.ID, UserID: alert.UserID, Todo: todo, bot: bots[alert.UserID]})
+	}
+	return due, this.AlertModel.DeleteAlerts(now.Add(-alertRetention))
+}
+
+// alertLeads are the lead times of the todo, its own ones, the ones of the user or the default ones
+func alertLeads(todoLeads map[int][]time.Duration, todoID int) []time.Duration {
+	if leads, ok := todoLeads[todoID]; ok {
+		return leads
+	}
+	if leads, ok := todoLeads[0]; ok {
+		return leads
+	}
+	return DefaultAlertLeads
+}
+
+// AlertMessage is "⏰ Pay rent is due in 15 minutes, Today at 9:00",
+// or "⏰ Pay rent was due 30 minutes ago, Today at 6:30" after the quiet hours
+func (this *TodoBot) AlertMessage(now time.Time, todo model.Todo) string {
+	if !todo.Due.After(now) {
+		return this.T("alertWasDue", Args{"Task": todo.Task, "Ago": this.FormatLead(now.Sub(todo.Due)), "Due": this.FormatDate(now, todo.Due)})
+	}
+	return this.T("alert", Args{"Task": todo.Task, "Left": this.FormatLead(todo.Due.Sub(now)), "Due": this.FormatDate(now, todo.Due)})
+}
+
+// FormatLead is "15 minutes", "2 hours" or "1 day", rounded
+func (this *TodoBot) FormatLead(lead time.Duration) string {
+	minutes := int((lead + time.Minute - 1) / time.Minute)
+	if minutes < 60 {
+		return this.N("minutes", minutes, nil)
+	} else if minutes < 24*60 {
+		return this.N("hours", (minutes+30)/60, nil)
+	}
+	return this.N("days", (minutes+12*60)/(24*60), nil)
+}
+
+// AlertCommand handles "alert" to show the lead times, "alert 15m 1h" or "alert off" for every todo,
+// "alert 3 1d" for the todo 3 of the last listing and "default" to go back, it returns false for other messages,
+// like OverdueCommand for a task such as "alert mom : tomorrow" and for a group message it cannot read
+func (this *TodoBot) AlertCommand(userID string, msg string) (string, bool) {
+	fields := strings.Fields(strings.ToLower(msg))
+	if len(fields) == 0 || (fields[0] != "alert" && fields[0] != "alerts") || strings.Contains(msg, " : ") {
+		return "", false
+	}
+	fields = fields[1:]
+	todo := model.Todo{}
+	if len(fields) > 0 {
+		if position, err := strconv.Atoi(fields[0]); err == nil {
+			todo, err = this.todoAt(userID, position)
+			if err != nil {
+				return this.commandError(position, err), true
+			}
+			fields = fields[1:]
+		}
+	}
+	var err error
+	if len(fields) == 1 && fields[0] == "default" {
+		err = this.AlertModel.DeleteAlertLeads(userID, todo.ID)
+	} else if len(fields) == 1 && fields[0] == "off" {
+		err = this.AlertModel.SaveAlertLeads(userID, todo.ID, []time.Duration{})
+	} else if len(fields) > 0 {
+		leads, parseErr := ParseLeads(fields)
+		if parseErr != nil && IsGroupID(userID) {
+			// Group chats are not only for the bot
+			return "", false
+		} else if parseErr != nil {
+			return this.T("wrongAlert", nil), true
+		}
+		err = this.AlertModel.SaveAlertLeads(userID, todo.ID, leads)
+	}
+	if err != nil {
+		return this.ErrorReply(err), true
+	}
+	todoLeads, err := this.AlertModel.AlertLeads(userID)
+	if err != nil {
+		return this.ErrorReply(err), true
+	}
+	leads := alertLeads(todoLeads, todo.ID)
+	texts := []string{}
+	for _, lead := range leads {
+		texts = append(texts, this.FormatLead(lead))
+	}
+	switch {
+	case todo.ID == 0 && len(leads) == 0:
+		return this.T("alertOff", nil), true
+	case todo.ID == 0:
+		return this.T("alertLeads", Args{"Leads": strings.Join(texts, ", ")}), true
+	case len(leads) == 0:
+		return this.T("todoAlertOff", Args{"Task": todo.Task}), true
+	}
+	return this.T("todoAlertLeads", Args{"Task": todo.Task, "Leads": strings.Join(texts, ", ")}), true
+}
+
+// ParseLeads reads "15m", "1h" and "1d" up to a week, sorted and without duplicates
+func ParseLeads(words []string) ([]time.Duration, error) {
+	units := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+	seen := map[time.Duration]bool{}
+	leads := []time.Duration{}
+	for _, word := range words {
+		if len(word) < 2 {
+			return nil, errLead
+		}
+		unit, ok := units[word[len(word)-1:]]
+		value, err := strconv.Atoi(word[:len(word)-1])
+		if !ok || err != nil || value < 1 || value > int(maxAlertLead/unit) {
+			return nil, errLead
+		}
+		lead := time.Duration(value) * unit
+		if !seen[lead] {
+			seen[lead] = true
+			leads = append(leads, lead)
+		}
+	}
+	sort.Slice(leads, func(i, j int) bool {
+		return leads[i] < leads[j]
+	})
+	return leads, nil
+}
@@ -0,0 +1,182 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestParseLeads(t *testing.T) {
+	if leads, err := ParseLeads([]string{"1h", "15m", "1d", "60m"}); err != nil || fmt.Sprint(leads) != "[15m0s 1h0m0s 24h0m0s]" {
+		t.Errorf("ParseLeads() == %v, %v", leads, err)
+	}
+	for _, word := range []string{"15", "m", "0m", "-1h", "8d", "1w", "99999999999999999999m", "1.5h"} {
+		if _, err := ParseLeads([]string{word}); err != errLead {
+			t.Errorf("ParseLeads(%q) == %v, want %v", word, err, errLead)
+		}
+	}
+}
+
+func TestTodoBotFormatLead(t *testing.T) {
+	bot := &TodoBot{}
+	cases := map[time.Duration]string{
+		time.Minute:                     "1 minute",
+		14*time.Minute + 30*time.Second: "15 minutes",
+		time.Hour:                       "1 hour",
+		90 * time.Minute:                "2 hours",
+		24 * time.Hour:                  "1 day",
+		3*24*time.Hour + 11*time.Hour:   "3 days",
+	}
+	for lead, want := range cases {
+		if got := bot.FormatLead(lead); got != want {
+			t.Errorf("TodoBot.FormatLead(%v) == %q, want %q", lead, got, want)
+		}
+	}
+	if got := bot.In("th").FormatLead(2 * time.Hour); got != "2 ชั่วโมง" {
+		t.Errorf("TodoBot.In(%q).FormatLead() == %q", "th", got)
+	}
+}
+
+func TestTodoBotAlertCommand(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: time.Now().Add(time.Hour)})
+	bot := newMockBot(todoModel).In("en")
+	bot.Listing("U1")
+	cases := []struct {
+		msg  string
+		want string
+	}{
+		{"alert", "⏰ Your todos are alerted 15 minutes before they are due 🆗"},
+		{"alert 1h 15m", "⏰ Your todos are alerted 15 minutes, 1 hour before they are due 🆗"},
+		{"Alert off", "🔕 Your todos are not alerted before they are due 🆗"},
+		{"alert 1", "🔕 Pay rent is not alerted before it is due 🆗"},
+		{"alert 1 1d", "⏰ Pay rent is alerted 1 day before it is due 🆗"},
+		{"alert default", "⏰ Your todos are alerted 15 minutes before they are due 🆗"},
+		{"alert 1 default", "⏰ Pay rent is alerted 15 minutes before it is due 🆗"},
+		{"alert 1 off", "🔕 Pay rent is not alerted before it is due 🆗"},
+		{"alert 2 1h", "There is no todo 2, send \"list\" to see the numbers"},
+		{"alert soon", `Try "alert 15m 1h", "alert off" or "alert 3 1d" for the todo 3 of the list, up to 7d`},
+	}
+	for _, c := range cases {
+		if reply, ok := bot.AlertCommand("U1", c.msg); !ok || reply != c.want {
+			t.Errorf("TodoBot.AlertCommand(%q) == %q, %v, want %q", c.msg, reply, ok, c.want)
+		}
+	}
+	for _, msg := range []string{"alerting : tomorrow", "alert mom : tomorrow"} {
+		if _, ok := bot.AlertCommand("U1", msg); ok {
+			t.Errorf("TodoBot.AlertCommand(%q) of a task == true", msg)
+		}
+	}
+	if reply, ok := bot.AlertCommand("C1", "alert everyone about the party"); ok {
+		t.Errorf("TodoBot.AlertCommand() of a group chat == %q, want unhandled", reply)
+	}
+	if reply, ok := bot.AlertCommand("C1", "alert 1h"); !ok || reply != "⏰ Your todos are alerted 1 hour before they are due 🆗" {
+		t.Errorf("TodoBot.AlertCommand() in a group == %q, %v", reply, ok)
+	}
+}
+
+func TestTodoBotDueAlerts(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 8, 50, 0, 0, loc)
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: now.Add(10 * time.Minute)})
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Call mom", Due: now.Add(3 * time.Hour)})
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Overdue", Due: now.Add(-time.Hour)})
+	todoModel.Create(model.Todo{UserID: "U2", Task: "Not alerted", Due: now.Add(10 * time.Minute)})
+	todoModel.Create(model.Todo{UserID: "U2", Task: "Alerted a day before", Due: now.Add(20 * time.Hour)})
+	todoModel.SaveAlertLeads("U1", 1, []time.Duration{15 * time.Minute, time.Hour})
+	todoModel.SaveAlertLeads("U2", 0, []time.Duration{})
+	todoModel.SaveAlertLeads("U2", 5, []time.Duration{24 * time.Hour})
+	bot := newMockBot(todoModel)
+
+	// Both leads of Pay rent have passed, it is alerted once
+	alerts, err := bot.DueAlerts(now)
+	if err != nil || len(alerts) != 2 || alerts[0].UserID != "U1" || alerts[0].Todo.Task != "Pay rent" || alerts[1].UserID != "U2" || alerts[1].Todo.Task != "Alerted a day before" {
+		t.Errorf("TodoBot.DueAlerts() == %+v, %v", alerts, err)
+	}
+	// Another instance while they are pushed
+	if alerts, err := newMockBot(todoModel).DueAlerts(now.Add(time.Minute)); err != nil || len(alerts) != 0 {
+		t.Errorf("TodoBot.DueAlerts() again == %+v, %v, want none", alerts, err)
+	}
+	for _, alert := range alerts {
+		bot.deliver(alert.AlertID, now, nil)
+	}
+
+	// Done or moved since it was scheduled
+	todos, _ := todoModel.List("U1")
+	todos[1].Due = now.Add(4 * time.Hour)
+	todoModel.Edit("U1", todos[1])
+	if alerts, err := bot.DueAlerts(now.Add(2*time.Hour + 45*time.Minute)); err != nil || len(alerts) != 0 {
+		t.Errorf("TodoBot.DueAlerts() of a moved todo == %+v, %v, want none", alerts, err)
+	}
+	if alerts, err := bot.DueAlerts(now.Add(3*time.Hour + 45*time.Minute)); err != nil || len(alerts) != 1 || alerts[0].Todo.Task != "Call mom" {
+		t.Errorf("TodoBot.DueAlerts() at the new time == %+v, %v", alerts, err)
+	}
+
+	todoModel.willError = true
+	if _, err := bot.DueAlerts(now); err == nil {
+		t.Errorf("TodoBot.DueAlerts() == %v, want error", err)
+	}
+}
+
+func TestTodoBotDueAlertsAfterQuietHours(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	due := time.Date(2018, 11, 16, 6, 30, 0, 0, loc)
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: due})
+	todoModel.SaveAlertLeads("U1", 0, []time.Duration{15 * time.Minute, time.Hour})
+	todoModel.SaveSetting(model.Setting{UserID: "U1", QuietStart: "22:00", QuietEnd: "07:00"})
+	bot := newMockBot(todoModel)
+
+	for _, now := range []time.Time{due.Add(-9 * time.Hour), due.Add(-time.Hour), due.Add(-15 * time.Minute)} {
+		if alerts, err := bot.DueAlerts(now); err != nil || len(alerts) != 0 {
+			t.Errorf("TodoBot.DueAlerts(%v) in the quiet hours == %+v, %v, want none", now, alerts, err)
+		}
+	}
+	// Due before the quiet hours end, the alert is pushed when they do
+	now := due.Add(30 * time.Minute)
+	alerts, err := bot.DueAlerts(now)
+	if err != nil || len(alerts) != 1 || alerts[0].Todo.Task != "Pay rent" {
+		t.Fatalf("TodoBot.DueAlerts() after the quiet hours == %+v, %v", alerts, err)
+	}
+	if got := alerts[0].bot.In("en").AlertMessage(now, alerts[0].Todo); got != "⏰ Pay rent was due 30 minutes ago, Today at 06:30" {
+		t.Errorf("TodoBot.AlertMessage() after the due date == %q", got)
+	}
+	bot.deliver(alerts[0].AlertID, now, nil)
+	if pending, _ := todoModel.PendingAlerts(now.Add(5 * time.Minute)); len(pending) != 0 {
+		t.Errorf("TodoModel.PendingAlerts() after the alert == %+v, want none", pending)
+	}
+}
+
+func TestTodoBotAlert(t *testing.T) {
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	now := time.Now()
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: now.Add(10 * time.Minute)})
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	// The push fails without a channel, the alert is pushed again by the next runs until it gives up
+	for attempt := 1; attempt <= maxAlertAttempts; attempt++ {
+		if err := bot.Alert(now); err != nil {
+			t.Errorf("TodoBot.Alert() == %v, want %v", err, nil)
+		}
+		if pending, _ := todoModel.PendingAlerts(now); len(pending) != 1 || pending[0].Attempts != attempt {
+			t.Errorf("TodoModel.PendingAlerts() after a failed TodoBot.Alert() == %+v, want it with %d attempts", pending, attempt)
+		}
+	}
+	if err := bot.Alert(now); err != nil {
+		t.Errorf("TodoBot.Alert() == %v, want %v", err, nil)
+	}
+	if pending, _ := todoModel.PendingAlerts(now.Add(time.Hour)); len(pending) != 0 {
+		t.Errorf("TodoModel.PendingAlerts() after the last attempt == %v, want none", pending)
+	}
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	todo := model.Todo{Task: "Pay rent", Due: time.Date(2018, 11, 15, 9, 0, 0, 0, loc)}
+	if got := bot.In("en").AlertMessage(todo.Due.Add(-15*time.Minute), todo); got != "⏰ Pay rent is due in 15 minutes, Today at 09:00" {
+		t.Errorf("TodoBot.AlertMessage() == %q", got)
+	}
+}
@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// DefaultLocale is used when the user has no locale and for the messages missing in other catalogs
+const DefaultLocale = "en"
+
+// Args are the values of the placeholders of a message, e.g. {{.List}}
+type Args map[string]interface{}
+
+// Message has a template for each plural form, a plain string in JSON is the "other" form
+type Message map[string]string
+
+func (this *Message) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*this = Message{"other": text}
+		return nil
+	}
+	forms := map[string]string{}
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return err
+	}
+	*this = Message(forms)
+	return nil
+}
+
+// pluralRules pick the plural form of a count, a catalog names its rule
+var pluralRules = map[string]func(count int) string{
+	// English and most European languages
+	"one_other": func(count int) string {
+		if count == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	// Thai, Chinese and Japanese have no plural
+	"other": func(count int) string {
+		return "other"
+	},
+}
+
+// Catalog is the messages of a locale, one JSON file in the locales directory
+type Catalog struct {
+	Locale string
+	Name   string
+	// Script of the letters that tell the locale of a message, e.g. Thai
+	Script string
+	Plural string
+	// Commands are the messages switching the replies to the locale
+	Commands  []string
+	Weekdays  []string
+	Months    []string
+	Messages  map[string]Message
+	templates map[string]*template.Template
+}
+
+var catalogs = map[string]*Catalog{}
+
+// LoadCatalogs reads every *.json catalog of the directory, English is required
+func LoadCatalogs(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	loaded := map[string]*Catalog{}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		catalog := &Catalog{}
+		if err := json.Unmarshal(data, catalog); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if err := catalog.compile(); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		loaded[catalog.Locale] = catalog
+	}
+	if _, ok := loaded[DefaultLocale]; !ok {
+		return fmt.Errorf("No %s catalog in %s", DefaultLocale, dir)
+	}
+	catalogs = loaded
+	return nil
+}
+
+func (this *Catalog) compile() error {
+	if this.Locale == "" {
+		return fmt.Errorf("No locale")
+	}
+	if _, ok := pluralRules[this.Plural]; !ok {
+		return fmt.Errorf("Unknown plural rule %q", this.Plural)
+	}
+	if len(this.Weekdays) != 7 || len(this.Months) != 12 {
+		return fmt.Errorf("Want 7 weekdays and 12 months")
+	}
+	if this.Script != "" && unicode.Scripts[this.Script] == nil {
+		return fmt.Errorf("Unknown script %q", this.Script)
+	}
+	this.templates = map[string]*template.Template{}
+	for key, message := range this.Messages {
+		for form, text := range message {
+			tmpl, err := template.New(key).Option("missingkey=zero").Parse(text)
+			if err != nil {
+				return err
+			}
+			this.templates[key+"."+form] = tmpl
+		}
+	}
+	return nil
+}
+
+// Locales are the locales of the loaded catalogs
+func Locales() []string {
+	locales := []string{}
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// DetectLocale is the locale of the script of the text, or "" when the letters are Latin or unknown
+func DetectLocale(text string) string {
+	for _, r := range text {
+		for locale, catalog := range catalogs {
+			if catalog.Script != "" && unicode.Is(unicode.Scripts[catalog.Script], r) {
+				return locale
+			}
+		}
+	}
+	return ""
+}
+
+// CommandLocale is the locale the message switches to, e.g. "language th" or "ภาษาไทย"
+func CommandLocale(msg string) (string, bool) {
+	msg = strings.ToLower(strings.Join(strings.Fields(msg), " "))
+	for locale, catalog := range catalogs {
+		if msg == "language "+locale || msg == "language "+strings.ToLower(catalog.Name) {
+			return locale, true
+		}
+		for _, command := range catalog.Commands {
+			if msg == strings.ToLower(command) {
+				return locale, true
+			}
+		}
+	}
+	return "", false
+}
+
+// render uses the English message when the locale has not translated it, and the key when nobody has
+func render(locale string, key string, form string, args Args) string {
+	for _, catalog := range []*Catalog{catalogs[locale], catalogs[DefaultLocale]} {
+		if catalog == nil {
+			continue
+		}
+		tmpl, ok := catalog.templates[key+"."+form]
+		if !ok {
+			tmpl, ok = catalog.templates[key+".other"]
+		}
+		if !ok {
+			continue
+		}
+		var text bytes.Buffer
+		if err := tmpl.Execute(&text, args); err != nil {
+			return key
+		}
+		return text.String()
+	}
+	return key
+}
+
+// T is the message in the locale of the bot
+func (this *TodoBot) T(key string, args Args) string {
+	return render(this.Locale, key, "other", args)
+}
+
+// N is the plural form of the message for the count, which is {{.Count}} in the templates
+func (this *TodoBot) N(key string, count int, args Args) string {
+	form := "other"
+	if catalog, ok := catalogs[this.Locale]; ok {
+		form = pluralRules[catalog.Plural](count)
+	} else if catalog, ok := catalogs[DefaultLocale]; ok {
+		form = pluralRules[catalog.Plural](count)
+	}
+	if args == nil {
+		args = Args{}
+	}
+	args["Count"] = count
+	return render(this.Locale, key, form, args)
+}
+
+// In is a copy of the bot replying in the locale
+func (this *TodoBot) In(locale string) *TodoBot {
+	bot := *this
+	bot.Locale = locale
+	return &bot
+}
+
+// ErrorReply is the reply to a command that failed, the errors the user cannot fix are logged
+func (this *TodoBot) ErrorReply(err error) string {
+	if dueErr, ok := err.(*DueError); ok {
+		return dueErr.Hint(this.Locale)
+	}
+	switch err {
+	case errSnooze:
+		return this.T("wrongSnooze", nil)
+	case errPosition, model.ErrNotFound:
+		return this.T("todoGone", nil)
+	case errLead:
+		return this.T("wrongAlert", nil)
+	case errPostback:
+		return this.T("wrongPostback", nil)
+	case model.ErrForbidden:
+		return this.T("forbidden", nil)
+	case model.ErrListName:
+		return this.T("wrongListName", nil)
+	case model.ErrReminder:
+		return this.T("wrongReminder", nil)
+	}
+	log.Println(err)
+	return this.T("failed", nil)
+}
+
+// dateArgs are the placeholders of the date messages, years are also in Buddhist era
+func (this *TodoBot) dateArgs(date time.Time) Args {
+	catalog, ok := catalogs[this.Locale]
+	if !ok {
+		catalog = catalogs[DefaultLocale]
+	}
+	args := Args{
+		"Time":              date.Format("15:04"),
+		"Day":               date.Day(),
+		"Year":              date.Year(),
+		"ShortYear":         date.Format("06"),
+		"BuddhistYear":      date.Year() + 543,
+		"ShortBuddhistYear": fmt.Sprintf("%02d", (date.Year()+543)%100),
+		"Weekday":           date.Format("Mon"),
+		"Month":             date.Format("Jan"),
+	}
+	if catalog != nil {
+		args["Weekday"] = catalog.Weekdays[date.Weekday()]
+		args["Month"] = catalog.Months[date.Month()-1]
+	}
+	return args
+}
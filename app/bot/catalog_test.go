@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	if err := LoadCatalogs("../locales"); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLoadCatalogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer LoadCatalogs("../locales")
+
+	cases := []struct {
+		files   map[string]string
+		wantErr string
+	}{
+		{map[string]string{}, "No en catalog in " + dir},
+		{map[string]string{"en.json": `{`}, "unexpected end of JSON input"},
+		{map[string]string{"en.json": `{"locale": "en", "plural": "dual", "weekdays": [], "months": []}`}, `Unknown plural rule "dual"`},
+		{map[string]string{"en.json": `{"locale": "en", "plural": "other", "weekdays": [], "months": []}`}, "Want 7 weekdays and 12 months"},
+		{map[string]string{"en.json": `{"locale": "en", "plural": "other", "script": "Klingon", "weekdays": ["1","2","3","4","5","6","7"], "months": ["1","2","3","4","5","6","7","8","9","10","11","12"]}`}, `Unknown script "Klingon"`},
+		{map[string]string{"en.json": `{"locale": "en", "plural": "other", "weekdays": ["1","2","3","4","5","6","7"], "months": ["1","2","3","4","5","6","7","8","9","10","11","12"], "messages": {"hi": "{{.Name"}}`}, "unclosed action"},
+		{map[string]string{"en.json": `{"locale": "en", "plural": "other", "weekdays": ["1","2","3","4","5","6","7"], "months": ["1","2","3","4","5","6","7","8","9","10","11","12"], "messages": {"hi": "Hi {{.Name}}"}}`}, ""},
+	}
+	for _, c := range cases {
+		files, _ := filepath.Glob(filepath.Join(dir, "*"))
+		for _, file := range files {
+			os.Remove(file)
+		}
+		for name, content := range c.files {
+			ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		}
+		err := LoadCatalogs(dir)
+		if (c.wantErr == "" && err != nil) || (c.wantErr != "" && (err == nil || !strings.Contains(err.Error(), c.wantErr))) {
+			t.Errorf("LoadCatalogs(%v) == %v, want %q", c.files, err, c.wantErr)
+		}
+	}
+	if got := (&TodoBot{}).T("hi", Args{"Name": "Choo"}); got != "Hi Choo" {
+		t.Errorf("TodoBot.T(%q) == %q, want %q", "hi", got, "Hi Choo")
+	}
+}
+
+// TestCatalogs checks every locale translates every English message without unknown placeholders
+func TestCatalogs(t *testing.T) {
+	locales := Locales()
+	sort.Strings(locales)
+	if strings.Join(locales, ",") != "en,th" {
+		t.Errorf("Locales() == %v, want en and th", locales)
+	}
+	placeholders := func(text string) []string {
+		words := []string{}
+		for _, word := range strings.Split(text, "{{")[1:] {
+			words = append(words, strings.TrimPrefix(strings.Split(word, "}}")[0], "."))
+		}
+		return words
+	}
+	dateArgs := (&TodoBot{}).dateArgs(time.Now())
+	for _, locale := range locales {
+		for key, message := range catalogs[DefaultLocale].Messages {
+			translated, ok := catalogs[locale].Messages[key]
+			if !ok {
+				t.Errorf("Catalog %q has no %q", locale, key)
+				continue
+			}
+			known := map[string]bool{"Count": true}
+			for _, text := range message {
+				for _, name := range placeholders(text) {
+					known[name] = true
+				}
+			}
+			for _, text := range translated {
+				for _, name := range placeholders(text) {
+					if _, isDate := dateArgs[name]; !known[name] && !(isDate && known["Time"]) {
+						t.Errorf("Catalog %q %q has the unknown {{.%s}}", locale, key, name)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTodoBotT(t *testing.T) {
+	cases := []struct {
+		locale string
+		key    string
+		args   Args
+		want   string
+	}{
+		{"", "created", nil, "Task has been created 🆗"},
+		{"en", "createdIn", Args{"List": "shopping"}, "Task has been created in shopping 🆗"},
+		{"th", "created", nil, "สร้างงานแล้ว 🆗"},
+		{"th", "createdIn", Args{"List": "shopping"}, "สร้างงานใน shopping แล้ว 🆗"},
+		{"en", "edit", Args{"URL": "https://example.com"}, "Please go to https://example.com"},
+		// Unknown locale
+		{"fr", "created", nil, "Task has been created 🆗"},
+		// Unknown message
+		{"th", "dummy", nil, "dummy"},
+	}
+	for _, c := range cases {
+		bot := &TodoBot{}
+		if got := bot.In(c.locale).T(c.key, c.args); got != c.want {
+			t.Errorf("TodoBot.In(%q).T(%q) == %q, want %q", c.locale, c.key, got, c.want)
+		}
+	}
+}
+
+func TestTodoBotN(t *testing.T) {
+	cases := []struct {
+		locale string
+		count  int
+		want   string
+	}{
+		{"en", 1, "(0/1 step)"},
+		{"en", 2, "(0/2 steps)"},
+		{"en", 0, "(0/0 steps)"},
+		{"th", 1, "(0/1 ขั้นตอน)"},
+		{"th", 2, "(0/2 ขั้นตอน)"},
+	}
+	for _, c := range cases {
+		bot := &TodoBot{}
+		if got := bot.In(c.locale).N("steps", c.count, Args{"Done": 0}); got != c.want {
+			t.Errorf("TodoBot.In(%q).N(%q, %d) == %q, want %q", c.locale, "steps", c.count, got, c.want)
+		}
+	}
+}
+
+func TestDetectLocale(t *testing.T) {
+	for text, want := range map[string]string{"ซื้อของ : พรุ่งนี้": "th", "Go shopping : tomorrow": "", "Milk : 25/5/2561": "", "": ""} {
+		if got := DetectLocale(text); got != want {
+			t.Errorf("DetectLocale(%q) == %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestCommandLocale(t *testing.T) {
+	cases := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"language th", "th", true},
+		{"Language  Thai", "th", true},
+		{"ภาษาไทย", "th", true},
+		{"language EN", "en", true},
+		{"language english", "en", true},
+		{"ภาษาอังกฤษ", "en", true},
+		{"language fr", "", false},
+		{"Milk : today", "", false},
+	}
+	for _, c := range cases {
+		if got, ok := CommandLocale(c.in); got != c.want || ok != c.wantOK {
+			t.Errorf("CommandLocale(%q) == %q, %v, want %q, %v", c.in, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func TestTodoBotFormatDateThai(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	// Thursday
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	cases := []struct {
+		in   time.Time
+		want string
+	}{
+		{time.Date(2018, 11, 15, 17, 0, 0, 0, loc), "วันนี้ 17:00 น."},
+		{time.Date(2018, 11, 16, 9, 30, 0, 0, loc), "พรุ่งนี้ 09:30 น."},
+		{time.Date(2018, 11, 14, 9, 30, 0, 0, loc), "เมื่อวาน 09:30 น."},
+		{time.Date(2018, 11, 12, 9, 30, 0, 0, loc), "วันจันทร์ที่แล้ว 09:30 น."},
+		{time.Date(2018, 11, 18, 9, 30, 0, 0, loc), "วันอาทิตย์ 09:30 น."},
+		{time.Date(2018, 11, 23, 9, 30, 0, 0, loc), "วันศุกร์หน้า 09:30 น."},
+		{time.Date(2018, 12, 25, 9, 30, 0, 0, loc), "วันอังคาร 25 ธ.ค. 61 09:30 น."},
+	}
+	bot := (&TodoBot{}).In("th")
+	for _, c := range cases {
+		if got := bot.FormatDate(now, c.in); got != c.want {
+			t.Errorf("TodoBot.In(%q).FormatDate(%v) == %q, want %q", "th", c.in, got, c.want)
+		}
+	}
+}
+
+func TestTodoBotRemindMessageThai(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	bot := (&TodoBot{}).In("th")
+	got := bot.Digest(now, nil, nil)
+	if got != "เยี่ยมมาก ไม่มีงานค้างแล้ว 😎\n" {
+		t.Errorf("TodoBot.In(%q).Digest() == %q", "th", got)
+	}
+}
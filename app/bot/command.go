@@ -0,0 +1,234 @@
+package bot
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// errPosition is a number that is not in the last listing
+var errPosition = errors.New("No todo at the position")
+
+// TaskCommand handles "list", "done 3", "undo 3", "pin 3", "unpin 3", "delete 3", "move 3 to tomorrow", "snooze 3 until tomorrow 9am",
+// "wake 3" and "rename 3 New title",
+// the numbers are the positions in the last listing, it returns false for other messages
+func (this *TodoBot) TaskCommand(userID string, msg string) (string, bool) {
+	fields := strings.Fields(msg)
+	if isList(msg) {
+		return this.ListReply(userID, time.Now()), true
+	}
+	if len(fields) < 2 {
+		return "", false
+	}
+	position, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", false
+	}
+	command := strings.ToLower(fields[0])
+	switch {
+	case len(fields) == 2 && (command == "done" || command == "undo"):
+		return this.DoneReply(userID, position, command == "done"), true
+	case len(fields) == 2 && (command == "pin" || command == "unpin"):
+		return this.PinReply(userID, position, command == "pin"), true
+	case len(fields) == 2 && command == "delete":
+		return this.DeleteReply(userID, position), true
+	case len(fields) > 3 && command == "move" && strings.ToLower(fields[2]) == "to":
+		return this.MoveReply(userID, position, strings.Join(fields[3:], " ")), true
+	case len(fields) > 3 && command == "snooze" && strings.ToLower(fields[2]) == "until":
+		return this.SnoozeReply(userID, position, strings.Join(fields[3:], " ")), true
+	case len(fields) == 2 && command == "wake":
+		return this.WakeReply(userID, position), true
+	case len(fields) > 2 && command == "rename":
+		return this.RenameReply(userID, position, strings.Join(fields[2:], " ")), true
+	}
+	return "", false
+}
+
+// Listing is the report of the todos numbered like the digest orders them, the numbers are kept for the next commands
+func (this *TodoBot) Listing(userID string) (Report, error) {
+	todos, err := this.TodoModel.List(userID)
+	if err != nil {
+		return Report{}, err
+	}
+	// Same order as the digest, remaining first
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := todos[i], todos[j]
+		if a.Done != b.Done {
+			return !a.Done
+		}
+		if a.Pin != b.Pin {
+			return a.Pin
+		}
+		return a.Due.Before(b.Due)
+	})
+	todoIDs := []int{}
+	for _, todo := range todos {
+		todoIDs = append(todoIDs, todo.ID)
+	}
+	if err := this.ListingModel.SaveListing(userID, todoIDs); err != nil {
+		return Report{}, err
+	}
+	return Report{Title: this.T("listing", nil), Todos: todos, Numbered: true, UserID: userID}, nil
+}
+
+// ListReply is the listing in text
+func (this *TodoBot) ListReply(userID string, now time.Time) string {
+	report, err := this.Listing(userID)
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	if len(report.Todos) == 0 {
+		return this.T("emptyListing", nil)
+	}
+	return TextRenderer{}.Text(this, now, report)
+}
+
+// ListMessage is the listing drawn by the renderer of the bot
+func (this *TodoBot) ListMessage(userID string, now time.Time) linebot.SendingMessage {
+	report, err := this.Listing(userID)
+	if err != nil {
+		return linebot.NewTextMessage(this.ErrorReply(err))
+	}
+	if len(report.Todos) == 0 {
+		return linebot.NewTextMessage(this.T("emptyListing", nil))
+	}
+	return this.Message(now, report)
+}
+
+// isList is the "list" command
+func isList(msg string) bool {
+	return strings.ToLower(strings.TrimSpace(msg)) == "list"
+}
+
+// todoAt is the todo at the position of the last listing
+func (this *TodoBot) todoAt(userID string, position int) (model.Todo, error) {
+	todoIDs, err := this.ListingModel.Listing(userID)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	if position < 1 || position > len(todoIDs) {
+		return model.Todo{}, errPosition
+	}
+	todos, err := this.TodoModel.List(userID)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	for _, todo := range todos {
+		if todo.ID == todoIDs[position-1] {
+			return todo, nil
+		}
+	}
+	// Deleted since the listing
+	return model.Todo{}, errPosition
+}
+
+// commandError is the reply to the errors of the commands
+func (this *TodoBot) commandError(position int, err error) string {
+	if err == errPosition || err == model.ErrNotFound {
+		return this.T("noTask", Args{"Number": position})
+	}
+	return this.ErrorReply(err)
+}
+
+func (this *TodoBot) DoneReply(userID string, position int, done bool) string {
+	todo, err := this.todoAt(userID, position)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	reply, err := this.setDone(userID, todo, done)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	return reply
+}
+
+// setDone is shared by the commands and the buttons, see PostbackReply
+func (this *TodoBot) setDone(userID string, todo model.Todo, done bool) (string, error) {
+	todo.Done = done
+	if err := this.TodoModel.Done(userID, todo); err != nil {
+		return "", err
+	}
+	if !done {
+		return this.T("undoneTask", Args{"Task": todo.Task}), nil
+	}
+	this.NotifyDone(userID, todo.ID)
+	return this.T("doneTask", Args{"Task": todo.Task}), nil
+}
+
+func (this *TodoBot) PinReply(userID string, position int, pin bool) string {
+	todo, err := this.todoAt(userID, position)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	reply, err := this.setPin(userID, todo, pin)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	return reply
+}
+
+func (this *TodoBot) setPin(userID string, todo model.Todo, pin bool) (string, error) {
+	todo.Pin = pin
+	if err := this.TodoModel.Pin(userID, todo); err != nil {
+		return "", err
+	}
+	if !pin {
+		return this.T("unpinned", Args{"Task": todo.Task}), nil
+	}
+	return this.T("pinned", Args{"Task": todo.Task}), nil
+}
+
+func (this *TodoBot) DeleteReply(userID string, position int) string {
+	todo, err := this.todoAt(userID, position)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	if err := this.TodoModel.Delete(userID, todo); err != nil {
+		return this.commandError(position, err)
+	}
+	return this.T("deleted", Args{"Task": todo.Task})
+}
+
+// MoveReply changes the due date to the phrase, see ParseDue
+func (this *TodoBot) MoveReply(userID string, position int, phrase string) string {
+	todo, err := this.todoAt(userID, position)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	now := time.Now().In(this.location())
+	due, err := this.ParseDue(now, phrase)
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	reply, err := this.setDue(userID, todo, due, now)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	return reply
+}
+
+func (this *TodoBot) setDue(userID string, todo model.Todo, due time.Time, now time.Time) (string, error) {
+	todo.Due = due
+	if err := this.TodoModel.Edit(userID, todo); err != nil {
+		return "", err
+	}
+	return this.T("moved", Args{"Task": todo.Task, "Due": this.FormatDate(now, due)}), nil
+}
+
+func (this *TodoBot) RenameReply(userID string, position int, task string) string {
+	todo, err := this.todoAt(userID, position)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	old := todo.Task
+	todo.Task = task
+	if err := this.TodoModel.Edit(userID, todo); err != nil {
+		return this.commandError(position, err)
+	}
+	return this.T("renamed", Args{"Old": old, "Task": task})
+}
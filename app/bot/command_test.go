@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestTodoBotTaskCommand(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Now().In(loc)
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: now.AddDate(0, 0, 3)})
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Buy milk", Due: now.AddDate(0, 0, 1), Tags: []string{"home"}})
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Call mom", Due: now.AddDate(0, 0, 2)})
+	todoModel.Done("U1", model.Todo{ID: 3, Done: true})
+	todoModel.Create(model.Todo{UserID: "U2", Task: "Not mine", Due: now})
+	bot := newMockBot(todoModel).In("en")
+
+	// Numbers before any listing
+	if reply, ok := bot.TaskCommand("U1", "done 1"); !ok || reply != `There is no todo 1, send "list" to see the numbers` {
+		t.Errorf("TodoBot.TaskCommand(%q) == %q, %v", "done 1", reply, ok)
+	}
+
+	reply, ok := bot.TaskCommand("U1", "List")
+	if !ok || !strings.Contains(reply, "1. 📆 Buy milk #home") || !strings.Contains(reply, "2. 📆 Pay rent") ||
+		!strings.Contains(reply, "COMPLETED 🆗\n\n3. 📆 Call mom") || strings.Contains(reply, "Not mine") {
+		t.Errorf("TodoBot.TaskCommand(%q) == %q, %v", "list", reply, ok)
+	}
+
+	// The numbers survive a restart of the bot
+	bot = newMockBot(todoModel).In("en")
+	cases := []struct {
+		msg  string
+		want string
+	}{
+		{"pin 2", "⭐️ Pay rent is pinned"},
+		{"unpin 2", "📆 Pay rent is not pinned anymore"},
+		{"done 1", "✅ Buy milk is done"},
+		{"undo 3", "📆 Call mom is to be done again"},
+		{"rename 2 Pay the rent", "✏️ Pay rent is renamed to Pay the rent"},
+		{"move 3 to next friday 9am", "📆 Call mom is due "},
+		{"move 3 to someday", `I don't understand "someday", try "next friday 5pm" or "25 May"`},
+		{"delete 2", "🗑 Pay the rent is deleted"},
+		{"done 2", `There is no todo 2, send "list" to see the numbers`},
+		{"done 4", `There is no todo 4, send "list" to see the numbers`},
+		{"done 0", `There is no todo 0, send "list" to see the numbers`},
+	}
+	for _, c := range cases {
+		reply, ok := bot.TaskCommand("U1", c.msg)
+		if !ok || !strings.HasPrefix(reply, c.want) {
+			t.Errorf("TodoBot.TaskCommand(%q) == %q, %v, want %q", c.msg, reply, ok, c.want)
+		}
+	}
+
+	todos, _ := todoModel.List("U1")
+	for _, todo := range todos {
+		switch todo.Task {
+		case "Buy milk":
+			if !todo.Done || len(todo.Tags) != 1 {
+				t.Errorf("TodoBot.TaskCommand(%q) todo == %#v", "done 1", todo)
+			}
+		case "Call mom":
+			if todo.Done || todo.Due.In(loc).Weekday() != time.Friday || todo.Due.In(loc).Hour() != 9 {
+				t.Errorf("TodoBot.TaskCommand(%q) todo == %#v", "move 3 to next friday 9am", todo)
+			}
+		default:
+			t.Errorf("TodoBot.TaskCommand(%q) left %#v", "delete 2", todo)
+		}
+	}
+
+	// Another user has their own numbers
+	if reply, _ := bot.TaskCommand("U2", "done 1"); !strings.HasPrefix(reply, "There is no todo 1") {
+		t.Errorf("TodoBot.TaskCommand(%q) of another user == %q", "done 1", reply)
+	}
+
+	// Not commands
+	for _, msg := range []string{"list of things", "done shopping", "done 1 2", "move 1 tomorrow", "rename 1", "pin", "Buy milk : today", "list : today"} {
+		if reply, ok := bot.TaskCommand("U1", msg); ok {
+			t.Errorf("TodoBot.TaskCommand(%q) == %q, want unhandled", msg, reply)
+		}
+	}
+}
+
+func TestTodoBotTaskCommandEmpty(t *testing.T) {
+	bot := newMockBot(newMockTodoModel())
+	if reply, ok := bot.TaskCommand("U1", "list"); !ok || reply != `You have no todos, create one like "Go shopping : tomorrow 5pm"` {
+		t.Errorf("TodoBot.TaskCommand(%q) == %q, %v", "list", reply, ok)
+	}
+	if reply, _ := bot.In("th").TaskCommand("U1", "delete 1"); reply != `ไม่มีงานหมายเลข 1 ส่ง "list" เพื่อดูหมายเลขงาน` {
+		t.Errorf("TodoBot.In(%q).TaskCommand(%q) == %q", "th", "delete 1", reply)
+	}
+}
+
+func TestTodoBotResponseTaskCommand(t *testing.T) {
+	wantErr := errors.New("linebot: APIError 400 Invalid reply token")
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Buy milk", Due: time.Now()})
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	for _, text := range []string{"list", "done 1"} {
+		events := []*linebot.Event{{
+			Type:       linebot.EventTypeMessage,
+			Message:    &linebot.TextMessage{Text: text},
+			Source:     &linebot.EventSource{UserID: "U1"},
+			ReplyToken: "dummy",
+		}}
+		if err := bot.Response(events); err == nil || err.Error() != wantErr.Error() {
+			t.Errorf("TodoBot.Response(%q) == %v, want %v", text, err, wantErr)
+		}
+	}
+	// Handled as commands, not created as todos
+	todos, _ := todoModel.List("U1")
+	if len(todos) != 1 || !todos[0].Done {
+		t.Errorf("TodoModel.List() after %q == %#v", "done 1", todos)
+	}
+}
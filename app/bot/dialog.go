@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// The questions of the "add" dialog, in order
+const (
+	dialogTask = "task"
+	dialogDue  = "due"
+	dialogPin  = "pin"
+)
+
+// dialogTimeout forgets a dialog the user left, their next message is handled as usual
+const dialogTimeout = 10 * time.Minute
+
+var (
+	dialogStarts  = []string{"add", "new", "เพิ่ม", "เพิ่มงาน"}
+	dialogCancels = []string{"cancel", "stop", "ยกเลิก"}
+	dialogYeses   = []string{"yes", "y", "pin", "ใช่", "ปักหมุด"}
+	dialogNos     = []string{"no", "n", "ไม่", "ไม่ปักหมุด"}
+)
+
+func isWord(msg string, words []string) bool {
+	msg = strings.ToLower(strings.TrimSpace(msg))
+	for _, word := range words {
+		if msg == word {
+			return true
+		}
+	}
+	return false
+}
+
+// DialogMessage asks for the task, its due date and whether to pin it one question at a time, "add" starts and "cancel" stops it.
+// It is false when the user is in no dialog, in a group everyone talks so there is none.
+func (this *TodoBot) DialogMessage(userID string, msg string, now time.Time) (linebot.SendingMessage, bool) {
+	if IsGroupID(userID) {
+		return nil, false
+	}
+	dialog, err := this.DialogModel.Dialog(userID)
+	if err == nil && now.Sub(dialog.Updated) > dialogTimeout {
+		this.DialogModel.DeleteDialog(userID)
+		err = model.ErrNotFound
+	}
+	if err == model.ErrNotFound {
+		if !isWord(msg, dialogStarts) {
+			return nil, false
+		}
+		dialog = model.Dialog{UserID: userID, State: dialogTask}
+		return this.ask(dialog, now), true
+	} else if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	if isWord(msg, dialogCancels) {
+		if err := this.DialogModel.DeleteDialog(userID); err != nil {
+			return linebot.NewTextMessage(this.ErrorReply(err)), true
+		}
+		return linebot.NewTextMessage(this.T("dialogCancelled", nil)), true
+	}
+
+	loc := this.location()
+	switch dialog.State {
+	case dialogTask:
+		if !this.isDraft(msg) {
+			// "Buy milk : tomorrow" answers two questions
+			_, text := this.ParseListName(msg)
+			todo, err := this.ParseUserMessage(text)
+			if _, ok := err.(*DueError); ok {
+				// The task is kept and the next answer is its due date
+				dialog.Text = strings.TrimSpace(msg[:strings.Index(msg, " : ")])
+				dialog.State = dialogDue
+				return this.hint(dialog, now, err), true
+			} else if err != nil {
+				return this.ask(dialog, now), true
+			}
+			dialog.Text = strings.TrimSpace(msg[:strings.Index(msg, " : ")])
+			dialog.Due = todo.Due
+			dialog.Repeat = todo.Repeat
+			dialog.State = dialogPin
+		} else {
+			dialog.Text = strings.TrimSpace(msg)
+			dialog.State = dialogDue
+		}
+	case dialogDue:
+		due, err := this.ParseDue(now.In(loc), msg)
+		if _, ok := err.(*DueError); ok {
+			return this.hint(dialog, now, err), true
+		} else if err != nil {
+			return linebot.NewTextMessage(this.ErrorReply(err)), true
+		}
+		dialog.Due = due
+		dialog.State = dialogPin
+	case dialogPin:
+		if !isWord(msg, dialogYeses) && !isWord(msg, dialogNos) {
+			return this.ask(dialog, now), true
+		}
+		if err := this.DialogModel.DeleteDialog(userID); err != nil {
+			return linebot.NewTextMessage(this.ErrorReply(err)), true
+		}
+		listName, text := this.ParseListName(dialog.Text)
+		task, tags := this.ParseTags(text)
+		todo := model.Todo{UserID: userID, Task: task, Tags: tags, Due: dialog.Due, Repeat: dialog.Repeat, Pin: isWord(msg, dialogYeses)}
+		return linebot.NewTextMessage(this.CreateTodo(todo, listName)), true
+	default:
+		// A state of another version of the bot
+		this.DialogModel.DeleteDialog(userID)
+		return nil, false
+	}
+	return this.ask(dialog, now), true
+}
+
+// ask saves the dialog and asks its question
+func (this *TodoBot) ask(dialog model.Dialog, now time.Time) linebot.SendingMessage {
+	dialog.Updated = now
+	if err := this.DialogModel.SaveDialog(dialog); err != nil {
+		return linebot.NewTextMessage(this.ErrorReply(err))
+	}
+	_, text := this.ParseListName(dialog.Text)
+	task, _ := this.ParseTags(text)
+	switch dialog.State {
+	case dialogDue:
+		buttons := []*linebot.QuickReplyButton{}
+		for _, choice := range this.dueChoices(now.In(this.location())) {
+			buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewMessageAction(this.T(choice.label, nil), choice.phrase)))
+		}
+		return this.question(this.T("askDue", Args{"Task": task}), append(buttons, this.cancelButton())...)
+	case dialogPin:
+		return this.question(this.T("askPin", Args{"Task": task, "Due": this.FormatDate(now, dialog.Due)}),
+			linebot.NewQuickReplyButton("", linebot.NewMessageAction(this.T("yesButton", nil), "yes")),
+			linebot.NewQuickReplyButton("", linebot.NewMessageAction(this.T("noButton", nil), "no")),
+			this.cancelButton())
+	}
+	return this.question(this.T("askTask", nil), this.cancelButton())
+}
+
+// hint saves the dialog and replies how to fix the due date instead of asking again
+func (this *TodoBot) hint(dialog model.Dialog, now time.Time, err error) linebot.SendingMessage {
+	dialog.Updated = now
+	if err := this.DialogModel.SaveDialog(dialog); err != nil {
+		return linebot.NewTextMessage(this.ErrorReply(err))
+	}
+	return this.question(this.ErrorReply(err), this.cancelButton())
+}
+
+func (this *TodoBot) question(text string, buttons ...*linebot.QuickReplyButton) linebot.SendingMessage {
+	return linebot.NewTextMessage(text).WithQuickReplies(linebot.NewQuickReplyItems(buttons...))
+}
+
+func (this *TodoBot) cancelButton() *linebot.QuickReplyButton {
+	return linebot.NewQuickReplyButton("", linebot.NewMessageAction(this.T("cancelButton", nil), "cancel"))
+}
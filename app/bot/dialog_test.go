@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// dialogBots are two instances of the bot sharing a model, like after a restart or behind a load balancer
+func dialogBots() (*mockTodoModel, []*TodoBot) {
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	bots := []*TodoBot{newMockBot(todoModel), newMockBot(todoModel)}
+	for _, bot := range bots {
+		bot.Client = client
+	}
+	return todoModel, bots
+}
+
+func say(bot *TodoBot, userID string, text string) error {
+	return bot.Response([]*linebot.Event{{
+		Type:       linebot.EventTypeMessage,
+		Message:    &linebot.TextMessage{Text: text},
+		Source:     &linebot.EventSource{Type: linebot.EventSourceTypeUser, UserID: userID},
+		ReplyToken: "dummy",
+	}})
+}
+
+func TestTodoBotResponseDialog(t *testing.T) {
+	wantErr := "linebot: APIError 400 Invalid reply token"
+	todoModel, bots := dialogBots()
+	steps := []struct {
+		text  string
+		state string
+	}{
+		{"add", dialogTask},
+		{"help", dialogTask},
+		{"@Shopping Buy milk #home", dialogDue},
+		{"someday", dialogDue},
+		{"tomorrow 5pm", dialogPin},
+		{"maybe", dialogPin},
+		{"yes", ""},
+	}
+	for i, step := range steps {
+		// Every answer goes to another instance
+		if err := say(bots[i%2], "U1", step.text); err == nil || err.Error() != wantErr {
+			t.Errorf("TodoBot.Response(%q) == %v, want %v", step.text, err, wantErr)
+		}
+		dialog, _ := todoModel.Dialog("U1")
+		if dialog.State != step.state {
+			t.Errorf("TodoBot.Response(%q) dialog state == %q, want %q", step.text, dialog.State, step.state)
+		}
+	}
+	todos, _ := todoModel.List("U1")
+	if len(todos) != 1 || todos[0].Task != "Buy milk" || !todos[0].Pin || strings.Join(todos[0].Tags, ",") != "home" || todos[0].ListID == 0 ||
+		todos[0].Due.In(bots[0].location()).Hour() != 17 {
+		t.Fatalf("TodoModel.List() after the dialog == %#v", todos)
+	}
+
+	// Both questions in one answer, not pinned
+	for _, text := range []string{"new", "Call mom : tonight", "no"} {
+		say(bots[0], "U1", text)
+	}
+	todos, _ = todoModel.List("U1")
+	if len(todos) != 2 || todos[1].Task != "Call mom" || todos[1].Pin {
+		t.Errorf("TodoModel.List() after the dialog == %#v", todos)
+	}
+
+	// Cancelled at any question
+	for _, answers := range [][]string{{"add", "cancel"}, {"add", "Buy bread", "Cancel"}, {"เพิ่ม", "Buy bread", "tomorrow", "ยกเลิก"}} {
+		for _, text := range answers {
+			say(bots[1], "U1", text)
+		}
+		if _, err := todoModel.Dialog("U1"); err != model.ErrNotFound {
+			t.Errorf("TodoModel.Dialog() after %q == %v, want %v", answers, err, model.ErrNotFound)
+		}
+		if todos, _ := todoModel.List("U1"); len(todos) != 2 {
+			t.Errorf("TodoModel.List() after %q == %#v", answers, todos)
+		}
+	}
+
+	// Other users and groups are not in the dialog
+	say(bots[0], "U1", "add")
+	say(bots[0], "U2", "Buy milk : today")
+	if todos, _ := todoModel.List("U2"); len(todos) != 1 {
+		t.Errorf("TodoModel.List(%q) during the dialog of %q == %#v", "U2", "U1", todos)
+	}
+	group := []*linebot.Event{{
+		Type:       linebot.EventTypeMessage,
+		Message:    &linebot.TextMessage{Text: "add"},
+		Source:     &linebot.EventSource{Type: linebot.EventSourceTypeGroup, UserID: "U3", GroupID: "C1"},
+		ReplyToken: "dummy",
+	}}
+	if err := bots[0].Response(group); err != nil {
+		t.Errorf("TodoBot.Response(%q) in a group == %v, want %v", "add", err, nil)
+	}
+	if _, err := todoModel.Dialog("C1"); err != model.ErrNotFound {
+		t.Errorf("TodoModel.Dialog(%q) == %v, want %v", "C1", err, model.ErrNotFound)
+	}
+}
+
+func TestTodoBotResponseDialogTimeout(t *testing.T) {
+	todoModel, bots := dialogBots()
+	todoModel.SaveDialog(model.Dialog{UserID: "U1", State: dialogPin, Text: "Buy milk", Due: time.Now(), Updated: time.Now().Add(-dialogTimeout - time.Minute)})
+	// Handled as usual
+	say(bots[0], "U1", "Pay rent : tomorrow")
+	if _, err := todoModel.Dialog("U1"); err != model.ErrNotFound {
+		t.Errorf("TodoModel.Dialog() after the timeout == %v, want %v", err, model.ErrNotFound)
+	}
+	if todos, _ := todoModel.List("U1"); len(todos) != 1 || todos[0].Task != "Pay rent" {
+		t.Errorf("TodoModel.List() after the timeout == %#v", todos)
+	}
+
+	// An answer in time keeps it going
+	todoModel.SaveDialog(model.Dialog{UserID: "U1", State: dialogDue, Text: "Buy milk", Updated: time.Now().Add(-dialogTimeout + time.Minute)})
+	say(bots[1], "U1", "tonight")
+	if dialog, _ := todoModel.Dialog("U1"); dialog.State != dialogPin || time.Since(dialog.Updated) > time.Minute {
+		t.Errorf("TodoModel.Dialog() after an answer == %#v", dialog)
+	}
+}
+
+func TestTodoBotDialogMessage(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 13, 0, 0, 0, loc)
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel).In("en")
+	if _, ok := bot.DialogMessage("U1", "Buy milk", now); ok {
+		t.Errorf("TodoBot.DialogMessage(%q) is handled before %q", "Buy milk", "add")
+	}
+	cases := []struct {
+		text    string
+		want    string
+		buttons string
+	}{
+		{"add", "What's the task?", "Cancel"},
+		{"Buy milk #home", "When is Buy milk due?", "Tonight,Tomorrow,Next week,Cancel"},
+		{"someday", `I don't understand "someday", try "next friday 5pm" or "25 May"`, "Cancel"},
+		{"tomorrow 9am", "Pin Buy milk due Tomorrow at 09:00?", "Yes,No,Cancel"},
+		{"no", "Task has been created 🆗", ""},
+		// A due date that is not understood is asked again, a repeat rule is kept
+		{"add", "What's the task?", "Cancel"},
+		{"Call mom : someday", `I don't understand "someday", try "next friday 5pm" or "25 May"`, "Cancel"},
+		{"tomorrow 9am", "Pin Call mom due Tomorrow at 09:00?", "Yes,No,Cancel"},
+		{"no", "Task has been created 🆗", ""},
+		{"add", "What's the task?", "Cancel"},
+		{"Pay rent : every month on 1st : 09:00", "Pin Pay rent due ", "Yes,No,Cancel"},
+		{"yes", "Task has been created 🆗", ""},
+	}
+	for _, c := range cases {
+		message, ok := bot.DialogMessage("U1", c.text, now)
+		data, _ := json.Marshal(message)
+		var reply struct {
+			Text       string
+			QuickReply struct {
+				Items []struct {
+					Action struct {
+						Label string
+					}
+				}
+			}
+		}
+		json.Unmarshal(data, &reply)
+		labels := []string{}
+		for _, item := range reply.QuickReply.Items {
+			labels = append(labels, item.Action.Label)
+		}
+		if !ok || !strings.HasPrefix(reply.Text, c.want) || strings.Join(labels, ",") != c.buttons {
+			t.Errorf("TodoBot.DialogMessage(%q) == %s, %v, want %q with %q", c.text, data, ok, c.want, c.buttons)
+		}
+	}
+	todos, _ := todoModel.List("U1")
+	if len(todos) != 3 || !todos[2].Pin || todos[2].Repeat != "FREQ=MONTHLY;BYMONTHDAY=1" || todos[1].Task != "Call mom" {
+		t.Errorf("TodoModel.List() after the dialogs == %+v", todos)
+	}
+
+	// In Thai
+	bot = bot.In("th")
+	for _, c := range []struct {
+		text string
+		want string
+	}{
+		{"เพิ่มงาน", "งานอะไร?"},
+		{"ยกเลิก", "ยกเลิกแล้ว ไม่ได้สร้างงาน"},
+	} {
+		message, _ := bot.DialogMessage("U1", c.text, now)
+		data, _ := json.Marshal(message)
+		if !strings.Contains(string(data), c.want) {
+			t.Errorf("TodoBot.In(%q).DialogMessage(%q) == %s, want %q", "th", c.text, data, c.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// The quick replies to a draft, their data has the time of the draft instead of a todo ID, see PostbackData
+const (
+	PostbackDraftToday    = "draft.today"
+	PostbackDraftTonight  = "draft.tonight"
+	PostbackDraftTomorrow = "draft.tomorrow"
+	PostbackDraftNextWeek = "draft.nextweek"
+	// The datetime picker
+	PostbackDraftPick = "draft.pick"
+)
+
+type dueChoice struct {
+	action string
+	phrase string
+	label  string
+}
+
+// draftChoices are the due phrases of the quick replies, see ParseDue
+var draftChoices = []dueChoice{
+	{PostbackDraftToday, "today", "todayButton"},
+	{PostbackDraftTonight, "tonight", "tonightButton"},
+	{PostbackDraftTomorrow, "tomorrow", "tomorrowButton"},
+	{PostbackDraftNextWeek, "next week", "nextWeekButton"},
+}
+
+// pickerFormat is the format of the datetime picker, in the time zone of the user
+const pickerFormat = "2006-01-02T15:04"
+
+// isDraft is a task without a due date, "Buy milk" rather than "Buy milk : today"
+func (this *TodoBot) isDraft(msg string) bool {
+	if isHelp(msg) || strings.Contains(msg, " : ") {
+		return false
+	}
+	_, text := this.ParseListName(msg)
+	task, _ := this.ParseTags(text)
+	return task != ""
+}
+
+// DraftMessage keeps the task until the user answers when it is due with a quick reply
+func (this *TodoBot) DraftMessage(userID string, msg string, now time.Time) linebot.SendingMessage {
+	draft := model.Draft{
+		UserID:  userID,
+		Text:    strings.TrimSpace(msg),
+		Created: now,
+	}
+	if err := this.DraftModel.SaveDraft(draft); err != nil {
+		return linebot.NewTextMessage(this.ErrorReply(err))
+	}
+	draftID := int(now.Unix())
+	now = now.In(this.location())
+	buttons := []*linebot.QuickReplyButton{}
+	for _, choice := range this.dueChoices(now) {
+		label := this.T(choice.label, nil)
+		buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewPostbackAction(label, this.PostbackData(userID, choice.action, draftID), "", label)))
+	}
+	initial := now.Add(time.Hour).Truncate(time.Hour)
+	buttons = append(buttons, linebot.NewQuickReplyButton("", linebot.NewDatetimePickerAction(this.T("pickButton", nil),
+		this.PostbackData(userID, PostbackDraftPick, draftID), "datetime", initial.Format(pickerFormat), "", now.Format(pickerFormat))))
+
+	_, text := this.ParseListName(draft.Text)
+	task, _ := this.ParseTags(text)
+	return linebot.NewTextMessage(this.T("draftDue", Args{"Task": task})).WithQuickReplies(linebot.NewQuickReplyItems(buttons...))
+}
+
+// dueChoices are the quick replies still to come, today is gone after noon and tonight after 8pm
+func (this *TodoBot) dueChoices(now time.Time) []dueChoice {
+	choices := []dueChoice{}
+	for _, choice := range draftChoices {
+		if due, err := this.ParseDue(now, choice.phrase); err == nil && !due.Before(now) {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// DraftReply creates the todo of the draft when it is due, datetime is the answer of the picker
+func (this *TodoBot) DraftReply(userID string, action string, draftID int, datetime string, now time.Time) string {
+	draft, err := this.DraftModel.Draft(userID)
+	if err == model.ErrNotFound || (err == nil && int(draft.Created.Unix()) != draftID) {
+		// Answered already, or replaced by a newer draft
+		return this.T("draftGone", nil)
+	} else if err != nil {
+		return this.ErrorReply(err)
+	}
+	loc := this.location()
+	now = now.In(loc)
+	var due time.Time
+	if action == PostbackDraftPick {
+		due, err = time.ParseInLocation(pickerFormat, datetime, loc)
+	} else {
+		err = errPostback
+		for _, choice := range draftChoices {
+			if choice.action == action {
+				due, err = this.ParseDue(now, choice.phrase)
+			}
+		}
+	}
+	if err != nil {
+		return this.T("wrongPostback", nil)
+	}
+	listName, text := this.ParseListName(draft.Text)
+	task, tags := this.ParseTags(text)
+	deleted, err := this.DraftModel.DeleteDraft(draft)
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	if !deleted {
+		// Answered meanwhile, like a double tap or a retried webhook
+		return this.T("draftGone", nil)
+	}
+	return this.CreateTodo(model.Todo{UserID: userID, Task: task, Due: due, Tags: tags}, listName)
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestTodoBotDraftMessage(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	bot := newMockBot(newMockTodoModel()).In("en")
+	cases := []struct {
+		now  time.Time
+		want []string
+	}{
+		{time.Date(2018, 11, 15, 10, 20, 0, 0, loc), []string{"Today", "Tonight", "Tomorrow", "Next week", "Pick a date"}},
+		{time.Date(2018, 11, 15, 13, 0, 0, 0, loc), []string{"Tonight", "Tomorrow", "Next week", "Pick a date"}},
+		{time.Date(2018, 11, 15, 21, 0, 0, 0, loc), []string{"Tomorrow", "Next week", "Pick a date"}},
+	}
+	for _, c := range cases {
+		data, _ := json.Marshal(bot.DraftMessage("U1", "@Shopping Buy milk #home", c.now))
+		var message struct {
+			Text       string
+			QuickReply struct {
+				Items []struct {
+					Action struct {
+						Type    string
+						Label   string
+						Initial string
+						Min     string
+					}
+				}
+			}
+		}
+		json.Unmarshal(data, &message)
+		labels := []string{}
+		for _, item := range message.QuickReply.Items {
+			labels = append(labels, item.Action.Label)
+		}
+		if message.Text != `When is Buy milk due? Pick one below or send it again like "Buy milk : tomorrow 5pm"` || strings.Join(labels, ",") != strings.Join(c.want, ",") {
+			t.Errorf("TodoBot.DraftMessage() at %v == %s", c.now, data)
+		}
+		if picker := message.QuickReply.Items[len(message.QuickReply.Items)-1].Action; picker.Type != "datetimepicker" || picker.Min != c.now.Format(pickerFormat) {
+			t.Errorf("TodoBot.DraftMessage() picker == %#v", picker)
+		}
+	}
+	if draft, err := bot.DraftModel.Draft("U1"); err != nil || draft.Text != "@Shopping Buy milk #home" {
+		t.Errorf("TodoModel.Draft() == %#v, %v", draft, err)
+	}
+}
+
+func TestTodoBotDraftReply(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 10, 20, 0, 0, loc)
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel).In("en")
+	answer := func(action string, draftID int, datetime string) string {
+		postback := &linebot.Postback{Data: bot.PostbackData("U1", action, draftID)}
+		if datetime != "" {
+			postback.Params = &linebot.Params{Datetime: datetime}
+		}
+		return bot.PostbackReply("U1", postback, now)
+	}
+	draftID := int(now.Unix())
+	cases := []struct {
+		msg    string
+		action string
+		want   time.Time
+	}{
+		{"Buy milk", PostbackDraftToday, time.Date(2018, 11, 15, 12, 0, 0, 0, loc)},
+		{"Buy bread", PostbackDraftTonight, time.Date(2018, 11, 15, 20, 0, 0, 0, loc)},
+		{"Pay rent #home", PostbackDraftTomorrow, time.Date(2018, 11, 16, 12, 0, 0, 0, loc)},
+		{"Call mom", PostbackDraftNextWeek, time.Date(2018, 11, 19, 12, 0, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		bot.DraftMessage("U1", c.msg, now)
+		if reply := answer(c.action, draftID, ""); reply != "Task has been created 🆗" {
+			t.Errorf("TodoBot.PostbackReply(%q) of %q == %q", c.action, c.msg, reply)
+		}
+		todos, _ := todoModel.List("U1")
+		if todo := todos[len(todos)-1]; !todo.Due.Equal(c.want) {
+			t.Errorf("TodoBot.PostbackReply(%q) created %#v, want due %v", c.action, todo, c.want)
+		}
+	}
+
+	// Answered already
+	if reply := answer(PostbackDraftToday, draftID, ""); reply != "This task is created already or replaced by a newer one" {
+		t.Errorf("TodoBot.PostbackReply() of an answered draft == %q", reply)
+	}
+
+	// The picker, a newer draft replaces the older one
+	bot.DraftMessage("U1", "@Shopping Buy eggs", now)
+	bot.DraftMessage("U1", "@Shopping Buy butter #dairy", now.Add(time.Minute))
+	if reply := answer(PostbackDraftPick, draftID, "2018-11-20T08:30"); reply != "This task is created already or replaced by a newer one" {
+		t.Errorf("TodoBot.PostbackReply() of a replaced draft == %q", reply)
+	}
+	if reply := answer(PostbackDraftPick, draftID+60, "tomorrow"); reply != `This button does not work anymore, send "list" to see your todos` {
+		t.Errorf("TodoBot.PostbackReply() of a wrong datetime == %q", reply)
+	}
+	if reply := answer(PostbackDraftPick, draftID+60, "2018-11-20T08:30"); reply != "Task has been created in Shopping 🆗" {
+		t.Errorf("TodoBot.PostbackReply(%q) == %q", PostbackDraftPick, reply)
+	}
+	todos, _ := todoModel.List("U1")
+	if todo := todos[len(todos)-1]; todo.Task != "Buy butter" || strings.Join(todo.Tags, ",") != "dairy" || todo.ListID == 0 ||
+		!todo.Due.Equal(time.Date(2018, 11, 20, 8, 30, 0, 0, loc)) {
+		t.Errorf("TodoBot.PostbackReply(%q) created %#v", PostbackDraftPick, todo)
+	}
+
+	// Another tap answers it between reading and deleting the draft
+	bot.DraftModel = answeredDraftModel{bot.DraftModel}
+	bot.DraftMessage("U1", "Buy eggs", now)
+	if reply := answer(PostbackDraftToday, draftID, ""); reply != "This task is created already or replaced by a newer one" {
+		t.Errorf("TodoBot.PostbackReply() of a draft answered meanwhile == %q", reply)
+	}
+	if todos, _ := todoModel.List("U1"); len(todos) != 5 {
+		t.Errorf("TodoBot.PostbackReply() of a draft answered meanwhile created a todo, %d todos", len(todos))
+	}
+}
+
+// answeredDraftModel is a draft answered by another tap or a retried webhook before DeleteDraft
+type answeredDraftModel struct {
+	model.DraftModel
+}
+
+func (this answeredDraftModel) DeleteDraft(draft model.Draft) (bool, error) {
+	this.DraftModel.DeleteDraft(draft)
+	return this.DraftModel.DeleteDraft(draft)
+}
+
+func TestTodoBotResponseDraft(t *testing.T) {
+	wantErr := errors.New("linebot: APIError 400 Invalid reply token")
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	message := func(source *linebot.EventSource, text string) []*linebot.Event {
+		return []*linebot.Event{{
+			Type:       linebot.EventTypeMessage,
+			Message:    &linebot.TextMessage{Text: text},
+			Source:     source,
+			ReplyToken: "dummy",
+		}}
+	}
+	user := &linebot.EventSource{Type: linebot.EventSourceTypeUser, UserID: "U1"}
+	if err := bot.Response(message(user, "Buy milk")); err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("TodoBot.Response(%q) == %v, want %v", "Buy milk", err, wantErr)
+	}
+	if draft, err := todoModel.Draft("U1"); err != nil || draft.Text != "Buy milk" {
+		t.Errorf("TodoModel.Draft() after %q == %#v, %v", "Buy milk", draft, err)
+	}
+	if todos, _ := todoModel.List("U1"); len(todos) != 0 {
+		t.Errorf("TodoModel.List() after %q == %#v, want no todo yet", "Buy milk", todos)
+	}
+
+	// Help and wrong due dates are not drafts
+	for _, text := range []string{"help", "Buy bread : someday"} {
+		bot.Response(message(user, text))
+		if draft, _ := todoModel.Draft("U1"); draft.Text != "Buy milk" {
+			t.Errorf("TodoBot.Response(%q) saved the draft %#v", text, draft)
+		}
+	}
+
+	// Chat in a group
+	group := &linebot.EventSource{Type: linebot.EventSourceTypeGroup, UserID: "U1", GroupID: "C1"}
+	if err := bot.Response(message(group, "Hello everyone")); err != nil {
+		t.Errorf("TodoBot.Response(%q) in a group == %v, want %v", "Hello everyone", err, nil)
+	}
+	if _, err := todoModel.Draft("C1"); err != model.ErrNotFound {
+		t.Errorf("TodoModel.Draft(%q) == %v, want %v", "C1", err, model.ErrNotFound)
+	}
+}
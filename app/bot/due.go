@@ -0,0 +1,407 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DueError tells the user how to fix a due date that cannot be understood, Key is the hint in the catalogs
+type DueError struct {
+	Key  string
+	Args Args
+}
+
+// Error is the hint in English, see TodoBot.ErrorReply for the locale of the user
+func (this *DueError) Error() string {
+	return this.Hint(DefaultLocale)
+}
+
+// Hint is the hint in the locale, a time.Month argument is the month name of the locale
+func (this *DueError) Hint(locale string) string {
+	catalog, ok := catalogs[locale]
+	if !ok {
+		catalog = catalogs[DefaultLocale]
+	}
+	args := Args{}
+	for name, arg := range this.Args {
+		if month, ok := arg.(time.Month); ok && catalog != nil {
+			arg = catalog.Months[month-1]
+		}
+		args[name] = arg
+	}
+	return render(locale, this.Key, "other", args)
+}
+
+func dueHint(key string, args Args) error {
+	return &DueError{Key: key, Args: args}
+}
+
+var monthNames = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+const (
+	defaultDueHour = 12
+	tonightDueHour = 20
+)
+
+type dueParser struct {
+	now      time.Time
+	today    time.Time
+	phrase   string
+	fields   []string
+	date     time.Time
+	hasDate  bool
+	hour     int
+	minute   int
+	fallback int
+	exact    time.Time
+}
+
+// ParseDue understands the due date of a message relative to now
+// 1) today, tonight, tomorrow, 25/5/18, 25/5
+// 2) friday, this friday, next friday, next week, next month, end of week, end of month
+// 3) in 3 days, in 2 weeks, in 2 hours, in 30 minutes
+// 4) 25 May, May 25th, 25 May 2019
+// 5) any of them with a time: 5pm, 5:30pm, 5 pm, 17:30, noon, at 9am
+// 6) the same in Thai, see thaiDue, with Buddhist-era years like 25/5/2561
+// "next friday" is the Friday of next week like "Next Fri" in the digest,
+// a date without a year is the next one to come and a time alone is today, or tomorrow if it has passed
+func (this *TodoBot) ParseDue(now time.Time, phrase string) (time.Time, error) {
+	parser := dueParser{
+		now:      now,
+		today:    time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		phrase:   strings.TrimSpace(phrase),
+		fields:   strings.Fields(strings.ToLower(strings.Replace(thaiDue(phrase), ",", " ", -1))),
+		hour:     -1,
+		fallback: defaultDueHour,
+	}
+	return parser.parse()
+}
+
+func (this *dueParser) parse() (time.Time, error) {
+	if len(this.fields) == 0 {
+		return time.Time{}, dueHint("dueMissing", nil)
+	}
+	for i := 0; i < len(this.fields); i++ {
+		used, err := this.parseWord(this.fields[i], this.fields[i+1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		i += used - 1
+	}
+	if !this.exact.IsZero() {
+		if this.hasDate || this.hour >= 0 {
+			return time.Time{}, dueHint("dueAfterIn", Args{"Phrase": this.phrase})
+		}
+		return this.exact, nil
+	}
+	if !this.hasDate && this.hour < 0 {
+		return time.Time{}, dueHint("dueMissing", nil)
+	}
+	if !this.hasDate {
+		this.date = this.today
+		if this.at(this.today).Before(this.now) {
+			this.date = this.today.AddDate(0, 0, 1)
+		}
+	}
+	return this.at(this.date), nil
+}
+
+func (this *dueParser) at(date time.Time) time.Time {
+	hour, minute := this.hour, this.minute
+	if hour < 0 {
+		hour, minute = this.fallback, 0
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, date.Location())
+}
+
+// parseWord returns the number of words used
+func (this *dueParser) parseWord(word string, rest []string) (int, error) {
+	next := ""
+	if len(rest) > 0 {
+		next = rest[0]
+	}
+	switch word {
+	case "at", "on", "by", "the":
+		return 1, nil
+	case "today":
+		return 1, this.setDate(this.today)
+	case "tonight":
+		this.fallback = tonightDueHour
+		return 1, this.setDate(this.today)
+	case "tomorrow", "tmr":
+		return 1, this.setDate(this.today.AddDate(0, 0, 1))
+	case "noon":
+		return 1, this.setClock(12, 0)
+	case "this", "next":
+		return 2, this.parseNext(word, next)
+	case "in":
+		return this.parseIn(rest)
+	case "end":
+		return this.parseEnd(rest)
+	}
+	if weekday, ok := weekdayNames[word]; ok {
+		return 1, this.setDate(this.upcoming(weekday))
+	}
+	if month, ok := monthNames[word]; ok {
+		if day, ok := parseMonthDayNumber(next); ok {
+			used, err := this.setMonthDay(day, month, rest[1:])
+			return used + 2, err
+		}
+		return 1, dueHint("dueWhichDay", Args{"Month": month})
+	}
+	if strings.Contains(word, "/") {
+		return 1, this.parseSlashDate(word)
+	}
+	if hour, minute, used, err := parseClock(word, next); err != nil || used > 0 {
+		if err != nil {
+			return 0, err
+		}
+		return used, this.setClock(hour, minute)
+	}
+	if day, ok := parseMonthDayNumber(word); ok {
+		// 1st of March
+		of := 0
+		if next == "of" && len(rest) > 1 {
+			of, next = 1, rest[1]
+		}
+		if month, ok := monthNames[next]; ok {
+			used, err := this.setMonthDay(day, month, rest[of+1:])
+			return used + of + 2, err
+		}
+		return 1, dueHint("dueDateOrTime", Args{"Word": word, "Day": day})
+	}
+	return 1, dueHint("dueUnknown", Args{"Word": word})
+}
+
+func (this *dueParser) setDate(date time.Time) error {
+	if this.hasDate {
+		return dueHint("dueTwoDates", Args{"Phrase": this.phrase})
+	}
+	this.date, this.hasDate = date, true
+	return nil
+}
+
+func (this *dueParser) setClock(hour int, minute int) error {
+	if this.hour >= 0 {
+		return dueHint("dueTwoTimes", Args{"Phrase": this.phrase})
+	}
+	this.hour, this.minute = hour, minute
+	return nil
+}
+
+// upcoming is the weekday of this week from today
+func (this *dueParser) upcoming(weekday time.Weekday) time.Time {
+	return this.today.AddDate(0, 0, (int(weekday)-int(this.today.Weekday())+7)%7)
+}
+
+// nextWeek is the weekday of the ISO week after this one
+func (this *dueParser) nextWeek(weekday time.Weekday) time.Time {
+	monday := this.today.AddDate(0, 0, 7-(int(this.today.Weekday())+6)%7)
+	return monday.AddDate(0, 0, (int(weekday)+6)%7)
+}
+
+func (this *dueParser) parseNext(word string, next string) error {
+	if weekday, ok := weekdayNames[next]; ok {
+		if word == "next" {
+			return this.setDate(this.nextWeek(weekday))
+		}
+		return this.setDate(this.upcoming(weekday))
+	}
+	if word == "next" && next == "week" {
+		return this.setDate(this.nextWeek(time.Monday))
+	}
+	if word == "next" && next == "month" {
+		return this.setDate(time.Date(this.today.Year(), this.today.Month()+1, 1, 0, 0, 0, 0, this.today.Location()))
+	}
+	return dueHint("dueAfterWord", Args{"Word": word})
+}
+
+// in 3 days, in a week, in 2 hours, in 30 minutes
+func (this *dueParser) parseIn(rest []string) (int, error) {
+	hint := dueHint("dueHowLong", nil)
+	if len(rest) < 2 {
+		return 0, hint
+	}
+	count, err := strconv.Atoi(rest[0])
+	if rest[0] == "a" || rest[0] == "an" {
+		count, err = 1, nil
+	}
+	if err != nil || count < 1 {
+		return 0, hint
+	}
+	switch strings.TrimSuffix(rest[1], "s") {
+	case "minute", "min":
+		this.exact = this.now.Add(time.Duration(count) * time.Minute).Truncate(time.Minute)
+	case "hour", "hr":
+		this.exact = this.now.Add(time.Duration(count) * time.Hour).Truncate(time.Minute)
+	case "day":
+		err = this.setDate(this.today.AddDate(0, 0, count))
+	case "week":
+		err = this.setDate(this.today.AddDate(0, 0, 7*count))
+	case "month":
+		err = this.setDate(this.today.AddDate(0, count, 0))
+	default:
+		return 0, hint
+	}
+	return 3, err
+}
+
+// end of week is Sunday, end of month is its last day
+func (this *dueParser) parseEnd(rest []string) (int, error) {
+	used := 1
+	if len(rest) > 0 && rest[0] == "of" {
+		rest = rest[1:]
+		used++
+	}
+	if len(rest) > 0 && rest[0] == "the" {
+		rest = rest[1:]
+		used++
+	}
+	if len(rest) > 0 && rest[0] == "week" {
+		return used + 1, this.setDate(this.nextWeek(time.Monday).AddDate(0, 0, -1))
+	}
+	if len(rest) > 0 && rest[0] == "month" {
+		return used + 1, this.setDate(time.Date(this.today.Year(), this.today.Month()+1, 0, 0, 0, 0, 0, this.today.Location()))
+	}
+	return 0, dueHint("dueEndOf", nil)
+}
+
+// setMonthDay uses the year in rest if any, it returns the number of words used in rest
+func (this *dueParser) setMonthDay(day int, month time.Month, rest []string) (int, error) {
+	if len(rest) > 0 && len(rest[0]) == 4 {
+		if year, err := strconv.Atoi(rest[0]); err == nil {
+			return 1, this.setYearMonthDay(year, month, day)
+		}
+	}
+	return 0, this.setYearMonthDay(0, month, day)
+}
+
+// setYearMonthDay takes the next date to come when the year is 0
+func (this *dueParser) setYearMonthDay(year int, month time.Month, day int) error {
+	guess := year == 0
+	if year > 2400 {
+		// Buddhist era, 2561 is 2018
+		year -= 543
+	}
+	if guess {
+		year = this.today.Year()
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, this.today.Location())
+	if date.Day() != day || date.Month() != month {
+		return dueHint("dueNoDay", Args{"Day": day, "Month": month})
+	}
+	if guess && date.Before(this.today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return this.setDate(date)
+}
+
+// 25/5/18, 25/5/2018 or 25/5
+func (this *dueParser) parseSlashDate(word string) error {
+	hint := dueHint("dueNotDate", Args{"Word": word})
+	parts := strings.Split(word, "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		return hint
+	}
+	numbers := []int{}
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return hint
+		}
+		numbers = append(numbers, number)
+	}
+	if numbers[1] < 1 || numbers[1] > 12 {
+		return dueHint("dueNoMonth", Args{"Number": numbers[1], "Word": word})
+	}
+	year := 0
+	if len(numbers) == 3 {
+		year = numbers[2]
+		if len(parts[2]) == 2 {
+			year += 2000
+		} else if len(parts[2]) != 4 {
+			return hint
+		}
+	}
+	return this.setYearMonthDay(year, time.Month(numbers[1]), numbers[0])
+}
+
+// parseMonthDayNumber reads 25, 25th, 1st, 2nd or 3rd
+func parseMonthDayNumber(word string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		word = strings.TrimSuffix(word, suffix)
+	}
+	day, err := strconv.Atoi(word)
+	if err != nil || day < 1 || day > 31 {
+		return 0, false
+	}
+	return day, true
+}
+
+// parseClock reads 17:30, 5pm, 5:30pm or 5 pm, it returns 0 words used when the word is not a time
+func parseClock(word string, next string) (int, int, int, error) {
+	if word == "" || word[0] < '0' || word[0] > '9' {
+		return 0, 0, 0, nil
+	}
+	suffix := ""
+	for _, s := range []string{"am", "pm"} {
+		if strings.HasSuffix(word, s) {
+			suffix = s
+			word = strings.TrimSuffix(word, s)
+		}
+	}
+	used := 1
+	if suffix == "" && (next == "am" || next == "pm") {
+		suffix = next
+		used = 2
+	}
+	if suffix == "" && !strings.Contains(word, ":") {
+		return 0, 0, 0, nil
+	}
+	hint := dueHint("dueNotTime", Args{"Word": word + suffix})
+	parts := strings.Split(word, ":")
+	if len(parts) > 2 {
+		return 0, 0, 0, hint
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, 0, hint
+	}
+	minute := 0
+	if len(parts) == 2 {
+		if len(parts[1]) != 2 {
+			return 0, 0, 0, hint
+		}
+		if minute, err = strconv.Atoi(parts[1]); err != nil || minute > 59 {
+			return 0, 0, 0, hint
+		}
+	}
+	if suffix == "" {
+		if hour < 0 || hour > 23 {
+			return 0, 0, 0, hint
+		}
+		return hour, minute, used, nil
+	}
+	if hour < 1 || hour > 12 {
+		return 0, 0, 0, dueHint("dueAmPm", Args{"Word": word + suffix, "Hour": hour, "Hour12": hour % 12})
+	}
+	hour %= 12
+	if suffix == "pm" {
+		hour += 12
+	}
+	return hour, minute, used, nil
+}
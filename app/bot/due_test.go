@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTodoBotParseDue(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	// Thursday
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	cases := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		// Days
+		{in: "today", want: "2018-11-15 12:00"},
+		{in: "Today 15:30", want: "2018-11-15 15:30"},
+		{in: "tonight", want: "2018-11-15 20:00"},
+		{in: "tonight 9pm", want: "2018-11-15 21:00"},
+		{in: "tomorrow", want: "2018-11-16 12:00"},
+		{in: "tmr 8am", want: "2018-11-16 08:00"},
+		{in: "tomorrow 5pm", want: "2018-11-16 17:00"},
+		{in: "tomorrow at 5:30pm", want: "2018-11-16 17:30"},
+		{in: "tomorrow 5 pm", want: "2018-11-16 17:00"},
+		{in: "tomorrow 12am", want: "2018-11-16 00:00"},
+		{in: "tomorrow 12pm", want: "2018-11-16 12:00"},
+		{in: "tomorrow noon", want: "2018-11-16 12:00"},
+		// Day/month/year
+		{in: "25/5/18", want: "2018-05-25 12:00"},
+		{in: "2/1/06 15:04", want: "2006-01-02 15:04"},
+		{in: "25/5/2019 13:00", want: "2019-05-25 13:00"},
+		{in: "25/12", want: "2018-12-25 12:00"},
+		{in: "1/1", want: "2019-01-01 12:00"},
+		{in: "15/11", want: "2018-11-15 12:00"},
+		// Weekdays
+		{in: "friday", want: "2018-11-16 12:00"},
+		{in: "Thu", want: "2018-11-15 12:00"},
+		{in: "wed", want: "2018-11-21 12:00"},
+		{in: "on sunday 9am", want: "2018-11-18 09:00"},
+		{in: "this friday 9am", want: "2018-11-16 09:00"},
+		{in: "next friday", want: "2018-11-23 12:00"},
+		{in: "next monday 17:30", want: "2018-11-19 17:30"},
+		{in: "next week", want: "2018-11-19 12:00"},
+		{in: "next month", want: "2018-12-01 12:00"},
+		{in: "end of week", want: "2018-11-18 12:00"},
+		{in: "end of the week 6pm", want: "2018-11-18 18:00"},
+		{in: "end of month", want: "2018-11-30 12:00"},
+		// Relative
+		{in: "in 3 days", want: "2018-11-18 12:00"},
+		{in: "in 3 days 9am", want: "2018-11-18 09:00"},
+		{in: "in 1 day", want: "2018-11-16 12:00"},
+		{in: "in a week", want: "2018-11-22 12:00"},
+		{in: "in 2 weeks", want: "2018-11-29 12:00"},
+		{in: "in 1 month", want: "2018-12-15 12:00"},
+		{in: "in 2 hours", want: "2018-11-15 12:00"},
+		{in: "in an hour", want: "2018-11-15 11:00"},
+		{in: "in 30 minutes", want: "2018-11-15 10:30"},
+		{in: "in 90 mins", want: "2018-11-15 11:30"},
+		// Month names
+		{in: "25 May", want: "2019-05-25 12:00"},
+		{in: "25 dec", want: "2018-12-25 12:00"},
+		{in: "December 25th 8pm", want: "2018-12-25 20:00"},
+		{in: "25th May 2019", want: "2019-05-25 12:00"},
+		{in: "1st of march", want: "2019-03-01 12:00"},
+		{in: "May 1, 2020 at 9:15am", want: "2020-05-01 09:15"},
+		{in: "29 feb 2020", want: "2020-02-29 12:00"},
+		// Times alone
+		{in: "5pm", want: "2018-11-15 17:00"},
+		{in: "17:30", want: "2018-11-15 17:30"},
+		{in: "at 9am", want: "2018-11-16 09:00"},
+		{in: "noon", want: "2018-11-15 12:00"},
+		{in: "0:00", want: "2018-11-16 00:00"},
+		// Errors
+		{in: "", wantErr: `When is it due? Try "tomorrow 5pm"`},
+		{in: "at", wantErr: `When is it due? Try "tomorrow 5pm"`},
+		{in: "someday", wantErr: `I don't understand "someday", try "next friday 5pm" or "25 May"`},
+		{in: "tomorrow spam", wantErr: `I don't understand "spam", try "next friday 5pm" or "25 May"`},
+		{in: "5", wantErr: `Is "5" a date or a time? Try "5pm" or "5 May"`},
+		{in: "friday 5", wantErr: `Is "5" a date or a time? Try "5pm" or "5 May"`},
+		{in: "25:04", wantErr: `"25:04" is not a time, try "17:30" or "5:30pm"`},
+		{in: "17:5", wantErr: `"17:5" is not a time, try "17:30" or "5:30pm"`},
+		{in: "12:60", wantErr: `"12:60" is not a time, try "17:30" or "5:30pm"`},
+		{in: "13pm", wantErr: `"13pm" is not a time, use "1pm" or "13:00" but not both`},
+		{in: "32/1/06", wantErr: "There is no 32 Jan"},
+		{in: "30 feb", wantErr: "There is no 30 Feb"},
+		{in: "29 feb 2019", wantErr: "There is no 29 Feb"},
+		{in: "5/13/18", wantErr: `There is no month 13 in "5/13/18", dates are day/month/year like "25/5/18"`},
+		{in: "5/1/218", wantErr: `"5/1/218" is not a date, try "25/5/18"`},
+		{in: "5/x", wantErr: `"5/x" is not a date, try "25/5/18"`},
+		{in: "may", wantErr: `Which day of May? Try "25 May"`},
+		{in: "tomorrow friday", wantErr: `"tomorrow friday" has more than one date, try "next friday 5pm"`},
+		{in: "5pm 6pm", wantErr: `"5pm 6pm" has more than one time, try "tomorrow 17:30"`},
+		{in: "in 3", wantErr: `How long from now? Try "in 3 days" or "in 2 hours"`},
+		{in: "in 3 years", wantErr: `How long from now? Try "in 3 days" or "in 2 hours"`},
+		{in: "in 0 days", wantErr: `How long from now? Try "in 3 days" or "in 2 hours"`},
+		{in: "in 2 hours 5pm", wantErr: `"in 2 hours 5pm" has a date or time after "in", try "in 2 hours" or "in 3 days 9am"`},
+		{in: "next", wantErr: `What comes after "next"? Try "next friday" or "next week"`},
+		{in: "this year", wantErr: `What comes after "this"? Try "this friday" or "next week"`},
+		{in: "end of year", wantErr: `The end of what? Try "end of week" or "end of month"`},
+	}
+
+	bot := TodoBot{}
+	for _, c := range cases {
+		got, err := bot.ParseDue(now, c.in)
+		if c.wantErr != "" {
+			if _, ok := err.(*DueError); !ok || err.Error() != c.wantErr {
+				t.Errorf("TodoBot.ParseDue(%q) == %v, want %v", c.in, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Format("2006-01-02 15:04") != c.want || got.Location() != loc {
+			t.Errorf("TodoBot.ParseDue(%q) == %v, %v, want %v", c.in, got, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// LINE limits of a Flex Message, the text renderer takes over above them
+const (
+	maxFlexBubbles = 10
+	maxFlexBytes   = 30000
+	maxAltText     = 400
+)
+
+var errFlexTooLarge = errors.New("The report is too large for a Flex Message")
+
+// Due badge colors, text on background
+var (
+	buttonColor   = "#1E88E5"
+	overdueColors = [2]string{"#D32F2F", "#FDECEA"}
+	todayColors   = [2]string{"#E65100", "#FFF3E0"}
+	doneColors    = [2]string{"#9E9E9E", "#F5F5F5"}
+	dueColors     = [2]string{"#455A64", "#ECEFF1"}
+)
+
+// The components of the Flex Message JSON, see https://developers.line.biz/en/reference/messaging-api/#flex-message
+type flexCarousel struct {
+	Type     string       `json:"type"`
+	Contents []flexBubble `json:"contents"`
+}
+
+type flexBubble struct {
+	Type   string   `json:"type"`
+	Header *flexBox `json:"header,omitempty"`
+	Body   *flexBox `json:"body"`
+	Footer *flexBox `json:"footer,omitempty"`
+}
+
+type flexBox struct {
+	Type            string        `json:"type"`
+	Layout          string        `json:"layout"`
+	Contents        []interface{} `json:"contents"`
+	Flex            *int          `json:"flex,omitempty"`
+	Spacing         string        `json:"spacing,omitempty"`
+	Margin          string        `json:"margin,omitempty"`
+	BackgroundColor string        `json:"backgroundColor,omitempty"`
+	CornerRadius    string        `json:"cornerRadius,omitempty"`
+	PaddingAll      string        `json:"paddingAll,omitempty"`
+}
+
+type flexText struct {
+	Type       string      `json:"type"`
+	Text       string      `json:"text"`
+	Flex       *int        `json:"flex,omitempty"`
+	Size       string      `json:"size,omitempty"`
+	Weight     string      `json:"weight,omitempty"`
+	Color      string      `json:"color,omitempty"`
+	Decoration string      `json:"decoration,omitempty"`
+	Align      string      `json:"align,omitempty"`
+	Wrap       bool        `json:"wrap,omitempty"`
+	Action     *flexAction `json:"action,omitempty"`
+}
+
+type flexAction struct {
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	Data  string `json:"data"`
+}
+
+type flexSeparator struct {
+	Type   string `json:"type"`
+	Margin string `json:"margin,omitempty"`
+}
+
+func flex(n int) *int {
+	return &n
+}
+
+// FlexRenderer draws a bubble per section, pinned todos are bold and due dates are colored badges
+type FlexRenderer struct{}
+
+func (this FlexRenderer) Render(bot *TodoBot, now time.Time, report Report) (linebot.SendingMessage, error) {
+	contents, err := this.Contents(bot, now, report)
+	if err != nil {
+		return nil, err
+	}
+	container, err := linebot.UnmarshalFlexMessageJSON(contents)
+	if err != nil {
+		return nil, err
+	}
+	return linebot.NewFlexMessage(this.AltText(bot, now, report), container), nil
+}
+
+// AltText is the text of the notifications and of the clients without Flex
+func (this FlexRenderer) AltText(bot *TodoBot, now time.Time, report Report) string {
+	text := []rune(TextRenderer{}.Text(bot, now, report))
+	if len(text) > maxAltText {
+		return string(text[:maxAltText-1]) + "…"
+	}
+	return string(text)
+}
+
+// Contents is the JSON of the bubble, or of the carousel when there are more sections
+func (this FlexRenderer) Contents(bot *TodoBot, now time.Time, report Report) ([]byte, error) {
+	remaining := []model.Todo{}
+	completed := []model.Todo{}
+	for _, todo := range report.Todos {
+		if todo.Done {
+			completed = append(completed, todo)
+		} else {
+			remaining = append(remaining, todo)
+		}
+	}
+	bubbles := []flexBubble{}
+	if report.Numbered {
+		// One bubble so the numbers read in order
+		rows := this.rows(bot, now, report, remaining, 1)
+		if len(completed) > 0 {
+			rows = append(rows, flexSeparator{Type: "separator", Margin: "lg"}, this.title(bot.T("completed", nil), "sm"))
+			rows = append(rows, this.rows(bot, now, report, completed, len(remaining)+1)...)
+		}
+		bubbles = append(bubbles, this.bubble(report.Title, rows))
+	} else {
+		if len(remaining) == 0 {
+			bubbles = append(bubbles, this.bubble(bot.T("allDone", nil), nil))
+		}
+		for _, section := range bot.Sections(remaining, report.Lists) {
+			bubbles = append(bubbles, this.bubble(section.Title, this.rows(bot, now, report, section.Todos, 0)))
+		}
+		if len(completed) > 0 {
+			bubbles = append(bubbles, this.bubble(bot.T("completed", nil), this.rows(bot, now, report, completed, 0)))
+		}
+		// The summary and the footer close the last bubble
+		footer := []interface{}{}
+		if len(remaining) > 0 {
+			footer = append(footer, flexText{Type: "text", Text: bot.N("remaining", len(remaining), Args{"Total": len(report.Todos)}), Size: "sm", Weight: "bold", Wrap: true})
+		}
+		if report.Footer != "" {
+			footer = append(footer, flexText{Type: "text", Text: report.Footer, Size: "xs", Color: "#9E9E9E", Wrap: true})
+		}
+		if len(footer) > 0 {
+			bubbles[len(bubbles)-1].Footer = &flexBox{Type: "box", Layout: "vertical", Spacing: "sm", Contents: footer}
+		}
+	}
+	if len(bubbles) > maxFlexBubbles {
+		return nil, errFlexTooLarge
+	}
+	var contents interface{} = bubbles[0]
+	if len(bubbles) > 1 {
+		contents = flexCarousel{Type: "carousel", Contents: bubbles}
+	}
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFlexBytes {
+		return nil, errFlexTooLarge
+	}
+	return data, nil
+}
+
+func (this FlexRenderer) title(text string, size string) flexText {
+	return flexText{Type: "text", Text: text, Size: size, Weight: "bold", Wrap: true}
+}
+
+func (this FlexRenderer) bubble(title string, rows []interface{}) flexBubble {
+	if len(rows) == 0 {
+		// A bubble without a body is not valid
+		return flexBubble{Type: "bubble", Body: &flexBox{Type: "box", Layout: "vertical", Contents: []interface{}{this.title(title, "md")}}}
+	}
+	return flexBubble{
+		Type:   "bubble",
+		Header: &flexBox{Type: "box", Layout: "vertical", Contents: []interface{}{this.title(title, "md")}},
+		Body:   &flexBox{Type: "box", Layout: "vertical", Spacing: "md", Contents: rows},
+	}
+}
+
+// rows are numbered from first, or not numbered when it is 0
+func (this FlexRenderer) rows(bot *TodoBot, now time.Time, report Report, todos []model.Todo, first int) []interface{} {
+	rows := []interface{}{}
+	for i, todo := range todos {
+		number := 0
+		if first > 0 {
+			number = first + i
+		}
+		rows = append(rows, this.row(bot, now, report, todo, number))
+	}
+	return rows
+}
+
+// row is the icon, the task and the badges of the due date, the repeat and the steps below, then the buttons of a remaining todo
+func (this FlexRenderer) row(bot *TodoBot, now time.Time, report Report, todo model.Todo, number int) flexBox {
+	icon := "📆"
+	if todo.Done {
+		icon = "✅"
+	} else if todo.Pin {
+		icon = "⭐️"
+	}
+	task := todo.Task
+	for _, tag := range todo.Tags {
+		task += " #" + tag
+	}
+	if number > 0 {
+		task = fmt.Sprintf("%d. %s", number, task)
+	}
+	taskText := flexText{Type: "text", Text: task, Size: "sm", Wrap: true}
+	if todo.Pin && !todo.Done {
+		taskText.Weight = "bold"
+	}
+	if todo.Done {
+		taskText.Color = doneColors[0]
+		taskText.Decoration = "line-through"
+	}
+
+	due := bot.FormatDate(now, todo.Due)
+	colors := dueColors
+	loc := bot.location()
+	switch {
+	case todo.Done:
+		colors = doneColors
+	case now.After(todo.Due):
+		colors = overdueColors
+		due += " " + bot.T("overdue", nil)
+	case now.In(loc).Format("2006-01-02") == todo.Due.In(loc).Format("2006-01-02"):
+		colors = todayColors
+	}
+	badges := []interface{}{
+		flexBox{Type: "box", Layout: "vertical", Flex: flex(0), BackgroundColor: colors[1], CornerRadius: "md", PaddingAll: "xs",
+			Contents: []interface{}{flexText{Type: "text", Text: due, Size: "xxs", Color: colors[0]}}},
+	}
+	if todo.Repeat != "" {
+		badges = append(badges, flexText{Type: "text", Text: "🔁", Flex: flex(0), Size: "xxs"})
+	}
+	if done, total := todo.Progress(); total > 0 {
+		badges = append(badges, flexText{Type: "text", Text: bot.N("steps", total, Args{"Done": done}), Flex: flex(0), Size: "xxs", Color: dueColors[0]})
+	}
+	if snoozed := bot.SnoozedBadge(now, todo); snoozed != "" {
+		badges = append(badges, flexText{Type: "text", Text: snoozed, Flex: flex(0), Size: "xxs", Color: doneColors[0]})
+	}
+	details := []interface{}{
+		taskText,
+		flexBox{Type: "box", Layout: "horizontal", Spacing: "sm", Contents: badges},
+	}
+	if report.UserID != "" && !todo.Done {
+		details = append(details, this.buttons(bot, now, report.UserID, todo))
+	}
+	return flexBox{
+		Type:    "box",
+		Layout:  "horizontal",
+		Spacing: "sm",
+		Contents: []interface{}{
+			flexText{Type: "text", Text: icon, Flex: flex(0), Size: "sm"},
+			flexBox{Type: "box", Layout: "vertical", Spacing: "xs", Contents: details},
+		},
+	}
+}
+
+// buttons send the postback events of PostbackReply, texts rather than button components keep the rows compact
+func (this FlexRenderer) buttons(bot *TodoBot, now time.Time, userID string, todo model.Todo) flexBox {
+	pin, pinLabel := PostbackPin, bot.T("pinButton", nil)
+	if todo.Pin {
+		pin, pinLabel = PostbackUnpin, bot.T("unpinButton", nil)
+	}
+	snooze, snoozeLabel := PostbackSnooze, bot.T("snoozeButton", nil)
+	if todo.IsSnoozed(now) {
+		snooze, snoozeLabel = PostbackWake, bot.T("wakeButton", nil)
+	}
+	actions := []struct {
+		action string
+		label  string
+	}{
+		{PostbackDone, bot.T("doneButton", nil)},
+		{pin, pinLabel},
+		{snooze, snoozeLabel},
+		{PostbackTomorrow, bot.T("tomorrowButton", nil)},
+	}
+	contents := []interface{}{}
+	for _, action := range actions {
+		contents = append(contents, flexText{Type: "text", Text: action.label, Size: "xxs", Color: buttonColor, Align: "center",
+			Action: &flexAction{Type: "postback", Label: action.label, Data: bot.PostbackData(userID, action.action, todo.ID)}})
+	}
+	return flexBox{Type: "box", Layout: "horizontal", Spacing: "sm", Margin: "sm", Contents: contents}
+}
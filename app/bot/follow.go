@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"log"
+	"time"
+)
+
+// Welcome greets a user who follows the bot or a group it joins, the ones coming back are reminded again and find their todos
+func (this *TodoBot) Welcome(userID string) string {
+	back, err := this.TodoModel.Activate(userID)
+	if err != nil {
+		log.Println(err)
+	}
+	if back {
+		return this.T("welcomeBack", nil)
+	}
+	if IsGroupID(userID) {
+		return this.T("groupWelcome", nil) + this.T("groupHowto", nil)
+	}
+	return this.T("welcome", nil) + this.T("howto", nil)
+}
+
+// PurgeInactive deletes the data of the users who blocked the bot, and of the groups it left, longer than the retention ago
+func (this *TodoBot) PurgeInactive(now time.Time, retention time.Duration) error {
+	userIDs, err := this.TodoModel.InactiveUsers(now.Add(-retention))
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		// The todo model last, the user stays inactive until everything is purged
+		for _, purge := range []func(string) error{this.ListingModel.Purge, this.DraftModel.Purge, this.DialogModel.Purge, this.AlertModel.Purge, this.TodoModel.Purge} {
+			if err := purge(userID); err != nil {
+				return err
+			}
+		}
+	}
+	if len(userIDs) > 0 {
+		log.Printf("Purged %d inactive users", len(userIDs))
+	}
+	return nil
+}
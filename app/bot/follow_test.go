@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestTodoBotWelcome(t *testing.T) {
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel).In("en")
+	if got := bot.Welcome("U1"); !strings.HasPrefix(got, bot.T("welcome", nil)) || !strings.HasSuffix(got, bot.T("howto", nil)) {
+		t.Errorf("TodoBot.Welcome(%q) == %q, want the onboarding", "U1", got)
+	}
+	if got := bot.Welcome("C1"); got != bot.T("groupWelcome", nil)+bot.T("groupHowto", nil) {
+		t.Errorf("TodoBot.Welcome(%q) == %q, want the group onboarding", "C1", got)
+	}
+	todoModel.Deactivate("U1", time.Now())
+	if got := bot.Welcome("U1"); got != `Welcome back! Your todos are still here, send "list" to see them` {
+		t.Errorf("TodoBot.Welcome(%q) after an unfollow == %q", "U1", got)
+	}
+	if userIDs, _ := todoModel.InactiveUsers(time.Now().Add(time.Hour)); len(userIDs) != 0 {
+		t.Errorf("TodoBot.Welcome() left %v inactive", userIDs)
+	}
+}
+
+func TestTodoBotResponseFollow(t *testing.T) {
+	wantErr := "linebot: APIError 400 Invalid reply token"
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Buy milk", Due: time.Now()})
+	todoModel.Create(model.Todo{UserID: "C1", Task: "Book a room", Due: time.Now()})
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	event := func(eventType linebot.EventType, source *linebot.EventSource) []*linebot.Event {
+		return []*linebot.Event{{Type: eventType, Source: source, ReplyToken: "dummy"}}
+	}
+	user := &linebot.EventSource{Type: linebot.EventSourceTypeUser, UserID: "U1"}
+	group := &linebot.EventSource{Type: linebot.EventSourceTypeGroup, UserID: "U1", GroupID: "C1"}
+	reminded := func() string {
+		userTodos, _ := todoModel.remindAll()
+		userIDs := []string{}
+		for _, userID := range []string{"C1", "U1"} {
+			if len(userTodos[userID]) > 0 {
+				userIDs = append(userIDs, userID)
+			}
+		}
+		return strings.Join(userIDs, ",")
+	}
+
+	if err := bot.Response(event(linebot.EventTypeFollow, user)); err == nil || err.Error() != wantErr {
+		t.Errorf("TodoBot.Response(follow) == %v, want %v", err, wantErr)
+	}
+
+	// No reply token, nothing is sent
+	if err := bot.Response(event(linebot.EventTypeUnfollow, user)); err != nil {
+		t.Errorf("TodoBot.Response(unfollow) == %v, want %v", err, nil)
+	}
+	if got := reminded(); got != "C1" {
+		t.Errorf("TodoModel.Remind() after an unfollow of %q == %v", "U1", got)
+	}
+	if err := bot.Response(event(linebot.EventTypeLeave, group)); err != nil {
+		t.Errorf("TodoBot.Response(leave) == %v, want %v", err, nil)
+	}
+	if got := reminded(); got != "" {
+		t.Errorf("TodoModel.Remind() after leaving %q == %v", "C1", got)
+	}
+
+	// Back again, the todos are kept
+	bot.Response(event(linebot.EventTypeFollow, user))
+	bot.Response(event(linebot.EventTypeJoin, group))
+	if got := reminded(); got != "C1,U1" {
+		t.Errorf("TodoModel.Remind() after a follow and a join == %v", got)
+	}
+}
+
+func TestTodoBotPurgeInactive(t *testing.T) {
+	now := time.Now()
+	todoModel := newMockTodoModel()
+	for _, userID := range []string{"U1", "U2", "U3"} {
+		todoModel.Create(model.Todo{UserID: userID, Task: "Buy milk", Due: now})
+	}
+	todoModel.Deactivate("U1", now.AddDate(0, 0, -31))
+	todoModel.Deactivate("U2", now.AddDate(0, 0, -29))
+	todoModel.SaveDraft(model.Draft{UserID: "U1", Text: "Buy bread", Created: now})
+	todoModel.SaveListing("U1", []int{1})
+	bot := newMockBot(todoModel)
+	if err := bot.PurgeInactive(now, 30*24*time.Hour); err != nil {
+		t.Errorf("TodoBot.PurgeInactive() == %v, want %v", err, nil)
+	}
+	for userID, want := range map[string]int{"U1": 0, "U2": 1, "U3": 1} {
+		if todos, _ := todoModel.List(userID); len(todos) != want {
+			t.Errorf("TodoModel.List(%q) after TodoBot.PurgeInactive() == %v, want %d todos", userID, todos, want)
+		}
+	}
+	if userIDs, _ := todoModel.InactiveUsers(now); len(userIDs) != 1 || userIDs[0] != "U2" {
+		t.Errorf("TodoModel.InactiveUsers() after TodoBot.PurgeInactive() == %v, want %v", userIDs, []string{"U2"})
+	}
+	if _, err := todoModel.Draft("U1"); err != model.ErrNotFound {
+		t.Errorf("DraftModel.Draft() after TodoBot.PurgeInactive() == %v, want %v", err, model.ErrNotFound)
+	}
+	if todoIDs, _ := todoModel.Listing("U1"); len(todoIDs) != 0 {
+		t.Errorf("ListingModel.Listing() after TodoBot.PurgeInactive() == %v, want none", todoIDs)
+	}
+}
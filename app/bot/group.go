@@ -0,0 +1,29 @@
+package bot
+
+import (
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// SourceID is the owner of the tasks sent from the source, a group or room owns the tasks sent in it
+func (this *TodoBot) SourceID(source *linebot.EventSource) string {
+	switch source.Type {
+	case linebot.EventSourceTypeGroup:
+		return source.GroupID
+	case linebot.EventSourceTypeRoom:
+		return source.RoomID
+	}
+	return source.UserID
+}
+
+// IsGroupID tells group IDs (C...) and room IDs (R...) from user IDs (U...)
+func IsGroupID(id string) bool {
+	return len(id) > 0 && (id[0] == 'C' || id[0] == 'R')
+}
+
+// GroupRemindMessage is the digest pushed to a group, its tasks are not on the web
+func (this *TodoBot) GroupRemindMessage(now time.Time, todos []model.Todo, lists []model.TodoList) string {
+	return this.Digest(now, todos, lists) + this.T("groupRemindFooter", nil)
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestTodoBotSourceID(t *testing.T) {
+	cases := []struct {
+		in   linebot.EventSource
+		want string
+	}{
+		{linebot.EventSource{Type: linebot.EventSourceTypeUser, UserID: "U1"}, "U1"},
+		{linebot.EventSource{Type: linebot.EventSourceTypeGroup, UserID: "U1", GroupID: "C1"}, "C1"},
+		{linebot.EventSource{Type: linebot.EventSourceTypeRoom, UserID: "U1", RoomID: "R1"}, "R1"},
+		{linebot.EventSource{UserID: "U1"}, "U1"},
+	}
+	bot := TodoBot{}
+	for _, c := range cases {
+		if got := bot.SourceID(&c.in); got != c.want {
+			t.Errorf("TodoBot.SourceID(%#v) == %q, want %q", c.in, got, c.want)
+		}
+	}
+	for id, want := range map[string]bool{"C1": true, "R1": true, "U1": false, "": false} {
+		if got := IsGroupID(id); got != want {
+			t.Errorf("IsGroupID(%q) == %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestTodoBotGroupRemindMessage(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	todos := []model.Todo{
+		{Task: "Book a room", Due: time.Date(2018, 11, 15, 18, 0, 0, 0, loc)},
+	}
+	bot := TodoBot{}
+	got := bot.GroupRemindMessage(now, todos, nil)
+	want := "🎯 TASKS TO BE DONE 🎯\n\n📆 Book a room : Today at 18:00\n\n1 of 1 remaining, just do it! 💪\n\nTo add a task send \"Task : tomorrow\" here"
+	if got != want {
+		t.Errorf("TodoBot.GroupRemindMessage() == %q, want %q", got, want)
+	}
+}
+
+func TestTodoBotResponseGroup(t *testing.T) {
+	wantErr := "linebot: APIError 400 Invalid reply token"
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	source := &linebot.EventSource{
+		Type:    linebot.EventSourceTypeGroup,
+		UserID:  "U1",
+		GroupID: "C1",
+	}
+	message := func(text string) []*linebot.Event {
+		return []*linebot.Event{{
+			Type:       linebot.EventTypeMessage,
+			Message:    &linebot.TextMessage{Text: text},
+			Source:     source,
+			ReplyToken: "dummy",
+		}}
+	}
+
+	// Chat is ignored
+	for _, text := range []string{"Hello everyone", "join us"} {
+		if err := bot.Response(message(text)); err != nil {
+			t.Errorf("TodoBot.Response(%q) in a group == %v, want %v", text, err, nil)
+		}
+	}
+
+	// Help
+	if err := bot.Response(message("help")); err == nil || err.Error() != wantErr {
+		t.Errorf("TodoBot.Response(%q) in a group == %v, want %v", "help", err, wantErr)
+	}
+
+	// Tasks belong to the group
+	if err := bot.Response(message("Book a room : tomorrow : 10:00")); err == nil || err.Error() != wantErr {
+		t.Errorf("TodoBot.Response() in a group == %v, want %v", err, wantErr)
+	}
+	if todos, _ := todoModel.List("C1"); len(todos) != 1 {
+		t.Errorf("TodoModel.List(%q) == %v, want %d todo", "C1", todos, 1)
+	}
+	if todos, _ := todoModel.List("U1"); len(todos) != 0 {
+		t.Errorf("TodoModel.List(%q) == %v, want no todo", "U1", todos)
+	}
+	userTodos, _ := todoModel.remindAll()
+	if _, ok := userTodos["C1"]; !ok {
+		t.Errorf("TodoModel.Remind() == %v, want the digest of %q", userTodos, "C1")
+	}
+
+	// Join
+	join := []*linebot.Event{{
+		Type:       linebot.EventTypeJoin,
+		Source:     source,
+		ReplyToken: "dummy",
+	}}
+	if err := bot.Response(join); err == nil || err.Error() != wantErr {
+		t.Errorf("TodoBot.Response(join) == %v, want %v", err, wantErr)
+	}
+}
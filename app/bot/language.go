@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"log"
+	"strings"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// Language is the locale of the replies to the user, the saved preference comes before the script of the message
+func (this *TodoBot) Language(userID string, msg string) string {
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		log.Println(err)
+	}
+	return languageOf(setting, msg)
+}
+
+func languageOf(setting model.Setting, msg string) string {
+	if _, ok := catalogs[setting.Language]; ok {
+		return setting.Language
+	}
+	if locale := DetectLocale(msg); locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// LanguageCommand handles "language th", "language auto" and the commands of the catalogs like "ภาษาไทย", it returns false for other messages
+func (this *TodoBot) LanguageCommand(userID string, msg string) (string, bool) {
+	language, ok := CommandLocale(msg)
+	if !ok && strings.ToLower(strings.Join(strings.Fields(msg), " ")) != "language auto" {
+		return "", false
+	}
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		return this.ErrorReply(err), true
+	}
+	setting.Language = language
+	if err := this.TodoModel.SaveSetting(setting); err != nil {
+		return this.ErrorReply(err), true
+	}
+	if language == "" {
+		return this.T("autoLanguage", nil), true
+	}
+	return this.In(language).T("language", nil), true
+}
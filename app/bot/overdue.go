@@ -0,0 +1,394 @@
+package bot
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/choobot/choo-todo-bot/app/scheduler"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// DefaultOverdueIntervals are the times after the due date to notify an overdue todo again for the users who have set none
+var DefaultOverdueIntervals = []time.Duration{time.Hour, 24 * time.Hour}
+
+const (
+	// defaultBuddyDays is how long a pinned todo is overdue before the buddy is told when the user has not set it
+	defaultBuddyDays = 3
+	// overdueTick is how often the overdue todos are notified again
+	overdueTick = "*/15 * * * *"
+	// maxEscalation is how long after the due date a todo is notified at most, the longest buddy days
+	maxEscalation = model.MaxBuddyDays * 24 * time.Hour
+)
+
+// Escalation is a notice about an overdue todo, to its owner and to the buddy of the owner when Buddy is set
+type Escalation struct {
+	UserID string
+	Todo   model.Todo
+	// Owner is whether the owner is notified, a pinned todo overdue for the buddy days only tells the buddy unless it is one of the intervals of the owner too
+	Owner bool
+	Buddy string
+	// AlertIDs are the claimed notices it sends to the owner, several when several intervals have passed at once,
+	// the buddy days which are an interval of the owner too are sent with them
+	AlertIDs []int
+	// BuddyAlertIDs are the claimed notices of the buddy days alone, retried without the owner
+	BuddyAlertIDs []int
+	// bot and buddyBot are in the language and the time zone of the owner and of the buddy
+	bot      *TodoBot
+	buddyBot *TodoBot
+}
+
+// OverdueJob notifies the overdue todos again, see Escalate
+func (this *TodoBot) OverdueJob() scheduler.Job {
+	tick, _ := scheduler.ParseSchedule(overdueTick)
+	return scheduler.Job{
+		Name:     "overdue",
+		Schedule: tick,
+		Run: func(from time.Time, to time.Time) error {
+			return this.Escalate(to)
+		},
+	}
+}
+
+// Escalate pushes the notices of the todos overdue for one of the intervals of their owner,
+// like Alert they are marked sent once pushed or released for the next run, the owner and the buddy apart
+func (this *TodoBot) Escalate(now time.Time) error {
+	escalations, err := this.DueEscalations(now)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, escalation := range escalations {
+		wg.Add(1)
+		//Fork for massive API calls
+		go func(escalation Escalation) {
+			defer wg.Done()
+			var err error
+			if escalation.Owner {
+				err = this.PushMessage(escalation.UserID, escalation.bot.OverdueMessage(now, escalation.Todo))
+			}
+			for _, alertID := range escalation.AlertIDs {
+				this.deliver(alertID, now, err)
+			}
+			// The buddy days sent with the owner notice are retried with it
+			if escalation.Buddy == "" || (err != nil && len(escalation.BuddyAlertIDs) == 0) {
+				return
+			}
+			err = this.PushMessage(escalation.Buddy, escalation.buddyBot.BuddyMessage(now, escalation.buddyBot.DisplayName(escalation.UserID), escalation.Todo))
+			for _, alertID := range escalation.BuddyAlertIDs {
+				this.deliver(alertID, now, err)
+			}
+		}(escalation)
+	}
+	wg.Wait()
+	return nil
+}
+
+// DueEscalations schedules the notices of the overdue todos and returns the ones to send now, claimed,
+// they are stored with the alerts with a negative lead, so each one is sent once across restarts and instances
+func (this *TodoBot) DueEscalations(now time.Time) ([]Escalation, error) {
+	// Only the todos which may be notified by the horizon, the other pending notices are stale
+	userTodos, err := this.TodoModel.DueTodos(now.Add(-alertHorizon-maxEscalation), now)
+	if err != nil {
+		return nil, err
+	}
+	bots, err := this.userBots(userIDsOf(userTodos))
+	if err != nil {
+		return nil, err
+	}
+	todos := map[alertKey]model.Todo{}
+	alerts := []model.Alert{}
+	for userID, list := range userTodos {
+		for _, todo := range list {
+			todos[alertKey{userID, todo.ID}] = todo
+			for _, interval := range bots[userID].escalationIntervals(todo) {
+				at := todo.Due.Add(interval)
+				// Only the recent ones, the older ones may have been deleted after they were sent
+				if at.After(now.Add(alertHorizon)) || !at.After(now.Add(-alertHorizon)) {
+					continue
+				}
+				alerts = append(alerts, model.Alert{UserID: userID, TodoID: todo.ID, Due: todo.Due, Lead: -interval})
+			}
+		}
+	}
+	if err := this.AlertModel.ScheduleAlerts(alerts); err != nil {
+		return nil, err
+	}
+	pending, err := this.AlertModel.PendingAlerts(now)
+	if err != nil {
+		return nil, err
+	}
+	escalations := []Escalation{}
+	notified := map[alertKey]int{}
+	for _, alert := range pending {
+		if alert.Lead >= 0 {
+			// An alert before the due date, see DueAlerts
+			continue
+		}
+		key := alertKey{alert.UserID, alert.TodoID}
+		todo, ok := todos[key]
+		if ok && bots[alert.UserID].isQuiet(now) {
+			// Left pending until the quiet hours end
+			continue
+		}
+		if ok && todo.IsSnoozed(now) {
+			// Left pending until the todo wakes up
+			continue
+		}
+		claimed, err := this.AlertModel.ClaimAlert(alert.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			// Another instance is pushing it
+			continue
+		}
+		owner, buddy := false, ""
+		if ok {
+			setting := bots[alert.UserID].Setting
+			owner = isInterval(overdueIntervals(setting), -alert.Lead)
+			if todo.Pin && setting.Buddy != "" && -alert.Lead == buddyAfter(setting) {
+				buddy = setting.Buddy
+			}
+		}
+		// Done, moved or deleted since it was scheduled, or no longer one of the intervals
+		skip := !ok || !todo.Due.Truncate(time.Second).Equal(alert.Due) || (!owner && buddy == "")
+		if skip || alert.Attempts >= maxAlertAttempts {
+			if err := this.AlertModel.SendAlert(alert.ID, now); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		i, ok := notified[key]
+		if !ok {
+			i = len(escalations)
+			notified[key] = i
+			escalations = append(escalations, Escalation{UserID: alert.UserID, Todo: todo, bot: bots[alert.UserID]})
+		}
+		// Several intervals may have passed at once, one notice is enough
+		escalations[i].Owner = escalations[i].Owner || owner
+		if buddy != "" {
+			escalations[i].Buddy = buddy
+		}
+		if owner {
+			escalations[i].AlertIDs = append(escalations[i].AlertIDs, alert.ID)
+		} else {
+			escalations[i].BuddyAlertIDs = append(escalations[i].BuddyAlertIDs, alert.ID)
+		}
+	}
+	buddies := []string{}
+	for _, escalation := range escalations {
+		if escalation.Buddy != "" {
+			buddies = append(buddies, escalation.Buddy)
+		}
+	}
+	buddyBots, err := this.userBots(buddies)
+	if err != nil {
+		return nil, err
+	}
+	for i := range escalations {
+		escalations[i].buddyBot = buddyBots[escalations[i].Buddy]
+	}
+	return escalations, nil
+}
+
+// escalationIntervals are the intervals of the user and the buddy days for a pinned todo
+func (this *TodoBot) escalationIntervals(todo model.Todo) []time.Duration {
+	intervals := overdueIntervals(this.Setting)
+	if todo.Pin && this.Setting.Buddy != "" && !isInterval(intervals, buddyAfter(this.Setting)) {
+		intervals = append(intervals, buddyAfter(this.Setting))
+	}
+	return intervals
+}
+
+// overdueIntervals are the ones of the user or the default ones
+func overdueIntervals(setting model.Setting) []time.Duration {
+	if intervals := setting.Intervals(); intervals != nil {
+		return intervals
+	}
+	return DefaultOverdueIntervals
+}
+
+func isInterval(intervals []time.Duration, interval time.Duration) bool {
+	for _, i := range intervals {
+		if i == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// buddyAfter is how long a pinned todo is overdue before the buddy is told
+func buddyAfter(setting model.Setting) time.Duration {
+	days := setting.BuddyDays
+	if days == 0 {
+		days = defaultBuddyDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// OverdueMessage is "⚠️ Pay rent is overdue by 1 hour, it was due Today at 9:00", "🚨" and pinned for a pinned todo
+func (this *TodoBot) OverdueMessage(now time.Time, todo model.Todo) string {
+	args := Args{"Task": todo.Task, "Overdue": this.FormatLead(now.Sub(todo.Due)), "Due": this.FormatDate(now, todo.Due)}
+	if todo.Pin {
+		return this.T("pinnedOverdueNotice", args)
+	}
+	return this.T("overdueNotice", args)
+}
+
+// BuddyMessage tells the buddy that a pinned todo of their friend is overdue, name is the display name of the friend
+func (this *TodoBot) BuddyMessage(now time.Time, name string, todo model.Todo) string {
+	return this.T("buddyNotice", Args{"Name": name, "Task": todo.Task, "Overdue": this.FormatLead(now.Sub(todo.Due))})
+}
+
+// DisplayName is the LINE name of the user, "your friend" when the profile cannot be read
+func (this *TodoBot) DisplayName(userID string) string {
+	if this.Client != nil {
+		if profile, err := this.Client.GetProfile(userID).Do(); err == nil && profile.DisplayName != "" {
+			return profile.DisplayName
+		}
+	}
+	return this.T("friend", nil)
+}
+
+// pinnedOverdue are the remaining pinned todos past their due date
+func pinnedOverdue(todos []model.Todo, now time.Time) []model.Todo {
+	pinned := []model.Todo{}
+	for _, todo := range todos {
+		if todo.Pin && !todo.Done && now.After(todo.Due) {
+			pinned = append(pinned, todo)
+		}
+	}
+	return pinned
+}
+
+// PinnedOverdueMessage is sent on top of the digest when pinned todos are overdue
+func (this *TodoBot) PinnedOverdueMessage(now time.Time, todos []model.Todo) linebot.SendingMessage {
+	message := this.T("pinnedOverdue", nil) + "\n\n"
+	for _, todo := range todos {
+		message += this.FormatTodo(now, todo)
+	}
+	return linebot.NewTextMessage(strings.TrimRight(message, "\n"))
+}
+
+// OverdueCommand handles "overdue" to show the intervals, "overdue 1h 1d", "overdue off" and "overdue default",
+// it returns false for other messages like "overdue books"
+func (this *TodoBot) OverdueCommand(userID string, msg string) (string, bool) {
+	fields := strings.Fields(strings.ToLower(msg))
+	if len(fields) == 0 || fields[0] != "overdue" || strings.Contains(msg, " : ") {
+		return "", false
+	}
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		return this.ErrorReply(err), true
+	}
+	if len(fields) == 2 && fields[1] == "default" {
+		setting.OverdueIntervals = ""
+	} else if len(fields) == 2 && fields[1] == "off" {
+		setting.OverdueIntervals = "off"
+	} else if len(fields) > 1 {
+		intervals, err := ParseLeads(fields[1:])
+		if err != nil {
+			return "", false
+		}
+		minutes := []string{}
+		for _, interval := range intervals {
+			minutes = append(minutes, strconv.Itoa(int(interval/time.Minute)))
+		}
+		setting.OverdueIntervals = strings.Join(minutes, ",")
+	}
+	if len(fields) > 1 {
+		if err := this.TodoModel.SaveSetting(setting); err != nil {
+			return this.ErrorReply(err), true
+		}
+	}
+	intervals := overdueIntervals(setting)
+	if len(intervals) == 0 {
+		return this.T("overdueOff", nil), true
+	}
+	texts := []string{}
+	for _, interval := range intervals {
+		texts = append(texts, this.FormatLead(interval))
+	}
+	return this.T("overdueIntervals", Args{"Intervals": strings.Join(texts, ", ")}), true
+}
+
+// buddyCodePattern is a code of model.NewInviteCode, "buddy" followed by another word is not one
+var buddyCodePattern = regexp.MustCompile(`^[A-Z2-7]{8}$`)
+
+// BuddyCommand handles "buddy" to get the code for the buddy, "buddy off" and "buddy after 3" in one-on-one chats,
+// and "buddy CODE" from the buddy or the group of the buddy, it returns false for other messages
+func (this *TodoBot) BuddyCommand(userID string, msg string) (string, bool) {
+	fields := strings.Fields(msg)
+	if len(fields) == 0 || len(fields) > 3 || strings.ToLower(fields[0]) != "buddy" {
+		return "", false
+	}
+	if len(fields) == 2 && buddyCodePattern.MatchString(model.NormalizeInviteCode(fields[1])) {
+		return this.JoinBuddy(userID, fields[1]), true
+	}
+	if IsGroupID(userID) {
+		// The buddy of a group would be told about the todos of the group by the group itself
+		return "", false
+	}
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		return this.ErrorReply(err), true
+	}
+	switch {
+	case len(fields) == 1:
+		reply := ""
+		if setting.Buddy != "" {
+			reply = this.T("buddyOn", Args{"Days": this.N("days", int(buddyAfter(setting)/(24*time.Hour)), nil)}) + "\n\n"
+		}
+		if setting.BuddyCode == "" {
+			// The code is random so it tells nothing about the user, it is kept so a code sent earlier still works
+			setting.BuddyCode = model.NewInviteCode()
+			if err := this.TodoModel.SaveSetting(setting); err != nil {
+				return this.ErrorReply(err), true
+			}
+		}
+		return reply + this.T("buddyCode", Args{"Code": setting.BuddyCode}), true
+	case len(fields) == 2 && strings.ToLower(fields[1]) == "off":
+		setting.Buddy = ""
+	case len(fields) == 3 && strings.ToLower(fields[1]) == "after":
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(fields[2]), "d"))
+		if err != nil || days < 1 || days > model.MaxBuddyDays {
+			return this.T("wrongBuddy", nil), true
+		}
+		setting.BuddyDays = days
+	default:
+		return "", false
+	}
+	if err := this.TodoModel.SaveSetting(setting); err != nil {
+		return this.ErrorReply(err), true
+	}
+	if setting.Buddy == "" {
+		return this.T("buddyOff", nil), true
+	}
+	return this.T("buddyOn", Args{"Days": this.N("days", int(buddyAfter(setting)/(24*time.Hour)), nil)}), true
+}
+
+// JoinBuddy makes the chat the buddy of the user of the code
+func (this *TodoBot) JoinBuddy(buddy string, code string) string {
+	userID, err := this.TodoModel.BuddyOwner(code)
+	if err == model.ErrNotFound {
+		return this.T("wrongBuddyCode", nil)
+	} else if err != nil {
+		return this.ErrorReply(err)
+	}
+	if userID == buddy {
+		return this.T("buddySelf", nil)
+	}
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	setting.Buddy = buddy
+	if err := this.TodoModel.SaveSetting(setting); err != nil {
+		return this.ErrorReply(err)
+	}
+	return this.T("buddyJoined", Args{"Name": this.DisplayName(userID), "Days": this.N("days", int(buddyAfter(setting)/(24*time.Hour)), nil)})
+}
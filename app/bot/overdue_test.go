@@ -0,0 +1,245 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestTodoBotOverdueCommand(t *testing.T) {
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel).In("en")
+	cases := []struct {
+		msg  string
+		want string
+	}{
+		{"overdue", "⚠️ Your overdue todos are notified again 1 hour, 1 day after they are due 🆗"},
+		{"Overdue 3d 30m", "⚠️ Your overdue todos are notified again 30 minutes, 3 days after they are due 🆗"},
+		{"overdue off", "🔕 Your overdue todos are not notified again 🆗"},
+		{"overdue default", "⚠️ Your overdue todos are notified again 1 hour, 1 day after they are due 🆗"},
+	}
+	for _, c := range cases {
+		if reply, ok := bot.OverdueCommand("U1", c.msg); !ok || reply != c.want {
+			t.Errorf("TodoBot.OverdueCommand(%q) == %q, %v, want %q", c.msg, reply, ok, c.want)
+		}
+	}
+	for _, msg := range []string{"overdue books", "overdue report : tomorrow", "overdues"} {
+		if _, ok := bot.OverdueCommand("U1", msg); ok {
+			t.Errorf("TodoBot.OverdueCommand(%q) == true, want false", msg)
+		}
+	}
+	bot.OverdueCommand("U1", "overdue 2h")
+	if setting, _ := todoModel.Setting("U1"); setting.OverdueIntervals != "120" {
+		t.Errorf("TodoModel.Setting().OverdueIntervals == %q, want %q", setting.OverdueIntervals, "120")
+	}
+}
+
+func TestTodoBotBuddyCommand(t *testing.T) {
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	bot.Secret = "secret"
+	bot = bot.In("en")
+
+	if reply, ok := bot.BuddyCommand("U1", "buddy"); !ok || !strings.Contains(reply, "👇\nbuddy ") || strings.Contains(reply, "Your buddy") {
+		t.Errorf("TodoBot.BuddyCommand() without a buddy == %q, %v", reply, ok)
+	}
+	// The code is random and kept
+	setting, _ := todoModel.Setting("U1")
+	code := setting.BuddyCode
+	if !buddyCodePattern.MatchString(code) || strings.Contains(code, "U1") {
+		t.Errorf("TodoModel.Setting().BuddyCode == %q, want a random code", code)
+	}
+	if reply, ok := bot.BuddyCommand("U1", "buddy "+code); !ok || reply != "Send it to your friend or their group, not here 😉" {
+		t.Errorf("TodoBot.BuddyCommand() of the own code == %q, %v", reply, ok)
+	}
+	if reply, ok := bot.BuddyCommand("C1", "buddy "+model.NewInviteCode()); !ok || reply != "Wrong buddy code" {
+		t.Errorf("TodoBot.BuddyCommand() of a wrong code == %q, %v", reply, ok)
+	}
+	if reply, ok := bot.BuddyCommand("C1", "buddy "+strings.ToLower(code)); !ok || reply != "🤝 You will be told here when a pinned todo of your friend is overdue for 3 days 🆗" {
+		t.Errorf("TodoBot.BuddyCommand() of the code == %q, %v", reply, ok)
+	}
+	if setting, _ := todoModel.Setting("U1"); setting.Buddy != "C1" {
+		t.Errorf("TodoModel.Setting().Buddy == %q, want %q", setting.Buddy, "C1")
+	}
+	if reply, ok := bot.BuddyCommand("U1", "buddy after 5"); !ok || !strings.HasPrefix(reply, "🤝 Your buddy is told when a pinned todo is overdue for 5 days 🆗") {
+		t.Errorf("TodoBot.BuddyCommand() after 5 == %q, %v", reply, ok)
+	}
+	if reply, ok := bot.BuddyCommand("U1", "buddy"); !ok || !strings.HasPrefix(reply, "🤝 Your buddy is told") || !strings.HasSuffix(reply, code) {
+		t.Errorf("TodoBot.BuddyCommand() with a buddy == %q, %v", reply, ok)
+	}
+	for _, msg := range []string{"buddy after 0", "buddy after 31", "buddy after soon"} {
+		if reply, ok := bot.BuddyCommand("U1", msg); !ok || reply != `Try "buddy after 3" for 3 days, up to 30` {
+			t.Errorf("TodoBot.BuddyCommand(%q) == %q, %v", msg, reply, ok)
+		}
+	}
+	if reply, ok := bot.BuddyCommand("U1", "buddy off"); !ok || reply != "🤝 Nobody is told about your overdue todos 🆗" {
+		t.Errorf("TodoBot.BuddyCommand() off == %q, %v", reply, ok)
+	}
+	if setting, _ := todoModel.Setting("U1"); setting.Buddy != "" || setting.BuddyDays != 5 {
+		t.Errorf("TodoModel.Setting() after off == %+v", setting)
+	}
+	for _, msg := range []string{"buddy up", "buddies", "buddy off please now"} {
+		if _, ok := bot.BuddyCommand("U1", msg); ok {
+			t.Errorf("TodoBot.BuddyCommand(%q) == true, want false", msg)
+		}
+	}
+	// Only the code in a group
+	if _, ok := bot.BuddyCommand("C1", "buddy off"); ok {
+		t.Errorf("TodoBot.BuddyCommand() off in a group == true, want false")
+	}
+}
+
+func TestTodoBotDueEscalations(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 9, 0, 0, 0, loc)
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: now.Add(-90 * time.Minute), Pin: true})
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Call mom", Due: now.Add(-30 * time.Minute)})
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Not yet due", Due: now.Add(10 * time.Minute)})
+	todoModel.Create(model.Todo{UserID: "U2", Task: "Not notified", Due: now.Add(-2 * time.Hour)})
+	todoModel.SaveSetting(model.Setting{UserID: "U1", Buddy: "C1", BuddyDays: 1})
+	todoModel.SaveSetting(model.Setting{UserID: "U2", OverdueIntervals: "off"})
+	bot := newMockBot(todoModel)
+
+	escalations, err := bot.DueEscalations(now)
+	if err != nil || len(escalations) != 1 || escalations[0].Todo.Task != "Pay rent" || !escalations[0].Owner || escalations[0].Buddy != "" {
+		t.Errorf("TodoBot.DueEscalations() == %+v, %v", escalations, err)
+	}
+	// Another instance while it is pushed
+	if escalations, err := newMockBot(todoModel).DueEscalations(now.Add(time.Minute)); err != nil || len(escalations) != 0 {
+		t.Errorf("TodoBot.DueEscalations() again == %+v, %v, want none", escalations, err)
+	}
+	delivered(bot, now, escalations)
+	// The alert before the due date is left to DueAlerts
+	if alerts, err := bot.DueAlerts(now.Add(5 * time.Minute)); err != nil || len(alerts) != 1 || alerts[0].Todo.Task != "Not yet due" {
+		t.Errorf("TodoBot.DueAlerts() == %+v, %v", alerts, err)
+	}
+
+	// Done since it was scheduled
+	todos, _ := todoModel.List("U1")
+	for _, todo := range todos {
+		if todo.Task != "Pay rent" {
+			todo.Done = true
+			todoModel.Done("U1", todo)
+		}
+	}
+	if escalations, err := bot.DueEscalations(now.Add(31 * time.Minute)); err != nil || len(escalations) != 0 {
+		t.Errorf("TodoBot.DueEscalations() of a done todo == %+v, %v, want none", escalations, err)
+	}
+
+	// The buddy days of a pinned todo, the day interval of the owner too
+	escalations, err = bot.DueEscalations(now.Add(22*time.Hour + 30*time.Minute))
+	if err != nil || len(escalations) != 1 || !escalations[0].Owner || escalations[0].Buddy != "C1" {
+		t.Errorf("TodoBot.DueEscalations() after a day == %+v, %v", escalations, err)
+	}
+	delivered(bot, now.Add(22*time.Hour+30*time.Minute), escalations)
+
+	// The buddy only
+	todoModel.SaveSetting(model.Setting{UserID: "U1", Buddy: "C1", BuddyDays: 2})
+	escalations, err = bot.DueEscalations(now.Add(46*time.Hour + 30*time.Minute))
+	if err != nil || len(escalations) != 1 || escalations[0].Owner || escalations[0].Buddy != "C1" {
+		t.Errorf("TodoBot.DueEscalations() after the buddy days == %+v, %v", escalations, err)
+	}
+
+	todoModel.willError = true
+	if _, err := bot.DueEscalations(now); err == nil {
+		t.Errorf("TodoBot.DueEscalations() == %v, want error", err)
+	}
+}
+
+// delivered marks the escalations sent like Escalate after their pushes
+func delivered(bot *TodoBot, now time.Time, escalations []Escalation) {
+	for _, escalation := range escalations {
+		for _, alertID := range append(escalation.AlertIDs, escalation.BuddyAlertIDs...) {
+			bot.deliver(alertID, now, nil)
+		}
+	}
+}
+
+func TestTodoBotEscalate(t *testing.T) {
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	now := time.Now()
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: now.Add(-time.Hour)})
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	// The push fails without a channel, the notice is left to the next run
+	if err := bot.Escalate(now); err != nil {
+		t.Errorf("TodoBot.Escalate() == %v, want %v", err, nil)
+	}
+	if pending, _ := todoModel.PendingAlerts(now); len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Errorf("TodoModel.PendingAlerts() after a failed TodoBot.Escalate() == %+v, want it with an attempt", pending)
+	}
+}
+
+func TestTodoBotEscalateBuddyFails(t *testing.T) {
+	pushes := map[string]int{}
+	var mutex sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ To string }
+		json.NewDecoder(r.Body).Decode(&body)
+		mutex.Lock()
+		pushes[body.To]++
+		mutex.Unlock()
+		if body.To == "C1" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	client, _ := linebot.New("secret", "token", linebot.WithEndpointBase(server.URL))
+	now := time.Now()
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: now.Add(-24 * time.Hour), Pin: true})
+	todoModel.SaveSetting(model.Setting{UserID: "U1", OverdueIntervals: "1430", Buddy: "C1", BuddyDays: 1})
+	bot := newMockBot(todoModel)
+	bot.Client = client
+
+	if err := bot.Escalate(now); err != nil || pushes["U1"] != 1 || pushes["C1"] != 1 {
+		t.Errorf("TodoBot.Escalate() == %v, pushes %v, want one each", err, pushes)
+	}
+	// Only the notice of the buddy is left to the next run
+	if pending, _ := todoModel.PendingAlerts(now); len(pending) != 1 || pending[0].Lead != -24*time.Hour || pending[0].Attempts != 1 {
+		t.Errorf("TodoModel.PendingAlerts() after the buddy push failed == %+v, want the buddy days with an attempt", pending)
+	}
+	bot.Escalate(now.Add(15 * time.Minute))
+	if pushes["U1"] != 1 || pushes["C1"] != 2 {
+		t.Errorf("TodoBot.Escalate() again pushes %v, want the buddy only", pushes)
+	}
+}
+
+func TestTodoBotOverdueMessage(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	bot := (&TodoBot{}).In("en")
+	todo := model.Todo{Task: "Pay rent", Due: time.Date(2018, 11, 15, 9, 0, 0, 0, loc)}
+	if got := bot.OverdueMessage(now, todo); got != "⚠️ Pay rent is overdue by 1 hour, it was due Today at 09:00" {
+		t.Errorf("TodoBot.OverdueMessage() == %q", got)
+	}
+	todo.Pin = true
+	if got := bot.OverdueMessage(now, todo); got != "🚨 Pay rent is pinned and overdue by 1 hour, it was due Today at 09:00" {
+		t.Errorf("TodoBot.OverdueMessage() of a pinned todo == %q", got)
+	}
+	if got := bot.BuddyMessage(now.Add(3*24*time.Hour), "Somchai", todo); got != "🤝 A pinned todo of Somchai, Pay rent, is overdue by 3 days, maybe give them a nudge" {
+		t.Errorf("TodoBot.BuddyMessage() == %q", got)
+	}
+
+	todos := []model.Todo{todo, {Task: "Call mom", Due: todo.Due}, {Task: "Done", Due: todo.Due, Pin: true, Done: true}, {Task: "Later", Due: now.Add(time.Hour), Pin: true}}
+	pinned := pinnedOverdue(todos, now)
+	if len(pinned) != 1 || pinned[0].Task != "Pay rent" {
+		t.Errorf("pinnedOverdue() == %v", pinned)
+	}
+	message := bot.PinnedOverdueMessage(now, pinned).(*linebot.TextMessage)
+	if message.Text != "🚨 Pinned and overdue, get these done first\n\n⭐️ Pay rent : Today at 09:00 (overdue)" {
+		t.Errorf("TodoBot.PinnedOverdueMessage() == %q", message.Text)
+	}
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// The actions of the buttons under each todo
+const (
+	PostbackDone     = "done"
+	PostbackPin      = "pin"
+	PostbackUnpin    = "unpin"
+	PostbackSnooze   = "snooze"
+	PostbackWake     = "wake"
+	PostbackTomorrow = "tomorrow"
+)
+
+var errPostback = errors.New("Wrong postback data")
+
+// PostbackData is the data of a button, "done:42:signature", signed for the owner of the todo so it does not work in another chat
+func (this *TodoBot) PostbackData(userID string, action string, todoID int) string {
+	payload := action + ":" + strconv.Itoa(todoID)
+	return payload + ":" + this.sign(userID, payload)
+}
+
+func (this *TodoBot) sign(userID string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(this.Secret))
+	mac.Write([]byte(userID + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// ParsePostback is the action and the todo ID, or the draft time, of the data after checking its signature
+func (this *TodoBot) ParsePostback(userID string, data string) (string, int, error) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 {
+		return "", 0, errPostback
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(this.sign(userID, parts[0]+":"+parts[1]))) {
+		return "", 0, errPostback
+	}
+	todoID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, errPostback
+	}
+	return parts[0], todoID, nil
+}
+
+// PostbackReply does the action of the button and replies the todo as it is now
+func (this *TodoBot) PostbackReply(userID string, postback *linebot.Postback, now time.Time) string {
+	action, todoID, err := this.ParsePostback(userID, postback.Data)
+	if err != nil {
+		return this.T("wrongPostback", nil)
+	}
+	if strings.HasPrefix(action, "draft.") {
+		datetime := ""
+		if postback.Params != nil {
+			datetime = postback.Params.Datetime
+		}
+		return this.DraftReply(userID, action, todoID, datetime, now)
+	}
+	todo, err := this.findTodo(userID, todoID)
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	loc := this.location()
+	now = now.In(loc)
+	var reply string
+	switch action {
+	case PostbackDone:
+		reply, err = this.setDone(userID, todo, true)
+	case PostbackPin, PostbackUnpin:
+		reply, err = this.setPin(userID, todo, action == PostbackPin)
+	case PostbackSnooze:
+		reply, err = this.Snooze(userID, todo, now.Add(snoozeButton).Truncate(time.Minute), now)
+	case PostbackWake:
+		reply, err = this.Snooze(userID, todo, time.Time{}, now)
+	case PostbackTomorrow:
+		// Same time tomorrow
+		due := todo.Due.In(loc)
+		tomorrow := now.AddDate(0, 0, 1)
+		reply, err = this.setDue(userID, todo, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), due.Hour(), due.Minute(), 0, 0, loc), now)
+	default:
+		return this.T("wrongPostback", nil)
+	}
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	return reply
+}
+
+// findTodo is the todo of the user by ID
+func (this *TodoBot) findTodo(userID string, todoID int) (model.Todo, error) {
+	todos, err := this.TodoModel.List(userID)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	for _, todo := range todos {
+		if todo.ID == todoID {
+			return todo, nil
+		}
+	}
+	return model.Todo{}, model.ErrNotFound
+}
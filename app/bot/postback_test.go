@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestTodoBotParsePostback(t *testing.T) {
+	bot := &TodoBot{Secret: "secret"}
+	data := bot.PostbackData("U1", PostbackDone, 42)
+	if action, todoID, err := bot.ParsePostback("U1", data); action != PostbackDone || todoID != 42 || err != nil {
+		t.Errorf("TodoBot.ParsePostback(%q) == %q, %d, %v", data, action, todoID, err)
+	}
+
+	signature := data[strings.LastIndex(data, ":")+1:]
+	for _, c := range []struct {
+		userID string
+		data   string
+	}{
+		{"U2", data},
+		{"U1", "done:43:" + signature},
+		{"U1", "pin:42:" + signature},
+		{"U1", "done:42"},
+		{"U1", "done"},
+		{"U1", ""},
+	} {
+		if _, _, err := bot.ParsePostback(c.userID, c.data); err != errPostback {
+			t.Errorf("TodoBot.ParsePostback(%q, %q) == %v, want %v", c.userID, c.data, err, errPostback)
+		}
+	}
+
+	// Another secret
+	if _, _, err := (&TodoBot{Secret: "other"}).ParsePostback("U1", data); err != errPostback {
+		t.Errorf("TodoBot.ParsePostback() with another secret == %v, want %v", err, errPostback)
+	}
+}
+
+func TestTodoBotPostbackReply(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 10, 20, 30, 0, loc)
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: time.Date(2018, 11, 14, 9, 0, 0, 0, loc)})
+	todoModel.Create(model.Todo{UserID: "U2", Task: "Not mine", Due: now})
+	bot := newMockBot(todoModel)
+	bot.Secret = "secret"
+	bot = bot.In("en")
+
+	cases := []struct {
+		action string
+		want   string
+	}{
+		{PostbackPin, "⭐️ Pay rent is pinned"},
+		{PostbackUnpin, "📆 Pay rent is not pinned anymore"},
+		{PostbackSnooze, "💤 Pay rent is snoozed until Today at 11:20, it is still due Yesterday at 09:00"},
+		{PostbackWake, "⏰ Pay rent is back in the reminders"},
+		{PostbackTomorrow, "📆 Pay rent is due Tomorrow at 09:00"},
+		{PostbackDone, "✅ Pay rent is done"},
+		{"delete", `This button does not work anymore, send "list" to see your todos`},
+	}
+	for _, c := range cases {
+		if reply := bot.PostbackReply("U1", &linebot.Postback{Data: bot.PostbackData("U1", c.action, 1)}, now); reply != c.want {
+			t.Errorf("TodoBot.PostbackReply(%q) == %q, want %q", c.action, reply, c.want)
+		}
+	}
+	todos, _ := todoModel.List("U1")
+	if len(todos) != 1 || !todos[0].Done || todos[0].Pin || !todos[0].Due.Equal(time.Date(2018, 11, 16, 9, 0, 0, 0, loc)) || !todos[0].Snoozed.IsZero() {
+		t.Errorf("TodoModel.List() after the postbacks == %#v", todos)
+	}
+
+	// The todo of another user, even signed for this one
+	if reply := bot.PostbackReply("U1", &linebot.Postback{Data: bot.PostbackData("U1", PostbackDone, 2)}, now); reply != `This todo is not there anymore, send "list" to see your todos` {
+		t.Errorf("TodoBot.PostbackReply() of another user == %q", reply)
+	}
+	if reply := bot.PostbackReply("U1", &linebot.Postback{Data: "done:1:forged"}, now); !strings.HasPrefix(reply, "This button does not work") {
+		t.Errorf("TodoBot.PostbackReply() of forged data == %q", reply)
+	}
+	if reply := bot.In("th").PostbackReply("U1", &linebot.Postback{Data: bot.PostbackData("U1", PostbackPin, 3)}, now); reply != `ไม่มีงานนี้แล้ว ส่ง "list" เพื่อดูรายการงาน` {
+		t.Errorf("TodoBot.In(%q).PostbackReply() == %q", "th", reply)
+	}
+}
+
+func TestTodoBotResponsePostback(t *testing.T) {
+	wantErr := errors.New("linebot: APIError 400 Invalid reply token")
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Buy milk", Due: time.Now()})
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	bot.Secret = "secret"
+	events := []*linebot.Event{{
+		Type:       linebot.EventTypePostback,
+		Postback:   &linebot.Postback{Data: bot.PostbackData("U1", PostbackDone, 1)},
+		Source:     &linebot.EventSource{UserID: "U1"},
+		ReplyToken: "dummy",
+	}}
+	if err := bot.Response(events); err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("TodoBot.Response() of a postback == %v, want %v", err, wantErr)
+	}
+	if todos, _ := todoModel.List("U1"); len(todos) != 1 || !todos[0].Done {
+		t.Errorf("TodoModel.List() after the postback == %#v", todos)
+	}
+}
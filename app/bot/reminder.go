@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// hasOverdue is whether a remaining todo is past its due date
+func hasOverdue(todos []model.Todo, now time.Time) bool {
+	for _, todo := range todos {
+		if !todo.Done && now.After(todo.Due) {
+			return true
+		}
+	}
+	return false
+}
+
+// remainingTodos leaves the completed todos out
+func remainingTodos(todos []model.Todo) []model.Todo {
+	remaining := []model.Todo{}
+	for _, todo := range todos {
+		if !todo.Done {
+			remaining = append(remaining, todo)
+		}
+	}
+	return remaining
+}
+
+// ReminderCommand handles "remind" to show the reminder setting, "remind at 08:00 18:30", "remind on mon-fri", "remind completed off",
+// "remind overdue only", "remind always" and "quiet 22:00-07:00" or "quiet off", it returns false for other messages like "remind mom : tomorrow"
+func (this *TodoBot) ReminderCommand(userID string, msg string) (string, bool) {
+	fields := strings.Fields(strings.ToLower(msg))
+	if len(fields) == 0 || strings.Contains(msg, " : ") || (fields[0] != "remind" && fields[0] != "quiet") {
+		return "", false
+	}
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		return this.ErrorReply(err), true
+	}
+	if fields[0] == "quiet" {
+		if !parseQuiet(&setting, fields[1:]) {
+			return this.T("wrongReminder", nil), true
+		}
+	} else if len(fields) > 1 {
+		switch {
+		case fields[1] == "at" && len(fields) > 2:
+			setting.RemindTimes, err = parseRemindTimes(fields[2:])
+		case fields[1] == "on" && len(fields) > 2:
+			setting.RemindDays, err = parseRemindDays(fields[2:])
+		case fields[1] == "completed" && len(fields) == 3 && (fields[2] == "on" || fields[2] == "off"):
+			setting.HideCompleted = fields[2] == "off"
+		case fields[1] == "overdue" && len(fields) == 3 && fields[2] == "only":
+			setting.OnlyOverdue = true
+		case fields[1] == "always" && len(fields) == 2:
+			setting.OnlyOverdue = false
+		default:
+			// Not every message starting with remind is a command
+			return "", false
+		}
+		if err != nil {
+			return this.T("wrongReminder", nil), true
+		}
+	}
+	if len(fields) > 1 {
+		if err := this.TodoModel.SaveSetting(setting); err != nil {
+			return this.ErrorReply(err), true
+		}
+	}
+	return this.ReminderSetting(setting), true
+}
+
+// parseQuiet reads "22:00-07:00", "10pm 7am" or "off", nothing leaves the setting as it is
+func parseQuiet(setting *model.Setting, fields []string) bool {
+	words := strings.FieldsFunc(strings.Join(fields, " "), func(r rune) bool {
+		return r == ' ' || r == '-'
+	})
+	switch {
+	case len(words) == 0:
+		return true
+	case len(words) == 1 && words[0] == "off":
+		setting.QuietStart, setting.QuietEnd = "", ""
+		return true
+	case len(words) != 2:
+		return false
+	}
+	start, err := parseRemindTimes(words[:1])
+	if err != nil || start == "" {
+		return false
+	}
+	end, err := parseRemindTimes(words[1:])
+	if err != nil || end == "" || end == start {
+		return false
+	}
+	setting.QuietStart, setting.QuietEnd = start, end
+	return true
+}
+
+// parseRemindTimes reads "8:00 18:30" or "8am 6:30pm" into "08:00,18:30", "default" is ""
+func parseRemindTimes(words []string) (string, error) {
+	if len(words) == 1 && words[0] == "default" {
+		return "", nil
+	}
+	clocks := []string{}
+	seen := map[string]bool{}
+	for _, word := range strings.Split(strings.Join(words, ","), ",") {
+		if word == "" {
+			continue
+		}
+		hour, minute, used, err := parseClock(word, "")
+		if err != nil || used == 0 {
+			return "", model.ErrReminder
+		}
+		clock := fmt.Sprintf("%02d:%02d", hour, minute)
+		if !seen[clock] {
+			seen[clock] = true
+			clocks = append(clocks, clock)
+		}
+	}
+	if len(clocks) == 0 {
+		return "", model.ErrReminder
+	}
+	sort.Strings(clocks)
+	return strings.Join(clocks, ","), nil
+}
+
+// parseRemindDays reads "mon-fri", "mon wed fri", "weekdays", "weekends" or "every day" into "1,2,3,4,5", every day is ""
+func parseRemindDays(words []string) (string, error) {
+	phrase := strings.Join(words, " ")
+	switch phrase {
+	case "every day", "everyday", "daily":
+		return "", nil
+	case "weekdays":
+		phrase = "mon-fri"
+	case "weekends":
+		phrase = "sat sun"
+	}
+	days := map[time.Weekday]bool{}
+	for _, word := range strings.FieldsFunc(phrase, func(r rune) bool {
+		return r == ' ' || r == ','
+	}) {
+		bounds := strings.Split(word, "-")
+		first, ok := weekdayNames[bounds[0]]
+		if !ok || len(bounds) > 2 {
+			return "", model.ErrReminder
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdayNames[bounds[1]]; !ok {
+				return "", model.ErrReminder
+			}
+		}
+		// fri-mon goes over the weekend
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	if len(days) == 0 || len(days) == 7 {
+		return "", nil
+	}
+	numbers := []string{}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if days[day] {
+			numbers = append(numbers, strconv.Itoa(int(day)))
+		}
+	}
+	return strings.Join(numbers, ","), nil
+}
+
+// ReminderSetting is the reminder setting in words
+func (this *TodoBot) ReminderSetting(setting model.Setting) string {
+	times := this.T("defaultTimes", nil)
+	if setting.RemindTimes != "" {
+		times = strings.Replace(setting.RemindTimes, ",", ", ", -1)
+	}
+	days := this.T("everyDay", nil)
+	if setting.RemindDays != "" {
+		names := []string{}
+		weekdays := setting.Weekdays()
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if weekdays[day] {
+				names = append(names, this.weekdayName(day))
+			}
+		}
+		days = strings.Join(names, ", ")
+	}
+	quiet := this.T("settingOff", nil)
+	if setting.QuietStart != "" {
+		quiet = setting.QuietStart + "-" + setting.QuietEnd
+	}
+	return this.T("reminderSetting", Args{
+		"Times":     times,
+		"Days":      days,
+		"Quiet":     quiet,
+		"Completed": this.onOff(!setting.HideCompleted),
+		"Overdue":   this.onOff(setting.OnlyOverdue),
+	})
+}
+
+func (this *TodoBot) onOff(on bool) string {
+	if on {
+		return this.T("settingOn", nil)
+	}
+	return this.T("settingOff", nil)
+}
+
+// weekdayName is the short name of the day in the locale of the bot
+func (this *TodoBot) weekdayName(day time.Weekday) string {
+	// Any date on that day
+	return this.dateArgs(time.Date(2018, 11, 11+int(day), 0, 0, 0, 0, time.UTC))["Weekday"].(string)
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/choobot/choo-todo-bot/app/scheduler"
+)
+
+func TestTodoBotReminderCommand(t *testing.T) {
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel).In("en")
+	cases := []struct {
+		msg  string
+		want string
+	}{
+		{"remind", "⏰ Digest at the usual time on every day\n🔕 Quiet hours: off\n✅ Completed tasks: on\n⚠️ Only when overdue: off"},
+		{"remind at 8:00 6:30pm", "⏰ Digest at 08:00, 18:30 on every day\n🔕 Quiet hours: off\n✅ Completed tasks: on\n⚠️ Only when overdue: off"},
+		{"remind on mon-fri", "⏰ Digest at 08:00, 18:30 on Mon, Tue, Wed, Thu, Fri\n🔕 Quiet hours: off\n✅ Completed tasks: on\n⚠️ Only when overdue: off"},
+		{"remind on fri-mon", "⏰ Digest at 08:00, 18:30 on Sun, Mon, Fri, Sat\n🔕 Quiet hours: off\n✅ Completed tasks: on\n⚠️ Only when overdue: off"},
+		{"Quiet 22:00-07:00", "⏰ Digest at 08:00, 18:30 on Sun, Mon, Fri, Sat\n🔕 Quiet hours: 22:00-07:00\n✅ Completed tasks: on\n⚠️ Only when overdue: off"},
+		{"remind completed off", "⏰ Digest at 08:00, 18:30 on Sun, Mon, Fri, Sat\n🔕 Quiet hours: 22:00-07:00\n✅ Completed tasks: off\n⚠️ Only when overdue: off"},
+		{"remind overdue only", "⏰ Digest at 08:00, 18:30 on Sun, Mon, Fri, Sat\n🔕 Quiet hours: 22:00-07:00\n✅ Completed tasks: off\n⚠️ Only when overdue: on"},
+		{"remind always", "⏰ Digest at 08:00, 18:30 on Sun, Mon, Fri, Sat\n🔕 Quiet hours: 22:00-07:00\n✅ Completed tasks: off\n⚠️ Only when overdue: off"},
+		{"remind on every day", "⏰ Digest at 08:00, 18:30 on every day\n🔕 Quiet hours: 22:00-07:00\n✅ Completed tasks: off\n⚠️ Only when overdue: off"},
+		{"remind at default", "⏰ Digest at the usual time on every day\n🔕 Quiet hours: 22:00-07:00\n✅ Completed tasks: off\n⚠️ Only when overdue: off"},
+		{"quiet off", "⏰ Digest at the usual time on every day\n🔕 Quiet hours: off\n✅ Completed tasks: off\n⚠️ Only when overdue: off"},
+	}
+	for _, c := range cases {
+		reply, ok := bot.ReminderCommand("U1", c.msg)
+		if want := c.want + "\n" + `Change with "remind at 08:00 18:30", "remind on mon-fri", "quiet 22:00-07:00", "remind completed off" or "remind overdue only"`; !ok || reply != want {
+			t.Errorf("TodoBot.ReminderCommand(%q) == %q, %v, want %q", c.msg, reply, ok, want)
+		}
+	}
+
+	for _, msg := range []string{"remind at 25:00", "remind at 8", "remind on someday", "quiet 22:00", "quiet 22:00-22:00"} {
+		if reply, ok := bot.ReminderCommand("U1", msg); !ok || reply != bot.T("wrongReminder", nil) {
+			t.Errorf("TodoBot.ReminderCommand(%q) == %q, %v, want the hint", msg, reply, ok)
+		}
+	}
+	for _, msg := range []string{"remind mom : tomorrow", "remind mom", "reminder"} {
+		if _, ok := bot.ReminderCommand("U1", msg); ok {
+			t.Errorf("TodoBot.ReminderCommand(%q) == true, want false", msg)
+		}
+	}
+	if setting, _ := todoModel.Setting("U1"); setting != (model.Setting{UserID: "U1", HideCompleted: true}) {
+		t.Errorf("TodoModel.Setting() after the commands == %#v", setting)
+	}
+}
+
+func TestTodoBotIsDueSetting(t *testing.T) {
+	schedule, _ := scheduler.ParseSchedule("0 8 * * *")
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	// Thursday
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2018, 11, 15, hour, minute, 0, 0, loc)
+	}
+	cases := []struct {
+		setting model.Setting
+		from    time.Time
+		want    bool
+	}{
+		{model.Setting{}, at(7, 50), true},
+		{model.Setting{RemindTimes: "07:30,18:30"}, at(7, 50), false},
+		{model.Setting{RemindTimes: "07:30,18:30"}, at(18, 20), true},
+		{model.Setting{RemindDays: "0,6"}, at(7, 50), false},
+		{model.Setting{RemindDays: "4"}, at(7, 50), true},
+		// Deferred until the end of the quiet hours
+		{model.Setting{QuietStart: "22:00", QuietEnd: "09:00"}, at(7, 50), false},
+		{model.Setting{QuietStart: "22:00", QuietEnd: "09:00"}, at(8, 50), true},
+		{model.Setting{QuietStart: "07:00", QuietEnd: "09:00", RemindDays: "5"}, at(8, 50), false},
+		{model.Setting{QuietStart: "22:00", QuietEnd: "07:00", RemindTimes: "23:00"}, at(6, 50), true},
+		{model.Setting{QuietStart: "22:00", QuietEnd: "07:00"}, at(6, 50), false},
+	}
+	for _, c := range cases {
+		bot := &TodoBot{Setting: c.setting}
+		if got := bot.IsDue(schedule, c.from, c.from.Add(15*time.Minute)); got != c.want {
+			t.Errorf("TodoBot{%#v}.IsDue(%v) == %v, want %v", c.setting, c.from, got, c.want)
+		}
+	}
+}
+
+func TestTodoBotIsDeferred(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2018, 11, day, hour, minute, 0, 0, loc)
+	}
+	bot := &TodoBot{Setting: model.Setting{QuietStart: "22:00", QuietEnd: "07:00"}}
+	cases := []struct {
+		manual time.Time
+		from   time.Time
+		want   bool
+	}{
+		// A manual digest at 23:30 is sent when the quiet hours end at 07:00
+		{at(14, 23, 30), at(15, 6, 50), true},
+		{at(14, 23, 30), at(15, 6, 30), false},
+		{at(14, 23, 30), at(15, 7, 5), false},
+		// It was sent then
+		{at(14, 21, 30), at(15, 6, 50), false},
+		// Quiet hours of another night
+		{at(13, 23, 30), at(15, 6, 50), false},
+		{time.Time{}, at(15, 6, 50), false},
+	}
+	for _, c := range cases {
+		if got := bot.isDeferred(c.manual, c.from, c.from.Add(15*time.Minute)); got != c.want {
+			t.Errorf("TodoBot.isDeferred(%v, %v) == %v, want %v", c.manual, c.from, got, c.want)
+		}
+	}
+	if (&TodoBot{}).isDeferred(at(14, 23, 30), at(15, 6, 50), at(15, 7, 5)) {
+		t.Errorf("TodoBot.isDeferred() without quiet hours == true, want false")
+	}
+
+	// Remind keeps its time for the scheduled job
+	todoModel := newMockTodoModel()
+	bot = newMockBot(todoModel)
+	before := time.Now().Add(-time.Second)
+	if err := bot.Remind(); err != nil {
+		t.Fatalf("TodoBot.Remind() == %v, want %v", err, nil)
+	}
+	if last, err := todoModel.LastRun(remindNow); err != nil || last.Before(before) || last.After(time.Now()) {
+		t.Errorf("JobRunModel.LastRun(%q) after Remind() == %v, %v", remindNow, last, err)
+	}
+}
+
+func TestTodoBotRemindSetting(t *testing.T) {
+	now := time.Now()
+	todos := []model.Todo{
+		{Task: "Done", Done: true, Due: now.Add(-time.Hour)},
+		{Task: "Later", Due: now.Add(time.Hour)},
+	}
+	if hasOverdue(todos, now) {
+		t.Errorf("hasOverdue() of a done todo == true")
+	}
+	if !hasOverdue(append(todos, model.Todo{Task: "Late", Due: now.Add(-time.Minute)}), now) {
+		t.Errorf("hasOverdue() == false")
+	}
+	if remaining := remainingTodos(todos); len(remaining) != 1 || remaining[0].Task != "Later" {
+		t.Errorf("remainingTodos() == %#v", remaining)
+	}
+}
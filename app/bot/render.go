@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// Report is the todos of a reminder or a listing, the renderers draw it as text or Flex
+type Report struct {
+	// Title of a listing, the digests have their own
+	Title string
+	Todos []model.Todo
+	Lists []model.TodoList
+	// Footer of a digest like the edit link
+	Footer string
+	// Numbered todos are the positions for the commands, see TaskCommand
+	Numbered bool
+	// UserID of the chat the report is sent to, the Flex todos have buttons for it, see PostbackData
+	UserID string
+}
+
+// Section is a titled part of a report, a list or a tag
+type Section struct {
+	Title string
+	Todos []model.Todo
+}
+
+// Renderer draws a report as a LINE message, see TextRenderer and FlexRenderer
+type Renderer interface {
+	Render(bot *TodoBot, now time.Time, report Report) (linebot.SendingMessage, error)
+}
+
+// NewRenderer is the renderer of the format, "text" or "flex" by default
+func NewRenderer(format string) Renderer {
+	if strings.ToLower(format) == "text" {
+		return TextRenderer{}
+	}
+	return FlexRenderer{}
+}
+
+// TextRenderer draws plain text, every LINE client shows it
+type TextRenderer struct{}
+
+func (this TextRenderer) Render(bot *TodoBot, now time.Time, report Report) (linebot.SendingMessage, error) {
+	return linebot.NewTextMessage(this.Text(bot, now, report)), nil
+}
+
+// Text is the digest of RemindMessage or the numbered listing
+func (this TextRenderer) Text(bot *TodoBot, now time.Time, report Report) string {
+	if !report.Numbered {
+		return bot.Digest(now, report.Todos, report.Lists) + report.Footer
+	}
+	message := report.Title + "\n\n"
+	for i, todo := range report.Todos {
+		if todo.Done && (i == 0 || !report.Todos[i-1].Done) {
+			message += "\n" + bot.T("completed", nil) + "\n\n"
+		}
+		message += fmt.Sprintf("%d. %s", i+1, bot.FormatTodo(now, todo))
+	}
+	return strings.TrimRight(message, "\n")
+}
+
+// renderer is the text one until main sets another
+func (this *TodoBot) renderer() Renderer {
+	if this.Renderer == nil {
+		return TextRenderer{}
+	}
+	return this.Renderer
+}
+
+// Message is the report drawn by the renderer of the bot, in text when the renderer fails
+func (this *TodoBot) Message(now time.Time, report Report) linebot.SendingMessage {
+	message, err := this.renderer().Render(this, now, report)
+	if err != nil {
+		log.Println(err)
+		message, _ = TextRenderer{}.Render(this, now, report)
+	}
+	return message
+}
+
+// RemindReport is the digest Remind pushes to the user, group or room
+func (this *TodoBot) RemindReport(userID string, todos []model.Todo, lists []model.TodoList) Report {
+	footer := this.T("remindFooter", Args{"URL": os.Getenv("EDIT_URL")})
+	if IsGroupID(userID) {
+		footer = this.T("groupRemindFooter", nil)
+	}
+	return Report{
+		Todos:  todos,
+		Lists:  lists,
+		Footer: footer,
+		UserID: userID,
+	}
+}
+
+// Sections parts the todos like the digest, under their list, under their tags with GroupByTag or all together
+func (this *TodoBot) Sections(todos []model.Todo, lists []model.TodoList) []Section {
+	if len(lists) > 0 {
+		known := map[int]bool{}
+		for _, list := range lists {
+			known[list.ID] = true
+		}
+		sections := []Section{}
+		for _, list := range append([]model.TodoList{{Name: this.T("inbox", nil)}}, lists...) {
+			section := Section{Title: "📂 " + list.Name}
+			for _, todo := range todos {
+				if todo.ListID == list.ID || (list.ID == 0 && !known[todo.ListID]) {
+					section.Todos = append(section.Todos, todo)
+				}
+			}
+			if len(section.Todos) > 0 {
+				sections = append(sections, section)
+			}
+		}
+		return sections
+	}
+	if !this.GroupByTag {
+		return []Section{{Title: this.T("toBeDone", nil), Todos: todos}}
+	}
+	tagTodos := map[string][]model.Todo{}
+	untagged := []model.Todo{}
+	for _, todo := range todos {
+		if len(todo.Tags) == 0 {
+			untagged = append(untagged, todo)
+		}
+		for _, tag := range todo.Tags {
+			tagTodos[tag] = append(tagTodos[tag], todo)
+		}
+	}
+	tags := []string{}
+	for tag := range tagTodos {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	sections := []Section{}
+	for _, tag := range tags {
+		sections = append(sections, Section{Title: "🏷 #" + tag, Todos: tagTodos[tag]})
+	}
+	if len(untagged) > 0 {
+		sections = append(sections, Section{Title: "🏷 " + this.T("noTag", nil), Todos: untagged})
+	}
+	return sections
+}
@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// $ go test ./bot -update rewrites the golden files in testdata after a change of the rendering
+var update = flag.Bool("update", false, "update the golden files")
+
+func renderFixtures() (time.Time, map[string]Report) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	// Thursday
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	todos := []model.Todo{
+		{ID: 1, Task: "Pay rent", Pin: true, Due: time.Date(2018, 11, 14, 9, 0, 0, 0, loc), Repeat: "FREQ=MONTHLY"},
+		{ID: 2, Task: "Buy milk", Due: time.Date(2018, 11, 15, 17, 0, 0, 0, loc), Tags: []string{"home"}, ListID: 1},
+		{ID: 3, Task: "Write report", Due: time.Date(2018, 11, 20, 12, 0, 0, 0, loc), Tags: []string{"work"},
+			Steps: []model.Step{{Task: "Outline", Done: true}, {Task: "Draft"}}},
+		{ID: 4, Task: "Call mom", Done: true, Due: time.Date(2018, 11, 13, 12, 0, 0, 0, loc)},
+	}
+	lists := []model.TodoList{{ID: 1, Name: "Shopping"}}
+	return now, map[string]Report{
+		"digest":          {Todos: todos, Footer: "To edit go to https://example.com/"},
+		"digest_lists":    {Todos: todos, Lists: lists, Footer: "To edit go to https://example.com/"},
+		"digest_group":    {Todos: todos[1:2], Footer: "To add a task send \"Task : tomorrow\" here"},
+		"digest_done":     {Todos: todos[3:]},
+		"listing":         {Title: "Your todos:", Todos: todos, Numbered: true},
+		"listing_empty":   {Title: "Your todos:", Numbered: true},
+		"listing_buttons": {Title: "Your todos:", Todos: todos, Numbered: true, UserID: "U1"},
+	}
+}
+
+func TestRenderersGolden(t *testing.T) {
+	now, reports := renderFixtures()
+	renderers := map[string]Renderer{"text": TextRenderer{}, "flex": FlexRenderer{}}
+	bot := (&TodoBot{}).In("en")
+	for name, report := range reports {
+		for format, renderer := range renderers {
+			message, err := renderer.Render(bot, now, report)
+			if err != nil {
+				t.Errorf("%T.Render(%q) == %v", renderer, name, err)
+				continue
+			}
+			got, _ := json.MarshalIndent(message, "", "  ")
+			checkGolden(t, filepath.Join("testdata", name+"."+format+".json"), got)
+		}
+	}
+
+	// Tags are sections without lists
+	bot.GroupByTag = true
+	message, _ := FlexRenderer{}.Render(bot, now, reports["digest"])
+	got, _ := json.MarshalIndent(message, "", "  ")
+	checkGolden(t, filepath.Join("testdata", "digest_tags.flex.json"), got)
+}
+
+// checkGolden compares the JSON values, the SDK may order the keys of the Flex components differently
+func checkGolden(t *testing.T, path string, got []byte) {
+	if *update {
+		if err := ioutil.WriteFile(path, append(got, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("Golden file %v: %v, run go test with -update", path, err)
+		return
+	}
+	var want, gotValue interface{}
+	json.Unmarshal(data, &want)
+	json.Unmarshal(got, &gotValue)
+	if !reflect.DeepEqual(gotValue, want) {
+		t.Errorf("Rendered %v ==\n%s\nwant\n%s", path, got, data)
+	}
+}
+
+func TestTodoBotMessage(t *testing.T) {
+	now, reports := renderFixtures()
+	bot := (&TodoBot{Renderer: FlexRenderer{}}).In("en")
+	if message, _ := json.Marshal(bot.Message(now, reports["digest"])); !strings.HasPrefix(string(message), `{"type":"flex"`) {
+		t.Errorf("TodoBot.Message() == %s, want a Flex Message", message)
+	}
+
+	// More sections than bubbles in a carousel
+	report := Report{}
+	lists := []model.TodoList{}
+	for i := 1; i <= maxFlexBubbles+1; i++ {
+		lists = append(lists, model.TodoList{ID: i, Name: fmt.Sprint("List ", i)})
+		report.Todos = append(report.Todos, model.Todo{ID: i, Task: "Task", Due: now, ListID: i})
+	}
+	report.Lists = lists
+	if _, err := (FlexRenderer{}).Contents(bot, now, report); err != errFlexTooLarge {
+		t.Errorf("FlexRenderer.Contents() of %d sections == %v, want %v", len(lists), err, errFlexTooLarge)
+	}
+	if message, _ := json.Marshal(bot.Message(now, report)); !strings.HasPrefix(string(message), `{"type":"text"`) {
+		t.Errorf("TodoBot.Message() == %s, want the text fallback", message)
+	}
+
+	// Too many todos for the size of a Flex Message and for the alt text
+	report = Report{Numbered: true}
+	for i := 0; i < 200; i++ {
+		report.Todos = append(report.Todos, model.Todo{ID: i, Task: "Task", Due: now})
+	}
+	if _, err := (FlexRenderer{}).Contents(bot, now, report); err != errFlexTooLarge {
+		t.Errorf("FlexRenderer.Contents() of %d todos == %v, want %v", len(report.Todos), err, errFlexTooLarge)
+	}
+	if altText := []rune((FlexRenderer{}).AltText(bot, now, report)); len(altText) != maxAltText || string(altText[len(altText)-1]) != "…" {
+		t.Errorf("FlexRenderer.AltText() is %d long, want %d", len(altText), maxAltText)
+	}
+
+	// Text by default
+	bot.Renderer = nil
+	if message, _ := json.Marshal(bot.Message(now, reports["digest"])); !strings.HasPrefix(string(message), `{"type":"text"`) {
+		t.Errorf("TodoBot.Message() == %s, want text", message)
+	}
+}
+
+func TestNewRenderer(t *testing.T) {
+	if _, ok := NewRenderer("text").(TextRenderer); !ok {
+		t.Errorf("NewRenderer(%q) is not text", "text")
+	}
+	for _, format := range []string{"", "flex"} {
+		if _, ok := NewRenderer(format).(FlexRenderer); !ok {
+			t.Errorf("NewRenderer(%q) is not Flex", format)
+		}
+	}
+}
+
+func TestTodoBotRemindReport(t *testing.T) {
+	now, reports := renderFixtures()
+	bot := (&TodoBot{}).In("en")
+	todos := reports["digest"].Todos
+	if got, want := (TextRenderer{}).Text(bot, now, bot.RemindReport("U1", todos, nil)), bot.RemindMessage(now, todos, nil); got != want {
+		t.Errorf("TodoBot.RemindReport(%q) text == %q, want %q", "U1", got, want)
+	}
+	if got, want := (TextRenderer{}).Text(bot, now, bot.RemindReport("C1", todos, nil)), bot.GroupRemindMessage(now, todos, nil); got != want {
+		t.Errorf("TodoBot.RemindReport(%q) text == %q, want %q", "C1", got, want)
+	}
+}
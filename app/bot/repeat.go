@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+func (this *TodoBot) IsRepeat(phrase string) bool {
+	phrase = strings.ToLower(strings.TrimSpace(phrase))
+	switch phrase {
+	case "daily", "weekly", "monthly":
+		return true
+	}
+	return strings.HasPrefix(phrase, "every ") || strings.HasPrefix(phrase, "rrule:") || strings.HasPrefix(phrase, "freq=")
+}
+
+// 1) every day, daily, every 3 days
+// 2) every week, weekly, every 2 weeks, every weekday, every weekend
+// 3) every monday, every mon, wed and fri, every 2 weeks on monday and thursday
+// 4) every month, monthly, every month on 1st, every 3 months on the 15th
+// 5) RRULE:FREQ=WEEKLY;BYDAY=MO,FR
+func (this *TodoBot) ParseRepeat(phrase string) (model.Recurrence, error) {
+	wrongFormat := errors.New("Wrong format")
+	phrase = strings.ToLower(strings.TrimSpace(phrase))
+	if strings.HasPrefix(phrase, "rrule:") || strings.HasPrefix(phrase, "freq=") {
+		return model.ParseRecurrence(phrase)
+	}
+	switch phrase {
+	case "daily":
+		phrase = "every day"
+	case "weekly":
+		phrase = "every week"
+	case "monthly":
+		phrase = "every month"
+	}
+	if !strings.HasPrefix(phrase, "every ") {
+		return model.Recurrence{}, wrongFormat
+	}
+	rest := strings.TrimPrefix(phrase, "every ")
+	on := ""
+	if i := strings.Index(rest, " on "); i >= 0 {
+		on = strings.TrimSpace(rest[i+4:])
+		rest = rest[:i]
+	}
+	recurrence := model.Recurrence{
+		Interval: 1,
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 2 {
+		interval, err := strconv.Atoi(fields[0])
+		if err != nil || interval < 1 {
+			return model.Recurrence{}, wrongFormat
+		}
+		recurrence.Interval = interval
+		fields = fields[1:]
+	}
+	unit := ""
+	if len(fields) == 1 {
+		unit = strings.TrimSuffix(fields[0], "s")
+	}
+	switch unit {
+	case "day":
+		recurrence.Freq = "DAILY"
+	case "week":
+		recurrence.Freq = "WEEKLY"
+		if on != "" {
+			days, err := this.parseWeekdays(on)
+			if err != nil {
+				return model.Recurrence{}, err
+			}
+			recurrence.ByDay = days
+		}
+	case "month":
+		recurrence.Freq = "MONTHLY"
+		if on != "" {
+			day, err := this.parseMonthDay(on)
+			if err != nil {
+				return model.Recurrence{}, err
+			}
+			recurrence.ByMonthDay = day
+		}
+	case "weekday":
+		recurrence.Freq = "WEEKLY"
+		recurrence.ByDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	case "weekend":
+		recurrence.Freq = "WEEKLY"
+		recurrence.ByDay = []time.Weekday{time.Saturday, time.Sunday}
+	default:
+		if recurrence.Interval != 1 || on != "" {
+			return model.Recurrence{}, wrongFormat
+		}
+		days, err := this.parseWeekdays(rest)
+		if err != nil {
+			return model.Recurrence{}, err
+		}
+		recurrence.Freq = "WEEKLY"
+		recurrence.ByDay = days
+	}
+	if on != "" && recurrence.ByDay == nil && recurrence.ByMonthDay == 0 {
+		return model.Recurrence{}, wrongFormat
+	}
+	return recurrence, nil
+}
+
+// mon, wed and fri
+func (this *TodoBot) parseWeekdays(text string) ([]time.Weekday, error) {
+	text = strings.Replace(text, ",", " ", -1)
+	days := []time.Weekday{}
+	for _, word := range strings.Fields(text) {
+		if word == "and" {
+			continue
+		}
+		day, ok := weekdayNames[word]
+		if !ok {
+			return nil, errors.New("Wrong format")
+		}
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return nil, errors.New("Wrong format")
+	}
+	return days, nil
+}
+
+// 1st, the 2nd, 15
+func (this *TodoBot) parseMonthDay(text string) (int, error) {
+	text = strings.TrimPrefix(text, "the ")
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		text = strings.TrimSuffix(text, suffix)
+	}
+	day, err := strconv.Atoi(text)
+	if err != nil || day < 1 || day > 31 {
+		return 0, errors.New("Wrong format")
+	}
+	return day, nil
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/choobot/choo-todo-bot/app/scheduler"
+)
+
+// remindTick is how often the scheduler looks for the digests to send, the time zones are 15 minutes apart at least
+const remindTick = "*/15 * * * *"
+
+// remindNow is the job run of the last Remind, the digests it deferred are sent by RemindBetween
+const remindNow = "remind-now"
+
+// RemindBetween sends the digests of the users whose schedule has a time after from until to in their time zone,
+// and the ones the last Remind deferred when the quiet hours end
+func (this *TodoBot) RemindBetween(from time.Time, to time.Time, schedule *scheduler.Schedule) error {
+	manual, err := this.JobRunModel.LastRun(remindNow)
+	if err != nil {
+		return err
+	}
+	return this.remind(func(bot *TodoBot) bool {
+		return bot.IsDue(schedule, from, to) || bot.isDeferred(manual, from, to)
+	})
+}
+
+// isDeferred is whether the quiet hours ending after from until to were on at the time of a manual digest
+func (this *TodoBot) isDeferred(manual time.Time, from time.Time, to time.Time) bool {
+	start, end, ok := this.quietEnd(from, to)
+	return ok && !manual.IsZero() && !manual.Before(start) && manual.Before(end)
+}
+
+// IsDue is whether the digest is due after from until to in the time zone of the bot, at the times and on the days of the setting,
+// the schedule by default, or when the quiet hours end if it was due during them
+func (this *TodoBot) IsDue(schedule *scheduler.Schedule, from time.Time, to time.Time) bool {
+	for _, t := range this.digestTimes(schedule, from, to) {
+		if !this.isQuiet(t) {
+			return true
+		}
+	}
+	start, end, ok := this.quietEnd(from, to)
+	// The times from the start until the end of the quiet hours
+	return ok && len(this.digestTimes(schedule, start.Add(-time.Nanosecond), end.Add(-time.Nanosecond))) > 0
+}
+
+// digestTimes are the times of the digest after from until to
+func (this *TodoBot) digestTimes(schedule *scheduler.Schedule, from time.Time, to time.Time) []time.Time {
+	schedules := []*scheduler.Schedule{schedule}
+	if clocks := this.Setting.Clocks(); len(clocks) > 0 {
+		schedules = []*scheduler.Schedule{}
+		for _, clock := range clocks {
+			if own, err := scheduler.ParseSchedule(fmt.Sprintf("%d %d * * *", clock%60, clock/60)); err == nil {
+				schedules = append(schedules, own)
+			}
+		}
+	}
+	weekdays := this.Setting.Weekdays()
+	from = from.In(this.location())
+	times := []time.Time{}
+	for _, schedule := range schedules {
+		for t := schedule.Next(from); !t.IsZero() && !t.After(to); t = schedule.Next(t) {
+			if weekdays[t.Weekday()] {
+				times = append(times, t)
+			}
+		}
+	}
+	return times
+}
+
+// quietHours are the minutes of the day of the start and the end of the quiet hours, false when there are none
+func (this *TodoBot) quietHours() (int, int, bool) {
+	start, err := model.ParseClock(this.Setting.QuietStart)
+	if err != nil {
+		return 0, 0, false
+	}
+	end, err := model.ParseClock(this.Setting.QuietEnd)
+	if err != nil || start == end {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// isQuiet is whether the time is in the quiet hours, "22:00" to "07:00" is over midnight
+func (this *TodoBot) isQuiet(t time.Time) bool {
+	start, end, ok := this.quietHours()
+	if !ok {
+		return false
+	}
+	t = t.In(this.location())
+	minutes := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minutes >= start && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+// quietEnd is the start and the end of the last quiet hours ending after from until to
+func (this *TodoBot) quietEnd(from time.Time, to time.Time) (time.Time, time.Time, bool) {
+	startClock, endClock, ok := this.quietHours()
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	loc := this.location()
+	to = to.In(loc)
+	end := time.Date(to.Year(), to.Month(), to.Day(), endClock/60, endClock%60, 0, 0, loc)
+	if end.After(to) {
+		end = time.Date(to.Year(), to.Month(), to.Day()-1, endClock/60, endClock%60, 0, 0, loc)
+	}
+	if !end.After(from) {
+		return time.Time{}, time.Time{}, false
+	}
+	day := end.Day()
+	if startClock > endClock {
+		// Since the day before
+		day--
+	}
+	return time.Date(end.Year(), end.Month(), day, startClock/60, startClock%60, 0, 0, loc), end, true
+}
+
+// RemindJob sends each digest at the times of the schedule in the time zone of the user, a quarter late at most
+func (this *TodoBot) RemindJob(schedule *scheduler.Schedule) scheduler.Job {
+	tick, _ := scheduler.ParseSchedule(remindTick)
+	return scheduler.Job{
+		Name:     "remind",
+		Schedule: tick,
+		Run: func(from time.Time, to time.Time) error {
+			return this.RemindBetween(from, to, schedule)
+		},
+	}
+}
+
+// PurgeJob deletes the inactive users once a day, see PurgeInactive
+func (this *TodoBot) PurgeJob(retention time.Duration) scheduler.Job {
+	daily, _ := scheduler.ParseSchedule("@daily")
+	return scheduler.Job{
+		Name:     "purge",
+		Schedule: daily,
+		Run: func(from time.Time, to time.Time) error {
+			return this.PurgeInactive(to, retention)
+		},
+	}
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// ListNotifier tells the other members of a shared list about changes made by a user
+type ListNotifier interface {
+	NotifyCreated(todo model.Todo)
+	NotifyDone(userID string, todoID int)
+}
+
+// InviteURL opens the chat with the bot with the join message typed in, LINE_BOT_ID is the bot ID like @gpd2291p
+func InviteURL(code string) string {
+	botID := os.Getenv("LINE_BOT_ID")
+	if botID == "" {
+		return ""
+	}
+	return "https://line.me/R/oaMessage/" + url.PathEscape(botID) + "/?" + url.PathEscape("join "+code)
+}
+
+// ShareCommand handles "share shopping", "join K3XQ7M2A" and "leave shopping" in one-on-one chats, it returns false for other messages
+func (this *TodoBot) ShareCommand(userID string, msg string) (string, bool) {
+	if IsGroupID(userID) {
+		// "join us" in a group is a chat
+		return "", false
+	}
+	fields := strings.Fields(msg)
+	if len(fields) != 2 {
+		return "", false
+	}
+	arg := strings.TrimPrefix(fields[1], "@")
+	switch strings.ToLower(fields[0]) {
+	case "share":
+		return this.ShareReply(userID, arg), true
+	case "join":
+		return this.JoinReply(userID, arg), true
+	case "leave":
+		return this.LeaveReply(userID, arg), true
+	}
+	return "", false
+}
+
+func (this *TodoBot) ShareReply(userID string, name string) string {
+	list, err := this.TodoModel.FindList(userID, name)
+	if err == model.ErrNotFound {
+		return this.T("listNotFound", Args{"List": name})
+	}
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	code, err := this.TodoModel.ShareList(userID, list)
+	if err == model.ErrForbidden {
+		return this.T("shareForbidden", Args{"List": list.Name})
+	}
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	reply := this.T("share", Args{"List": list.Name, "Code": code})
+	if link := InviteURL(code); link != "" {
+		reply += this.T("shareLink", Args{"URL": link})
+	}
+	return reply
+}
+
+func (this *TodoBot) JoinReply(userID string, code string) string {
+	list, err := this.TodoModel.JoinList(userID, code)
+	if err == model.ErrNotFound {
+		return this.T("wrongInviteCode", nil)
+	}
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	return this.T("joined", Args{"List": list.Name})
+}
+
+func (this *TodoBot) LeaveReply(userID string, name string) string {
+	list, err := this.TodoModel.FindList(userID, name)
+	if err == model.ErrNotFound {
+		return this.T("listNotFound", Args{"List": name})
+	}
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	err = this.TodoModel.LeaveList(userID, list)
+	if err == model.ErrForbidden {
+		return this.T("leaveForbidden", Args{"List": list.Name})
+	}
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	return this.T("left", Args{"List": list.Name})
+}
+
+// MemberMessage is the notification of a todo created or done in the shared list
+func (this *TodoBot) MemberMessage(now time.Time, list model.TodoList, todo model.Todo) string {
+	header := this.T("memberCreated", Args{"List": list.Name})
+	if todo.Done {
+		header = this.T("memberDone", Args{"List": list.Name})
+	}
+	return header + "\n" + this.FormatTodo(now, todo)
+}
+
+// notifyMembers pushes the message to the members of the list except the user
+func (this *TodoBot) notifyMembers(userID string, todo model.Todo) {
+	if todo.ListID == 0 {
+		return
+	}
+	members, err := this.TodoModel.ListMembers(todo.ListID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if len(members) < 2 {
+		return
+	}
+	lists, err := this.TodoModel.Lists(userID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, list := range lists {
+		if list.ID == todo.ListID {
+			for _, member := range members {
+				if member != userID {
+					message := this.For(member, "").MemberMessage(time.Now(), list, todo)
+					go this.PushMessage(member, message)
+				}
+			}
+		}
+	}
+}
+
+func (this *TodoBot) NotifyCreated(todo model.Todo) {
+	this.notifyMembers(todo.UserID, todo)
+}
+
+func (this *TodoBot) NotifyDone(userID string, todoID int) {
+	todos, err := this.TodoModel.List(userID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, todo := range todos {
+		if todo.ID == todoID && todo.Done {
+			this.notifyMembers(userID, todo)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+func TestInviteURL(t *testing.T) {
+	botID := os.Getenv("LINE_BOT_ID")
+	defer os.Setenv("LINE_BOT_ID", botID)
+
+	os.Setenv("LINE_BOT_ID", "")
+	if got := InviteURL("K3XQ7M2A"); got != "" {
+		t.Errorf("InviteURL() == %q, want %q", got, "")
+	}
+	os.Setenv("LINE_BOT_ID", "@gpd2291p")
+	want := "https://line.me/R/oaMessage/@gpd2291p/?join%20K3XQ7M2A"
+	if got := InviteURL("K3XQ7M2A"); got != want {
+		t.Errorf("InviteURL() == %q, want %q", got, want)
+	}
+}
+
+func TestTodoBotShareCommand(t *testing.T) {
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	todoModel.CreateList(model.TodoList{UserID: "owner", Name: "Family"})
+
+	if _, ok := bot.ShareCommand("owner", "Milk : today"); ok {
+		t.Errorf("TodoBot.ShareCommand(%q) handled", "Milk : today")
+	}
+	if reply, _ := bot.ShareCommand("owner", "share @work"); reply != "List work not found" {
+		t.Errorf("TodoBot.ShareCommand(%q) == %q", "share @work", reply)
+	}
+	reply, ok := bot.ShareCommand("owner", "share family")
+	lines := strings.Split(reply, "\n")
+	if !ok || len(lines) < 2 || !strings.HasPrefix(lines[1], "join ") {
+		t.Fatalf("TodoBot.ShareCommand(%q) == %q, %v", "share family", reply, ok)
+	}
+	join := lines[1]
+
+	if reply, _ := bot.ShareCommand("member", "join WRONG"); reply != "Wrong invite code" {
+		t.Errorf("TodoBot.ShareCommand(%q) == %q", "join WRONG", reply)
+	}
+	if reply, _ := bot.ShareCommand("member", join); reply != "You have joined Family 🆗, add tasks to it with @Family" {
+		t.Errorf("TodoBot.ShareCommand(%q) == %q", join, reply)
+	}
+	if reply, _ := bot.ShareCommand("member", "share family"); reply != "Only the owner can share Family" {
+		t.Errorf("TodoBot.ShareCommand(%q) by a member == %q", "share family", reply)
+	}
+
+	// Members add to the shared list by name
+	if reply := bot.CreateTodo(model.Todo{UserID: "member", Task: "Milk", Due: time.Now()}, "family"); reply != "Task has been created in Family 🆗" {
+		t.Errorf("TodoBot.CreateTodo() == %q", reply)
+	}
+	if todos, _ := todoModel.List("owner"); len(todos) != 1 {
+		t.Errorf("TodoModel.List(%q) == %v, want the task of %q", "owner", todos, "member")
+	}
+
+	if reply, _ := bot.ShareCommand("owner", "leave family"); reply != "You own Family, delete it on the web instead" {
+		t.Errorf("TodoBot.ShareCommand(%q) by the owner == %q", "leave family", reply)
+	}
+	if reply, _ := bot.ShareCommand("member", "leave family"); reply != "You have left Family 🆗" {
+		t.Errorf("TodoBot.ShareCommand(%q) == %q", "leave family", reply)
+	}
+}
+
+func TestTodoBotMemberMessage(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	list := model.TodoList{ID: 1, Name: "Family"}
+	todo := model.Todo{Task: "Milk", Due: time.Date(2018, 11, 15, 18, 0, 0, 0, loc), ListID: 1}
+	bot := TodoBot{}
+
+	want := "🆕 New task in Family\n📆 Milk : Today at 18:00\n"
+	if got := bot.MemberMessage(now, list, todo); got != want {
+		t.Errorf("TodoBot.MemberMessage() == %q, want %q", got, want)
+	}
+	todo.Done = true
+	want = "✅ Task done in Family\n📆 Milk : Today at 18:00\n"
+	if got := bot.MemberMessage(now, list, todo); got != want {
+		t.Errorf("TodoBot.MemberMessage() == %q, want %q", got, want)
+	}
+}
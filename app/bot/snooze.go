@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"errors"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// errSnooze is a snooze until a time that has passed
+var errSnooze = errors.New("Snooze until a later time")
+
+// snoozeButton is how long the Snooze button of the Flex Messages snoozes a todo
+const snoozeButton = time.Hour
+
+// awakeTodos leaves the snoozed todos out of the digest
+func awakeTodos(todos []model.Todo, now time.Time) []model.Todo {
+	awake := []model.Todo{}
+	for _, todo := range todos {
+		if !todo.IsSnoozed(now) {
+			awake = append(awake, todo)
+		}
+	}
+	return awake
+}
+
+// SnoozeReply handles "snooze 3 until tomorrow 9am" for the todo 3 of the last listing, see ParseDue
+func (this *TodoBot) SnoozeReply(userID string, position int, phrase string) string {
+	todo, err := this.todoAt(userID, position)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	now := time.Now().In(this.location())
+	until, err := this.ParseDue(now, phrase)
+	if err != nil {
+		return this.ErrorReply(err)
+	}
+	reply, err := this.Snooze(userID, todo, until, now)
+	if err == errSnooze {
+		return this.T("wrongSnooze", nil)
+	} else if err != nil {
+		return this.commandError(position, err)
+	}
+	return reply
+}
+
+// WakeReply handles "wake 3" that shows the snoozed todo 3 again
+func (this *TodoBot) WakeReply(userID string, position int) string {
+	todo, err := this.todoAt(userID, position)
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	reply, err := this.Snooze(userID, todo, time.Time{}, time.Now())
+	if err != nil {
+		return this.commandError(position, err)
+	}
+	return reply
+}
+
+// Snooze hides the todo from the digests and the alerts until then without moving its due date, a zero time wakes it up,
+// it is shared by the commands and the buttons, see PostbackReply
+func (this *TodoBot) Snooze(userID string, todo model.Todo, until time.Time, now time.Time) (string, error) {
+	if !until.IsZero() && !until.After(now) {
+		return "", errSnooze
+	}
+	todo.Snoozed = until
+	if err := this.TodoModel.Snooze(userID, todo); err != nil {
+		return "", err
+	}
+	if until.IsZero() {
+		return this.T("woken", Args{"Task": todo.Task}), nil
+	}
+	return this.T("snoozed", Args{"Task": todo.Task, "Until": this.FormatDate(now, until), "Due": this.FormatDate(now, todo.Due)}), nil
+}
+
+// SnoozedBadge is "💤 Tomorrow at 09:00" for a snoozed todo, empty for the others
+func (this *TodoBot) SnoozedBadge(now time.Time, todo model.Todo) string {
+	if !todo.IsSnoozed(now) {
+		return ""
+	}
+	return this.T("snoozedBadge", Args{"Until": this.FormatDate(now, todo.Snoozed)})
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+func TestTodoBotSnoozeCommand(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Now().In(loc)
+	due := now.AddDate(0, 0, 3)
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: due})
+	bot := newMockBot(todoModel).In("en")
+	bot.Listing("U1")
+
+	cases := []struct {
+		msg  string
+		want string
+	}{
+		{"snooze 1 until next friday 9am", "💤 Pay rent is snoozed until "},
+		{"snooze 1 until someday", `I don't understand "someday", try "next friday 5pm" or "25 May"`},
+		{"snooze 2 until tomorrow", `There is no todo 2, send "list" to see the numbers`},
+	}
+	for _, c := range cases {
+		if reply, ok := bot.TaskCommand("U1", c.msg); !ok || !strings.HasPrefix(reply, c.want) {
+			t.Errorf("TodoBot.TaskCommand(%q) == %q, %v, want %q", c.msg, reply, ok, c.want)
+		}
+	}
+	todos, _ := todoModel.List("U1")
+	if snoozed := todos[0].Snoozed.In(loc); snoozed.Weekday() != time.Friday || snoozed.Hour() != 9 || !todos[0].Due.Equal(due) {
+		t.Errorf("TodoBot.TaskCommand(%q) todo == %#v", "snooze 1 until next friday 9am", todos[0])
+	}
+	if reply := bot.ListReply("U1", now); !strings.Contains(reply, "💤 ") {
+		t.Errorf("TodoBot.ListReply() of a snoozed todo == %q", reply)
+	}
+
+	if _, err := bot.Snooze("U1", todos[0], now.Add(-time.Minute), now); err != errSnooze {
+		t.Errorf("TodoBot.Snooze() until a passed time == %v, want %v", err, errSnooze)
+	}
+	if reply, ok := bot.TaskCommand("U1", "Wake 1"); !ok || reply != "⏰ Pay rent is back in the reminders" {
+		t.Errorf("TodoBot.TaskCommand(%q) == %q, %v", "wake 1", reply, ok)
+	}
+	if todos, _ := todoModel.List("U1"); !todos[0].Snoozed.IsZero() {
+		t.Errorf("TodoBot.TaskCommand(%q) todo == %#v", "wake 1", todos[0])
+	}
+
+	for _, msg := range []string{"snooze 1 tomorrow", "snooze 1", "wake 1 up"} {
+		if reply, ok := bot.TaskCommand("U1", msg); ok {
+			t.Errorf("TodoBot.TaskCommand(%q) == %q, want unhandled", msg, reply)
+		}
+	}
+}
+
+func TestTodoBotSnoozedBadge(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	bot := (&TodoBot{}).In("en")
+	todo := model.Todo{Task: "Pay rent", Due: time.Date(2018, 11, 14, 9, 0, 0, 0, loc), Snoozed: time.Date(2018, 11, 16, 9, 0, 0, 0, loc)}
+	if got := bot.FormatTodo(now, todo); got != "📆 Pay rent : Yesterday at 09:00 (overdue) 💤 Tomorrow at 09:00\n" {
+		t.Errorf("TodoBot.FormatTodo() of a snoozed todo == %q", got)
+	}
+	if got := bot.SnoozedBadge(todo.Snoozed, todo); got != "" {
+		t.Errorf("TodoBot.SnoozedBadge() once awake == %q, want none", got)
+	}
+	buttons := FlexRenderer{}.buttons(bot, now, "U1", todo)
+	if label := buttons.Contents[2].(flexText).Text; label != "Wake up" {
+		t.Errorf("FlexRenderer.buttons() of a snoozed todo == %q, want %q", label, "Wake up")
+	}
+
+	todos := awakeTodos([]model.Todo{todo, {Task: "Call mom", Due: now}}, now)
+	if len(todos) != 1 || todos[0].Task != "Call mom" {
+		t.Errorf("awakeTodos() == %v", todos)
+	}
+}
+
+func TestTodoBotSnoozedAlerts(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 8, 50, 0, 0, loc)
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: now.Add(10 * time.Minute)})
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Call mom", Due: now.Add(-time.Hour)})
+	todos, _ := todoModel.List("U1")
+	for _, todo := range todos {
+		todo.Snoozed = now.Add(30 * time.Minute)
+		todoModel.Snooze("U1", todo)
+	}
+	bot := newMockBot(todoModel)
+
+	if alerts, err := bot.DueAlerts(now); err != nil || len(alerts) != 0 {
+		t.Errorf("TodoBot.DueAlerts() of a snoozed todo == %+v, %v, want none", alerts, err)
+	}
+	if escalations, err := bot.DueEscalations(now); err != nil || len(escalations) != 0 {
+		t.Errorf("TodoBot.DueEscalations() of a snoozed todo == %+v, %v, want none", escalations, err)
+	}
+	// The overdue notice is sent once it wakes up, the alert of a todo due by then is dropped
+	if alerts, err := bot.DueAlerts(now.Add(30 * time.Minute)); err != nil || len(alerts) != 0 {
+		t.Errorf("TodoBot.DueAlerts() after the snooze == %+v, %v, want none", alerts, err)
+	}
+	if escalations, err := bot.DueEscalations(now.Add(30 * time.Minute)); err != nil || len(escalations) != 1 || escalations[0].Todo.Task != "Call mom" {
+		t.Errorf("TodoBot.DueEscalations() after the snooze == %+v, %v", escalations, err)
+	}
+}
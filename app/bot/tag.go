@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// ParseTags takes the #tags out of the task, e.g. "Buy milk #home" is "Buy milk" tagged home
+func (this *TodoBot) ParseTags(task string) (string, []string) {
+	words := []string{}
+	tags := []string{}
+	for _, word := range strings.Fields(task) {
+		if strings.HasPrefix(word, "#") && len(word) > 1 {
+			tags = append(tags, word)
+		} else {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " "), model.NormalizeTags(tags)
+}
+
+// TagSections lists the todos under each of their tags, untagged todos come last
+func (this *TodoBot) TagSections(now time.Time, todos []model.Todo) string {
+	tagTodos := map[string][]model.Todo{}
+	untagged := []model.Todo{}
+	for _, todo := range todos {
+		if len(todo.Tags) == 0 {
+			untagged = append(untagged, todo)
+		}
+		for _, tag := range todo.Tags {
+			tagTodos[tag] = append(tagTodos[tag], todo)
+		}
+	}
+	tags := []string{}
+	for tag := range tagTodos {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	message := ""
+	for _, tag := range tags {
+		message += "🏷 #" + tag + "\n"
+		for _, todo := range tagTodos[tag] {
+			message += this.FormatTodo(now, todo)
+		}
+		message += "\n"
+	}
+	if len(untagged) > 0 {
+		message += "🏷 " + this.T("noTag", nil) + "\n"
+		for _, todo := range untagged {
+			message += this.FormatTodo(now, todo)
+		}
+		message += "\n"
+	}
+	return message
+}
@@ -0,0 +1,117 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+func TestTodoBotParseUserMessageTags(t *testing.T) {
+	cases := []struct {
+		in       string
+		wantTask string
+		wantTags string
+		wantErr  string
+	}{
+		{
+			in:       "Go shopping #home : today",
+			wantTask: "Go shopping",
+			wantTags: "[home]",
+		},
+		{
+			in:       "#Work Write report #urgent : tomorrow : 09:00",
+			wantTask: "Write report",
+			wantTags: "[urgent work]",
+		},
+		{
+			in:       "Fix issue # 5 : today",
+			wantTask: "Fix issue # 5",
+			wantTags: "[]",
+		},
+		{
+			in:      "#home : today",
+			wantErr: "Wrong format",
+		},
+	}
+
+	bot := TodoBot{}
+	for _, c := range cases {
+		got, err := bot.ParseUserMessage(c.in)
+		if c.wantErr != "" {
+			if err == nil || err.Error() != c.wantErr {
+				t.Errorf("TodoBot.ParseUserMessage(%q) == %v, want %v", c.in, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Task != c.wantTask || fmt.Sprint(got.Tags) != c.wantTags {
+			t.Errorf("TodoBot.ParseUserMessage(%q) == %q %v, %v, want %q %v", c.in, got.Task, got.Tags, err, c.wantTask, c.wantTags)
+		}
+	}
+}
+
+func TestTodoBotRemindMessage(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 9, 0, 0, 0, loc)
+	due := time.Date(2018, 11, 15, 15, 4, 0, 0, loc)
+	todos := []model.Todo{
+		{Task: "Report", Due: due, Tags: []string{"urgent", "work"}},
+		{Task: "Milk", Due: due, Tags: []string{"home"}},
+		{Task: "Call mom", Due: due},
+		{Task: "Laundry", Due: due, Done: true, Tags: []string{"home"}},
+	}
+	defer os.Setenv("EDIT_URL", os.Getenv("EDIT_URL"))
+	os.Setenv("EDIT_URL", "https://dummy")
+
+	bot := TodoBot{}
+	want := `🎯 TASKS TO BE DONE 🎯
+
+📆 Report #urgent #work : Today at 15:04
+📆 Milk #home : Today at 15:04
+📆 Call mom : Today at 15:04
+
+🆗 TASKS COMPLETED 🆗
+
+📆 Laundry #home : Today at 15:04
+
+3 of 4 remaining, just do it! 💪
+
+To edit go to https://dummy`
+	if got := bot.RemindMessage(now, todos, nil); got != want {
+		t.Errorf("TodoBot.RemindMessage() == %q, want %q", got, want)
+	}
+
+	bot.GroupByTag = true
+	got := bot.RemindMessage(now, todos, nil)
+	want = `🎯 TASKS TO BE DONE 🎯
+
+🏷 #home
+📆 Milk #home : Today at 15:04
+
+🏷 #urgent
+📆 Report #urgent #work : Today at 15:04
+
+🏷 #work
+📆 Report #urgent #work : Today at 15:04
+
+🏷 No tag
+📆 Call mom : Today at 15:04
+`
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("TodoBot.RemindMessage() == %q, want prefix %q", got, want)
+	}
+	if !strings.Contains(got, "3 of 4 remaining") {
+		t.Errorf("TodoBot.RemindMessage() == %q, want %q", got, "3 of 4 remaining")
+	}
+
+	// Steps
+	todos = []model.Todo{
+		{Task: "Move", Due: due, Steps: []model.Step{{Done: true}, {Done: true}, {Done: true}, {}, {}}},
+	}
+	if got := bot.RemindMessage(now, todos, nil); !strings.Contains(got, "📆 Move : Today at 15:04 (3/5 steps)\n") {
+		t.Errorf("TodoBot.RemindMessage() == %q, want %q", got, "3/5 steps")
+	}
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// thaiDueWords are replaced in order, longer words first
+var thaiDueWords = []string{
+	"วันอาทิตย์หน้า", " next sunday ",
+	"สัปดาห์หน้า", " next week ",
+	// Without วัน it is next week in speech
+	"อาทิตย์หน้า", " next week ",
+	"เดือนหน้า", " next month ",
+	"สิ้นสัปดาห์", " end of week ",
+	"สิ้นเดือน", " end of month ",
+	"มะรืนนี้", " in 2 days ",
+	"มะรืน", " in 2 days ",
+	"พรุ่งนี้", " tomorrow ",
+	"วันนี้", " today ",
+	"คืนนี้", " tonight ",
+	"เที่ยงวัน", " noon ",
+	"เที่ยง", " noon ",
+	"วันที่", " ",
+	"เวลา", " at ",
+	"ตอน", " at ",
+	"อีก", " in ",
+	"มกราคม", " jan ", "ม.ค.", " jan ",
+	"กุมภาพันธ์", " feb ", "ก.พ.", " feb ",
+	"มีนาคม", " mar ", "มี.ค.", " mar ",
+	"เมษายน", " apr ", "เม.ย.", " apr ",
+	"พฤษภาคม", " may ", "พ.ค.", " may ",
+	"มิถุนายน", " jun ", "มิ.ย.", " jun ",
+	"กรกฎาคม", " jul ", "ก.ค.", " jul ",
+	"สิงหาคม", " aug ", "ส.ค.", " aug ",
+	"กันยายน", " sep ", "ก.ย.", " sep ",
+	"ตุลาคม", " oct ", "ต.ค.", " oct ",
+	"พฤศจิกายน", " nov ", "พ.ย.", " nov ",
+	"ธันวาคม", " dec ", "ธ.ค.", " dec ",
+}
+
+// thaiUnits come after the weekdays so that วัน of วันศุกร์ is not a day
+var thaiUnits = strings.NewReplacer(
+	"ชั่วโมง", " hours ",
+	"ชม.", " hours ",
+	"นาที", " minutes ",
+	"สัปดาห์", " weeks ",
+	"เดือน", " months ",
+	"วัน", " days ",
+)
+
+var thaiDigits = strings.NewReplacer("๐", "0", "๑", "1", "๒", "2", "๓", "3", "๔", "4", "๕", "5", "๖", "6", "๗", "7", "๘", "8", "๙", "9")
+
+var thaiWeekdayPattern = regexp.MustCompile(`(วัน)?(จันทร์|อังคาร|พุธ|พฤหัสบดี|พฤหัส|ศุกร์|เสาร์|อาทิตย์)(หน้า|นี้)?`)
+
+var thaiWeekdays = map[string]string{
+	"จันทร์": "monday", "อังคาร": "tuesday", "พุธ": "wednesday", "พฤหัสบดี": "thursday",
+	"พฤหัส": "thursday", "ศุกร์": "friday", "เสาร์": "saturday", "อาทิตย์": "sunday",
+}
+
+// อาทิตย์ is a week after อีก 2, not Sunday
+var thaiWeeksPattern = regexp.MustCompile(`อีก\s*(\d+)\s*อาทิตย์`)
+
+// 17.30 น., 17:30 น. or 17 น.
+var thaiClockPattern = regexp.MustCompile(`(\d{1,2})(?:[.:](\d{2}))?\s*น\.`)
+
+// IsThai tells if the text has any Thai letter
+func IsThai(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Thai, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// thaiDue rewrites the Thai words of a due date in English for ParseDue, e.g. "วันศุกร์หน้า 17.30 น." is " next friday 17:30"
+func thaiDue(phrase string) string {
+	if !IsThai(phrase) {
+		return phrase
+	}
+	phrase = thaiDigits.Replace(phrase)
+	phrase = thaiClockPattern.ReplaceAllStringFunc(phrase, func(clock string) string {
+		match := thaiClockPattern.FindStringSubmatch(clock)
+		minute := match[2]
+		if minute == "" {
+			minute = "00"
+		}
+		return " " + match[1] + ":" + minute + " "
+	})
+	phrase = thaiWeeksPattern.ReplaceAllString(phrase, "อีก $1 สัปดาห์")
+	for i := 0; i < len(thaiDueWords); i += 2 {
+		phrase = strings.Replace(phrase, thaiDueWords[i], thaiDueWords[i+1], -1)
+	}
+	phrase = thaiWeekdayPattern.ReplaceAllStringFunc(phrase, func(word string) string {
+		match := thaiWeekdayPattern.FindStringSubmatch(word)
+		prefix := ""
+		if match[3] == "หน้า" {
+			prefix = "next "
+		}
+		return " " + prefix + thaiWeekdays[match[2]] + " "
+	})
+	return thaiUnits.Replace(phrase)
+}
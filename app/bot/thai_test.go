@@ -0,0 +1,158 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+func TestTodoBotParseDueThai(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	// Thursday
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "วันนี้", want: "2018-11-15 12:00"},
+		{in: "วันนี้ 15:30", want: "2018-11-15 15:30"},
+		{in: "พรุ่งนี้", want: "2018-11-16 12:00"},
+		{in: "พรุ่งนี้ 17.30 น.", want: "2018-11-16 17:30"},
+		{in: "พรุ่งนี้เวลา 9 น.", want: "2018-11-16 09:00"},
+		{in: "มะรืนนี้", want: "2018-11-17 12:00"},
+		{in: "มะรืนนี้ 8am", want: "2018-11-17 08:00"},
+		{in: "คืนนี้", want: "2018-11-15 20:00"},
+		{in: "พรุ่งนี้เที่ยง", want: "2018-11-16 12:00"},
+		{in: "วันศุกร์", want: "2018-11-16 12:00"},
+		{in: "ศุกร์นี้", want: "2018-11-16 12:00"},
+		{in: "วันศุกร์หน้า", want: "2018-11-23 12:00"},
+		{in: "วันจันทร์หน้า 17:00 น.", want: "2018-11-19 17:00"},
+		{in: "พฤหัส", want: "2018-11-15 12:00"},
+		{in: "วันพฤหัสบดีหน้า", want: "2018-11-22 12:00"},
+		{in: "วันอาทิตย์", want: "2018-11-18 12:00"},
+		{in: "วันอาทิตย์หน้า", want: "2018-11-25 12:00"},
+		{in: "อาทิตย์หน้า", want: "2018-11-19 12:00"},
+		{in: "สัปดาห์หน้า", want: "2018-11-19 12:00"},
+		{in: "เดือนหน้า", want: "2018-12-01 12:00"},
+		{in: "สิ้นเดือน", want: "2018-11-30 12:00"},
+		{in: "อีก 3 วัน", want: "2018-11-18 12:00"},
+		{in: "อีก 2 ชั่วโมง", want: "2018-11-15 12:00"},
+		{in: "อีก 30 นาที", want: "2018-11-15 10:30"},
+		{in: "อีก ๒ สัปดาห์", want: "2018-11-29 12:00"},
+		{in: "อีก 2 อาทิตย์", want: "2018-11-29 12:00"},
+		{in: "อีก1อาทิตย์ 9:00", want: "2018-11-22 09:00"},
+		{in: "25 พฤษภาคม", want: "2019-05-25 12:00"},
+		{in: "วันที่ 25 ธ.ค.", want: "2018-12-25 12:00"},
+		{in: "1 มิ.ย. 2561", want: "2018-06-01 12:00"},
+		{in: "๑ มกราคม ๒๕๖๒ 9:00", want: "2019-01-01 09:00"},
+		// Buddhist era
+		{in: "25/5/2561", want: "2018-05-25 12:00"},
+		{in: "25/5/2561 13:00", want: "2018-05-25 13:00"},
+		{in: "29 ก.พ. 2563", want: "2020-02-29 12:00"},
+		{in: "29 ก.พ. 2562", wantErr: true},
+		{in: "สักวัน", wantErr: true},
+		{in: "พรุ่งนี้ วันศุกร์", wantErr: true},
+	}
+
+	bot := TodoBot{}
+	for _, c := range cases {
+		got, err := bot.ParseDue(now, c.in)
+		if c.wantErr {
+			if _, ok := err.(*DueError); !ok {
+				t.Errorf("TodoBot.ParseDue(%q) == %v, %v, want a hint", c.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got.Format("2006-01-02 15:04") != c.want {
+			t.Errorf("TodoBot.ParseDue(%q) == %v, %v, want %v", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestTodoBotParseUserMessageThai(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	bot := TodoBot{}
+	todo, err := bot.ParseUserMessage("ซื้อของ #บ้าน : 25/5/2561 : 13:00")
+	if err != nil || todo.Task != "ซื้อของ" || len(todo.Tags) != 1 || todo.Due.In(loc).Format(time.RFC3339) != "2018-05-25T13:00:00+07:00" {
+		t.Errorf("TodoBot.ParseUserMessage() == %#v, %v", todo, err)
+	}
+}
+
+func TestTodoBotErrorReplyThai(t *testing.T) {
+	now := time.Date(2018, 11, 15, 10, 0, 0, 0, time.UTC)
+	bot := (&TodoBot{}).In("th")
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"สักวัน", `ไม่เข้าใจ "สัก" ลองพิมพ์ "วันศุกร์หน้า 17:00" หรือ "25 พ.ค."`},
+		{"30 ก.พ.", "ไม่มีวันที่ 30 ก.พ."},
+		{"ธ.ค.", `วันที่เท่าไหร่ของ ธ.ค. ลองพิมพ์ "25 ธ.ค."`},
+	}
+	for _, c := range cases {
+		_, err := bot.ParseDue(now, c.in)
+		if got := bot.ErrorReply(err); got != c.want {
+			t.Errorf("TodoBot.ErrorReply() of %q == %q, want %q", c.in, got, c.want)
+		}
+	}
+	if got := bot.ErrorReply(errSnooze); got != bot.T("wrongSnooze", nil) {
+		t.Errorf("TodoBot.ErrorReply(%v) == %q", errSnooze, got)
+	}
+	if got := bot.ErrorReply(errors.New("dummy")); got != "เกิดข้อผิดพลาด กรุณาลองใหม่ภายหลัง" {
+		t.Errorf("TodoBot.ErrorReply() of an unknown error == %q", got)
+	}
+}
+
+func TestIsThai(t *testing.T) {
+	for text, want := range map[string]bool{"ซื้อของ : พรุ่งนี้": true, "Go shopping : tomorrow": false, "Milk : 25/5/2561": false, "": false} {
+		if got := IsThai(text); got != want {
+			t.Errorf("IsThai(%q) == %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestTodoBotLanguage(t *testing.T) {
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	if got := bot.Language("U1", "Milk : today"); got != "en" {
+		t.Errorf("TodoBot.Language(%q) == %q, want %q", "Milk : today", got, "en")
+	}
+	if got := bot.Language("U1", "นม : วันนี้"); got != "th" {
+		t.Errorf("TodoBot.Language(%q) == %q, want %q", "นม : วันนี้", got, "th")
+	}
+
+	// The preference comes first
+	cases := []struct {
+		msg          string
+		wantReply    string
+		wantLanguage string
+	}{
+		{"ภาษาไทย", "ต่อไปจะตอบเป็นภาษาไทย 🆗", "th"},
+		{"Language  EN", "I will reply in English 🆗", "en"},
+		{"language th", "ต่อไปจะตอบเป็นภาษาไทย 🆗", "th"},
+	}
+	for _, c := range cases {
+		reply, ok := bot.LanguageCommand("U1", c.msg)
+		if !ok || reply != c.wantReply {
+			t.Errorf("TodoBot.LanguageCommand(%q) == %q, %v, want %q", c.msg, reply, ok, c.wantReply)
+		}
+		if got := bot.Language("U1", "Milk : today"); got != c.wantLanguage {
+			t.Errorf("TodoBot.Language() after %q == %q, want %q", c.msg, got, c.wantLanguage)
+		}
+	}
+	if setting, _ := todoModel.Setting("U1"); setting != (model.Setting{UserID: "U1", Language: "th"}) {
+		t.Errorf("TodoModel.Setting(%q) == %#v", "U1", setting)
+	}
+	if reply, ok := bot.LanguageCommand("U1", "language auto"); !ok || reply != "I will reply in the language of your messages 🆗" {
+		t.Errorf("TodoBot.LanguageCommand(%q) == %q, %v", "language auto", reply, ok)
+	}
+	if got := bot.Language("U1", "Milk : today"); got != "en" {
+		t.Errorf("TodoBot.Language() after %q == %q, want %q", "language auto", got, "en")
+	}
+	if _, ok := bot.LanguageCommand("U1", "language of love"); ok {
+		t.Errorf("TodoBot.LanguageCommand(%q) handled", "language of love")
+	}
+}
@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// For is a copy of the bot replying to the user in their language and time zone
+func (this *TodoBot) For(userID string, msg string) *TodoBot {
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		log.Println(err)
+	}
+	return this.with(setting, msg)
+}
+
+// with is For with the setting already loaded, like the ones of every user at once
+func (this *TodoBot) with(setting model.Setting, msg string) *TodoBot {
+	bot := this.In(languageOf(setting, msg))
+	bot.Location = setting.Location()
+	bot.Setting = setting
+	return bot
+}
+
+// location is the time zone of the bot, the default one until For
+func (this *TodoBot) location() *time.Location {
+	if this.Location == nil {
+		return model.Setting{}.Location()
+	}
+	return this.Location
+}
+
+// TimeZoneCommand handles "timezone Europe/Berlin", "timezone" shows the current one, it returns false for other messages
+func (this *TodoBot) TimeZoneCommand(userID string, msg string) (string, bool) {
+	words := strings.Fields(msg)
+	if len(words) > 1 && strings.ToLower(words[0]) == "time" && strings.ToLower(words[1]) == "zone" {
+		words = append([]string{"timezone"}, words[2:]...)
+	}
+	if len(words) == 0 || len(words) > 2 || strings.ToLower(words[0]) != "timezone" {
+		return "", false
+	}
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		return this.ErrorReply(err), true
+	}
+	if len(words) == 2 {
+		setting.TimeZone = timeZoneName(words[1])
+		if err := this.TodoModel.SaveSetting(setting); err == model.ErrTimeZone {
+			return this.T("wrongTimeZone", Args{"TimeZone": words[1]}), true
+		} else if err != nil {
+			return this.ErrorReply(err), true
+		}
+	}
+	loc := setting.Location()
+	return this.T("timeZone", Args{"TimeZone": loc.String(), "Time": time.Now().In(loc).Format("15:04")}), true
+}
+
+// timeZoneName corrects the case of the IANA names like america/new_york, they are case sensitive on most systems
+func timeZoneName(name string) string {
+	if model.CheckTimeZone(name) == nil {
+		return name
+	}
+	if upper := strings.ToUpper(name); upper == "UTC" || upper == "GMT" {
+		return upper
+	}
+	fixed := []byte(strings.ToLower(name))
+	for i := range fixed {
+		if i == 0 || fixed[i-1] == '/' || fixed[i-1] == '_' || fixed[i-1] == '-' {
+			fixed[i] = strings.ToUpper(string(fixed[i]))[0]
+		}
+	}
+	return string(fixed)
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/choobot/choo-todo-bot/app/scheduler"
+)
+
+func TestTodoBotTimeZoneCommand(t *testing.T) {
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	cases := []struct {
+		msg          string
+		wantReply    string
+		wantTimeZone string
+	}{
+		{"timezone", "Due dates are in Asia/Bangkok time", ""},
+		{"timezone Europe/Berlin", "Due dates are in Europe/Berlin time", "Europe/Berlin"},
+		{"Time zone america/new_york", "Due dates are in America/New_York time", "America/New_York"},
+		{"TIMEZONE utc", "Due dates are in UTC time", "UTC"},
+		{"timezone Mars/Olympus", `I don't know the time zone "Mars/Olympus"`, "UTC"},
+		{"timezone Local", `I don't know the time zone "Local"`, "UTC"},
+	}
+	for _, c := range cases {
+		reply, ok := bot.TimeZoneCommand("U1", c.msg)
+		if !ok || !strings.HasPrefix(reply, c.wantReply) {
+			t.Errorf("TodoBot.TimeZoneCommand(%q) == %q, %v, want %q", c.msg, reply, ok, c.wantReply)
+		}
+		if setting, _ := todoModel.Setting("U1"); setting.TimeZone != c.wantTimeZone {
+			t.Errorf("TodoModel.Setting() after %q == %#v, want %q", c.msg, setting, c.wantTimeZone)
+		}
+	}
+	for _, msg := range []string{"timezones are hard", "time zone is Europe/Berlin", "Milk : today"} {
+		if _, ok := bot.TimeZoneCommand("U1", msg); ok {
+			t.Errorf("TodoBot.TimeZoneCommand(%q) handled", msg)
+		}
+	}
+}
+
+func TestTodoBotFor(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.SaveSetting(model.Setting{UserID: "U1", Language: "th", TimeZone: "Europe/Berlin"})
+	bot := newMockBot(todoModel).For("U1", "Milk : today")
+	if bot.Locale != "th" || bot.Location.String() != "Europe/Berlin" {
+		t.Errorf("TodoBot.For(%q) == %q in %v, want %q in %q", "U1", bot.Locale, bot.Location, "th", "Europe/Berlin")
+	}
+
+	// Due dates are parsed and shown in the time zone of the user
+	todo, err := bot.ParseUserMessage("Milk : tomorrow 9am")
+	if err != nil || todo.Due.In(bot.Location).Format("15:04") != "09:00" {
+		t.Errorf("TodoBot.ParseUserMessage() == %v, %v, want 09:00 in Berlin", todo.Due, err)
+	}
+	now := time.Date(2018, 11, 15, 22, 30, 0, 0, time.UTC)
+	due := time.Date(2018, 11, 16, 7, 0, 0, 0, time.UTC)
+	bot = bot.In("en")
+	if got := bot.FormatDate(now, due); got != "Tomorrow at 08:00" {
+		t.Errorf("TodoBot.FormatDate(%v) in Berlin == %q, want %q", due, got, "Tomorrow at 08:00")
+	}
+	// The same time is today and 14:00 in Bangkok
+	bot.Location = nil
+	if got := bot.FormatDate(now, due); got != "Today at 14:00" {
+		t.Errorf("TodoBot.FormatDate(%v) in Bangkok == %q, want %q", due, got, "Today at 14:00")
+	}
+}
+
+func TestTimeZoneName(t *testing.T) {
+	for in, want := range map[string]string{
+		"Europe/Berlin":                  "Europe/Berlin",
+		"america/new_york":               "America/New_York",
+		"america/port-au-prince":         "America/Port-Au-Prince",
+		"utc":                            "UTC",
+		"asia/bangkok":                   "Asia/Bangkok",
+		"America/Argentina/Buenos_Aires": "America/Argentina/Buenos_Aires",
+	} {
+		if got := timeZoneName(in); got != want {
+			t.Errorf("timeZoneName(%q) == %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTodoBotIsDue(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.SaveSetting(model.Setting{UserID: "U1", TimeZone: "Europe/Berlin"})
+	bot := newMockBot(todoModel)
+	schedule, _ := scheduler.ParseSchedule("0 8 * * *")
+	// 8:00 in Bangkok, 2:00 in Berlin
+	from := time.Date(2018, 11, 15, 0, 50, 0, 0, time.UTC)
+	to := from.Add(15 * time.Minute)
+	if !bot.For("U2", "").IsDue(schedule, from, to) {
+		t.Errorf("TodoBot.IsDue() in Bangkok == false")
+	}
+	if bot.For("U1", "").IsDue(schedule, from, to) {
+		t.Errorf("TodoBot.IsDue() in Berlin == true")
+	}
+	// 8:00 in Berlin
+	from = time.Date(2018, 11, 15, 6, 50, 0, 0, time.UTC)
+	if !bot.For("U1", "").IsDue(schedule, from, from.Add(15*time.Minute)) {
+		t.Errorf("TodoBot.IsDue() in Berlin at 8:00 == false")
+	}
+}
@@ -15,86 +15,209 @@ import (
 type TodoBot struct {
 	Client    *linebot.Client
 	TodoModel model.TodoModel
+	// The other models keep what the bot needs between the messages and the runs of its jobs, see model.Models
+	ListingModel model.ListingModel
+	DraftModel   model.DraftModel
+	DialogModel  model.DialogModel
+	AlertModel   model.AlertModel
+	JobRunModel  model.JobRunModel
+	GroupByTag   bool
+	// Locale of the messages, see In
+	Locale string
+	// Location is the time zone of the user to parse and show due dates, see For
+	Location *time.Location
+	// Renderer draws the reminders and the listings, in text by default
+	Renderer Renderer
+	// Secret signs the data of the buttons, see PostbackData
+	Secret string
+	// Setting is the preferences of the user, see For
+	Setting model.Setting
 }
 
+// Remind sends every digest now, the ones of the users in their quiet hours when the quiet hours end, see RemindBetween
 func (this *TodoBot) Remind() error {
-	userTodos, err := this.TodoModel.Remind()
+	now := time.Now()
+	last, err := this.JobRunModel.LastRun(remindNow)
 	if err != nil {
 		return err
 	}
-	for userID, todos := range userTodos {
-		message := ""
-		showDone := false
-		remaining := 0
-		for i, todo := range todos {
-			if i == 0 && todo.Done {
-				message += "Well done, you have no remaining tasks to be done 😎\n"
-			} else if i == 0 {
-				message += "🎯 TASKS TO BE DONE 🎯\n\n"
-			}
-			if !todo.Done {
-				remaining++
-			} else if todo.Done && !showDone {
-				message += "\n🆗 TASKS COMPLETED 🆗\n\n"
-				showDone = true
-			}
-			if todo.Pin {
-				message += "⭐️ "
-			} else {
-				message += "📆 "
-			}
-			due := this.FormatDate(time.Now(), todo.Due)
-			if !todo.Done && time.Now().After(todo.Due) {
-				due += " (overdue)"
-			}
-			message += fmt.Sprintf("%v : %v\n", todo.Task, due)
+	// Another instance moving it at once is as good
+	if _, err := this.JobRunModel.ClaimRun(remindNow, last, now); err != nil {
+		return err
+	}
+	return this.remind(func(bot *TodoBot) bool {
+		return !bot.isQuiet(now)
+	})
+}
 
+// remind pushes the digests of the users it is due for, their settings are loaded at once and the todos of the due ones only
+func (this *TodoBot) remind(due func(bot *TodoBot) bool) error {
+	userIDs, err := this.TodoModel.RemindUsers()
+	if err != nil {
+		return err
+	}
+	bots, err := this.userBots(userIDs)
+	if err != nil {
+		return err
+	}
+	dueUserIDs := []string{}
+	for _, userID := range userIDs {
+		if due(bots[userID]) {
+			dueUserIDs = append(dueUserIDs, userID)
 		}
-		if remaining != 0 {
-			message += fmt.Sprintf("\n%d of %d remaining, just do it! 💪\n\n", remaining, len(todos))
+	}
+	userTodos, err := this.TodoModel.Remind(dueUserIDs)
+	if err != nil {
+		return err
+	}
+	for userID, todos := range userTodos {
+		bot := bots[userID]
+		// The snoozed todos are left out until they wake up
+		todos = awakeTodos(todos, time.Now())
+		if bot.Setting.OnlyOverdue && !hasOverdue(todos, time.Now()) {
+			continue
+		}
+		if bot.Setting.HideCompleted {
+			todos = remainingTodos(todos)
+		}
+		lists, err := this.TodoModel.Lists(userID)
+		if err != nil {
+			log.Println(err)
 		}
-		message += "To edit go to " + os.Getenv("EDIT_URL")
+		messages := []linebot.SendingMessage{}
+		if pinned := pinnedOverdue(todos, time.Now()); len(pinned) > 0 {
+			// On top of the digest
+			messages = append(messages, bot.PinnedOverdueMessage(time.Now(), pinned))
+		}
+		messages = append(messages, bot.Message(time.Now(), bot.RemindReport(userID, todos, lists)))
 		//Fork for massive API calls
-		go this.PushMessage(userID, message)
+		go this.Push(userID, messages...)
 	}
 	return nil
 }
 
+// RemindMessage is the digest of the todos ordered by done, pin then due, sectioned by the lists of the user
+func (this *TodoBot) RemindMessage(now time.Time, todos []model.Todo, lists []model.TodoList) string {
+	return this.Digest(now, todos, lists) + this.T("remindFooter", Args{"URL": os.Getenv("EDIT_URL")})
+}
+
+// Digest is the reminder without the footer
+func (this *TodoBot) Digest(now time.Time, todos []model.Todo, lists []model.TodoList) string {
+	remaining := []model.Todo{}
+	completed := []model.Todo{}
+	for _, todo := range todos {
+		if todo.Done {
+			completed = append(completed, todo)
+		} else {
+			remaining = append(remaining, todo)
+		}
+	}
+	message := ""
+	if len(remaining) == 0 {
+		message += this.T("allDone", nil) + "\n"
+	} else {
+		message += this.T("toBeDone", nil) + "\n\n"
+		if len(lists) == 0 {
+			message += this.TodoLines(now, remaining)
+		} else {
+			message += this.ListSections(now, remaining, lists)
+		}
+		// Sections end with a blank line
+		message = strings.TrimRight(message, "\n") + "\n"
+	}
+	if len(completed) > 0 {
+		message += "\n" + this.T("completed", nil) + "\n\n"
+		for _, todo := range completed {
+			message += this.FormatTodo(now, todo)
+		}
+	}
+	if len(remaining) != 0 {
+		message += "\n" + this.N("remaining", len(remaining), Args{"Total": len(todos)}) + "\n\n"
+	}
+	return message
+}
+
+func (this *TodoBot) TodoLines(now time.Time, todos []model.Todo) string {
+	if this.GroupByTag {
+		return this.TagSections(now, todos)
+	}
+	lines := ""
+	for _, todo := range todos {
+		lines += this.FormatTodo(now, todo)
+	}
+	return lines
+}
+
+func (this *TodoBot) FormatTodo(now time.Time, todo model.Todo) string {
+	line := "📆 "
+	if todo.Pin {
+		line = "⭐️ "
+	}
+	task := todo.Task
+	for _, tag := range todo.Tags {
+		task += " #" + tag
+	}
+	due := this.FormatDate(now, todo.Due)
+	if !todo.Done && now.After(todo.Due) {
+		due += " " + this.T("overdue", nil)
+	}
+	if todo.Repeat != "" {
+		due += " 🔁"
+	}
+	if done, total := todo.Progress(); total > 0 {
+		due += " " + this.N("steps", total, Args{"Done": done})
+	}
+	if snoozed := this.SnoozedBadge(now, todo); snoozed != "" {
+		due += " " + snoozed
+	}
+	return line + fmt.Sprintf("%v : %v\n", task, due)
+}
+
+// FormatDate is relative to now in the locale and the time zone of the bot
 func (this *TodoBot) FormatDate(now time.Time, date time.Time) string {
+	now = now.In(this.location())
+	date = date.In(this.location())
 	// Mon Jan 2 15:04:05 -0700 MST 2006
 	dateText := date.Format("2006-01-02")
-	timeText := date.Format("15:04")
 	_, todayWeek := now.ISOWeek()
 	_, dueWeek := date.ISOWeek()
 	today := now.Format("2006-01-02")
 	tomorrow := now.AddDate(0, 0, 1).Format("2006-01-02")
 	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	args := this.dateArgs(date)
 	if dateText == today {
 		// Today
-		return "Today at " + timeText
+		return this.T("today", args)
 	} else if dateText == tomorrow {
 		// Tomorrow
-		return "Tomorrow at " + timeText
+		return this.T("tomorrow", args)
 	} else if dateText == yesterday {
 		// Yesterday
-		return "Yesterday at " + timeText
+		return this.T("yesterday", args)
 	} else if todayWeek == dueWeek && now.After(date) {
 		// This week
-		return "Last " + date.Format("Mon at 15:04")
+		return this.T("lastWeekday", args)
 	} else if todayWeek == dueWeek {
 		// This week in the past
-		return date.Format("Mon at 15:04")
+		return this.T("weekday", args)
 	} else if dueWeek-todayWeek == 1 {
 		// Next week
-		return "Next " + date.Format("Mon at 15:04")
+		return this.T("nextWeekday", args)
 	}
-	return date.Format("Mon 2 Jan 06 at 15:04")
+	return this.T("date", args)
+}
+
+func (this *TodoBot) PushMessage(userID string, message string) error {
+	return this.Push(userID, linebot.NewTextMessage(message))
 }
 
-func (this *TodoBot) PushMessage(userID string, message string) {
-	if _, err := this.Client.PushMessage(userID, linebot.NewTextMessage(message)).Do(); err != nil {
+// Push logs a failed push and returns it for the callers that retry
+func (this *TodoBot) Push(userID string, messages ...linebot.SendingMessage) error {
+	_, err := this.Client.PushMessage(userID, messages...).Do()
+	if err != nil {
 		log.Println(err)
 	}
+	return err
 }
 
 // 1) Go shopping : 2/5/18 : 13:00
@@ -103,93 +226,155 @@ func (this *TodoBot) PushMessage(userID string, message string) {
 // 4) Go shopping : today
 // 5) Go shopping : tomorrow : 18:00
 // 6) Go shopping : tomorrow
+// 7) Pay rent : every month on 1st : 09:00
+// 8) Go shopping #home : today
+// 9) Go shopping : next friday 5pm, see ParseDue
 func (this *TodoBot) ParseUserMessage(msg string) (model.Todo, error) {
-	loc, _ := time.LoadLocation("Asia/Bangkok")
-	getDate := func(word string) string {
-		format := "2/1/06"
-		if strings.ToLower(word) == "today" {
-			return time.Now().In(loc).Format(format)
-		} else if strings.ToLower(word) == "tomorrow" {
-			return time.Now().In(loc).AddDate(0, 0, 1).Format(format)
-		}
-		return word
-	}
-	layout := "2/1/06 15:04"
+	loc := this.location()
+	now := time.Now().In(loc)
 	words := strings.Split(msg, " : ")
-	task := ""
+	repeat := ""
 	var due time.Time
 	var err error
-	if len(words) == 2 {
-		task = words[0]
-		due, err = time.ParseInLocation(layout, getDate(words[1])+" 12:00", loc)
+	if len(words) != 2 && len(words) != 3 {
+		return model.Todo{}, errors.New("Wrong format")
+	}
+	task, tags := this.ParseTags(words[0])
+	if task == "" {
+		return model.Todo{}, errors.New("Wrong format")
+	}
+	if this.IsRepeat(words[1]) {
+		clock := "12:00"
+		if len(words) == 3 {
+			clock = words[2]
+		}
+		recurrence, err := this.ParseRepeat(words[1])
 		if err != nil {
 			return model.Todo{}, errors.New("Wrong format")
 		}
-	} else if len(words) == 3 {
-		task = words[0]
-		due, err = time.ParseInLocation(layout, getDate(words[1])+" "+words[2], loc)
+		start, err := time.ParseInLocation("2/1/06 15:04", now.Format("2/1/06")+" "+clock, loc)
 		if err != nil {
 			return model.Todo{}, errors.New("Wrong format")
 		}
+		due = recurrence.First(start, time.Now())
+		repeat = recurrence.String()
 	} else {
-		return model.Todo{}, errors.New("Wrong format")
+		due, err = this.ParseDue(now, strings.Join(words[1:], " "))
+		if err != nil {
+			return model.Todo{}, err
+		}
 	}
 	todo := model.Todo{
-		Task: task,
-		Due:  due,
+		Task:   task,
+		Due:    due,
+		Repeat: repeat,
+		Tags:   tags,
 	}
 	return todo, nil
 }
 
 func (this *TodoBot) Response(events []*linebot.Event) error {
-	howto := `You can create todo list by using these formats:
-• Go shopping : 25/5/18 : 13:00
-• Go shopping : 25/5/18
-• Go shopping : today : 15:30
-• Go shopping : today
-• Go shopping : tomorrow : 18:00
-• Go shopping : tomorrow
-You can edit todo list by input word "edit".`
-
 	for _, event := range events {
 		if event.Type == linebot.EventTypeMessage {
 			switch message := event.Message.(type) {
 			case *linebot.TextMessage:
 				msg := message.Text
-				if strings.ToLower(msg) == "edit" {
-					reply := "Please go to " + os.Getenv("EDIT_URL")
-					if _, err := this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(reply)).Do(); err != nil {
-						return err
-					}
-				} else {
-					todo, err := this.ParseUserMessage(msg)
-					if err != nil {
-						if _, err = this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(howto)).Do(); err != nil {
-							return err
-						}
-					} else {
-						todo.UserID = event.Source.UserID
-						if err := this.TodoModel.Create(todo); err != nil {
-							reply := err.Error()
-							if _, err = this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(reply)).Do(); err != nil {
-								return err
-							}
-						} else {
-							reply := "Task has been created 🆗"
-							if _, err = this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(reply)).Do(); err != nil {
-								return err
-							}
-						}
-					}
+				sourceID := this.SourceID(event.Source)
+				reply := this.For(sourceID, msg).TextReply(sourceID, msg, time.Now())
+				if reply == nil {
+					// Not every message in a group is meant for the bot
+					continue
 				}
-
+				if _, err := this.Client.ReplyMessage(event.ReplyToken, reply).Do(); err != nil {
+					return err
+				}
+			}
+		} else if event.Type == linebot.EventTypePostback && event.Postback != nil {
+			sourceID := this.SourceID(event.Source)
+			bot := this.For(sourceID, "")
+			reply := bot.PostbackReply(sourceID, event.Postback, time.Now())
+			if _, err := this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(reply)).Do(); err != nil {
+				return err
+			}
+		} else if event.Type == linebot.EventTypeJoin || event.Type == linebot.EventTypeFollow {
+			sourceID := this.SourceID(event.Source)
+			bot := this.For(sourceID, "")
+			if _, err := this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(bot.Welcome(sourceID))).Do(); err != nil {
+				return err
 			}
-		} else if event.Type == linebot.EventTypeJoin {
-			replyMessage := "Thanks for adding me. I'm Choo Todo Bot, I'm here to help you to manage your tasks.\n" + howto
-			if _, err := this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(replyMessage)).Do(); err != nil {
+		} else if event.Type == linebot.EventTypeUnfollow || event.Type == linebot.EventTypeLeave {
+			// Pushing to a user who blocked the bot or to a group it left wastes the quota
+			if err := this.TodoModel.Deactivate(this.SourceID(event.Source), time.Now()); err != nil {
 				return err
 			}
 		}
 	}
 	return nil
 }
+
+// commands are the text commands in the order they are tried, each one returns false for the messages it does not handle
+func (this *TodoBot) commands() []func(userID string, msg string) (string, bool) {
+	return []func(userID string, msg string) (string, bool){
+		this.EditCommand,
+		this.ShareCommand,
+		this.LanguageCommand,
+		this.TimeZoneCommand,
+		this.AlertCommand,
+		this.ReminderCommand,
+		this.OverdueCommand,
+		this.BuddyCommand,
+		this.TaskCommand,
+	}
+}
+
+// TextReply is the reply to a text message, a dialog answer, a command or a todo to create,
+// it is nil for a message in a group that is not meant for the bot
+func (this *TodoBot) TextReply(userID string, msg string, now time.Time) linebot.SendingMessage {
+	if message, ok := this.DialogMessage(userID, msg, now); ok {
+		return message
+	}
+	if isList(msg) {
+		return this.ListMessage(userID, now)
+	}
+	for _, command := range this.commands() {
+		if reply, ok := command(userID, msg); ok {
+			return linebot.NewTextMessage(reply)
+		}
+	}
+	listName, text := this.ParseListName(msg)
+	todo, err := this.ParseUserMessage(text)
+	if err != nil && IsGroupID(userID) && !isHelp(msg) {
+		return nil
+	} else if err != nil && this.isDraft(msg) && !IsGroupID(userID) {
+		// Ask when it is due
+		return this.DraftMessage(userID, msg, now)
+	} else if err != nil {
+		reply := this.T("howto", nil)
+		if _, ok := err.(*DueError); ok {
+			reply = this.ErrorReply(err)
+		}
+		if IsGroupID(userID) {
+			reply = this.T("groupHowto", nil)
+		}
+		return linebot.NewTextMessage(reply)
+	}
+	todo.UserID = userID
+	return linebot.NewTextMessage(this.CreateTodo(todo, listName))
+}
+
+// EditCommand handles "edit" with the link to the web
+func (this *TodoBot) EditCommand(userID string, msg string) (string, bool) {
+	if strings.ToLower(msg) != "edit" {
+		return "", false
+	}
+	if IsGroupID(userID) {
+		return this.T("groupEdit", Args{"URL": os.Getenv("EDIT_URL")}), true
+	}
+	return this.T("edit", Args{"URL": os.Getenv("EDIT_URL")}), true
+}
+
+// isHelp is "help" or "วิธีใช้"
+func isHelp(msg string) bool {
+	msg = strings.ToLower(strings.TrimSpace(msg))
+	return msg == "help" || msg == "วิธีใช้"
+}
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -65,13 +66,13 @@ func TestTodoBotParseUserMessage(t *testing.T) {
 			in:       "Go shopping : 2/1/06 : 25:04",
 			wantTask: "",
 			wantDue:  "0001-01-01T06:42:04+06:42",
-			wantErr:  "Wrong format",
+			wantErr:  `"25:04" is not a time, try "17:30" or "5:30pm"`,
 		},
 		{
 			in:       "Go shopping : 32/1/06",
 			wantTask: "",
 			wantDue:  "0001-01-01T06:42:04+06:42",
-			wantErr:  "Wrong format",
+			wantErr:  "There is no 32 Jan",
 		},
 	}
 
@@ -84,6 +85,103 @@ func TestTodoBotParseUserMessage(t *testing.T) {
 	}
 }
 
+func TestTodoBotParseUserMessageRepeat(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	cases := []struct {
+		in         string
+		wantTask   string
+		wantRepeat string
+		wantClock  string
+		wantErr    string
+	}{
+		{
+			in:         "Pay rent : every month on 1st : 09:00",
+			wantTask:   "Pay rent",
+			wantRepeat: "FREQ=MONTHLY;BYMONTHDAY=1",
+			wantClock:  "09:00",
+		},
+		{
+			in:         "Standup notes : every weekday : 09:30",
+			wantTask:   "Standup notes",
+			wantRepeat: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+			wantClock:  "09:30",
+		},
+		{
+			in:         "Water plants : every 3 days",
+			wantTask:   "Water plants",
+			wantRepeat: "FREQ=DAILY;INTERVAL=3",
+			wantClock:  "12:00",
+		},
+		{
+			in:         "Gym : RRULE:FREQ=WEEKLY;BYDAY=TU,TH : 18:00",
+			wantTask:   "Gym",
+			wantRepeat: "FREQ=WEEKLY;BYDAY=TU,TH",
+			wantClock:  "18:00",
+		},
+		{
+			in:      "Pay rent : every month on 32nd",
+			wantErr: "Wrong format",
+		},
+		{
+			in:      "Pay rent : every month : 25:00",
+			wantErr: "Wrong format",
+		},
+	}
+
+	bot := TodoBot{}
+	for _, c := range cases {
+		got, err := bot.ParseUserMessage(c.in)
+		if c.wantErr != "" {
+			if err == nil || err.Error() != c.wantErr {
+				t.Errorf("TodoBot.ParseUserMessage(%q) == %v, want %v", c.in, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.Task != c.wantTask || got.Repeat != c.wantRepeat || got.Due.In(loc).Format("15:04") != c.wantClock || got.Due.Before(time.Now()) {
+			t.Errorf("TodoBot.ParseUserMessage(%q) == %v, %v, %v, %v, want %v, %v, %v", c.in, got.Task, got.Repeat, got.Due, err, c.wantTask, c.wantRepeat, c.wantClock)
+		}
+	}
+}
+
+func TestTodoBotParseRepeat(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "every day", want: "FREQ=DAILY"},
+		{in: "Daily", want: "FREQ=DAILY"},
+		{in: "every 3 days", want: "FREQ=DAILY;INTERVAL=3"},
+		{in: "every week", want: "FREQ=WEEKLY"},
+		{in: "every 2 weeks", want: "FREQ=WEEKLY;INTERVAL=2"},
+		{in: "every weekday", want: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{in: "every weekend", want: "FREQ=WEEKLY;BYDAY=SA,SU"},
+		{in: "every monday", want: "FREQ=WEEKLY;BYDAY=MO"},
+		{in: "every mon, wed and fri", want: "FREQ=WEEKLY;BYDAY=MO,WE,FR"},
+		{in: "every 2 weeks on monday and thursday", want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{in: "every month", want: "FREQ=MONTHLY"},
+		{in: "monthly", want: "FREQ=MONTHLY"},
+		{in: "every month on 1st", want: "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{in: "every 3 months on the 15th", want: "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=15"},
+		{in: "FREQ=DAILY;INTERVAL=2", want: "FREQ=DAILY;INTERVAL=2"},
+		{in: "every", wantErr: true},
+		{in: "every blue moon", wantErr: true},
+		{in: "every 0 days", wantErr: true},
+		{in: "every day on monday", wantErr: true},
+		{in: "every month on monday", wantErr: true},
+		{in: "every 2 mondays", wantErr: true},
+		{in: "tomorrow", wantErr: true},
+	}
+
+	bot := TodoBot{}
+	for _, c := range cases {
+		got, err := bot.ParseRepeat(c.in)
+		if (err != nil) != c.wantErr || (err == nil && got.String() != c.want) {
+			t.Errorf("TodoBot.ParseRepeat(%q) == %q, %v, want %q", c.in, got.String(), err, c.want)
+		}
+	}
+}
+
 func TestTodoBotFormatDate(t *testing.T) {
 	loc, _ := time.LoadLocation("Asia/Bangkok")
 	layout := "2006-01-02 15:04"
@@ -139,68 +237,103 @@ func TestTodoBotPushMessage(t *testing.T) {
 	bot.PushMessage("dummy", "dummy")
 }
 
+// mockTodoModel has the other models too, the bots on it share them like the instances share the database
 type mockTodoModel struct {
-	willError       bool
-	willNoRemaining bool
+	*model.TodoMemoryModel
+	*model.ListingMemoryModel
+	*model.DraftMemoryModel
+	*model.DialogMemoryModel
+	*model.AlertMemoryModel
+	*model.JobRunMemoryModel
+	willError bool
+	// reminded are the users whose todos the last Remind loaded
+	reminded []string
 }
 
-func (this *mockTodoModel) List(userID string) ([]model.Todo, error) {
-	return nil, nil
+func newMockTodoModel() *mockTodoModel {
+	return &mockTodoModel{
+		TodoMemoryModel:    model.NewTodoMemoryModel(),
+		ListingMemoryModel: model.NewListingMemoryModel(),
+		DraftMemoryModel:   model.NewDraftMemoryModel(),
+		DialogMemoryModel:  model.NewDialogMemoryModel(),
+		AlertMemoryModel:   model.NewAlertMemoryModel(),
+		JobRunMemoryModel:  model.NewJobRunMemoryModel(),
+	}
+}
+
+func newMockBot(todoModel *mockTodoModel) *TodoBot {
+	return &TodoBot{
+		TodoModel:    todoModel,
+		ListingModel: todoModel.ListingMemoryModel,
+		DraftModel:   todoModel.DraftMemoryModel,
+		DialogModel:  todoModel.DialogMemoryModel,
+		AlertModel:   todoModel.AlertMemoryModel,
+		JobRunModel:  todoModel.JobRunMemoryModel,
+	}
 }
+
+func (this *mockTodoModel) Purge(userID string) error {
+	return this.TodoMemoryModel.Purge(userID)
+}
+
 func (this *mockTodoModel) Create(todo model.Todo) error {
 	if this.willError {
 		this.willError = false
 		return errors.New("dummy")
 	}
-	return nil
-}
-func (this *mockTodoModel) Pin(todo model.Todo) error {
-	return nil
+	return this.TodoMemoryModel.Create(todo)
 }
-func (this *mockTodoModel) Done(todo model.Todo) error {
-	return nil
+func (this *mockTodoModel) RemindUsers() ([]string, error) {
+	if this.willError {
+		this.willError = false
+		return nil, errors.New("dummy")
+	}
+	return this.TodoMemoryModel.RemindUsers()
 }
-func (this *mockTodoModel) Edit(todo model.Todo) error {
-	return nil
+
+func (this *mockTodoModel) Remind(userIDs []string) (map[string][]model.Todo, error) {
+	this.reminded = userIDs
+	return this.TodoMemoryModel.Remind(userIDs)
 }
-func (this *mockTodoModel) Delete(todo model.Todo) error {
-	return nil
+
+// remindAll is the digest of every user like the bot gets it
+func (this *mockTodoModel) remindAll() (map[string][]model.Todo, error) {
+	userIDs, err := this.RemindUsers()
+	if err != nil {
+		return nil, err
+	}
+	return this.Remind(userIDs)
 }
-func (this *mockTodoModel) Remind() (map[string][]model.Todo, error) {
+func (this *mockTodoModel) DueTodos(from time.Time, to time.Time) (map[string][]model.Todo, error) {
 	if this.willError {
 		this.willError = false
 		return nil, errors.New("dummy")
 	}
-	userTodos := map[string][]model.Todo{}
-	todos := userTodos["dummy"]
-	if this.willNoRemaining {
-		this.willNoRemaining = false
-		todo := model.Todo{
-			Done: true,
-		}
-		todos = append(todos, todo)
-	} else {
-		todo := model.Todo{
-			Done: false,
-		}
-		todos = append(todos, todo)
-	}
+	return this.TodoMemoryModel.DueTodos(from, to)
+}
 
-	todo := model.Todo{
-		Pin: true,
+func TestTodoBotRemindDueUsers(t *testing.T) {
+	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	todoModel := newMockTodoModel()
+	todoModel.Create(model.Todo{UserID: "U1", Task: "Pay rent", Due: time.Now()})
+	todoModel.Create(model.Todo{UserID: "U2", Task: "Call mom", Due: time.Now()})
+	todoModel.SaveSetting(model.Setting{UserID: "U2", OnlyOverdue: true})
+	bot := newMockBot(todoModel)
+	bot.Client = client
+	// The todos of the users it is not due for are not loaded
+	if err := bot.remind(func(bot *TodoBot) bool { return !bot.Setting.OnlyOverdue }); err != nil {
+		t.Errorf("TodoBot.remind() == %v, want %v", err, nil)
+	}
+	if len(todoModel.reminded) != 1 || todoModel.reminded[0] != "U1" {
+		t.Errorf("TodoModel.Remind() of %v, want %q only", todoModel.reminded, "U1")
 	}
-	todos = append(todos, todo)
-	userTodos["dummy"] = todos
-	return userTodos, nil
 }
 
 func TestTodoBotRemind(t *testing.T) {
 	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
-	todoModel := mockTodoModel{}
-	bot := TodoBot{
-		Client:    client,
-		TodoModel: &todoModel,
-	}
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	bot.Client = client
 
 	todoModel.willError = true
 	err := bot.Remind()
@@ -208,12 +341,16 @@ func TestTodoBotRemind(t *testing.T) {
 		t.Errorf("TodoBot.Remind() == %v want %v", nil, err)
 	}
 
-	todoModel.willNoRemaining = true
+	// No remaining
+	todoModel.Create(model.Todo{UserID: "dummy", Task: "dummy", Due: time.Now()})
+	todoModel.Done("dummy", model.Todo{ID: 1, Done: true})
 	err = bot.Remind()
 	if err != nil {
 		t.Errorf("TodoBot.Remind() == %v want %v", err, nil)
 	}
 
+	todoModel.Create(model.Todo{UserID: "dummy", Task: "dummy", Due: time.Now()})
+	todoModel.Pin("dummy", model.Todo{ID: 2, Pin: true})
 	err = bot.Remind()
 	if err != nil {
 		t.Errorf("TodoBot.Remind() == %v want %v", err, nil)
@@ -223,11 +360,9 @@ func TestTodoBotRemind(t *testing.T) {
 func TestTodoBotResponse(t *testing.T) {
 	wantErr := errors.New("linebot: APIError 400 Invalid reply token")
 	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
-	todoModel := mockTodoModel{}
-	bot := &TodoBot{
-		Client:    client,
-		TodoModel: &todoModel,
-	}
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	bot.Client = client
 
 	events := []*linebot.Event{}
 	err := bot.Response(events)
@@ -322,3 +457,35 @@ func TestTodoBotResponse(t *testing.T) {
 		t.Errorf("TodoBot.Response(%v) == %v, want %v", events, err, wantErr)
 	}
 }
+
+func TestTodoBotTextReply(t *testing.T) {
+	now := time.Now()
+	bot := newMockBot(newMockTodoModel()).In("en")
+	cases := []struct {
+		userID string
+		msg    string
+		want   string
+	}{
+		{"U1", "Edit", "Please go to "},
+		{"C1", "edit", "Group tasks are managed in this chat"},
+		{"U1", "timezone Asia/Tokyo", "Due dates are in Asia/Tokyo time"},
+		{"U1", "done 1", "There is no todo 1"},
+		{"U1", "Go shopping : tomorrow 5pm", "Task has been created 🆗"},
+		{"U1", "Go shopping : someday", `I don't understand "someday"`},
+		{"U1", "hello there", "When is hello there due?"},
+		{"C1", "help", "Tasks sent here belong to the group"},
+	}
+	for _, c := range cases {
+		reply, ok := bot.TextReply(c.userID, c.msg, now).(*linebot.TextMessage)
+		if !ok || !strings.HasPrefix(reply.Text, c.want) {
+			t.Errorf("TodoBot.TextReply(%q, %q) == %#v, want %q", c.userID, c.msg, reply, c.want)
+		}
+	}
+	if reply := bot.TextReply("U1", "list", now); reply == nil {
+		t.Errorf("TodoBot.TextReply(%q) == nil, want the listing", "list")
+	}
+	// A chat in a group is not for the bot
+	if reply := bot.TextReply("C1", "hello there", now); reply != nil {
+		t.Errorf("TodoBot.TextReply() of a chat in a group == %#v, want nil", reply)
+	}
+}
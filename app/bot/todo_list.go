@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+// ParseListName takes the leading @list out of the message, e.g. "@shopping Milk : today"
+func (this *TodoBot) ParseListName(msg string) (string, string) {
+	msg = strings.TrimSpace(msg)
+	if !strings.HasPrefix(msg, "@") {
+		return "", msg
+	}
+	fields := strings.SplitN(msg, " ", 2)
+	if len(fields) != 2 {
+		return "", msg
+	}
+	return strings.TrimPrefix(fields[0], "@"), strings.TrimSpace(fields[1])
+}
+
+// TargetList finds the list of the user by name, it is created on first use
+func (this *TodoBot) TargetList(userID string, name string) (model.TodoList, error) {
+	list, err := this.TodoModel.FindList(userID, name)
+	if err != model.ErrNotFound {
+		return list, err
+	}
+	if err := this.TodoModel.CreateList(model.TodoList{UserID: userID, Name: name}); err != nil {
+		return model.TodoList{}, err
+	}
+	return this.TodoModel.FindList(userID, name)
+}
+
+// CreateTodo creates the todo in the list named by the user and returns the reply
+func (this *TodoBot) CreateTodo(todo model.Todo, listName string) string {
+	reply := this.T("created", nil)
+	if listName != "" {
+		list, err := this.TargetList(todo.UserID, listName)
+		if err != nil {
+			return this.ErrorReply(err)
+		}
+		todo.ListID = list.ID
+		reply = this.T("createdIn", Args{"List": list.Name})
+	}
+	if err := this.TodoModel.Create(todo); err != nil {
+		return this.ErrorReply(err)
+	}
+	this.NotifyCreated(todo)
+	return reply
+}
+
+// ListSections lists the todos under their list, the default list comes first
+func (this *TodoBot) ListSections(now time.Time, todos []model.Todo, lists []model.TodoList) string {
+	known := map[int]bool{}
+	for _, list := range lists {
+		known[list.ID] = true
+	}
+	sections := append([]model.TodoList{{Name: this.T("inbox", nil)}}, lists...)
+	message := ""
+	for _, list := range sections {
+		listTodos := []model.Todo{}
+		for _, todo := range todos {
+			if todo.ListID == list.ID || (list.ID == 0 && !known[todo.ListID]) {
+				listTodos = append(listTodos, todo)
+			}
+		}
+		if len(listTodos) == 0 {
+			continue
+		}
+		message += "📂 " + list.Name + "\n"
+		message += this.TodoLines(now, listTodos)
+		if !this.GroupByTag {
+			message += "\n"
+		}
+	}
+	return message
+}
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-todo-bot/app/model"
+)
+
+func TestTodoBotParseListName(t *testing.T) {
+	cases := []struct {
+		in       string
+		wantList string
+		wantText string
+	}{
+		{"@shopping Milk : today", "shopping", "Milk : today"},
+		{"Milk : today", "", "Milk : today"},
+		{"@shopping", "", "@shopping"},
+		{"Email bob@example.com : today", "", "Email bob@example.com : today"},
+	}
+	bot := TodoBot{}
+	for _, c := range cases {
+		list, text := bot.ParseListName(c.in)
+		if list != c.wantList || text != c.wantText {
+			t.Errorf("TodoBot.ParseListName(%q) == %q, %q, want %q, %q", c.in, list, text, c.wantList, c.wantText)
+		}
+	}
+}
+
+func TestTodoBotCreateTodo(t *testing.T) {
+	todoModel := newMockTodoModel()
+	bot := newMockBot(todoModel)
+	todo := model.Todo{UserID: "dummy", Task: "Milk", Due: time.Now()}
+
+	// Default list
+	if reply := bot.CreateTodo(todo, ""); reply != "Task has been created 🆗" {
+		t.Errorf("TodoBot.CreateTodo() == %q", reply)
+	}
+	// The list is created on first use then reused
+	for i := 0; i < 2; i++ {
+		if reply := bot.CreateTodo(todo, "shopping"); reply != "Task has been created in shopping 🆗" {
+			t.Errorf("TodoBot.CreateTodo() == %q", reply)
+		}
+	}
+	lists, _ := todoModel.Lists("dummy")
+	if len(lists) != 1 {
+		t.Fatalf("TodoModel.Lists() == %v, want %d list", lists, 1)
+	}
+	todos, _ := todoModel.List("dummy")
+	if len(model.FilterByList(todos, lists[0].ID)) != 2 || len(model.FilterByList(todos, 0)) != 1 {
+		t.Errorf("TodoModel.List() == %v", todos)
+	}
+	// Reserved name
+	if reply := bot.CreateTodo(todo, "inbox"); reply != "That list name is taken, try another one" {
+		t.Errorf("TodoBot.CreateTodo() == %q, want %q", reply, "That list name is taken, try another one")
+	}
+	// Error from Model
+	todoModel.willError = true
+	if reply := bot.CreateTodo(todo, ""); reply != "Something went wrong, please try again later" {
+		t.Errorf("TodoBot.CreateTodo() == %q, want %q", reply, "Something went wrong, please try again later")
+	}
+}
+
+func TestTodoBotRemindMessageLists(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	now := time.Date(2018, 11, 15, 9, 0, 0, 0, loc)
+	due := time.Date(2018, 11, 15, 15, 4, 0, 0, loc)
+	lists := []model.TodoList{
+		{ID: 1, Name: "Shopping"},
+		{ID: 2, Name: "Work"},
+	}
+	todos := []model.Todo{
+		{Task: "Report", Due: due, ListID: 2},
+		{Task: "Milk", Due: due, ListID: 1},
+		{Task: "Call mom", Due: due},
+		{Task: "Lost", Due: due, ListID: 3},
+	}
+	defer os.Setenv("EDIT_URL", os.Getenv("EDIT_URL"))
+	os.Setenv("EDIT_URL", "https://dummy")
+
+	bot := TodoBot{}
+	want := `🎯 TASKS TO BE DONE 🎯
+
+📂 Inbox
+📆 Call mom : Today at 15:04
+📆 Lost : Today at 15:04
+
+📂 Shopping
+📆 Milk : Today at 15:04
+
+📂 Work
+📆 Report : Today at 15:04
+
+4 of 4 remaining, just do it! 💪
+
+To edit go to https://dummy`
+	if got := bot.RemindMessage(now, todos, lists); got != want {
+		t.Errorf("TodoBot.RemindMessage() == %q, want %q", got, want)
+	}
+}
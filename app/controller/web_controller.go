@@ -4,7 +4,10 @@ import (
 	"go/build"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/choobot/choo-todo-bot/app/bot"
 	"github.com/choobot/choo-todo-bot/app/model"
 	"github.com/choobot/choo-todo-bot/app/service"
 	"github.com/labstack/echo"
@@ -16,6 +19,7 @@ type WebController struct {
 	JwtService     service.JwtService
 	TodoModel      model.TodoModel
 	SessionService service.SessionService
+	Notifier       bot.ListNotifier
 }
 
 func (this *WebController) SetNoCache(c echo.Context) {
@@ -24,6 +28,18 @@ func (this *WebController) SetNoCache(c echo.Context) {
 	c.Response().Header().Set("Expires", "0")
 }
 
+func (this *WebController) ErrorStatus(err error) int {
+	switch err {
+	case model.ErrNotFound:
+		return http.StatusNotFound
+	case model.ErrForbidden:
+		return http.StatusForbidden
+	case model.ErrListName, model.ErrTimeZone, model.ErrReminder:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func (this *WebController) Index(c echo.Context) error {
 	this.SetNoCache(c)
 	oauthToken := this.SessionService.Get(c, "oauthToken")
@@ -77,29 +93,82 @@ func (this *WebController) List(c echo.Context) error {
 	if err != nil {
 		return c.HTML(http.StatusInternalServerError, err.Error())
 	}
+	// /list?tag=work&tag=urgent lists the todos having all the tags
+	if tags := c.QueryParams()["tag"]; len(tags) > 0 {
+		todos = model.FilterByTags(todos, tags)
+	}
+	if list := c.QueryParam("list"); list != "" {
+		listID, err := strconv.Atoi(list)
+		if err != nil {
+			return c.HTML(http.StatusBadRequest, "wrong list")
+		}
+		todos = model.FilterByList(todos, listID)
+	}
+	// Due dates are shown in the time zone of the user, the offset tells the page
+	loc := this.location(userID.(string))
+	for i := range todos {
+		todos[i].Due = todos[i].Due.In(loc)
+		if !todos[i].Snoozed.IsZero() {
+			todos[i].Snoozed = todos[i].Snoozed.In(loc)
+		}
+	}
 	return c.JSON(http.StatusOK, todos)
 }
 
 func (this *WebController) Pin(c echo.Context) error {
 	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
 	todo := new(model.Todo)
 	if err := c.Bind(todo); err != nil {
 		return c.HTML(http.StatusInternalServerError, err.Error())
 	}
-	if err := this.TodoModel.Pin(*todo); err != nil {
+	todo.UserID = userID.(string)
+	if err := this.TodoModel.Pin(todo.UserID, *todo); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// Snooze hides the todo from the digests and the alerts until Snoozed, null wakes it up
+func (this *WebController) Snooze(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	todo := new(model.Todo)
+	if err := c.Bind(todo); err != nil {
 		return c.HTML(http.StatusInternalServerError, err.Error())
 	}
+	if !todo.Snoozed.IsZero() && !todo.Snoozed.After(time.Now()) {
+		return c.HTML(http.StatusBadRequest, "Snooze until a later time")
+	}
+	todo.UserID = userID.(string)
+	if err := this.TodoModel.Snooze(todo.UserID, *todo); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
 	return c.NoContent(http.StatusOK)
 }
 
 func (this *WebController) Done(c echo.Context) error {
 	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
 	todo := new(model.Todo)
 	if err := c.Bind(todo); err != nil {
 		return c.HTML(http.StatusInternalServerError, err.Error())
 	}
-	if err := this.TodoModel.Done(*todo); err != nil {
-		return c.HTML(http.StatusInternalServerError, err.Error())
+	todo.UserID = userID.(string)
+	if err := this.TodoModel.Done(todo.UserID, *todo); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	if todo.Done && this.Notifier != nil {
+		this.Notifier.NotifyDone(todo.UserID, todo.ID)
 	}
 	return c.NoContent(http.StatusOK)
 }
@@ -134,24 +203,273 @@ func (this *WebController) Logout(c echo.Context) error {
 
 func (this *WebController) Edit(c echo.Context) error {
 	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
 	todo := new(model.Todo)
 	if err := c.Bind(todo); err != nil {
 		return c.HTML(http.StatusInternalServerError, err.Error())
 	}
-	if err := this.TodoModel.Edit(*todo); err != nil {
-		return c.HTML(http.StatusInternalServerError, err.Error())
+	todo.UserID = userID.(string)
+	// The page posts the clock of the user, the offset of the browser may be another time zone
+	due := todo.Due
+	todo.Due = time.Date(due.Year(), due.Month(), due.Day(), due.Hour(), due.Minute(), due.Second(), 0, this.location(todo.UserID))
+	if err := this.TodoModel.Edit(todo.UserID, *todo); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
 	}
 	return c.NoContent(http.StatusOK)
 }
 
 func (this *WebController) Delete(c echo.Context) error {
 	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
 	todo := new(model.Todo)
 	if err := c.Bind(todo); err != nil {
 		return c.HTML(http.StatusInternalServerError, err.Error())
 	}
-	if err := this.TodoModel.Delete(*todo); err != nil {
+	todo.UserID = userID.(string)
+	if err := this.TodoModel.Delete(todo.UserID, *todo); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) AddStep(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	step := new(model.Step)
+	if err := c.Bind(step); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if err := this.TodoModel.AddStep(userID.(string), *step); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) DoneStep(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	step := new(model.Step)
+	if err := c.Bind(step); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if err := this.TodoModel.DoneStep(userID.(string), *step); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) ReorderSteps(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	order := new(struct {
+		TodoID  int
+		StepIDs []int
+	})
+	if err := c.Bind(order); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if err := this.TodoModel.ReorderSteps(userID.(string), order.TodoID, order.StepIDs); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) DeleteStep(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	step := new(model.Step)
+	if err := c.Bind(step); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if err := this.TodoModel.DeleteStep(userID.(string), *step); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) Lists(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	lists, err := this.TodoModel.Lists(userID.(string))
+	if err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	// The default list holds the todos without a list
+	lists = append([]model.TodoList{{Name: model.DefaultListName, UserID: userID.(string)}}, lists...)
+	return c.JSON(http.StatusOK, lists)
+}
+
+func (this *WebController) CreateList(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	list := new(model.TodoList)
+	if err := c.Bind(list); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	list.UserID = userID.(string)
+	if err := this.TodoModel.CreateList(*list); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) RenameList(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	list := new(model.TodoList)
+	if err := c.Bind(list); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if err := this.TodoModel.RenameList(userID.(string), *list); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) DeleteList(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	list := new(model.TodoList)
+	if err := c.Bind(list); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if err := this.TodoModel.DeleteList(userID.(string), *list); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) ShareList(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	list := new(model.TodoList)
+	if err := c.Bind(list); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	code, err := this.TodoModel.ShareList(userID.(string), *list)
+	if err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	data := map[string]string{
+		"InviteCode": code,
+		"InviteURL":  bot.InviteURL(code),
+	}
+	return c.JSON(http.StatusOK, data)
+}
+
+func (this *WebController) JoinList(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	invite := new(struct {
+		InviteCode string
+	})
+	if err := c.Bind(invite); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	list, err := this.TodoModel.JoinList(userID.(string), invite.InviteCode)
+	if err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+func (this *WebController) LeaveList(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	list := new(model.TodoList)
+	if err := c.Bind(list); err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if err := this.TodoModel.LeaveList(userID.(string), *list); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+func (this *WebController) Setting(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	setting, err := this.TodoModel.Setting(userID.(string))
+	if err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	if setting.TimeZone == "" {
+		setting.TimeZone = model.DefaultTimeZone
+	}
+	return c.JSON(http.StatusOK, setting)
+}
+
+func (this *WebController) SaveSetting(c echo.Context) error {
+	this.SetNoCache(c)
+	userID := this.SessionService.Get(c, "oauthId")
+	if userID == nil {
+		return c.HTML(http.StatusInternalServerError, "user not found")
+	}
+	// Only the posted preferences change
+	setting, err := this.TodoModel.Setting(userID.(string))
+	if err != nil {
+		return c.HTML(http.StatusInternalServerError, err.Error())
+	}
+	// The buddy is only set by the buddy with the code, see bot.JoinBuddy
+	buddy, buddyCode := setting.Buddy, setting.BuddyCode
+	if err := c.Bind(&setting); err != nil {
 		return c.HTML(http.StatusInternalServerError, err.Error())
 	}
+	setting.UserID = userID.(string)
+	setting.Buddy, setting.BuddyCode = buddy, buddyCode
+	if err := this.TodoModel.SaveSetting(setting); err != nil {
+		return c.HTML(this.ErrorStatus(err), err.Error())
+	}
 	return c.NoContent(http.StatusOK)
 }
+
+// location is the time zone of the user, the default one when it cannot be read
+func (this *WebController) location(userID string) *time.Location {
+	setting, err := this.TodoModel.Setting(userID)
+	if err != nil {
+		log.Println(err)
+	}
+	return setting.Location()
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -17,10 +18,12 @@ import (
 )
 
 type mockTodoModel struct {
-	willError       bool
-	willNoRemaining bool
+	*model.TodoMemoryModel
+	willError bool
 }
 
+var bangkok, _ = time.LoadLocation("Asia/Bangkok")
+
 var todos = []model.Todo{
 	{
 		ID:     1,
@@ -28,78 +31,117 @@ var todos = []model.Todo{
 		Task:   "task",
 		Done:   false,
 		Pin:    true,
-		Due:    time.Now(),
+		Due:    time.Date(2018, 11, 15, 15, 4, 0, 0, bangkok),
 	},
 }
 
+type mockListNotifier struct {
+	created []model.Todo
+	done    []int
+}
+
+func (this *mockListNotifier) NotifyCreated(todo model.Todo) {
+	this.created = append(this.created, todo)
+}
+func (this *mockListNotifier) NotifyDone(userID string, todoID int) {
+	this.done = append(this.done, todoID)
+}
+
+func newMockTodoModel() *mockTodoModel {
+	todoModel := model.NewTodoMemoryModel()
+	for _, todo := range todos {
+		todoModel.Create(todo)
+		todoModel.Pin(todo.UserID, todo)
+		todoModel.Done(todo.UserID, todo)
+	}
+	return &mockTodoModel{
+		TodoMemoryModel: todoModel,
+	}
+}
+
 func (this *mockTodoModel) List(userID string) ([]model.Todo, error) {
 	if this.willError {
 		this.willError = false
 		return nil, errors.New("dummy")
 	}
-	return todos, nil
+	return this.TodoMemoryModel.List(userID)
 }
-func (this *mockTodoModel) Create(todo model.Todo) error {
+func (this *mockTodoModel) Pin(userID string, todo model.Todo) error {
 	if this.willError {
 		this.willError = false
 		return errors.New("dummy")
 	}
-	return nil
+	return this.TodoMemoryModel.Pin(userID, todo)
 }
-func (this *mockTodoModel) Pin(todo model.Todo) error {
+func (this *mockTodoModel) Snooze(userID string, todo model.Todo) error {
 	if this.willError {
 		this.willError = false
 		return errors.New("dummy")
 	}
-	return nil
+	return this.TodoMemoryModel.Snooze(userID, todo)
 }
-func (this *mockTodoModel) Done(todo model.Todo) error {
+func (this *mockTodoModel) Done(userID string, todo model.Todo) error {
 	if this.willError {
 		this.willError = false
 		return errors.New("dummy")
 	}
-	return nil
+	return this.TodoMemoryModel.Done(userID, todo)
 }
-func (this *mockTodoModel) Remind() (map[string][]model.Todo, error) {
+func (this *mockTodoModel) Edit(userID string, todo model.Todo) error {
 	if this.willError {
 		this.willError = false
-		return nil, errors.New("dummy")
+		return errors.New("dummy")
 	}
-	userTodos := map[string][]model.Todo{}
-	todos := userTodos["dummy"]
-	if this.willNoRemaining {
-		this.willNoRemaining = false
-		todo := model.Todo{
-			Done: true,
-		}
-		todos = append(todos, todo)
-	} else {
-		todo := model.Todo{
-			Done: false,
-		}
-		todos = append(todos, todo)
+	return this.TodoMemoryModel.Edit(userID, todo)
+}
+func (this *mockTodoModel) Delete(userID string, todo model.Todo) error {
+	if this.willError {
+		this.willError = false
+		return errors.New("dummy")
 	}
-
-	todo := model.Todo{
-		Pin: true,
+	return this.TodoMemoryModel.Delete(userID, todo)
+}
+func (this *mockTodoModel) AddStep(userID string, step model.Step) error {
+	if this.willError {
+		this.willError = false
+		return errors.New("dummy")
+	}
+	return this.TodoMemoryModel.AddStep(userID, step)
+}
+func (this *mockTodoModel) DoneStep(userID string, step model.Step) error {
+	if this.willError {
+		this.willError = false
+		return errors.New("dummy")
+	}
+	return this.TodoMemoryModel.DoneStep(userID, step)
+}
+func (this *mockTodoModel) ReorderSteps(userID string, todoID int, stepIDs []int) error {
+	if this.willError {
+		this.willError = false
+		return errors.New("dummy")
 	}
-	todos = append(todos, todo)
-	userTodos["dummy"] = todos
-	return userTodos, nil
+	return this.TodoMemoryModel.ReorderSteps(userID, todoID, stepIDs)
 }
-func (this *mockTodoModel) Edit(todo model.Todo) error {
+func (this *mockTodoModel) DeleteStep(userID string, step model.Step) error {
 	if this.willError {
 		this.willError = false
 		return errors.New("dummy")
 	}
-	return nil
+	return this.TodoMemoryModel.DeleteStep(userID, step)
+}
+func (this *mockTodoModel) Lists(userID string) ([]model.TodoList, error) {
+	if this.willError {
+		this.willError = false
+		return nil, errors.New("dummy")
+	}
+	return this.TodoMemoryModel.Lists(userID)
 }
-func (this *mockTodoModel) Delete(todo model.Todo) error {
+func (this *mockTodoModel) CreateList(list model.TodoList) error {
 	if this.willError {
 		this.willError = false
 		return errors.New("dummy")
 	}
-	return nil
+	return this.TodoMemoryModel.CreateList(list)
 }
 
 type mockSessionService struct {
@@ -148,12 +190,12 @@ func (this *mockOAuthService) Signout(oauthToken string) error {
 }
 
 func TestWebControllerIndex(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 	}
 	e := echo.New()
@@ -177,18 +219,18 @@ func TestWebControllerIndex(t *testing.T) {
 }
 
 func TestWebControllerList(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 	}
 	e := echo.New()
 
 	// OK
-	sessionService.Mock("oauthId", "dummy")
+	sessionService.Mock("oauthId", "user id")
 	b, _ := json.Marshal(todos)
 	wantJSON := string(b)
 	req := httptest.NewRequest(http.MethodGet, "/list", nil)
@@ -200,6 +242,59 @@ func TestWebControllerList(t *testing.T) {
 		assert.Equal(t, wantJSON, rec.Body.String())
 	}
 
+	// Filtered by tags
+	todoModel.Create(model.Todo{UserID: "user id", Task: "tagged", Due: todos[0].Due, Tags: []string{"home", "work"}})
+	req = httptest.NewRequest(http.MethodGet, "/list?tag=work&tag=%23Home", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.List(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var filtered []model.Todo
+		json.Unmarshal(rec.Body.Bytes(), &filtered)
+		if assert.Len(t, filtered, 1) {
+			assert.Equal(t, "tagged", filtered[0].Task)
+			assert.Equal(t, []string{"home", "work"}, filtered[0].Tags)
+		}
+	}
+
+	// Filtered by list
+	todoModel.CreateList(model.TodoList{UserID: "user id", Name: "Shopping"})
+	shopping, _ := todoModel.FindList("user id", "shopping")
+	todoModel.Create(model.Todo{UserID: "user id", Task: "milk", Due: todos[0].Due, ListID: shopping.ID})
+	req = httptest.NewRequest(http.MethodGet, "/list?list="+strconv.Itoa(shopping.ID), nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.List(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var filtered []model.Todo
+		json.Unmarshal(rec.Body.Bytes(), &filtered)
+		if assert.Len(t, filtered, 1) {
+			assert.Equal(t, "milk", filtered[0].Task)
+		}
+	}
+
+	// Wrong list
+	req = httptest.NewRequest(http.MethodGet, "/list?list=shopping", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.List(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "wrong list", rec.Body.String())
+	}
+
+	// No todo has the tag
+	req = httptest.NewRequest(http.MethodGet, "/list?tag=shopping", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.List(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "null", strings.TrimSpace(rec.Body.String()))
+	}
+
 	// No userID
 	sessionService.Mock("oauthId", nil)
 	req = httptest.NewRequest(http.MethodGet, "/list", nil)
@@ -224,18 +319,112 @@ func TestWebControllerList(t *testing.T) {
 	}
 }
 
+func TestWebControllerSnooze(t *testing.T) {
+	todoModel := newMockTodoModel()
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Valid, the due date is kept and the snooze is listed in the time zone of the user
+	sessionService.Mock("oauthId", "user id")
+	until := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	b, _ := json.Marshal(model.Todo{ID: 1, Snoozed: until})
+	req := httptest.NewRequest(http.MethodPost, "/snooze", strings.NewReader(string(b)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Snooze(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/list", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.List(c)) {
+		assert.Contains(t, rec.Body.String(), `"Snoozed":"`+until.In(bangkok).Format(time.RFC3339)+`"`)
+	}
+	listed, _ := todoModel.List("user id")
+	assert.True(t, listed[0].Due.Equal(todos[0].Due))
+
+	// A time that has passed
+	b, _ = json.Marshal(model.Todo{ID: 1, Snoozed: time.Now().Add(-time.Minute)})
+	req = httptest.NewRequest(http.MethodPost, "/snooze", strings.NewReader(string(b)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Snooze(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "Snooze until a later time", rec.Body.String())
+	}
+
+	// Woken up
+	req = httptest.NewRequest(http.MethodPost, "/snooze", strings.NewReader(`{"ID":1,"Snoozed":null}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Snooze(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	listed, _ = todoModel.List("user id")
+	assert.True(t, listed[0].Snoozed.IsZero())
+
+	// Error from Model
+	todoModel.willError = true
+	req = httptest.NewRequest(http.MethodPost, "/snooze", strings.NewReader(`{"ID":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Snooze(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "dummy", rec.Body.String())
+	}
+
+	// Not found
+	req = httptest.NewRequest(http.MethodPost, "/snooze", strings.NewReader(`{"ID":99}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Snooze(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodPost, "/snooze", strings.NewReader(`{"ID":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Snooze(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
+}
+
 func TestWebControllerPin(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 	}
 	e := echo.New()
 
 	// Valid
+	sessionService.Mock("oauthId", "user id")
 	b, _ := json.Marshal(todos[0])
 	inputJSON := string(b)
 	req := httptest.NewRequest(http.MethodPost, "/pin", strings.NewReader(inputJSON))
@@ -274,20 +463,62 @@ func TestWebControllerPin(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		assert.Equal(t, "dummy", rec.Body.String())
 	}
+
+	// Not found
+	b, _ = json.Marshal(model.Todo{ID: 2})
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/pin", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Pin(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "No record", rec.Body.String())
+	}
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	b, _ = json.Marshal(todos[0])
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/pin", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Pin(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodPost, "/pin", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Pin(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
 }
 
 func TestWebControllerDone(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
+	notifier := mockListNotifier{}
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
+		Notifier:       &notifier,
 	}
 	e := echo.New()
 
 	// Valid
+	sessionService.Mock("oauthId", "user id")
 	b, _ := json.Marshal(todos[0])
 	inputJSON := string(b)
 	req := httptest.NewRequest(http.MethodPost, "/done", strings.NewReader(inputJSON))
@@ -299,6 +530,18 @@ func TestWebControllerDone(t *testing.T) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Equal(t, "", rec.Body.String())
 	}
+	assert.Empty(t, notifier.done)
+
+	// Completed todos are notified to the members of the list
+	req = httptest.NewRequest(http.MethodPost, "/done", strings.NewReader(`{"ID":1,"Done":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Done(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, []int{1}, notifier.done)
 
 	// Invalid JSON
 	b, _ = json.Marshal("")
@@ -326,15 +569,54 @@ func TestWebControllerDone(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		assert.Equal(t, "dummy", rec.Body.String())
 	}
+
+	// Not found
+	b, _ = json.Marshal(model.Todo{ID: 2})
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/done", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Done(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "No record", rec.Body.String())
+	}
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	b, _ = json.Marshal(todos[0])
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/done", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Done(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodPost, "/done", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Done(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
 }
 
 func TestWebControllerUserInfo(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 	}
 	e := echo.New()
@@ -371,7 +653,7 @@ func TestWebControllerUserInfo(t *testing.T) {
 }
 
 func TestWebControllerLogout(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
@@ -383,7 +665,7 @@ func TestWebControllerLogout(t *testing.T) {
 	oAuthService.On("Signout", mock.Anything).Return(nil)
 
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 		OAuthService:   &oAuthService,
 	}
@@ -402,7 +684,7 @@ func TestWebControllerLogout(t *testing.T) {
 	oAuthService = mockOAuthService{}
 	oAuthService.On("Signout", mock.Anything).Return(errors.New("dummy"))
 	controller = WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 		OAuthService:   &oAuthService,
 	}
@@ -417,7 +699,7 @@ func TestWebControllerLogout(t *testing.T) {
 }
 
 func TestWebControllerLogin(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
@@ -427,7 +709,7 @@ func TestWebControllerLogin(t *testing.T) {
 	oAuthService := mockOAuthService{}
 
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 		OAuthService:   &oAuthService,
 	}
@@ -444,7 +726,7 @@ func TestWebControllerLogin(t *testing.T) {
 }
 
 func TestWebControllerAuth(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
@@ -455,7 +737,7 @@ func TestWebControllerAuth(t *testing.T) {
 	oAuthService.On("Signout", mock.Anything).Return(nil)
 
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 		OAuthService:   &oAuthService,
 	}
@@ -483,17 +765,18 @@ func TestWebControllerAuth(t *testing.T) {
 }
 
 func TestWebControllerEdit(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 	}
 	e := echo.New()
 
 	// Valid
+	sessionService.Mock("oauthId", "user id")
 	b, _ := json.Marshal(todos[0])
 	inputJSON := string(b)
 	req := httptest.NewRequest(http.MethodPost, "/edit", strings.NewReader(inputJSON))
@@ -532,20 +815,60 @@ func TestWebControllerEdit(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		assert.Equal(t, "dummy", rec.Body.String())
 	}
+
+	// Not found
+	b, _ = json.Marshal(model.Todo{ID: 2})
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/edit", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Edit(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "No record", rec.Body.String())
+	}
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	b, _ = json.Marshal(todos[0])
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/edit", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Edit(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodPost, "/edit", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Edit(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
 }
 
 func TestWebControllerDelete(t *testing.T) {
-	todoModel := mockTodoModel{}
+	todoModel := newMockTodoModel()
 	sessionService := mockSessionService{
 		sessions: map[string]interface{}{},
 	}
 	controller := WebController{
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &sessionService,
 	}
 	e := echo.New()
 
 	// Valid
+	sessionService.Mock("oauthId", "user id")
 	b, _ := json.Marshal(todos[0])
 	inputJSON := string(b)
 	req := httptest.NewRequest(http.MethodPost, "/delete", strings.NewReader(inputJSON))
@@ -584,4 +907,699 @@ func TestWebControllerDelete(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		assert.Equal(t, "dummy", rec.Body.String())
 	}
+
+	// Not found
+	b, _ = json.Marshal(model.Todo{ID: 2})
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/delete", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Delete(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "No record", rec.Body.String())
+	}
+
+	// Owned by another user
+	todoModel = newMockTodoModel()
+	controller.TodoModel = todoModel
+	sessionService.Mock("oauthId", "another user")
+	b, _ = json.Marshal(todos[0])
+	inputJSON = string(b)
+	req = httptest.NewRequest(http.MethodPost, "/delete", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Delete(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodPost, "/delete", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Delete(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
+}
+
+func TestWebControllerAddStep(t *testing.T) {
+	todoModel := newMockTodoModel()
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Valid
+	sessionService.Mock("oauthId", "user id")
+	b, _ := json.Marshal(model.Step{TodoID: 1, Task: "step"})
+	inputJSON := string(b)
+	req := httptest.NewRequest(http.MethodPost, "/add-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.AddStep(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	list, _ := todoModel.List("user id")
+	if assert.Len(t, list[0].Steps, 1) {
+		assert.Equal(t, "step", list[0].Steps[0].Task)
+	}
+
+	// Error from Model
+	todoModel.willError = true
+	req = httptest.NewRequest(http.MethodPost, "/add-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.AddStep(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "dummy", rec.Body.String())
+	}
+
+	// Todo owned by another user
+	sessionService.Mock("oauthId", "another user")
+	req = httptest.NewRequest(http.MethodPost, "/add-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.AddStep(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodPost, "/add-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.AddStep(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
+}
+
+func TestWebControllerDoneStep(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.AddStep("user id", model.Step{TodoID: 1, Task: "step"})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Valid
+	sessionService.Mock("oauthId", "user id")
+	b, _ := json.Marshal(model.Step{ID: 1, Done: true})
+	inputJSON := string(b)
+	req := httptest.NewRequest(http.MethodPost, "/done-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DoneStep(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+
+	// Invalid JSON
+	b, _ = json.Marshal("")
+	req = httptest.NewRequest(http.MethodPost, "/done-step", strings.NewReader(string(b)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DoneStep(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "code=400, message=Unmarshal type error: expected=model.Step, got=string, field=, offset=2", rec.Body.String())
+	}
+
+	// Not found
+	b, _ = json.Marshal(model.Step{ID: 2, Done: true})
+	req = httptest.NewRequest(http.MethodPost, "/done-step", strings.NewReader(string(b)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DoneStep(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "No record", rec.Body.String())
+	}
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	req = httptest.NewRequest(http.MethodPost, "/done-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DoneStep(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+}
+
+func TestWebControllerReorderSteps(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.AddStep("user id", model.Step{TodoID: 1, Task: "step 1"})
+	todoModel.AddStep("user id", model.Step{TodoID: 1, Task: "step 2"})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Valid
+	sessionService.Mock("oauthId", "user id")
+	inputJSON := `{"TodoID":1,"StepIDs":[2,1]}`
+	req := httptest.NewRequest(http.MethodPost, "/reorder-steps", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.ReorderSteps(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	list, _ := todoModel.List("user id")
+	if assert.Len(t, list[0].Steps, 2) {
+		assert.Equal(t, "step 2", list[0].Steps[0].Task)
+	}
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	req = httptest.NewRequest(http.MethodPost, "/reorder-steps", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.ReorderSteps(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+}
+
+func TestWebControllerDeleteStep(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.AddStep("user id", model.Step{TodoID: 1, Task: "step"})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	b, _ := json.Marshal(model.Step{ID: 1})
+	inputJSON := string(b)
+	req := httptest.NewRequest(http.MethodPost, "/delete-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DeleteStep(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// Valid
+	sessionService.Mock("oauthId", "user id")
+	req = httptest.NewRequest(http.MethodPost, "/delete-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DeleteStep(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+
+	// Error from Model
+	todoModel.willError = true
+	req = httptest.NewRequest(http.MethodPost, "/delete-step", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DeleteStep(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "dummy", rec.Body.String())
+	}
+}
+
+func TestWebControllerLists(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.CreateList(model.TodoList{UserID: "user id", Name: "Work"})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// OK
+	sessionService.Mock("oauthId", "user id")
+	req := httptest.NewRequest(http.MethodGet, "/lists", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Lists(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var lists []model.TodoList
+		json.Unmarshal(rec.Body.Bytes(), &lists)
+		assert.Equal(t, []model.TodoList{
+			{ID: 0, UserID: "user id", Name: model.DefaultListName},
+			{ID: 1, UserID: "user id", Name: "Work"},
+		}, lists)
+	}
+
+	// Error from Model
+	todoModel.willError = true
+	req = httptest.NewRequest(http.MethodGet, "/lists", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Lists(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "dummy", rec.Body.String())
+	}
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodGet, "/lists", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Lists(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
+}
+
+func TestWebControllerCreateList(t *testing.T) {
+	todoModel := newMockTodoModel()
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Valid
+	sessionService.Mock("oauthId", "user id")
+	inputJSON := `{"Name":"Shopping","UserID":"another user"}`
+	req := httptest.NewRequest(http.MethodPost, "/create-list", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.CreateList(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	_, err := todoModel.FindList("user id", "Shopping")
+	assert.NoError(t, err)
+
+	// Name taken
+	req = httptest.NewRequest(http.MethodPost, "/create-list", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.CreateList(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "Wrong list name", rec.Body.String())
+	}
+
+	// Error from Model
+	todoModel.willError = true
+	req = httptest.NewRequest(http.MethodPost, "/create-list", strings.NewReader(`{"Name":"Work"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.CreateList(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "dummy", rec.Body.String())
+	}
+}
+
+func TestWebControllerRenameList(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.CreateList(model.TodoList{UserID: "user id", Name: "Work"})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	inputJSON := `{"ID":1,"Name":"Office"}`
+	req := httptest.NewRequest(http.MethodPost, "/rename-list", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.RenameList(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// Valid
+	sessionService.Mock("oauthId", "user id")
+	req = httptest.NewRequest(http.MethodPost, "/rename-list", strings.NewReader(inputJSON))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.RenameList(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	list, _ := todoModel.FindList("user id", "office")
+	assert.Equal(t, 1, list.ID)
+}
+
+func TestWebControllerDeleteList(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.CreateList(model.TodoList{UserID: "user id", Name: "Work"})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Not found
+	sessionService.Mock("oauthId", "user id")
+	req := httptest.NewRequest(http.MethodPost, "/delete-list", strings.NewReader(`{"ID":2}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DeleteList(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "No record", rec.Body.String())
+	}
+
+	// Valid
+	req = httptest.NewRequest(http.MethodPost, "/delete-list", strings.NewReader(`{"ID":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.DeleteList(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	lists, _ := todoModel.Lists("user id")
+	assert.Empty(t, lists)
+}
+
+func TestWebControllerShareList(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.CreateList(model.TodoList{UserID: "user id", Name: "Family"})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Owned by another user
+	sessionService.Mock("oauthId", "another user")
+	req := httptest.NewRequest(http.MethodPost, "/share-list", strings.NewReader(`{"ID":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.ShareList(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// Valid
+	sessionService.Mock("oauthId", "user id")
+	req = httptest.NewRequest(http.MethodPost, "/share-list", strings.NewReader(`{"ID":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.ShareList(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	data := map[string]string{}
+	json.Unmarshal(rec.Body.Bytes(), &data)
+	assert.NotEmpty(t, data["InviteCode"])
+}
+
+func TestWebControllerJoinList(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.CreateList(model.TodoList{UserID: "user id", Name: "Family"})
+	code, _ := todoModel.ShareList("user id", model.TodoList{ID: 1})
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// Wrong code
+	sessionService.Mock("oauthId", "another user")
+	req := httptest.NewRequest(http.MethodPost, "/join-list", strings.NewReader(`{"InviteCode":"WRONG"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.JoinList(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "No record", rec.Body.String())
+	}
+
+	// Valid
+	req = httptest.NewRequest(http.MethodPost, "/join-list", strings.NewReader(`{"InviteCode":"`+code+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.JoinList(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"ID":1,"UserID":"user id","Name":"Family","InviteCode":"","Joined":true}`, strings.TrimSpace(rec.Body.String()))
+	}
+	todos, _ := todoModel.List("another user")
+	assert.Empty(t, todos)
+	lists, _ := todoModel.Lists("another user")
+	assert.Len(t, lists, 1)
+}
+
+func TestWebControllerLeaveList(t *testing.T) {
+	todoModel := newMockTodoModel()
+	todoModel.CreateList(model.TodoList{UserID: "user id", Name: "Family"})
+	code, _ := todoModel.ShareList("user id", model.TodoList{ID: 1})
+	todoModel.JoinList("another user", code)
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// The owner deletes instead
+	sessionService.Mock("oauthId", "user id")
+	req := httptest.NewRequest(http.MethodPost, "/leave-list", strings.NewReader(`{"ID":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.LeaveList(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "Forbidden", rec.Body.String())
+	}
+
+	// Valid
+	sessionService.Mock("oauthId", "another user")
+	req = httptest.NewRequest(http.MethodPost, "/leave-list", strings.NewReader(`{"ID":1}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.LeaveList(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	lists, _ := todoModel.Lists("another user")
+	assert.Empty(t, lists)
+}
+
+func TestWebControllerSetting(t *testing.T) {
+	todoModel := newMockTodoModel()
+	sessionService := mockSessionService{
+		sessions: map[string]interface{}{},
+	}
+	controller := WebController{
+		TodoModel:      todoModel,
+		SessionService: &sessionService,
+	}
+	e := echo.New()
+
+	// The default time zone
+	sessionService.Mock("oauthId", "user id")
+	req := httptest.NewRequest(http.MethodGet, "/setting", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Setting(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"UserID":"user id","Language":"","TimeZone":"Asia/Bangkok","RemindTimes":"","RemindDays":"","QuietStart":"","QuietEnd":"","HideCompleted":false,"OnlyOverdue":false,"OverdueIntervals":"","Buddy":"","BuddyDays":0,"BuddyCode":""}`, strings.TrimSpace(rec.Body.String()))
+	}
+
+	// Unknown time zone
+	req = httptest.NewRequest(http.MethodPost, "/setting", strings.NewReader(`{"TimeZone":"Mars/Olympus"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.SaveSetting(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "Unknown time zone", rec.Body.String())
+	}
+
+	// Half the quiet hours
+	req = httptest.NewRequest(http.MethodPost, "/setting", strings.NewReader(`{"QuietStart":"22:00"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.SaveSetting(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "Wrong reminder setting", rec.Body.String())
+	}
+
+	// Valid, the language is kept
+	todoModel.SaveSetting(model.Setting{UserID: "user id", Language: "th"})
+	req = httptest.NewRequest(http.MethodPost, "/setting", strings.NewReader(`{"UserID":"another user","TimeZone":"Europe/Berlin"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.SaveSetting(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "", rec.Body.String())
+	}
+	setting, _ := todoModel.Setting("user id")
+	assert.Equal(t, model.Setting{UserID: "user id", Language: "th", TimeZone: "Europe/Berlin"}, setting)
+
+	// The reminder setting, the time zone is kept
+	req = httptest.NewRequest(http.MethodPost, "/setting", strings.NewReader(`{"RemindTimes":"08:00,18:30","RemindDays":"1,2,3,4,5","QuietStart":"22:00","QuietEnd":"07:00","OnlyOverdue":true}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.SaveSetting(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	setting, _ = todoModel.Setting("user id")
+	assert.Equal(t, model.Setting{UserID: "user id", Language: "th", TimeZone: "Europe/Berlin", RemindTimes: "08:00,18:30", RemindDays: "1,2,3,4,5",
+		QuietStart: "22:00", QuietEnd: "07:00", OnlyOverdue: true}, setting)
+
+	// The overdue intervals but not the buddy
+	todoModel.SaveSetting(model.Setting{UserID: "user id", Buddy: "dummy buddy", BuddyCode: "K3XQ7M2A"})
+	req = httptest.NewRequest(http.MethodPost, "/setting", strings.NewReader(`{"OverdueIntervals":"60,1440","Buddy":"another user","BuddyDays":5,"BuddyCode":"AAAAAAAA"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.SaveSetting(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	setting, _ = todoModel.Setting("user id")
+	assert.Equal(t, model.Setting{UserID: "user id", OverdueIntervals: "60,1440", Buddy: "dummy buddy", BuddyDays: 5, BuddyCode: "K3XQ7M2A"}, setting)
+	todoModel.SaveSetting(model.Setting{UserID: "user id", Language: "th", TimeZone: "Europe/Berlin"})
+
+	// Due dates are listed in the time zone of the user
+	req = httptest.NewRequest(http.MethodGet, "/list", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.List(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"Due":"2018-11-15T09:04:00+01:00"`)
+	}
+
+	// Edited due dates are the clock of the user whatever the offset
+	todo := todos[0]
+	todo.Due = time.Date(2018, 11, 20, 9, 30, 0, 0, bangkok)
+	b, _ := json.Marshal(todo)
+	req = httptest.NewRequest(http.MethodPost, "/edit", strings.NewReader(string(b)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Edit(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	edited, _ := todoModel.List("user id")
+	assert.Equal(t, "2018-11-20T08:30:00Z", edited[0].Due.Format(time.RFC3339))
+
+	// No userID
+	sessionService.Mock("oauthId", nil)
+	req = httptest.NewRequest(http.MethodGet, "/setting", nil)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.Setting(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodPost, "/setting", strings.NewReader(`{"TimeZone":"UTC"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+
+	if assert.NoError(t, controller.SaveSetting(c)) {
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "user not found", rec.Body.String())
+	}
 }
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"go/build"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/choobot/choo-todo-bot/app/bot"
 	"github.com/choobot/choo-todo-bot/app/controller"
 	"github.com/choobot/choo-todo-bot/app/model"
+	"github.com/choobot/choo-todo-bot/app/scheduler"
 	"github.com/choobot/choo-todo-bot/app/service"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo"
@@ -17,25 +24,114 @@ import (
 	"github.com/line/line-bot-sdk-go/linebot"
 )
 
+// $ app migrate [version] migrates the database schema up or down to the version, latest by default
+func migrate(migrator model.Migrator, args []string) error {
+	version := model.LatestSchemaVersion()
+	if len(args) > 0 {
+		var err error
+		version, err = strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	if err := migrator.MigrateTo(version); err != nil {
+		return err
+	}
+	current, err := migrator.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	log.Printf("Schema version is %d\n", current)
+	return nil
+}
+
+// schedulerJobs sends the digests at REMIND_SCHEDULE in the time zone of each user, 8:00 by default, the alerts before the due dates,
+// the notices after them and deletes the users who blocked the bot INACTIVE_RETENTION_DAYS ago
+func schedulerJobs(todoBot *bot.TodoBot) ([]scheduler.Job, error) {
+	expression := os.Getenv("REMIND_SCHEDULE")
+	if expression == "" {
+		expression = "0 8 * * *"
+	}
+	schedule, err := scheduler.ParseSchedule(expression)
+	if err != nil {
+		return nil, err
+	}
+	jobs := []scheduler.Job{todoBot.RemindJob(schedule), todoBot.AlertJob(), todoBot.OverdueJob()}
+	if days := os.Getenv("INACTIVE_RETENTION_DAYS"); days != "" {
+		retentionDays, err := strconv.Atoi(days)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, todoBot.PurgeJob(time.Duration(retentionDays)*24*time.Hour))
+	}
+	return jobs, nil
+}
+
 func main() {
+	models := model.NewModels()
+	todoModel := models.TodoModel
+	migrator, isMigrator := todoModel.(model.Migrator)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if !isMigrator {
+			log.Fatal("DATA_SOURCE_NAME has no schema to migrate")
+		}
+		if err := migrate(migrator, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if isMigrator {
+		if err := migrator.Migrate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := bot.LoadCatalogs(build.Default.GOPATH + "/src/github.com/choobot/choo-todo-bot/app/locales"); err != nil {
+		log.Fatal(err)
+	}
+
 	client, err := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	todoModel := model.NewTodoMySqlModel()
-
 	bot := &bot.TodoBot{
-		TodoModel: &todoModel,
-		Client:    client,
+		TodoModel:    todoModel,
+		ListingModel: models.ListingModel,
+		DraftModel:   models.DraftModel,
+		DialogModel:  models.DialogModel,
+		AlertModel:   models.AlertModel,
+		JobRunModel:  models.JobRunModel,
+		Client:       client,
+		GroupByTag:   os.Getenv("REMIND_GROUP_BY_TAG") == "true",
+		Renderer:     bot.NewRenderer(os.Getenv("MESSAGE_FORMAT")),
+		Secret:       os.Getenv("LINE_BOT_SECRET"),
+	}
+	jobs, err := schedulerJobs(bot)
+	if err != nil {
+		log.Fatal(err)
 	}
+	jitter := time.Duration(0)
+	if value := os.Getenv("REMIND_JITTER"); value != "" {
+		if jitter, err = time.ParseDuration(value); err != nil {
+			log.Fatal(err)
+		}
+	}
+	jobScheduler := &scheduler.Scheduler{
+		Store:  models.JobRunModel,
+		Jobs:   jobs,
+		Jitter: jitter,
+	}
+	jobScheduler.Start()
+
 	oAuthSerivce := service.NewLineOAuthService()
 	jwtService := service.NewLineJwtService()
 	webController := controller.WebController{
 		OAuthService:   &oAuthSerivce,
 		JwtService:     &jwtService,
-		TodoModel:      &todoModel,
+		TodoModel:      todoModel,
 		SessionService: &service.CookieSessionService{},
+		Notifier:       bot,
 	}
 
 	e := echo.New()
@@ -59,9 +155,16 @@ func main() {
 		}
 		return c.NoContent(http.StatusOK)
 	})
+	// The scheduler sends the digests, /remind sends them all now
 	e.GET("/remind", func(c echo.Context) error {
-		err := bot.Remind()
-		if err != nil {
+		token := os.Getenv("REMIND_TOKEN")
+		if token == "" {
+			return c.NoContent(http.StatusForbidden)
+		}
+		if subtle.ConstantTimeCompare([]byte(c.Request().Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+		if err := bot.Remind(); err != nil {
 			return c.HTML(http.StatusInternalServerError, err.Error())
 		}
 		return c.NoContent(http.StatusOK)
@@ -73,14 +176,42 @@ func main() {
 	e.GET("/list", webController.List)
 	e.POST("/pin", webController.Pin)
 	e.POST("/done", webController.Done)
+	e.POST("/snooze", webController.Snooze)
 	e.GET("/user-info", webController.UserInfo)
 	e.GET("/logout", webController.Logout)
 	e.POST("/edit", webController.Edit)
 	e.POST("/delete", webController.Delete)
+	e.POST("/add-step", webController.AddStep)
+	e.POST("/done-step", webController.DoneStep)
+	e.POST("/reorder-steps", webController.ReorderSteps)
+	e.POST("/delete-step", webController.DeleteStep)
+	e.GET("/lists", webController.Lists)
+	e.POST("/create-list", webController.CreateList)
+	e.POST("/rename-list", webController.RenameList)
+	e.POST("/delete-list", webController.DeleteList)
+	e.POST("/share-list", webController.ShareList)
+	e.POST("/join-list", webController.JoinList)
+	e.POST("/leave-list", webController.LeaveList)
+	e.GET("/setting", webController.Setting)
+	e.POST("/setting", webController.SaveSetting)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "80"
 	}
-	e.Logger.Fatal(e.Start(":" + port))
+	// Heroku sends SIGTERM before a restart, the running jobs and requests finish first
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		jobScheduler.Stop()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := e.Shutdown(ctx); err != nil {
+			e.Logger.Fatal(err)
+		}
+	}()
+	if err := e.Start(":" + port); err != nil && err != http.ErrServerClosed {
+		e.Logger.Fatal(err)
+	}
 }
@@ -0,0 +1,416 @@
+package model
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alertClaimTimeout is how long an alert stays claimed by an instance that neither sent nor released it,
+// like one stopped while pushing, before another one takes it over
+const alertClaimTimeout = 10 * time.Minute
+
+// Alert is a notification of a todo shortly before it is due, it is pending until an instance claims it
+// to push it and sent once pushed, a failed push releases it for the next run
+type Alert struct {
+	ID     int
+	UserID string
+	TodoID int
+	// Due is the due date of the todo when the alert was scheduled, the alert is stale once the todo moves
+	Due  time.Time
+	Lead time.Duration
+	// At is Due minus Lead
+	At time.Time
+	// Claimed is zero while no instance is pushing the alert
+	Claimed time.Time
+	// Sent is zero while the alert is pending or claimed
+	Sent time.Time
+	// Attempts is how many pushes of the alert failed
+	Attempts int
+}
+
+// AlertModel keeps the lead times of the users and their pending and sent alerts
+type AlertModel interface {
+	AlertLeads(userID string) (map[int][]time.Duration, error)
+	AlertLeadsByUser(userIDs []string) (map[string]map[int][]time.Duration, error)
+	SaveAlertLeads(userID string, todoID int, leads []time.Duration) error
+	DeleteAlertLeads(userID string, todoID int) error
+	ScheduleAlerts(alerts []Alert) error
+	PendingAlerts(now time.Time) ([]Alert, error)
+	ClaimAlert(id int, now time.Time) (bool, error)
+	SendAlert(id int, sent time.Time) error
+	ReleaseAlert(id int) error
+	DeleteAlerts(before time.Time) error
+	Purge(userID string) error
+}
+
+type AlertSqlModel struct {
+	db      *sql.DB
+	dialect string
+}
+
+// leadsText is "15,60", the leads in minutes
+func leadsText(leads []time.Duration) string {
+	minutes := []string{}
+	for _, lead := range leads {
+		minutes = append(minutes, strconv.Itoa(int(lead/time.Minute)))
+	}
+	return strings.Join(minutes, ",")
+}
+
+func parseLeads(text string) ([]time.Duration, error) {
+	leads := []time.Duration{}
+	if text == "" {
+		return leads, nil
+	}
+	for _, minutes := range strings.Split(text, ",") {
+		value, err := strconv.Atoi(minutes)
+		if err != nil {
+			return nil, err
+		}
+		leads = append(leads, time.Duration(value)*time.Minute)
+	}
+	return leads, nil
+}
+
+// AlertLeads are the lead times set by the user by todo ID, 0 is for the todos without their own, no leads is no alert
+func (this *AlertSqlModel) AlertLeads(userID string) (map[int][]time.Duration, error) {
+	rows, err := this.db.Query("SELECT todo_id, leads FROM alert_lead WHERE user_id=?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todoLeads := map[int][]time.Duration{}
+	for rows.Next() {
+		var todoID int
+		var text string
+		if err := rows.Scan(&todoID, &text); err != nil {
+			return nil, err
+		}
+		if todoLeads[todoID], err = parseLeads(text); err != nil {
+			return nil, err
+		}
+	}
+	return todoLeads, rows.Err()
+}
+
+// AlertLeadsByUser are the AlertLeads of several users at once by user, the users who have set none are left out
+func (this *AlertSqlModel) AlertLeadsByUser(userIDs []string) (map[string]map[int][]time.Duration, error) {
+	userLeads := map[string]map[int][]time.Duration{}
+	if len(userIDs) == 0 {
+		return userLeads, nil
+	}
+	args := []interface{}{}
+	for _, userID := range userIDs {
+		args = append(args, userID)
+	}
+	rows, err := this.db.Query("SELECT user_id, todo_id, leads FROM alert_lead WHERE user_id IN ( ?"+strings.Repeat(", ?", len(userIDs)-1)+" )", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		var todoID int
+		var text string
+		if err := rows.Scan(&userID, &todoID, &text); err != nil {
+			return nil, err
+		}
+		leads, err := parseLeads(text)
+		if err != nil {
+			return nil, err
+		}
+		if userLeads[userID] == nil {
+			userLeads[userID] = map[int][]time.Duration{}
+		}
+		userLeads[userID][todoID] = leads
+	}
+	return userLeads, rows.Err()
+}
+
+// SaveAlertLeads sets the lead times of a todo, or of every todo of the user with todo ID 0
+func (this *AlertSqlModel) SaveAlertLeads(userID string, todoID int, leads []time.Duration) error {
+	_, err := this.db.Exec("REPLACE INTO alert_lead ( user_id, todo_id, leads ) VALUES( ?, ?, ? )", userID, todoID, leadsText(leads))
+	return err
+}
+
+// DeleteAlertLeads puts the todo back to the lead times of the user, or the user back to the default ones with todo ID 0
+func (this *AlertSqlModel) DeleteAlertLeads(userID string, todoID int) error {
+	_, err := this.db.Exec("DELETE FROM alert_lead WHERE user_id=? AND todo_id=?", userID, todoID)
+	return err
+}
+
+// ScheduleAlerts stores the alerts as pending, the ones already scheduled for the same due date and lead are left as they are
+func (this *AlertSqlModel) ScheduleAlerts(alerts []Alert) error {
+	insert := "INSERT IGNORE INTO alert ( user_id, todo_id, due, lead_minutes, alert_at ) VALUES( ?, ?, ?, ?, ? )"
+	if this.dialect == "sqlite3" {
+		insert = "INSERT OR IGNORE INTO alert ( user_id, todo_id, due, lead_minutes, alert_at ) VALUES( ?, ?, ?, ?, ? )"
+	}
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		due := alert.Due.UTC().Truncate(time.Second)
+		lead := alert.Lead / time.Minute
+		if _, err := tx.Exec(insert, alert.UserID, alert.TodoID, due, int(lead), due.Add(-lead*time.Minute)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// PendingAlerts are the alerts neither sent nor claimed whose time has come, by user, todo and shortest lead first
+func (this *AlertSqlModel) PendingAlerts(now time.Time) ([]Alert, error) {
+	rows, err := this.db.Query("SELECT id, user_id, todo_id, due, lead_minutes, alert_at, claimed, attempts FROM alert WHERE sent IS NULL AND alert_at<=? AND (claimed IS NULL OR claimed<?) ORDER BY user_id, todo_id, lead_minutes",
+		now.UTC(), now.UTC().Add(-alertClaimTimeout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []Alert{}
+	for rows.Next() {
+		var alert Alert
+		var lead int
+		var claimed *time.Time
+		if err := rows.Scan(&alert.ID, &alert.UserID, &alert.TodoID, &alert.Due, &lead, &alert.At, &claimed, &alert.Attempts); err != nil {
+			return nil, err
+		}
+		if claimed != nil {
+			alert.Claimed = claimed.UTC()
+		}
+		alert.Due = alert.Due.UTC()
+		alert.At = alert.At.UTC()
+		alert.Lead = time.Duration(lead) * time.Minute
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// ClaimAlert takes the alert to push it, it is false when another instance has it so each alert is pushed by one
+func (this *AlertSqlModel) ClaimAlert(id int, now time.Time) (bool, error) {
+	result, err := this.db.Exec("UPDATE alert SET claimed=? WHERE id=? AND sent IS NULL AND (claimed IS NULL OR claimed<?)",
+		now.UTC().Truncate(time.Second), id, now.UTC().Add(-alertClaimTimeout))
+	if err != nil {
+		return false, err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return num == 1, nil
+}
+
+// SendAlert marks the claimed alert sent once it is pushed, or once it is no longer to be pushed
+func (this *AlertSqlModel) SendAlert(id int, sent time.Time) error {
+	_, err := this.db.Exec("UPDATE alert SET sent=? WHERE id=?", sent.UTC().Truncate(time.Second), id)
+	return err
+}
+
+// ReleaseAlert puts the claimed alert back to pending after a failed push, it counts the attempt
+func (this *AlertSqlModel) ReleaseAlert(id int) error {
+	_, err := this.db.Exec("UPDATE alert SET claimed=NULL, attempts=attempts+1 WHERE id=? AND sent IS NULL", id)
+	return err
+}
+
+// DeleteAlerts forgets the alerts of before the time, sent or not
+func (this *AlertSqlModel) DeleteAlerts(before time.Time) error {
+	_, err := this.db.Exec("DELETE FROM alert WHERE alert_at<?", before.UTC())
+	return err
+}
+
+// Purge forgets the lead times and the alerts of a purged user, see TodoModel.Purge
+func (this *AlertSqlModel) Purge(userID string) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range []string{"DELETE FROM alert_lead WHERE user_id=?", "DELETE FROM alert WHERE user_id=?"} {
+		if _, err := tx.Exec(statement, userID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type AlertMemoryModel struct {
+	mutex  sync.Mutex
+	leads  map[string]map[int][]time.Duration
+	nextID int
+	alerts []Alert
+}
+
+func NewAlertMemoryModel() *AlertMemoryModel {
+	return &AlertMemoryModel{
+		leads:  map[string]map[int][]time.Duration{},
+		nextID: 1,
+	}
+}
+
+func (this *AlertMemoryModel) AlertLeads(userID string) (map[int][]time.Duration, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	todoLeads := map[int][]time.Duration{}
+	for todoID, leads := range this.leads[userID] {
+		todoLeads[todoID] = append([]time.Duration{}, leads...)
+	}
+	return todoLeads, nil
+}
+
+func (this *AlertMemoryModel) AlertLeadsByUser(userIDs []string) (map[string]map[int][]time.Duration, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	userLeads := map[string]map[int][]time.Duration{}
+	for _, userID := range userIDs {
+		for todoID, leads := range this.leads[userID] {
+			if userLeads[userID] == nil {
+				userLeads[userID] = map[int][]time.Duration{}
+			}
+			userLeads[userID][todoID] = append([]time.Duration{}, leads...)
+		}
+	}
+	return userLeads, nil
+}
+
+func (this *AlertMemoryModel) SaveAlertLeads(userID string, todoID int, leads []time.Duration) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.leads[userID] == nil {
+		this.leads[userID] = map[int][]time.Duration{}
+	}
+	// Whole minutes like the lead_minutes column
+	saved := []time.Duration{}
+	for _, lead := range leads {
+		saved = append(saved, lead.Truncate(time.Minute))
+	}
+	this.leads[userID][todoID] = saved
+	return nil
+}
+
+func (this *AlertMemoryModel) DeleteAlertLeads(userID string, todoID int) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.leads[userID], todoID)
+	return nil
+}
+
+func (this *AlertMemoryModel) ScheduleAlerts(alerts []Alert) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, alert := range alerts {
+		alert.Due = alert.Due.UTC().Truncate(time.Second)
+		alert.Lead = alert.Lead.Truncate(time.Minute)
+		scheduled := false
+		for _, other := range this.alerts {
+			if other.UserID == alert.UserID && other.TodoID == alert.TodoID && other.Due.Equal(alert.Due) && other.Lead == alert.Lead {
+				scheduled = true
+				break
+			}
+		}
+		if scheduled {
+			continue
+		}
+		alert.ID = this.nextID
+		alert.At = alert.Due.Add(-alert.Lead)
+		alert.Claimed = time.Time{}
+		alert.Sent = time.Time{}
+		alert.Attempts = 0
+		this.nextID++
+		this.alerts = append(this.alerts, alert)
+	}
+	return nil
+}
+
+func (this *AlertMemoryModel) PendingAlerts(now time.Time) ([]Alert, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	alerts := []Alert{}
+	for _, alert := range this.alerts {
+		if alert.Sent.IsZero() && !alert.At.After(now) && (alert.Claimed.IsZero() || alert.Claimed.Before(now.Add(-alertClaimTimeout))) {
+			alerts = append(alerts, alert)
+		}
+	}
+	// Same order as ORDER BY user_id, todo_id, lead_minutes
+	sort.SliceStable(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		if a.TodoID != b.TodoID {
+			return a.TodoID < b.TodoID
+		}
+		return a.Lead < b.Lead
+	})
+	return alerts, nil
+}
+
+func (this *AlertMemoryModel) ClaimAlert(id int, now time.Time) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for i := range this.alerts {
+		alert := &this.alerts[i]
+		if alert.ID == id && alert.Sent.IsZero() && (alert.Claimed.IsZero() || alert.Claimed.Before(now.Add(-alertClaimTimeout))) {
+			alert.Claimed = now.UTC().Truncate(time.Second)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (this *AlertMemoryModel) SendAlert(id int, sent time.Time) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for i := range this.alerts {
+		if this.alerts[i].ID == id {
+			this.alerts[i].Sent = sent.UTC().Truncate(time.Second)
+		}
+	}
+	return nil
+}
+
+func (this *AlertMemoryModel) ReleaseAlert(id int) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for i := range this.alerts {
+		if this.alerts[i].ID == id && this.alerts[i].Sent.IsZero() {
+			this.alerts[i].Claimed = time.Time{}
+			this.alerts[i].Attempts++
+		}
+	}
+	return nil
+}
+
+func (this *AlertMemoryModel) DeleteAlerts(before time.Time) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	alerts := []Alert{}
+	for _, alert := range this.alerts {
+		if !alert.At.Before(before) {
+			alerts = append(alerts, alert)
+		}
+	}
+	this.alerts = alerts
+	return nil
+}
+
+func (this *AlertMemoryModel) Purge(userID string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.leads, userID)
+	alerts := []Alert{}
+	for _, alert := range this.alerts {
+		if alert.UserID != userID {
+			alerts = append(alerts, alert)
+		}
+	}
+	this.alerts = alerts
+	return nil
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAlertSqlModelAlertLeads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("REPLACE INTO alert_lead").WithArgs("dummy user", 3, "15,1440").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT todo_id, leads FROM alert_lead WHERE user_id=\\?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"todo_id", "leads"}).AddRow(0, "").AddRow(3, "15,1440"))
+	mock.ExpectExec("DELETE FROM alert_lead WHERE user_id=\\? AND todo_id=\\?").WithArgs("dummy user", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT user_id, todo_id, leads FROM alert_lead WHERE user_id IN \\( \\?, \\? \\)").WithArgs("dummy user", "another user").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "todo_id", "leads"}).AddRow("dummy user", 0, "60"))
+	model := AlertSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if err := model.SaveAlertLeads("dummy user", 3, []time.Duration{15 * time.Minute, 24 * time.Hour}); err != nil {
+		t.Errorf("Result AlertSqlModel.SaveAlertLeads() == %v, want %v", err, nil)
+	}
+	if todoLeads, err := model.AlertLeads("dummy user"); err != nil || fmt.Sprint(todoLeads) != "map[0:[] 3:[15m0s 24h0m0s]]" {
+		t.Errorf("Result AlertSqlModel.AlertLeads() == %v, %v", todoLeads, err)
+	}
+	if err := model.DeleteAlertLeads("dummy user", 3); err != nil {
+		t.Errorf("Result AlertSqlModel.DeleteAlertLeads() == %v, want %v", err, nil)
+	}
+	if userLeads, err := model.AlertLeadsByUser([]string{"dummy user", "another user"}); err != nil || fmt.Sprint(userLeads) != "map[dummy user:map[0:[1h0m0s]]]" {
+		t.Errorf("Result AlertSqlModel.AlertLeadsByUser() == %v, %v", userLeads, err)
+	}
+}
+
+func TestAlertSqlModelAlerts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := time.Date(2018, 11, 15, 9, 0, 0, 0, time.UTC)
+	now := due.Add(-10 * time.Minute)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT OR IGNORE INTO alert").WithArgs("dummy user", 1, due, 15, due.Add(-15*time.Minute)).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT OR IGNORE INTO alert").WithArgs("dummy user", 1, due, 60, due.Add(-time.Hour)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT id, user_id, todo_id, due, lead_minutes, alert_at, claimed, attempts FROM alert WHERE sent IS NULL AND alert_at<=\\? AND \\(claimed IS NULL OR claimed<\\?\\) ORDER BY user_id, todo_id, lead_minutes").WithArgs(now, now.Add(-alertClaimTimeout)).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "todo_id", "due", "lead_minutes", "alert_at", "claimed", "attempts"}).AddRow(1, "dummy user", 1, due, 15, due.Add(-15*time.Minute), nil, 1))
+	mock.ExpectExec("UPDATE alert SET claimed=\\? WHERE id=\\? AND sent IS NULL AND \\(claimed IS NULL OR claimed<\\?\\)").WithArgs(now, 1, now.Add(-alertClaimTimeout)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE alert SET claimed=\\? WHERE id=\\? AND sent IS NULL AND \\(claimed IS NULL OR claimed<\\?\\)").WithArgs(now, 1, now.Add(-alertClaimTimeout)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE alert SET claimed=NULL, attempts=attempts\\+1 WHERE id=\\? AND sent IS NULL").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE alert SET sent=\\? WHERE id=\\?").WithArgs(now, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM alert WHERE alert_at<\\?").WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 1))
+	model := AlertSqlModel{
+		db:      db,
+		dialect: "sqlite3",
+	}
+	alerts := []Alert{
+		{UserID: "dummy user", TodoID: 1, Due: due.Add(400 * time.Millisecond), Lead: 15 * time.Minute},
+		{UserID: "dummy user", TodoID: 1, Due: due, Lead: time.Hour},
+	}
+	if err := model.ScheduleAlerts(alerts); err != nil {
+		t.Errorf("Result AlertSqlModel.ScheduleAlerts() == %v, want %v", err, nil)
+	}
+	want := Alert{ID: 1, UserID: "dummy user", TodoID: 1, Due: due, Lead: 15 * time.Minute, At: due.Add(-15 * time.Minute), Attempts: 1}
+	if alerts, err := model.PendingAlerts(now); err != nil || len(alerts) != 1 || alerts[0] != want {
+		t.Errorf("Result AlertSqlModel.PendingAlerts() == %#v, %v, want %#v", alerts, err, want)
+	}
+	if claimed, err := model.ClaimAlert(1, now); !claimed || err != nil {
+		t.Errorf("Result AlertSqlModel.ClaimAlert() == %v, %v, want %v", claimed, err, true)
+	}
+	if claimed, err := model.ClaimAlert(1, now); claimed || err != nil {
+		t.Errorf("Result AlertSqlModel.ClaimAlert() again == %v, %v, want %v", claimed, err, false)
+	}
+	if err := model.ReleaseAlert(1); err != nil {
+		t.Errorf("Result AlertSqlModel.ReleaseAlert() == %v, want %v", err, nil)
+	}
+	if err := model.SendAlert(1, now); err != nil {
+		t.Errorf("Result AlertSqlModel.SendAlert() == %v, want %v", err, nil)
+	}
+	if err := model.DeleteAlerts(now); err != nil {
+		t.Errorf("Result AlertSqlModel.DeleteAlerts() == %v, want %v", err, nil)
+	}
+}
+
+func TestAlertSqlModelPurge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM alert_lead WHERE user_id=\\?").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM alert WHERE user_id=\\?").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	model := AlertSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if err := model.Purge("dummy user"); err != nil {
+		t.Errorf("Result AlertSqlModel.Purge() == %v, want %v", err, nil)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Dialog is where the user is in a guided conversation of the bot, it is stored so any instance of the bot can continue it
+type Dialog struct {
+	UserID string
+	// State is the question the bot waits an answer to
+	State string
+	// Text is the task as the user sent it, with its list name and tags
+	Text string
+	// Due is zero until it is answered
+	Due time.Time
+	// Repeat is the rule of an answer like "Pay rent : every month on 1st", see Recurrence
+	Repeat  string
+	Updated time.Time
+}
+
+// DialogModel keeps the dialog of each user, a user is in one at most
+type DialogModel interface {
+	Dialog(userID string) (Dialog, error)
+	SaveDialog(dialog Dialog) error
+	DeleteDialog(userID string) error
+	Purge(userID string) error
+}
+
+type DialogSqlModel struct {
+	db *sql.DB
+}
+
+// Dialog returns ErrNotFound when the user is in no dialog
+func (this *DialogSqlModel) Dialog(userID string) (Dialog, error) {
+	dialog := Dialog{
+		UserID: userID,
+	}
+	var due *time.Time
+	err := this.db.QueryRow("SELECT state, text, due, repeat_rule, updated FROM dialog WHERE user_id=?", userID).Scan(&dialog.State, &dialog.Text, &due, &dialog.Repeat, &dialog.Updated)
+	if err == sql.ErrNoRows {
+		return Dialog{}, ErrNotFound
+	} else if err != nil {
+		return Dialog{}, err
+	}
+	if due != nil {
+		dialog.Due = due.UTC()
+	}
+	dialog.Updated = dialog.Updated.UTC()
+	return dialog, nil
+}
+
+// SaveDialog replaces the dialog of the user
+func (this *DialogSqlModel) SaveDialog(dialog Dialog) error {
+	var due interface{}
+	if !dialog.Due.IsZero() {
+		due = dialog.Due.UTC()
+	}
+	_, err := this.db.Exec("REPLACE INTO dialog ( user_id, state, text, due, repeat_rule, updated ) VALUES( ?, ?, ?, ?, ?, ? )",
+		dialog.UserID, dialog.State, dialog.Text, due, dialog.Repeat, dialog.Updated.UTC().Truncate(time.Second))
+	return err
+}
+
+func (this *DialogSqlModel) DeleteDialog(userID string) error {
+	_, err := this.db.Exec("DELETE FROM dialog WHERE user_id=?", userID)
+	return err
+}
+
+// Purge forgets the dialog of a purged user, see TodoModel.Purge
+func (this *DialogSqlModel) Purge(userID string) error {
+	return this.DeleteDialog(userID)
+}
+
+type DialogMemoryModel struct {
+	mutex   sync.Mutex
+	dialogs map[string]Dialog
+}
+
+func NewDialogMemoryModel() *DialogMemoryModel {
+	return &DialogMemoryModel{
+		dialogs: map[string]Dialog{},
+	}
+}
+
+func (this *DialogMemoryModel) Dialog(userID string) (Dialog, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	dialog, ok := this.dialogs[userID]
+	if !ok {
+		return Dialog{}, ErrNotFound
+	}
+	return dialog, nil
+}
+
+func (this *DialogMemoryModel) SaveDialog(dialog Dialog) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if !dialog.Due.IsZero() {
+		dialog.Due = dialog.Due.UTC()
+	}
+	dialog.Updated = dialog.Updated.UTC().Truncate(time.Second)
+	this.dialogs[dialog.UserID] = dialog
+	return nil
+}
+
+func (this *DialogMemoryModel) DeleteDialog(userID string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.dialogs, userID)
+	return nil
+}
+
+func (this *DialogMemoryModel) Purge(userID string) error {
+	return this.DeleteDialog(userID)
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDialogSqlModelDialog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Date(2018, 11, 15, 3, 0, 0, 0, time.UTC)
+	due := time.Date(2018, 11, 16, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT state, text, due, repeat_rule, updated FROM dialog WHERE user_id=\\?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"state", "text", "due", "repeat_rule", "updated"}).AddRow("task", "", nil, "", updated))
+	mock.ExpectQuery("SELECT state, text, due, repeat_rule, updated FROM dialog WHERE user_id=\\?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"state", "text", "due", "repeat_rule", "updated"}).AddRow("pin", "Pay rent", due, "FREQ=MONTHLY;BYMONTHDAY=1", updated))
+	mock.ExpectQuery("SELECT state, text, due, repeat_rule, updated FROM dialog WHERE user_id=\\?").WithArgs("dummy user").WillReturnError(sql.ErrNoRows)
+	model := DialogSqlModel{
+		db: db,
+	}
+	if dialog, err := model.Dialog("dummy user"); err != nil || dialog.State != "task" || !dialog.Due.IsZero() || !dialog.Updated.Equal(updated) {
+		t.Errorf("Result DialogSqlModel.Dialog(%q) == %#v, %v", "dummy user", dialog, err)
+	}
+	if dialog, err := model.Dialog("dummy user"); err != nil || dialog.State != "pin" || dialog.Text != "Pay rent" || !dialog.Due.Equal(due) || dialog.Repeat != "FREQ=MONTHLY;BYMONTHDAY=1" {
+		t.Errorf("Result DialogSqlModel.Dialog(%q) == %#v, %v", "dummy user", dialog, err)
+	}
+	if _, err := model.Dialog("dummy user"); err != ErrNotFound {
+		t.Errorf("Result DialogSqlModel.Dialog(%q) == %v, want %v", "dummy user", err, ErrNotFound)
+	}
+}
+
+func TestDialogSqlModelSaveDialog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Date(2018, 11, 15, 3, 0, 0, 0, time.UTC)
+	due := time.Date(2018, 11, 16, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec("REPLACE INTO dialog").WithArgs("dummy user", "task", "", nil, "", updated).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("REPLACE INTO dialog").WithArgs("dummy user", "pin", "Pay rent", due, "FREQ=MONTHLY;BYMONTHDAY=1", updated).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM dialog WHERE user_id=\\?").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	model := DialogSqlModel{
+		db: db,
+	}
+	if err := model.SaveDialog(Dialog{UserID: "dummy user", State: "task", Updated: updated}); err != nil {
+		t.Errorf("Result DialogSqlModel.SaveDialog() == %v, want %v", err, nil)
+	}
+	if err := model.SaveDialog(Dialog{UserID: "dummy user", State: "pin", Text: "Pay rent", Due: due, Repeat: "FREQ=MONTHLY;BYMONTHDAY=1", Updated: updated}); err != nil {
+		t.Errorf("Result DialogSqlModel.SaveDialog() == %v, want %v", err, nil)
+	}
+	if err := model.DeleteDialog("dummy user"); err != nil {
+		t.Errorf("Result DialogSqlModel.DeleteDialog() == %v, want %v", err, nil)
+	}
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Draft is a task the user sent without a due date, the bot asks when it is due and creates it from the answer
+type Draft struct {
+	UserID string
+	// Text is the message as it was sent, with its list name and tags
+	Text    string
+	Created time.Time
+}
+
+// DraftModel keeps the draft of each user, a user has one at most
+type DraftModel interface {
+	Draft(userID string) (Draft, error)
+	SaveDraft(draft Draft) error
+	DeleteDraft(draft Draft) (bool, error)
+	Purge(userID string) error
+}
+
+type DraftSqlModel struct {
+	db *sql.DB
+}
+
+// Draft returns ErrNotFound when the user has no draft
+func (this *DraftSqlModel) Draft(userID string) (Draft, error) {
+	draft := Draft{
+		UserID: userID,
+	}
+	err := this.db.QueryRow("SELECT text, created FROM draft WHERE user_id=?", userID).Scan(&draft.Text, &draft.Created)
+	if err == sql.ErrNoRows {
+		return Draft{}, ErrNotFound
+	} else if err != nil {
+		return Draft{}, err
+	}
+	draft.Created = draft.Created.UTC()
+	return draft, nil
+}
+
+// SaveDraft replaces the draft of the user
+func (this *DraftSqlModel) SaveDraft(draft Draft) error {
+	_, err := this.db.Exec("REPLACE INTO draft ( user_id, text, created ) VALUES( ?, ?, ? )", draft.UserID, draft.Text, draft.Created.UTC().Truncate(time.Second))
+	return err
+}
+
+// DeleteDraft deletes the draft only if it is still the one created then,
+// it is false when it was answered or replaced meanwhile so a double tap creates one todo
+func (this *DraftSqlModel) DeleteDraft(draft Draft) (bool, error) {
+	result, err := this.db.Exec("DELETE FROM draft WHERE user_id=? AND created=?", draft.UserID, draft.Created.UTC().Truncate(time.Second))
+	if err != nil {
+		return false, err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return num == 1, nil
+}
+
+// Purge forgets the draft of a purged user, see TodoModel.Purge
+func (this *DraftSqlModel) Purge(userID string) error {
+	_, err := this.db.Exec("DELETE FROM draft WHERE user_id=?", userID)
+	return err
+}
+
+type DraftMemoryModel struct {
+	mutex  sync.Mutex
+	drafts map[string]Draft
+}
+
+func NewDraftMemoryModel() *DraftMemoryModel {
+	return &DraftMemoryModel{
+		drafts: map[string]Draft{},
+	}
+}
+
+func (this *DraftMemoryModel) Draft(userID string) (Draft, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	draft, ok := this.drafts[userID]
+	if !ok {
+		return Draft{}, ErrNotFound
+	}
+	return draft, nil
+}
+
+func (this *DraftMemoryModel) SaveDraft(draft Draft) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	// Like the seconds of a DATETIME column
+	draft.Created = draft.Created.UTC().Truncate(time.Second)
+	this.drafts[draft.UserID] = draft
+	return nil
+}
+
+func (this *DraftMemoryModel) DeleteDraft(draft Draft) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	saved, ok := this.drafts[draft.UserID]
+	if !ok || !saved.Created.Equal(draft.Created.Truncate(time.Second)) {
+		return false, nil
+	}
+	delete(this.drafts, draft.UserID)
+	return true, nil
+}
+
+func (this *DraftMemoryModel) Purge(userID string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.drafts, userID)
+	return nil
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDraftSqlModelDraft(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2018, 11, 15, 3, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT text, created FROM draft WHERE user_id=\\?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"text", "created"}).AddRow("Buy milk", created))
+	mock.ExpectQuery("SELECT text, created FROM draft WHERE user_id=\\?").WithArgs("dummy user").WillReturnError(sql.ErrNoRows)
+	model := DraftSqlModel{
+		db: db,
+	}
+	if draft, err := model.Draft("dummy user"); err != nil || draft.Text != "Buy milk" || !draft.Created.Equal(created) {
+		t.Errorf("Result DraftSqlModel.Draft(%q) == %#v, %v", "dummy user", draft, err)
+	}
+	if _, err := model.Draft("dummy user"); err != ErrNotFound {
+		t.Errorf("Result DraftSqlModel.Draft(%q) == %v, want %v", "dummy user", err, ErrNotFound)
+	}
+}
+
+func TestDraftSqlModelSaveDraft(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2018, 11, 15, 3, 0, 0, 0, time.UTC)
+	mock.ExpectExec("REPLACE INTO draft").WithArgs("dummy user", "Buy milk", created).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM draft WHERE user_id=\\? AND created=\\?").WithArgs("dummy user", created).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM draft WHERE user_id=\\? AND created=\\?").WithArgs("dummy user", created).WillReturnResult(sqlmock.NewResult(0, 0))
+	model := DraftSqlModel{
+		db: db,
+	}
+	if err := model.SaveDraft(Draft{UserID: "dummy user", Text: "Buy milk", Created: created.Add(300 * time.Millisecond)}); err != nil {
+		t.Errorf("Result DraftSqlModel.SaveDraft() == %v, want %v", err, nil)
+	}
+	draft := Draft{UserID: "dummy user", Created: created}
+	if deleted, err := model.DeleteDraft(draft); !deleted || err != nil {
+		t.Errorf("Result DraftSqlModel.DeleteDraft() == %v, %v, want %v", deleted, err, true)
+	}
+	// Answered meanwhile
+	if deleted, err := model.DeleteDraft(draft); deleted || err != nil {
+		t.Errorf("Result DraftSqlModel.DeleteDraft() again == %v, %v, want %v", deleted, err, false)
+	}
+}
@@ -0,0 +1,81 @@
+package model
+
+import "time"
+
+// Deactivate stops the reminders of a user who blocked the bot, or of a group or a room it left, since is kept for the retention
+func (this *TodoSqlModel) Deactivate(userID string, since time.Time) error {
+	_, err := this.db.Exec("REPLACE INTO inactive_user ( user_id, since ) VALUES( ?, ? )", userID, since.UTC().Truncate(time.Second))
+	return err
+}
+
+// Activate resumes the reminders, it returns whether the user was inactive
+func (this *TodoSqlModel) Activate(userID string) (bool, error) {
+	result, err := this.db.Exec("DELETE FROM inactive_user WHERE user_id=?", userID)
+	if err != nil {
+		return false, err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return num > 0, nil
+}
+
+// InactiveUsers are the users inactive since before the time
+func (this *TodoSqlModel) InactiveUsers(before time.Time) ([]string, error) {
+	rows, err := this.db.Query("SELECT user_id FROM inactive_user WHERE since<? ORDER BY user_id", before.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// Purge deletes the todos, the lists and the settings of the user, the members of their shared lists lose them too,
+// the other models purge the rest of the user first as it is the last trace of an inactive user, see Models
+func (this *TodoSqlModel) Purge(userID string) error {
+	statements := []string{
+		"DELETE FROM step WHERE todo_id IN ( SELECT id FROM todo WHERE user_id=? )",
+		"DELETE FROM todo_tag WHERE todo_id IN ( SELECT id FROM todo WHERE user_id=? )",
+		"DELETE FROM todo WHERE user_id=?",
+		// The todos of the members move to their inbox like DeleteList
+		"UPDATE todo SET list_id=0 WHERE list_id IN ( SELECT id FROM todo_list WHERE user_id=? )",
+		"DELETE FROM list_member WHERE list_id IN ( SELECT id FROM todo_list WHERE user_id=? )",
+		"DELETE FROM todo_list WHERE user_id=?",
+		"DELETE FROM list_member WHERE user_id=?",
+		"DELETE FROM user_setting WHERE user_id=?",
+		"DELETE FROM inactive_user WHERE user_id=?",
+	}
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, userID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// withoutInactive removes the users who get no reminders
+func (this *TodoSqlModel) withoutInactive(userTodos map[string][]Todo) (map[string][]Todo, error) {
+	userIDs, err := this.InactiveUsers(time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		delete(userTodos, userID)
+	}
+	return userTodos, nil
+}
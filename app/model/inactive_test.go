@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTodoSqlModelActivate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2018, 11, 15, 3, 0, 0, 0, time.UTC)
+	mock.ExpectExec("REPLACE INTO inactive_user").WithArgs("dummy user", since).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM inactive_user WHERE user_id=\\?").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM inactive_user WHERE user_id=\\?").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT user_id FROM inactive_user WHERE since<\\? ORDER BY user_id").WithArgs(since).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}).AddRow("C1").AddRow("U1"))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if err := model.Deactivate("dummy user", since.Add(300*time.Millisecond)); err != nil {
+		t.Errorf("Result TodoSqlModel.Deactivate() == %v, want %v", err, nil)
+	}
+	if back, err := model.Activate("dummy user"); !back || err != nil {
+		t.Errorf("Result TodoSqlModel.Activate() == %v, %v, want %v", back, err, true)
+	}
+	if back, err := model.Activate("dummy user"); back || err != nil {
+		t.Errorf("Result TodoSqlModel.Activate() again == %v, %v, want %v", back, err, false)
+	}
+	if userIDs, err := model.InactiveUsers(since); err != nil || fmt.Sprint(userIDs) != "[C1 U1]" {
+		t.Errorf("Result TodoSqlModel.InactiveUsers() == %v, %v", userIDs, err)
+	}
+}
+
+func TestTodoSqlModelPurge(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectBegin()
+	for _, table := range []string{"step", "todo_tag", "todo"} {
+		mock.ExpectExec("DELETE FROM " + table + " WHERE").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("UPDATE todo SET list_id=0").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, table := range []string{"list_member", "todo_list", "list_member", "user_setting", "inactive_user"} {
+		mock.ExpectExec("DELETE FROM " + table + " WHERE").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if err := model.Purge("dummy user"); err != nil {
+		t.Errorf("Result TodoSqlModel.Purge() == %v, want %v", err, nil)
+	}
+
+	// Rolled back
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM step WHERE").WithArgs("dummy user").WillReturnError(wantErr)
+	mock.ExpectRollback()
+	if err := model.Purge("dummy user"); err != wantErr {
+		t.Errorf("Result TodoSqlModel.Purge() == %v, want %v", err, wantErr)
+	}
+}
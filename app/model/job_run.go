@@ -0,0 +1,81 @@
+package model
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// JobRunModel keeps the last run of each job of the scheduler, it is the scheduler.Store
+type JobRunModel interface {
+	LastRun(name string) (time.Time, error)
+	ClaimRun(name string, last time.Time, run time.Time) (bool, error)
+}
+
+type JobRunSqlModel struct {
+	db      *sql.DB
+	dialect string
+}
+
+// LastRun is the last time the scheduler ran the job, zero when it never did
+func (this *JobRunSqlModel) LastRun(name string) (time.Time, error) {
+	var last time.Time
+	err := this.db.QueryRow("SELECT last_run FROM job_run WHERE name=?", name).Scan(&last)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return last.UTC(), nil
+}
+
+// ClaimRun moves the last run of the job from last to run only if no other instance moved it first
+func (this *JobRunSqlModel) ClaimRun(name string, last time.Time, run time.Time) (bool, error) {
+	var result sql.Result
+	var err error
+	run = run.UTC().Truncate(time.Second)
+	if last.IsZero() {
+		insert := "INSERT IGNORE INTO job_run ( name, last_run ) VALUES( ?, ? )"
+		if this.dialect == "sqlite3" {
+			insert = "INSERT OR IGNORE INTO job_run ( name, last_run ) VALUES( ?, ? )"
+		}
+		result, err = this.db.Exec(insert, name, run)
+	} else {
+		result, err = this.db.Exec("UPDATE job_run SET last_run=? WHERE name=? AND last_run=?", run, name, last.UTC())
+	}
+	if err != nil {
+		return false, err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return num == 1, nil
+}
+
+type JobRunMemoryModel struct {
+	mutex sync.Mutex
+	runs  map[string]time.Time
+}
+
+func NewJobRunMemoryModel() *JobRunMemoryModel {
+	return &JobRunMemoryModel{
+		runs: map[string]time.Time{},
+	}
+}
+
+func (this *JobRunMemoryModel) LastRun(name string) (time.Time, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.runs[name], nil
+}
+
+func (this *JobRunMemoryModel) ClaimRun(name string, last time.Time, run time.Time) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if !this.runs[name].Equal(last) {
+		return false, nil
+	}
+	this.runs[name] = run.UTC().Truncate(time.Second)
+	return true, nil
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestJobRunSqlModelClaimRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := time.Date(2018, 11, 15, 1, 0, 0, 0, time.UTC)
+	run := last.Add(15 * time.Minute)
+	mock.ExpectQuery("SELECT last_run FROM job_run WHERE name=\\?").WithArgs("remind").WillReturnRows(
+		sqlmock.NewRows([]string{"last_run"}).AddRow(last))
+	mock.ExpectExec("INSERT IGNORE INTO job_run").WithArgs("remind", run).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE job_run SET last_run=\\? WHERE name=\\? AND last_run=\\?").WithArgs(run, "remind", last).WillReturnResult(sqlmock.NewResult(0, 0))
+	model := JobRunSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if got, err := model.LastRun("remind"); err != nil || !got.Equal(last) {
+		t.Errorf("Result JobRunSqlModel.LastRun() == %v, %v, want %v", got, err, last)
+	}
+	if claimed, err := model.ClaimRun("remind", time.Time{}, run.Add(500*time.Millisecond)); !claimed || err != nil {
+		t.Errorf("Result JobRunSqlModel.ClaimRun() of a new job == %v, %v, want %v", claimed, err, true)
+	}
+	if claimed, err := model.ClaimRun("remind", last, run); claimed || err != nil {
+		t.Errorf("Result JobRunSqlModel.ClaimRun() claimed by another instance == %v, %v, want %v", claimed, err, false)
+	}
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// ListingModel keeps the last numbered list the bot sent to each user, "done 3" is the third one
+type ListingModel interface {
+	Listing(userID string) ([]int, error)
+	SaveListing(userID string, todoIDs []int) error
+	Purge(userID string) error
+}
+
+type ListingSqlModel struct {
+	db *sql.DB
+}
+
+// Listing is the todo IDs of the last listing of the user
+func (this *ListingSqlModel) Listing(userID string) ([]int, error) {
+	rows, err := this.db.Query("SELECT todo_id FROM listing WHERE user_id=? ORDER BY position", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todoIDs := []int{}
+	for rows.Next() {
+		var todoID int
+		if err := rows.Scan(&todoID); err != nil {
+			return nil, err
+		}
+		todoIDs = append(todoIDs, todoID)
+	}
+	return todoIDs, rows.Err()
+}
+
+// SaveListing replaces the listing of the user, the positions start at 1
+func (this *ListingSqlModel) SaveListing(userID string, todoIDs []int) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM listing WHERE user_id=?", userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for i, todoID := range todoIDs {
+		if _, err := tx.Exec("INSERT INTO listing ( user_id, position, todo_id ) VALUES( ?, ?, ? )", userID, i+1, todoID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Purge forgets the listing of a purged user, see TodoModel.Purge
+func (this *ListingSqlModel) Purge(userID string) error {
+	_, err := this.db.Exec("DELETE FROM listing WHERE user_id=?", userID)
+	return err
+}
+
+type ListingMemoryModel struct {
+	mutex    sync.Mutex
+	listings map[string][]int
+}
+
+func NewListingMemoryModel() *ListingMemoryModel {
+	return &ListingMemoryModel{
+		listings: map[string][]int{},
+	}
+}
+
+func (this *ListingMemoryModel) Listing(userID string) ([]int, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return append([]int{}, this.listings[userID]...), nil
+}
+
+func (this *ListingMemoryModel) SaveListing(userID string, todoIDs []int) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.listings[userID] = append([]int{}, todoIDs...)
+	return nil
+}
+
+func (this *ListingMemoryModel) Purge(userID string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.listings, userID)
+	return nil
+}
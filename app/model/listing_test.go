@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestListingSqlModelListing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT todo_id FROM listing WHERE user_id=\\? ORDER BY position").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"todo_id"}).AddRow(3).AddRow(1))
+	model := ListingSqlModel{
+		db: db,
+	}
+	todoIDs, err := model.Listing("dummy user")
+	if err != nil || fmt.Sprint(todoIDs) != "[3 1]" {
+		t.Errorf("Result ListingSqlModel.Listing(%q) == %v, %v, want %v", "dummy user", todoIDs, err, []int{3, 1})
+	}
+}
+
+func TestListingSqlModelSaveListing(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	// Replaced
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM listing WHERE user_id=?").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO listing").WithArgs("dummy user", 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO listing").WithArgs("dummy user", 2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	model := ListingSqlModel{
+		db: db,
+	}
+	if err := model.SaveListing("dummy user", []int{3, 1}); err != nil {
+		t.Errorf("Result ListingSqlModel.SaveListing() == %v, want %v", err, nil)
+	}
+	// Rolled back
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM listing WHERE user_id=?").WithArgs("dummy user").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO listing").WithArgs("dummy user", 1, 3).WillReturnError(wantErr)
+	mock.ExpectRollback()
+	model = ListingSqlModel{
+		db: db,
+	}
+	if err := model.SaveListing("dummy user", []int{3, 1}); err != wantErr {
+		t.Errorf("Result ListingSqlModel.SaveListing() == %v, want %v", err, wantErr)
+	}
+}
@@ -0,0 +1,673 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMigrationLocked is returned when another instance kept the schema locked for migrationLockSeconds
+var ErrMigrationLocked = errors.New("Another instance is migrating the schema")
+
+const migrationLockSeconds = 60
+
+// Migration holds the statements of one schema version for each SQL dialect,
+// MySQL commits each DDL statement on its own so a version has one MySQL statement
+// and a failure leaves no half-applied version
+type Migration struct {
+	Version int
+	Up      map[string][]string
+	Down    map[string][]string
+}
+
+// Migrations must be kept in ascending version order, never edit a released one
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS todo (
+				id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(255) NOT NULL,
+				task TEXT NOT NULL,
+				done BOOL NOT NULL DEFAULT FALSE,
+				pin BOOL NOT NULL DEFAULT FALSE,
+				due DATETIME NOT NULL
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS todo (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id VARCHAR(255) NOT NULL,
+				task TEXT NOT NULL,
+				done BOOL NOT NULL DEFAULT 0,
+				pin BOOL NOT NULL DEFAULT 0,
+				due DATETIME NOT NULL
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE todo`},
+			"sqlite3": {`DROP TABLE todo`},
+		},
+	},
+	{
+		Version: 2,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE todo ADD COLUMN repeat_rule VARCHAR(255) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE todo ADD COLUMN repeat_rule VARCHAR(255) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE todo DROP COLUMN repeat_rule`},
+			"sqlite3": {`ALTER TABLE todo DROP COLUMN repeat_rule`},
+		},
+	},
+	{
+		Version: 3,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS step (
+				id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+				todo_id INT UNSIGNED NOT NULL,
+				task TEXT NOT NULL,
+				done BOOL NOT NULL DEFAULT FALSE,
+				position INT NOT NULL DEFAULT 0,
+				INDEX (todo_id)
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS step (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				todo_id INTEGER NOT NULL,
+				task TEXT NOT NULL,
+				done BOOL NOT NULL DEFAULT 0,
+				position INT NOT NULL DEFAULT 0
+			)`,
+				`CREATE INDEX IF NOT EXISTS step_todo_id ON step ( todo_id )`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE step`},
+			"sqlite3": {`DROP TABLE step`},
+		},
+	},
+	{
+		Version: 4,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS todo_tag (
+				todo_id INT UNSIGNED NOT NULL,
+				tag VARCHAR(255) NOT NULL,
+				PRIMARY KEY (todo_id, tag)
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS todo_tag (
+				todo_id INTEGER NOT NULL,
+				tag VARCHAR(255) NOT NULL,
+				PRIMARY KEY (todo_id, tag)
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE todo_tag`},
+			"sqlite3": {`DROP TABLE todo_tag`},
+		},
+	},
+	{
+		Version: 5,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS todo_list (
+				id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				INDEX (user_id)
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS todo_list (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL
+			)`,
+				`CREATE INDEX IF NOT EXISTS todo_list_user_id ON todo_list ( user_id )`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE todo_list`},
+			"sqlite3": {`DROP TABLE todo_list`},
+		},
+	},
+	{
+		Version: 6,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE todo ADD COLUMN list_id INT UNSIGNED NOT NULL DEFAULT 0`},
+			"sqlite3": {`ALTER TABLE todo ADD COLUMN list_id INTEGER NOT NULL DEFAULT 0`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE todo DROP COLUMN list_id`},
+			"sqlite3": {`ALTER TABLE todo DROP COLUMN list_id`},
+		},
+	},
+	{
+		Version: 7,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS list_member (
+				list_id INT UNSIGNED NOT NULL,
+				user_id VARCHAR(255) NOT NULL,
+				PRIMARY KEY (list_id, user_id),
+				INDEX (user_id)
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS list_member (
+				list_id INTEGER NOT NULL,
+				user_id VARCHAR(255) NOT NULL,
+				PRIMARY KEY (list_id, user_id)
+			)`,
+				`CREATE INDEX IF NOT EXISTS list_member_user_id ON list_member ( user_id )`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE list_member`},
+			"sqlite3": {`DROP TABLE list_member`},
+		},
+	},
+	{
+		Version: 8,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE todo_list ADD COLUMN invite_code VARCHAR(255) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE todo_list ADD COLUMN invite_code VARCHAR(255) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE todo_list DROP COLUMN invite_code`},
+			"sqlite3": {`ALTER TABLE todo_list DROP COLUMN invite_code`},
+		},
+	},
+	{
+		Version: 9,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS user_setting (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				language VARCHAR(16) NOT NULL DEFAULT ''
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS user_setting (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				language VARCHAR(16) NOT NULL DEFAULT ''
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE user_setting`},
+			"sqlite3": {`DROP TABLE user_setting`},
+		},
+	},
+	{
+		Version: 10,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN time_zone`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN time_zone`},
+		},
+	},
+	{
+		Version: 11,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS listing (
+				user_id VARCHAR(255) NOT NULL,
+				position INT UNSIGNED NOT NULL,
+				todo_id INT UNSIGNED NOT NULL,
+				PRIMARY KEY (user_id, position)
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS listing (
+				user_id VARCHAR(255) NOT NULL,
+				position INTEGER NOT NULL,
+				todo_id INTEGER NOT NULL,
+				PRIMARY KEY (user_id, position)
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE listing`},
+			"sqlite3": {`DROP TABLE listing`},
+		},
+	},
+	{
+		Version: 12,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS draft (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				text TEXT NOT NULL,
+				created DATETIME NOT NULL
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS draft (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				text TEXT NOT NULL,
+				created DATETIME NOT NULL
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE draft`},
+			"sqlite3": {`DROP TABLE draft`},
+		},
+	},
+	{
+		Version: 13,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS dialog (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				state VARCHAR(16) NOT NULL,
+				text TEXT NOT NULL,
+				due DATETIME NULL,
+				updated DATETIME NOT NULL
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS dialog (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				state VARCHAR(16) NOT NULL,
+				text TEXT NOT NULL,
+				due DATETIME NULL,
+				updated DATETIME NOT NULL
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE dialog`},
+			"sqlite3": {`DROP TABLE dialog`},
+		},
+	},
+	{
+		Version: 14,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS inactive_user (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				since DATETIME NOT NULL
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS inactive_user (
+				user_id VARCHAR(255) NOT NULL PRIMARY KEY,
+				since DATETIME NOT NULL
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE inactive_user`},
+			"sqlite3": {`DROP TABLE inactive_user`},
+		},
+	},
+	{
+		Version: 15,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS job_run (
+				name VARCHAR(64) NOT NULL PRIMARY KEY,
+				last_run DATETIME NOT NULL
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS job_run (
+				name VARCHAR(64) NOT NULL PRIMARY KEY,
+				last_run DATETIME NOT NULL
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE job_run`},
+			"sqlite3": {`DROP TABLE job_run`},
+		},
+	},
+	{
+		Version: 16,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS alert_lead (
+				user_id VARCHAR(255) NOT NULL,
+				todo_id INT UNSIGNED NOT NULL,
+				leads VARCHAR(255) NOT NULL,
+				PRIMARY KEY (user_id, todo_id)
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS alert_lead (
+				user_id VARCHAR(255) NOT NULL,
+				todo_id INTEGER NOT NULL,
+				leads VARCHAR(255) NOT NULL,
+				PRIMARY KEY (user_id, todo_id)
+			)`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE alert_lead`},
+			"sqlite3": {`DROP TABLE alert_lead`},
+		},
+	},
+	{
+		Version: 17,
+		Up: map[string][]string{
+			"mysql": {`
+			CREATE TABLE IF NOT EXISTS alert (
+				id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+				user_id VARCHAR(255) NOT NULL,
+				todo_id INT UNSIGNED NOT NULL,
+				due DATETIME NOT NULL,
+				lead_minutes INT NOT NULL,
+				alert_at DATETIME NOT NULL,
+				sent DATETIME NULL,
+				UNIQUE (user_id, todo_id, due, lead_minutes),
+				INDEX (alert_at)
+			) CHARACTER SET utf8 COLLATE utf8_general_ci`,
+			},
+			"sqlite3": {`
+			CREATE TABLE IF NOT EXISTS alert (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id VARCHAR(255) NOT NULL,
+				todo_id INTEGER NOT NULL,
+				due DATETIME NOT NULL,
+				lead_minutes INT NOT NULL,
+				alert_at DATETIME NOT NULL,
+				sent DATETIME NULL,
+				UNIQUE (user_id, todo_id, due, lead_minutes)
+			)`,
+				`CREATE INDEX IF NOT EXISTS alert_alert_at ON alert ( alert_at )`,
+			},
+		},
+		Down: map[string][]string{
+			"mysql":   {`DROP TABLE alert`},
+			"sqlite3": {`DROP TABLE alert`},
+		},
+	},
+	{
+		Version: 18,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN remind_times VARCHAR(255) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN remind_times VARCHAR(255) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN remind_times`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN remind_times`},
+		},
+	},
+	{
+		Version: 19,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN remind_days VARCHAR(16) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN remind_days VARCHAR(16) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN remind_days`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN remind_days`},
+		},
+	},
+	{
+		Version: 20,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN quiet_start VARCHAR(5) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN quiet_start VARCHAR(5) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN quiet_start`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN quiet_start`},
+		},
+	},
+	{
+		Version: 21,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN quiet_end VARCHAR(5) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN quiet_end VARCHAR(5) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN quiet_end`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN quiet_end`},
+		},
+	},
+	{
+		Version: 22,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN hide_completed BOOL NOT NULL DEFAULT FALSE`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN hide_completed BOOL NOT NULL DEFAULT 0`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN hide_completed`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN hide_completed`},
+		},
+	},
+	{
+		Version: 23,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN only_overdue BOOL NOT NULL DEFAULT FALSE`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN only_overdue BOOL NOT NULL DEFAULT 0`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN only_overdue`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN only_overdue`},
+		},
+	},
+	{
+		Version: 24,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN overdue_intervals VARCHAR(255) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN overdue_intervals VARCHAR(255) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN overdue_intervals`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN overdue_intervals`},
+		},
+	},
+	{
+		Version: 25,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN buddy VARCHAR(255) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN buddy VARCHAR(255) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN buddy`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN buddy`},
+		},
+	},
+	{
+		Version: 26,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN buddy_days INT NOT NULL DEFAULT 0`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN buddy_days INT NOT NULL DEFAULT 0`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN buddy_days`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN buddy_days`},
+		},
+	},
+	{
+		Version: 27,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE todo ADD COLUMN snoozed DATETIME NULL`},
+			"sqlite3": {`ALTER TABLE todo ADD COLUMN snoozed DATETIME NULL`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE todo DROP COLUMN snoozed`},
+			"sqlite3": {`ALTER TABLE todo DROP COLUMN snoozed`},
+		},
+	},
+	{
+		Version: 28,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE alert ADD COLUMN claimed DATETIME NULL`},
+			"sqlite3": {`ALTER TABLE alert ADD COLUMN claimed DATETIME NULL`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE alert DROP COLUMN claimed`},
+			"sqlite3": {`ALTER TABLE alert DROP COLUMN claimed`},
+		},
+	},
+	{
+		Version: 29,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE alert ADD COLUMN attempts INT NOT NULL DEFAULT 0`},
+			"sqlite3": {`ALTER TABLE alert ADD COLUMN attempts INT NOT NULL DEFAULT 0`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE alert DROP COLUMN attempts`},
+			"sqlite3": {`ALTER TABLE alert DROP COLUMN attempts`},
+		},
+	},
+	{
+		Version: 30,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE dialog ADD COLUMN repeat_rule VARCHAR(255) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE dialog ADD COLUMN repeat_rule VARCHAR(255) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE dialog DROP COLUMN repeat_rule`},
+			"sqlite3": {`ALTER TABLE dialog DROP COLUMN repeat_rule`},
+		},
+	},
+	{
+		Version: 31,
+		Up: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting ADD COLUMN buddy_code VARCHAR(255) NOT NULL DEFAULT ''`},
+			"sqlite3": {`ALTER TABLE user_setting ADD COLUMN buddy_code VARCHAR(255) NOT NULL DEFAULT ''`},
+		},
+		Down: map[string][]string{
+			"mysql":   {`ALTER TABLE user_setting DROP COLUMN buddy_code`},
+			"sqlite3": {`ALTER TABLE user_setting DROP COLUMN buddy_code`},
+		},
+	},
+}
+
+type Migrator interface {
+	Migrate() error
+	MigrateTo(version int) error
+	SchemaVersion() (int, error)
+}
+
+func LatestSchemaVersion() int {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
+
+func (this *TodoSqlModel) CreateSchemaVersionTable() error {
+	sql := `CREATE TABLE IF NOT EXISTS schema_version (
+		version INT NOT NULL PRIMARY KEY,
+		applied_at DATETIME NOT NULL
+	)`
+	_, err := this.db.Exec(sql)
+	return err
+}
+
+func (this *TodoSqlModel) SchemaVersion() (int, error) {
+	if err := this.CreateSchemaVersionTable(); err != nil {
+		return 0, err
+	}
+	var version int
+	err := this.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func (this *TodoSqlModel) Migrate() error {
+	return this.MigrateTo(LatestSchemaVersion())
+}
+
+func (this *TodoSqlModel) MigrateTo(version int) error {
+	if version < 0 || version > LatestSchemaVersion() {
+		return fmt.Errorf("Unknown schema version %d", version)
+	}
+	unlock, err := this.lockSchema()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	current, err := this.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	for _, migration := range Migrations {
+		if migration.Version > current && migration.Version <= version {
+			if err := this.apply(migration.Version, migration.Up[this.dialect], true); err != nil {
+				return err
+			}
+		}
+	}
+	for i := len(Migrations) - 1; i >= 0; i-- {
+		migration := Migrations[i]
+		if migration.Version <= current && migration.Version > version {
+			if err := this.apply(migration.Version, migration.Down[this.dialect], false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lockSchema keeps instances starting together from applying the same version twice,
+// SQLite needs none as a version is one transaction there and a second one fails on its schema_version row
+func (this *TodoSqlModel) lockSchema() (func(), error) {
+	if this.dialect != "mysql" {
+		return func() {}, nil
+	}
+	// GET_LOCK belongs to the connection, it must be released on the same one
+	ctx := context.Background()
+	conn, err := this.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK('schema_version', ?)", migrationLockSeconds).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		conn.Close()
+		return nil, ErrMigrationLocked
+	}
+	return func() {
+		conn.ExecContext(ctx, "DO RELEASE_LOCK('schema_version')")
+		conn.Close()
+	}, nil
+}
+
+func (this *TodoSqlModel) apply(version int, statements []string, up bool) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Migration %d: %v", version, err)
+		}
+	}
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_version ( version, applied_at ) VALUES( ?, ? )", version, time.Now().UTC())
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version=?", version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTodoSqlModelMigrate(t *testing.T) {
+	model := NewTodoSqliteModel(":memory:")
+	version, err := model.SchemaVersion()
+	if err != nil || version != 0 {
+		t.Errorf("Result TodoSqlModel.SchemaVersion() == %d, %v, want %d, %v", version, err, 0, nil)
+	}
+
+	// Up to latest
+	if err := model.Migrate(); err != nil {
+		t.Fatalf("Result TodoSqlModel.Migrate() == %v, want %v", err, nil)
+	}
+	version, err = model.SchemaVersion()
+	if err != nil || version != LatestSchemaVersion() {
+		t.Errorf("Result TodoSqlModel.SchemaVersion() == %d, %v, want %d, %v", version, err, LatestSchemaVersion(), nil)
+	}
+	if err := model.Create(Todo{UserID: "dummy user", Task: "dummy task", Due: time.Now()}); err != nil {
+		t.Errorf("Result TodoSqlModel.Create() == %v, want %v", err, nil)
+	}
+
+	// Running again does nothing
+	if err := model.Migrate(); err != nil {
+		t.Errorf("Result TodoSqlModel.Migrate() == %v, want %v", err, nil)
+	}
+
+	// Down to nothing
+	if err := model.MigrateTo(0); err != nil {
+		t.Fatalf("Result TodoSqlModel.MigrateTo(%d) == %v, want %v", 0, err, nil)
+	}
+	version, err = model.SchemaVersion()
+	if err != nil || version != 0 {
+		t.Errorf("Result TodoSqlModel.SchemaVersion() == %d, %v, want %d, %v", version, err, 0, nil)
+	}
+	if _, err := model.List("dummy user"); err == nil {
+		t.Errorf("Result TodoSqlModel.List() == %v, want no such table", err)
+	}
+
+	// Unknown version
+	if err := model.MigrateTo(LatestSchemaVersion() + 1); err == nil {
+		t.Errorf("Result TodoSqlModel.MigrateTo(%d) == %v, want error", LatestSchemaVersion()+1, err)
+	}
+}
+
+func TestTodoSqlModelMigrateExistingTable(t *testing.T) {
+	model := NewTodoSqliteModel(":memory:")
+	// Deployments before migrations created the table on first use
+	_, err := model.db.Exec(`
+		CREATE TABLE todo (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id VARCHAR(255) NOT NULL,
+			task TEXT NOT NULL,
+			done BOOL NOT NULL DEFAULT 0,
+			pin BOOL NOT NULL DEFAULT 0,
+			due DATETIME NOT NULL
+		)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := model.db.Exec("INSERT INTO todo ( user_id, task, due ) VALUES( ?, ?, ? )", "dummy user", "dummy task", time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.Migrate(); err != nil {
+		t.Fatalf("Result TodoSqlModel.Migrate() == %v, want %v", err, nil)
+	}
+	todos, err := model.List("dummy user")
+	if err != nil || len(todos) != 1 {
+		t.Errorf("Result TodoSqlModel.List() == %v, %v, want %d todo", todos, err, 1)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	for i, migration := range Migrations {
+		if i > 0 && migration.Version <= Migrations[i-1].Version {
+			t.Errorf("Migrations[%d].Version == %d, want greater than %d", i, migration.Version, Migrations[i-1].Version)
+		}
+		for _, dialect := range []string{"mysql", "sqlite3"} {
+			if len(migration.Up[dialect]) == 0 || len(migration.Down[dialect]) == 0 {
+				t.Errorf("Migrations[%d] has no %s statements", i, dialect)
+			}
+		}
+		if len(migration.Up["mysql"]) != 1 || len(migration.Down["mysql"]) != 1 {
+			t.Errorf("Migrations[%d] has %d and %d mysql statements, want one each way", i, len(migration.Up["mysql"]), len(migration.Down["mysql"]))
+		}
+	}
+}
+
+func TestTodoSqlModelMigrateLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	mock.ExpectQuery("SELECT GET_LOCK\\('schema_version', \\?\\)").WithArgs(migrationLockSeconds).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+	if err := model.Migrate(); err != ErrMigrationLocked {
+		t.Errorf("Result TodoSqlModel.Migrate() while locked == %v, want %v", err, ErrMigrationLocked)
+	}
+
+	mock.ExpectQuery("SELECT GET_LOCK\\('schema_version', \\?\\)").WithArgs(migrationLockSeconds).WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_version").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM schema_version").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(LatestSchemaVersion()))
+	mock.ExpectExec("DO RELEASE_LOCK\\('schema_version'\\)").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := model.Migrate(); err != nil {
+		t.Errorf("Result TodoSqlModel.Migrate() == %v, want %v", err, nil)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
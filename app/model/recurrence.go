@@ -0,0 +1,180 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrRecurrence = errors.New("Wrong repeat rule")
+
+var rruleDays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Recurrence is the supported subset of RFC 5545 RRULE: FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, BYDAY and BYMONTHDAY
+type Recurrence struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+}
+
+func ParseRecurrence(rule string) (Recurrence, error) {
+	recurrence := Recurrence{
+		Interval: 1,
+	}
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	for _, part := range strings.Split(rule, ";") {
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			return Recurrence{}, ErrRecurrence
+		}
+		switch pair[0] {
+		case "FREQ":
+			recurrence.Freq = pair[1]
+		case "INTERVAL":
+			interval, err := strconv.Atoi(pair[1])
+			if err != nil || interval < 1 {
+				return Recurrence{}, ErrRecurrence
+			}
+			recurrence.Interval = interval
+		case "BYDAY":
+			for _, day := range strings.Split(pair[1], ",") {
+				found := false
+				for weekday, name := range rruleDays {
+					if day == name {
+						recurrence.ByDay = append(recurrence.ByDay, time.Weekday(weekday))
+						found = true
+					}
+				}
+				if !found {
+					return Recurrence{}, ErrRecurrence
+				}
+			}
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(pair[1])
+			if err != nil || day < 1 || day > 31 {
+				return Recurrence{}, ErrRecurrence
+			}
+			recurrence.ByMonthDay = day
+		default:
+			return Recurrence{}, ErrRecurrence
+		}
+	}
+	switch recurrence.Freq {
+	case "DAILY":
+		if len(recurrence.ByDay) > 0 || recurrence.ByMonthDay > 0 {
+			return Recurrence{}, ErrRecurrence
+		}
+	case "WEEKLY":
+		if recurrence.ByMonthDay > 0 {
+			return Recurrence{}, ErrRecurrence
+		}
+	case "MONTHLY":
+		if len(recurrence.ByDay) > 0 {
+			return Recurrence{}, ErrRecurrence
+		}
+	default:
+		return Recurrence{}, ErrRecurrence
+	}
+	return recurrence, nil
+}
+
+func (this Recurrence) String() string {
+	rule := "FREQ=" + this.Freq
+	if this.Interval > 1 {
+		rule += fmt.Sprintf(";INTERVAL=%d", this.Interval)
+	}
+	if len(this.ByDay) > 0 {
+		days := []string{}
+		for _, day := range this.ByDay {
+			days = append(days, rruleDays[day])
+		}
+		rule += ";BYDAY=" + strings.Join(days, ",")
+	}
+	if this.ByMonthDay > 0 {
+		rule += fmt.Sprintf(";BYMONTHDAY=%d", this.ByMonthDay)
+	}
+	return rule
+}
+
+func (this Recurrence) hasDay(weekday time.Weekday) bool {
+	for _, day := range this.ByDay {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDay returns the date of the day in the month, short months use their last day
+func monthDay(year int, month time.Month, day int, clock time.Time) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, clock.Location()).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), 0, clock.Location())
+}
+
+func (this Recurrence) matches(date time.Time) bool {
+	switch this.Freq {
+	case "WEEKLY":
+		return len(this.ByDay) == 0 || this.hasDay(date.Weekday())
+	case "MONTHLY":
+		return this.ByMonthDay == 0 || monthDay(date.Year(), date.Month(), this.ByMonthDay, date).Day() == date.Day()
+	}
+	return true
+}
+
+// Next returns the occurrence following due at the same time of day
+func (this Recurrence) Next(due time.Time) time.Time {
+	interval := this.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	switch this.Freq {
+	case "WEEKLY":
+		if len(this.ByDay) == 0 {
+			return due.AddDate(0, 0, 7*interval)
+		}
+		for i := 1; i <= 7; i++ {
+			next := due.AddDate(0, 0, i)
+			if this.hasDay(next.Weekday()) {
+				// Weeks start on Monday, skip the weeks in between when the week changes
+				if (int(due.Weekday())+6)%7+i >= 7 {
+					next = next.AddDate(0, 0, 7*(interval-1))
+				}
+				return next
+			}
+		}
+	case "MONTHLY":
+		day := this.ByMonthDay
+		if day == 0 {
+			day = due.Day()
+		} else if due.Day() < monthDay(due.Year(), due.Month(), day, due).Day() {
+			return monthDay(due.Year(), due.Month(), day, due)
+		}
+		first := time.Date(due.Year(), due.Month()+time.Month(interval), 1, 0, 0, 0, 0, due.Location())
+		return monthDay(first.Year(), first.Month(), day, due)
+	}
+	return due.AddDate(0, 0, interval)
+}
+
+// NextAfter returns the first occurrence following due which is after now, so late completions do not spawn overdue tasks
+func (this Recurrence) NextAfter(due time.Time, now time.Time) time.Time {
+	next := this.Next(due)
+	for !next.After(now) {
+		next = this.Next(next)
+	}
+	return next
+}
+
+// First returns the first occurrence at the time of day of clock, starting from the day of clock
+func (this Recurrence) First(clock time.Time, now time.Time) time.Time {
+	if this.matches(clock) && !clock.Before(now) {
+		return clock
+	}
+	this.Interval = 1
+	return this.NextAfter(clock, now)
+}
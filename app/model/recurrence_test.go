@@ -0,0 +1,107 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr error
+	}{
+		{in: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{in: "RRULE:FREQ=DAILY;INTERVAL=3", want: "FREQ=DAILY;INTERVAL=3"},
+		{in: "freq=weekly;byday=mo,tu,we,th,fr", want: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=1", want: "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{in: "FREQ=MONTHLY;INTERVAL=1", want: "FREQ=MONTHLY"},
+		{in: "", wantErr: ErrRecurrence},
+		{in: "FREQ=YEARLY", wantErr: ErrRecurrence},
+		{in: "FREQ=DAILY;INTERVAL=0", wantErr: ErrRecurrence},
+		{in: "FREQ=WEEKLY;BYDAY=XX", wantErr: ErrRecurrence},
+		{in: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: ErrRecurrence},
+		{in: "FREQ=DAILY;BYDAY=MO", wantErr: ErrRecurrence},
+		{in: "FREQ=DAILY;COUNT=3", wantErr: ErrRecurrence},
+	}
+	for _, c := range cases {
+		got, err := ParseRecurrence(c.in)
+		if err != c.wantErr || (err == nil && got.String() != c.want) {
+			t.Errorf("ParseRecurrence(%q) == %q, %v, want %q, %v", c.in, got.String(), err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	layout := "2006-01-02 15:04"
+	cases := []struct {
+		rule string
+		in   string
+		want string
+	}{
+		{rule: "FREQ=DAILY", in: "2018-11-15 09:00", want: "2018-11-16 09:00"},
+		{rule: "FREQ=DAILY;INTERVAL=3", in: "2018-11-15 09:00", want: "2018-11-18 09:00"},
+		{rule: "FREQ=WEEKLY", in: "2018-11-15 09:00", want: "2018-11-22 09:00"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2", in: "2018-11-15 09:00", want: "2018-11-29 09:00"},
+		// Thursday to Friday, then Friday to Monday
+		{rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", in: "2018-11-15 09:00", want: "2018-11-16 09:00"},
+		{rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", in: "2018-11-16 09:00", want: "2018-11-19 09:00"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", in: "2018-11-12 09:00", want: "2018-11-15 09:00"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", in: "2018-11-15 09:00", want: "2018-11-26 09:00"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=1", in: "2018-11-01 09:00", want: "2018-12-01 09:00"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=20", in: "2018-11-15 09:00", want: "2018-11-20 09:00"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31", in: "2018-10-31 09:00", want: "2018-11-30 09:00"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31", in: "2018-11-30 09:00", want: "2018-12-31 09:00"},
+		{rule: "FREQ=MONTHLY", in: "2018-11-15 09:00", want: "2018-12-15 09:00"},
+		{rule: "FREQ=MONTHLY;INTERVAL=3", in: "2018-11-15 09:00", want: "2019-02-15 09:00"},
+	}
+	for _, c := range cases {
+		recurrence, _ := ParseRecurrence(c.rule)
+		in, _ := time.ParseInLocation(layout, c.in, loc)
+		got := recurrence.Next(in).Format(layout)
+		if got != c.want {
+			t.Errorf("Recurrence(%q).Next(%q) == %q, want %q", c.rule, c.in, got, c.want)
+		}
+	}
+}
+
+func TestRecurrenceNextAfter(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	layout := "2006-01-02 15:04"
+	recurrence, _ := ParseRecurrence("FREQ=DAILY")
+	due, _ := time.ParseInLocation(layout, "2018-11-10 09:00", loc)
+	now, _ := time.ParseInLocation(layout, "2018-11-15 10:00", loc)
+	if got := recurrence.NextAfter(due, now).Format(layout); got != "2018-11-16 09:00" {
+		t.Errorf("Recurrence.NextAfter() == %q, want %q", got, "2018-11-16 09:00")
+	}
+}
+
+func TestRecurrenceFirst(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	layout := "2006-01-02 15:04"
+	// Thursday
+	now, _ := time.ParseInLocation(layout, "2018-11-15 10:00", loc)
+	cases := []struct {
+		rule  string
+		clock string
+		want  string
+	}{
+		{rule: "FREQ=DAILY", clock: "2018-11-15 12:00", want: "2018-11-15 12:00"},
+		{rule: "FREQ=DAILY;INTERVAL=3", clock: "2018-11-15 09:00", want: "2018-11-16 09:00"},
+		{rule: "FREQ=WEEKLY;BYDAY=TH", clock: "2018-11-15 12:00", want: "2018-11-15 12:00"},
+		{rule: "FREQ=WEEKLY;BYDAY=MO", clock: "2018-11-15 12:00", want: "2018-11-19 12:00"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", clock: "2018-11-15 12:00", want: "2018-11-19 12:00"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=1", clock: "2018-11-15 09:00", want: "2018-12-01 09:00"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=15", clock: "2018-11-15 09:00", want: "2018-12-15 09:00"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=15", clock: "2018-11-15 11:00", want: "2018-11-15 11:00"},
+	}
+	for _, c := range cases {
+		recurrence, _ := ParseRecurrence(c.rule)
+		clock, _ := time.ParseInLocation(layout, c.clock, loc)
+		got := recurrence.First(clock, now).Format(layout)
+		if got != c.want {
+			t.Errorf("Recurrence(%q).First(%q) == %q, want %q", c.rule, c.clock, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTimeZone = errors.New("Unknown time zone")
+	ErrReminder = errors.New("Wrong reminder setting")
+)
+
+// DefaultTimeZone is the time zone of the users who have not set one
+const DefaultTimeZone = "Asia/Bangkok"
+
+// Setting is the preferences of a user, a group or a room, the zero values are the defaults
+type Setting struct {
+	UserID   string
+	Language string
+	// TimeZone is an IANA name like Europe/Berlin
+	TimeZone string
+	// RemindTimes are the clocks of the digest like "08:00,18:30", REMIND_SCHEDULE when empty
+	RemindTimes string
+	// RemindDays are the weekdays of the digest from 0 for Sunday like "1,2,3,4,5", every day when empty
+	RemindDays string
+	// QuietStart and QuietEnd like "22:00" and "07:00" defer the digest and the alerts in between until QuietEnd, none when empty
+	QuietStart string
+	QuietEnd   string
+	// HideCompleted leaves the completed todos out of the digest
+	HideCompleted bool
+	// OnlyOverdue skips the digest when no todo is overdue
+	OnlyOverdue bool
+	// OverdueIntervals are the minutes after the due date to notify an overdue todo again like "60,1440", the default ones when empty, none when "off"
+	OverdueIntervals string
+	// Buddy is the user or the group told when a pinned todo is overdue for BuddyDays days, none when empty
+	Buddy     string
+	BuddyDays int
+	// BuddyCode is the random code the buddy sends to join, it is created on first use, see NewInviteCode
+	BuddyCode string
+}
+
+// Location is the time zone of the user to show and parse due dates, they are stored in UTC
+func (this Setting) Location() *time.Location {
+	name := this.TimeZone
+	if name == "" {
+		name = DefaultTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// CheckTimeZone accepts the IANA names and "" for the default
+func CheckTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	// LoadLocation also takes "Local" and file paths
+	if name == "Local" || name[0] == '/' || name[0] == '.' {
+		return ErrTimeZone
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return ErrTimeZone
+	}
+	return nil
+}
+
+// MaxBuddyDays is a month, the buddy of a todo overdue for longer would not help
+const MaxBuddyDays = 30
+
+// Intervals are the durations of OverdueIntervals, nil when they are the default ones
+func (this Setting) Intervals() []time.Duration {
+	if this.OverdueIntervals == "" {
+		return nil
+	}
+	intervals := []time.Duration{}
+	for _, interval := range strings.Split(this.OverdueIntervals, ",") {
+		if minutes, err := strconv.Atoi(interval); err == nil && minutes > 0 {
+			intervals = append(intervals, time.Duration(minutes)*time.Minute)
+		}
+	}
+	return intervals
+}
+
+// ParseClock is the minutes of the day of "18:30"
+func ParseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil || len(clock) != 5 {
+		return 0, ErrReminder
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Clocks are the minutes of the day of RemindTimes
+func (this Setting) Clocks() []int {
+	clocks := []int{}
+	for _, clock := range strings.Split(this.RemindTimes, ",") {
+		if minutes, err := ParseClock(clock); err == nil {
+			clocks = append(clocks, minutes)
+		}
+	}
+	return clocks
+}
+
+// Weekdays are the days of RemindDays, every day when it is empty
+func (this Setting) Weekdays() map[time.Weekday]bool {
+	weekdays := map[time.Weekday]bool{}
+	for _, day := range strings.Split(this.RemindDays, ",") {
+		if weekday, err := strconv.Atoi(day); err == nil && weekday >= 0 && weekday <= 6 {
+			weekdays[time.Weekday(weekday)] = true
+		}
+	}
+	if len(weekdays) == 0 {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			weekdays[weekday] = true
+		}
+	}
+	return weekdays
+}
+
+// CheckReminder accepts "" or "HH:MM" lists for the times, 0 to 6 lists for the days and both or none of the quiet hours
+func CheckReminder(setting Setting) error {
+	if setting.RemindTimes != "" {
+		for _, clock := range strings.Split(setting.RemindTimes, ",") {
+			if _, err := ParseClock(clock); err != nil {
+				return ErrReminder
+			}
+		}
+	}
+	if setting.RemindDays != "" {
+		for _, day := range strings.Split(setting.RemindDays, ",") {
+			if weekday, err := strconv.Atoi(day); err != nil || weekday < 0 || weekday > 6 || len(day) != 1 {
+				return ErrReminder
+			}
+		}
+	}
+	if setting.OverdueIntervals != "" && setting.OverdueIntervals != "off" {
+		for _, interval := range strings.Split(setting.OverdueIntervals, ",") {
+			if minutes, err := strconv.Atoi(interval); err != nil || minutes < 1 {
+				return ErrReminder
+			}
+		}
+	}
+	if setting.BuddyDays < 0 || setting.BuddyDays > MaxBuddyDays {
+		return ErrReminder
+	}
+	if setting.QuietStart == "" && setting.QuietEnd == "" {
+		return nil
+	}
+	start, err := ParseClock(setting.QuietStart)
+	if err != nil {
+		return ErrReminder
+	}
+	end, err := ParseClock(setting.QuietEnd)
+	if err != nil || start == end {
+		return ErrReminder
+	}
+	return nil
+}
+
+// Setting returns the defaults when the user has saved nothing
+func (this *TodoSqlModel) Setting(userID string) (Setting, error) {
+	setting := Setting{
+		UserID: userID,
+	}
+	rows, err := this.db.Query("SELECT language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id=?", userID)
+	if err != nil {
+		return Setting{}, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&setting.Language, &setting.TimeZone, &setting.RemindTimes, &setting.RemindDays, &setting.QuietStart, &setting.QuietEnd, &setting.HideCompleted, &setting.OnlyOverdue, &setting.OverdueIntervals, &setting.Buddy, &setting.BuddyDays, &setting.BuddyCode); err != nil {
+			return Setting{}, err
+		}
+	}
+	return setting, rows.Err()
+}
+
+// Settings are the settings of several users at once by user, with the defaults for the ones who have saved nothing
+func (this *TodoSqlModel) Settings(userIDs []string) (map[string]Setting, error) {
+	settings := map[string]Setting{}
+	if len(userIDs) == 0 {
+		return settings, nil
+	}
+	args := []interface{}{}
+	for _, userID := range userIDs {
+		settings[userID] = Setting{UserID: userID}
+		args = append(args, userID)
+	}
+	rows, err := this.db.Query("SELECT user_id, language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id IN ( ?"+strings.Repeat(", ?", len(userIDs)-1)+" )", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var setting Setting
+		if err := rows.Scan(&setting.UserID, &setting.Language, &setting.TimeZone, &setting.RemindTimes, &setting.RemindDays, &setting.QuietStart, &setting.QuietEnd, &setting.HideCompleted, &setting.OnlyOverdue, &setting.OverdueIntervals, &setting.Buddy, &setting.BuddyDays, &setting.BuddyCode); err != nil {
+			return nil, err
+		}
+		settings[setting.UserID] = setting
+	}
+	return settings, rows.Err()
+}
+
+// SaveSetting writes every preference of the user at once
+func (this *TodoSqlModel) SaveSetting(setting Setting) error {
+	if err := CheckTimeZone(setting.TimeZone); err != nil {
+		return err
+	}
+	if err := CheckReminder(setting); err != nil {
+		return err
+	}
+	_, err := this.db.Exec("REPLACE INTO user_setting ( user_id, language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code ) VALUES( ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )",
+		setting.UserID, setting.Language, setting.TimeZone, setting.RemindTimes, setting.RemindDays, setting.QuietStart, setting.QuietEnd, setting.HideCompleted, setting.OnlyOverdue,
+		setting.OverdueIntervals, setting.Buddy, setting.BuddyDays, setting.BuddyCode)
+	return err
+}
+
+// BuddyOwner is the user of the buddy code, ErrNotFound when nobody has it
+func (this *TodoSqlModel) BuddyOwner(code string) (string, error) {
+	code = NormalizeInviteCode(code)
+	if code == "" {
+		return "", ErrNotFound
+	}
+	var userID string
+	err := this.db.QueryRow("SELECT user_id FROM user_setting WHERE buddy_code=?", code).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return userID, err
+}
+
+// location is the time zone of the user, the default one when it cannot be read
+func (this *TodoSqlModel) location(userID string) *time.Location {
+	setting, err := this.Setting(userID)
+	if err != nil {
+		return Setting{}.Location()
+	}
+	return setting.Location()
+}
@@ -0,0 +1,217 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTodoSqlModelSetting(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	// Saved
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id=?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"language", "time_zone", "remind_times", "remind_days", "quiet_start", "quiet_end", "hide_completed", "only_overdue", "overdue_intervals", "buddy", "buddy_days", "buddy_code"}).AddRow("th", "Europe/Berlin", "08:00,18:30", "1,2,3,4,5", "22:00", "07:00", true, false, "60,1440", "C1", 3, "K3XQ7M2A"))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	setting, err := model.Setting("dummy user")
+	want := Setting{UserID: "dummy user", Language: "th", TimeZone: "Europe/Berlin", RemindTimes: "08:00,18:30", RemindDays: "1,2,3,4,5", QuietStart: "22:00", QuietEnd: "07:00", HideCompleted: true,
+		OverdueIntervals: "60,1440", Buddy: "C1", BuddyDays: 3, BuddyCode: "K3XQ7M2A"}
+	if err != nil || setting != want {
+		t.Errorf("Result TodoSqlModel.Setting(%q) == %#v, %#v, want %#v", "dummy user", setting, err, want)
+	}
+	// Defaults
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id=?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"language", "time_zone", "remind_times", "remind_days", "quiet_start", "quiet_end", "hide_completed", "only_overdue", "overdue_intervals", "buddy", "buddy_days", "buddy_code"}))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	setting, err = model.Setting("dummy user")
+	if err != nil || setting != (Setting{UserID: "dummy user"}) {
+		t.Errorf("Result TodoSqlModel.Setting(%q) == %#v, %#v, want the defaults", "dummy user", setting, err)
+	}
+	// Error when query
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id=?").WithArgs("dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	_, err = model.Setting("dummy user")
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.Setting(%q) == %#v, want %#v", "dummy user", err, wantErr)
+	}
+}
+
+func TestTodoSqlModelSaveSetting(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	setting := Setting{
+		UserID:   "dummy user",
+		Language: "th",
+		TimeZone: "Asia/Tokyo",
+	}
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("REPLACE INTO user_setting").WithArgs("dummy user", "th", "Asia/Tokyo", "", "", "", "", false, false, "", "", 0, "").WillReturnResult(sqlmock.NewResult(0, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if err := model.SaveSetting(setting); err != nil {
+		t.Errorf("Result TodoSqlModel.SaveSetting(%#v) == %#v, want %#v", setting, err, nil)
+	}
+	// Error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("REPLACE INTO user_setting").WithArgs("dummy user", "th", "Asia/Tokyo", "", "", "", "", false, false, "", "", 0, "").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if err := model.SaveSetting(setting); err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.SaveSetting(%#v) == %#v, want %#v", setting, err, wantErr)
+	}
+	// Unknown time zone
+	setting.TimeZone = "Asia/Nowhere"
+	if err := model.SaveSetting(setting); err != ErrTimeZone {
+		t.Errorf("Result TodoSqlModel.SaveSetting(%#v) == %#v, want %#v", setting, err, ErrTimeZone)
+	}
+	// Wrong quiet hours
+	setting = Setting{UserID: "dummy user", QuietStart: "22:00"}
+	if err := model.SaveSetting(setting); err != ErrReminder {
+		t.Errorf("Result TodoSqlModel.SaveSetting(%#v) == %#v, want %#v", setting, err, ErrReminder)
+	}
+}
+
+func TestCheckReminder(t *testing.T) {
+	cases := []struct {
+		in   Setting
+		want error
+	}{
+		{Setting{}, nil},
+		{Setting{RemindTimes: "08:00,18:30", RemindDays: "0,6", QuietStart: "22:00", QuietEnd: "07:00"}, nil},
+		{Setting{RemindTimes: "8:00"}, ErrReminder},
+		{Setting{RemindTimes: "08:00,"}, ErrReminder},
+		{Setting{RemindTimes: "24:00"}, ErrReminder},
+		{Setting{RemindDays: "7"}, ErrReminder},
+		{Setting{RemindDays: "mon"}, ErrReminder},
+		{Setting{RemindDays: "01"}, ErrReminder},
+		{Setting{QuietEnd: "07:00"}, ErrReminder},
+		{Setting{QuietStart: "07:00", QuietEnd: "07:00"}, ErrReminder},
+		{Setting{OverdueIntervals: "off", Buddy: "C1", BuddyDays: 30}, nil},
+		{Setting{OverdueIntervals: "60,0"}, ErrReminder},
+		{Setting{OverdueIntervals: "1h"}, ErrReminder},
+		{Setting{BuddyDays: 31}, ErrReminder},
+	}
+	for _, c := range cases {
+		if got := CheckReminder(c.in); got != c.want {
+			t.Errorf("CheckReminder(%#v) == %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestSettingClocks(t *testing.T) {
+	setting := Setting{RemindTimes: "08:00,18:30", RemindDays: "1,5"}
+	if got := fmt.Sprint(setting.Clocks()); got != "[480 1110]" {
+		t.Errorf("Setting.Clocks() == %v", got)
+	}
+	if got := setting.Weekdays(); len(got) != 2 || !got[time.Monday] || !got[time.Friday] {
+		t.Errorf("Setting.Weekdays() == %v", got)
+	}
+	if got := (Setting{}).Weekdays(); len(got) != 7 {
+		t.Errorf("Setting{}.Weekdays() == %v, want every day", got)
+	}
+	if got := (Setting{OverdueIntervals: "60,1440"}).Intervals(); fmt.Sprint(got) != "[1h0m0s 24h0m0s]" {
+		t.Errorf("Setting.Intervals() == %v", got)
+	}
+	if got := (Setting{OverdueIntervals: "off"}).Intervals(); got == nil || len(got) != 0 {
+		t.Errorf("Setting.Intervals() when off == %#v, want none", got)
+	}
+	if got := (Setting{}).Intervals(); got != nil {
+		t.Errorf("Setting{}.Intervals() == %#v, want nil for the default ones", got)
+	}
+}
+
+func TestSettingLocation(t *testing.T) {
+	cases := []struct {
+		in   Setting
+		want string
+	}{
+		{Setting{}, "Asia/Bangkok"},
+		{Setting{TimeZone: "Europe/Berlin"}, "Europe/Berlin"},
+		{Setting{TimeZone: "Asia/Nowhere"}, "UTC"},
+	}
+	for _, c := range cases {
+		if got := c.in.Location().String(); got != c.want {
+			t.Errorf("Setting(%#v).Location() == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestTodoSqlModelBuddyOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id FROM user_setting WHERE buddy_code=\\?").WithArgs("K3XQ7M2A").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}).AddRow("dummy user"))
+	mock.ExpectQuery("SELECT user_id FROM user_setting WHERE buddy_code=\\?").WithArgs("K3XQ7M2B").WillReturnError(sql.ErrNoRows)
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if owner, err := model.BuddyOwner(" k3xq7m2a"); err != nil || owner != "dummy user" {
+		t.Errorf("Result TodoSqlModel.BuddyOwner() == %q, %v, want %q", owner, err, "dummy user")
+	}
+	if _, err := model.BuddyOwner("K3XQ7M2B"); err != ErrNotFound {
+		t.Errorf("Result TodoSqlModel.BuddyOwner() of an unknown code == %v, want %v", err, ErrNotFound)
+	}
+	if _, err := model.BuddyOwner(" "); err != ErrNotFound {
+		t.Errorf("Result TodoSqlModel.BuddyOwner() of no code == %v, want %v", err, ErrNotFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTodoSqlModelSettings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id, language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id IN \\( \\?, \\? \\)").WithArgs("dummy user", "another user").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "language", "time_zone", "remind_times", "remind_days", "quiet_start", "quiet_end", "hide_completed", "only_overdue", "overdue_intervals", "buddy", "buddy_days", "buddy_code"}).AddRow("dummy user", "th", "", "", "", "", "", false, false, "", "", 0, ""))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	settings, err := model.Settings([]string{"dummy user", "another user"})
+	if err != nil || len(settings) != 2 || settings["dummy user"] != (Setting{UserID: "dummy user", Language: "th"}) || settings["another user"] != (Setting{UserID: "another user"}) {
+		t.Errorf("Result TodoSqlModel.Settings() == %#v, %v", settings, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
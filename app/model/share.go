@@ -0,0 +1,209 @@
+package model
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"sort"
+	"strings"
+)
+
+// todoAccess matches the todos of the user and the todos in the lists the user owns or joined, the user is bound 3 times
+const todoAccess = `( user_id=? OR list_id IN ( SELECT id FROM todo_list WHERE user_id=? UNION SELECT list_id FROM list_member WHERE user_id=? ) )`
+
+// NewInviteCode returns a random code to join a list, e.g. "K3XQ7M2A"
+func NewInviteCode() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return base32.StdEncoding.EncodeToString(b)
+}
+
+// NormalizeInviteCode makes codes typed by hand match, e.g. " k3xq7m2a "
+func NormalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ShareTodos adds the todos of the shared lists to the other members, members maps list IDs to the owner and members
+func ShareTodos(userTodos map[string][]Todo, members map[int][]string) map[string][]Todo {
+	shared := map[string][]Todo{}
+	for userID, todos := range userTodos {
+		shared[userID] = append(shared[userID], todos...)
+		for _, todo := range todos {
+			for _, member := range members[todo.ListID] {
+				if member != userID {
+					shared[member] = append(shared[member], todo)
+				}
+			}
+		}
+	}
+	for _, todos := range shared {
+		sort.SliceStable(todos, func(i, j int) bool {
+			a, b := todos[i], todos[j]
+			if a.Done != b.Done {
+				return !a.Done
+			}
+			if a.Pin != b.Pin {
+				return a.Pin
+			}
+			return a.Due.Before(b.Due)
+		})
+	}
+	return shared
+}
+
+// CheckListMember checks the user owns or joined the list
+func (this *TodoSqlModel) CheckListMember(userID string, id int) error {
+	err := this.CheckListOwner(userID, id)
+	if err != ErrForbidden {
+		return err
+	}
+	var count int
+	err = this.db.QueryRow("SELECT COUNT(*) FROM list_member WHERE list_id=? AND user_id=?", id, userID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// ShareList returns the invite code of the list, it is created on first share
+func (this *TodoSqlModel) ShareList(userID string, list TodoList) (string, error) {
+	if err := this.CheckListOwner(userID, list.ID); err != nil {
+		return "", err
+	}
+	var code string
+	err := this.db.QueryRow("SELECT invite_code FROM todo_list WHERE id=?", list.ID).Scan(&code)
+	if err != nil {
+		return "", err
+	}
+	if code != "" {
+		return code, nil
+	}
+	code = NewInviteCode()
+	_, err = this.db.Exec("UPDATE todo_list SET invite_code=? WHERE id=? AND user_id=?", code, list.ID, userID)
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// JoinList makes the user a member of the list having the invite code
+func (this *TodoSqlModel) JoinList(userID string, code string) (TodoList, error) {
+	code = NormalizeInviteCode(code)
+	if code == "" {
+		return TodoList{}, ErrNotFound
+	}
+	list := TodoList{}
+	err := this.db.QueryRow("SELECT id, user_id, name FROM todo_list WHERE invite_code=?", code).Scan(&list.ID, &list.UserID, &list.Name)
+	if err == sql.ErrNoRows {
+		return TodoList{}, ErrNotFound
+	}
+	if err != nil {
+		return TodoList{}, err
+	}
+	if list.UserID == userID {
+		list.InviteCode = code
+		return list, nil
+	}
+	list.Joined = true
+	var count int
+	err = this.db.QueryRow("SELECT COUNT(*) FROM list_member WHERE list_id=? AND user_id=?", list.ID, userID).Scan(&count)
+	if err != nil {
+		return TodoList{}, err
+	}
+	if count > 0 {
+		return list, nil
+	}
+	_, err = this.db.Exec("INSERT INTO list_member ( list_id, user_id ) VALUES( ?, ? )", list.ID, userID)
+	if err != nil {
+		return TodoList{}, err
+	}
+	return list, nil
+}
+
+// LeaveList stops sharing the list with the member, the owner deletes it instead.
+// The todos the member added to it go back to the default list of the member.
+func (this *TodoSqlModel) LeaveList(userID string, list TodoList) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec("DELETE FROM list_member WHERE list_id=? AND user_id=?", list.ID, userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if num != 1 {
+		tx.Rollback()
+		if err := this.CheckListOwner(userID, list.ID); err != nil {
+			return err
+		}
+		return ErrForbidden
+	}
+	if _, err := tx.Exec("UPDATE todo SET list_id=0 WHERE list_id=? AND user_id=?", list.ID, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListMembers returns the owner of the list followed by its members
+func (this *TodoSqlModel) ListMembers(id int) ([]string, error) {
+	var owner string
+	err := this.db.QueryRow("SELECT user_id FROM todo_list WHERE id=?", id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	members := []string{owner}
+	rows, err := this.db.Query("SELECT user_id FROM list_member WHERE list_id=? ORDER BY user_id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member string
+		if err := rows.Scan(&member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SharedLists maps the IDs of the lists having members to the owner and members
+func (this *TodoSqlModel) SharedLists() (map[int][]string, error) {
+	members := map[int][]string{}
+	rows, err := this.db.Query("SELECT todo_list.id, todo_list.user_id FROM todo_list WHERE todo_list.id IN ( SELECT list_id FROM list_member ) UNION ALL SELECT list_id, user_id FROM list_member")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var listID int
+		var userID string
+		if err := rows.Scan(&listID, &userID); err != nil {
+			return nil, err
+		}
+		members[listID] = append(members[listID], userID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
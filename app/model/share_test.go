@@ -0,0 +1,139 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShareTodos(t *testing.T) {
+	now := time.Now()
+	userTodos := map[string][]Todo{
+		"owner": {
+			{ID: 1, UserID: "owner", Task: "eggs", ListID: 1, Due: now.Add(2 * time.Hour)},
+			{ID: 2, UserID: "owner", Task: "diary", Due: now},
+		},
+		"member": {
+			{ID: 3, UserID: "member", Task: "milk", ListID: 1, Due: now.Add(time.Hour)},
+		},
+	}
+	members := map[int][]string{
+		1: {"owner", "member", "friend"},
+	}
+	shared := ShareTodos(userTodos, members)
+	tasks := func(todos []Todo) string {
+		text := ""
+		for _, todo := range todos {
+			text += todo.Task + " "
+		}
+		return text
+	}
+	want := map[string]string{
+		"owner":  "diary milk eggs ",
+		"member": "milk eggs ",
+		"friend": "milk eggs ",
+	}
+	for userID, wantTasks := range want {
+		if got := tasks(shared[userID]); got != wantTasks {
+			t.Errorf("ShareTodos()[%q] == %q, want %q", userID, got, wantTasks)
+		}
+	}
+}
+
+func TestTodoSqlModelJoinList(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT id, user_id, name FROM todo_list WHERE invite_code=?").WithArgs("K3XQ7M2A").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(1, "another user", "Family"))
+	mock.ExpectQuery("SELECT COUNT").WithArgs(1, "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO list_member").WithArgs(1, "dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	list, err := model.JoinList("dummy user", " k3xq7m2a ")
+	if err != nil || list.ID != 1 || !list.Joined {
+		t.Errorf("Result TodoSqlModel.JoinList(%q) == %#v, %#v, want %d joined", "k3xq7m2a", list, err, 1)
+	}
+	// Unknown code
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT id, user_id, name FROM todo_list WHERE invite_code=?").WithArgs("WRONG").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "name"}))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	_, err = model.JoinList("dummy user", "wrong")
+	if err != ErrNotFound {
+		t.Errorf("Result TodoSqlModel.JoinList(%q) == %#v, want %#v", "wrong", err, ErrNotFound)
+	}
+	// Error when insert row
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT id, user_id, name FROM todo_list WHERE invite_code=?").WithArgs("K3XQ7M2A").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(1, "another user", "Family"))
+	mock.ExpectQuery("SELECT COUNT").WithArgs(1, "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO list_member").WithArgs(1, "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	_, err = model.JoinList("dummy user", "K3XQ7M2A")
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.JoinList(%q) == %#v, want %#v", "K3XQ7M2A", err, wantErr)
+	}
+}
+
+func TestTodoSqlModelLeaveList(t *testing.T) {
+	list := TodoList{
+		ID: 1,
+	}
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM list_member").WithArgs(1, "dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE todo SET list_id=0 WHERE list_id=\\? AND user_id=\\?").WithArgs(1, "dummy user").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.LeaveList("dummy user", list)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.LeaveList(%q, %#v) == %#v, want %#v", "dummy user", list, err, nil)
+	}
+	// The owner cannot leave
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM list_member").WithArgs(1, "dummy user").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery("SELECT user_id FROM todo_list WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}).AddRow("dummy user"))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.LeaveList("dummy user", list)
+	if err != ErrForbidden {
+		t.Errorf("Result TodoSqlModel.LeaveList(%q, %#v) == %#v, want %#v", "dummy user", list, err, ErrForbidden)
+	}
+}
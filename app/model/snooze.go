@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+)
+
+// IsSnoozed is whether the todo is hidden from the digests and the alerts at the time
+func (this Todo) IsSnoozed(now time.Time) bool {
+	return this.Snoozed.After(now)
+}
+
+// Snooze hides the todo until todo.Snoozed without changing its due date, a zero time wakes it up
+func (this *TodoSqlModel) Snooze(userID string, todo Todo) error {
+	var snoozed interface{}
+	if !todo.Snoozed.IsZero() {
+		snoozed = todo.Snoozed.UTC()
+	}
+	sql := `UPDATE todo SET snoozed=? WHERE id=? AND ` + todoAccess
+	result, err := this.db.Exec(sql, snoozed, todo.ID, userID, userID, userID)
+	if err != nil {
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num != 1 {
+		return this.CheckOwner(userID, todo.ID)
+	}
+	return nil
+}
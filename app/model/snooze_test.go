@@ -0,0 +1,52 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTodoSqlModelSnooze(t *testing.T) {
+	until := time.Date(2018, 11, 15, 9, 0, 0, 0, time.UTC)
+	todo := Todo{ID: 1, Snoozed: until}
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+
+	// Success
+	mock.ExpectExec("UPDATE todo SET snoozed=\\?").WithArgs(until, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := model.Snooze("dummy user", todo); err != nil {
+		t.Errorf("TodoSqlModel.Snooze(%q, %#v) == %v, want %v", "dummy user", todo, err, nil)
+	}
+
+	// Woken up
+	mock.ExpectExec("UPDATE todo SET snoozed=\\?").WithArgs(nil, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := model.Snooze("dummy user", Todo{ID: 1}); err != nil {
+		t.Errorf("TodoSqlModel.Snooze(%q) with zero == %v, want %v", "dummy user", err, nil)
+	}
+
+	// Error
+	wantErr := errors.New("dummy error")
+	mock.ExpectExec("UPDATE todo SET snoozed=\\?").WithArgs(until, 1, "dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	if err := model.Snooze("dummy user", todo); err != wantErr {
+		t.Errorf("TodoSqlModel.Snooze(%q, %#v) == %v, want %v", "dummy user", todo, err, wantErr)
+	}
+
+	// Owned by another user
+	mock.ExpectExec("UPDATE todo SET snoozed=\\?").WithArgs(until, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=\\?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("another user", 0))
+	if err := model.Snooze("dummy user", todo); err != ErrForbidden {
+		t.Errorf("TodoSqlModel.Snooze(%q, %#v) of another user == %v, want %v", "dummy user", todo, err, ErrForbidden)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
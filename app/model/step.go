@@ -0,0 +1,133 @@
+package model
+
+import (
+	"database/sql"
+)
+
+// Step is a checklist item of a todo
+type Step struct {
+	ID       int
+	TodoID   int
+	Task     string
+	Done     bool
+	Position int
+}
+
+// Progress returns the number of done steps and the number of steps
+func (this Todo) Progress() (int, int) {
+	done := 0
+	for _, step := range this.Steps {
+		if step.Done {
+			done++
+		}
+	}
+	return done, len(this.Steps)
+}
+
+// CheckStepOwner checks the owner of the todo the step belongs to
+func (this *TodoSqlModel) CheckStepOwner(userID string, id int) error {
+	var todoID int
+	err := this.db.QueryRow("SELECT todo_id FROM step WHERE id=?", id).Scan(&todoID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return this.CheckOwner(userID, todoID)
+}
+
+// ListSteps returns the steps found by the query grouped by todo ID
+func (this *TodoSqlModel) ListSteps(query string, args ...interface{}) (map[int][]Step, error) {
+	todoSteps := map[int][]Step{}
+	rows, err := this.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var step Step
+		if err := rows.Scan(&step.ID, &step.TodoID, &step.Task, &step.Done, &step.Position); err != nil {
+			return nil, err
+		}
+		todoSteps[step.TodoID] = append(todoSteps[step.TodoID], step)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return todoSteps, nil
+}
+
+func (this *TodoSqlModel) AddStep(userID string, step Step) error {
+	if err := this.CheckOwner(userID, step.TodoID); err != nil {
+		return err
+	}
+	sql := `INSERT INTO step ( todo_id, task, position ) SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM step WHERE todo_id=?`
+	result, err := this.db.Exec(sql, step.TodoID, step.Task, step.TodoID)
+	if err != nil {
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num != 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (this *TodoSqlModel) DoneStep(userID string, step Step) error {
+	sql := `UPDATE step SET done=? WHERE id=? AND todo_id IN ( SELECT id FROM todo WHERE ` + todoAccess + ` )`
+	result, err := this.db.Exec(sql, step.Done, step.ID, userID, userID, userID)
+	if err != nil {
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num != 1 {
+		return this.CheckStepOwner(userID, step.ID)
+	}
+
+	return nil
+}
+
+// ReorderSteps numbers the steps of the todo in the given order, IDs of other todos are ignored
+func (this *TodoSqlModel) ReorderSteps(userID string, todoID int, stepIDs []int) error {
+	if err := this.CheckOwner(userID, todoID); err != nil {
+		return err
+	}
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	for i, id := range stepIDs {
+		if _, err := tx.Exec("UPDATE step SET position=? WHERE id=? AND todo_id=?", i+1, id, todoID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (this *TodoSqlModel) DeleteStep(userID string, step Step) error {
+	sql := `DELETE FROM step WHERE id=? AND todo_id IN ( SELECT id FROM todo WHERE ` + todoAccess + ` )`
+	result, err := this.db.Exec(sql, step.ID, userID, userID, userID)
+	if err != nil {
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num != 1 {
+		return this.CheckStepOwner(userID, step.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTodoSqlModelAddStep(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	step := Step{
+		TodoID: 1,
+		Task:   "dummy step",
+	}
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("dummy user", 0))
+	mock.ExpectExec("INSERT INTO step").WithArgs(1, "dummy step", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.AddStep("dummy user", step)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.AddStep(%q, %#v) == %#v, want %#v", "dummy user", step, err, nil)
+	}
+	// Error when insert row
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("dummy user", 0))
+	mock.ExpectExec("INSERT INTO step").WithArgs(1, "dummy step", 1).WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.AddStep("dummy user", step)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.AddStep(%q, %#v) == %#v, want %#v", "dummy user", step, err, wantErr)
+	}
+	// Todo owned by another user
+	wantErr = errors.New("Forbidden")
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("another user", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.AddStep("dummy user", step)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.AddStep(%q, %#v) == %#v, want %#v", "dummy user", step, err, wantErr)
+	}
+}
+
+func TestTodoSqlModelDoneStep(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	step := Step{
+		ID:   1,
+		Done: true,
+	}
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE step SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.DoneStep("dummy user", step)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.DoneStep(%q, %#v) == %#v, want %#v", "dummy user", step, err, nil)
+	}
+	// Error when update row
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE step SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.DoneStep("dummy user", step)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.DoneStep(%q, %#v) == %#v, want %#v", "dummy user", step, err, wantErr)
+	}
+	// No row affected
+	wantErr = errors.New("No record")
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE step SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT todo_id FROM step WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"todo_id"}))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.DoneStep("dummy user", step)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.DoneStep(%q, %#v) == %#v, want %#v", "dummy user", step, err, wantErr)
+	}
+	// Owned by another user
+	wantErr = errors.New("Forbidden")
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE step SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT todo_id FROM step WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"todo_id"}).AddRow(2))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(2).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("another user", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.DoneStep("dummy user", step)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.DoneStep(%q, %#v) == %#v, want %#v", "dummy user", step, err, wantErr)
+	}
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+// NormalizeTags lower-cases the tags, strips the leading # and removes duplicates
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimLeft(strings.TrimSpace(tag), "#"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func (this Todo) HasTag(tag string) bool {
+	for _, own := range this.Tags {
+		if own == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByTags returns the todos having all the tags
+func FilterByTags(todos []Todo, tags []string) []Todo {
+	tags = NormalizeTags(tags)
+	var filtered []Todo
+	for _, todo := range todos {
+		match := true
+		for _, tag := range tags {
+			if !todo.HasTag(tag) {
+				match = false
+				break
+			}
+		}
+		if match {
+			filtered = append(filtered, todo)
+		}
+	}
+	return filtered
+}
+
+// ListTags returns the tags found by the query grouped by todo ID
+func (this *TodoSqlModel) ListTags(query string, args ...interface{}) (map[int][]string, error) {
+	todoTags := map[int][]string{}
+	rows, err := this.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todoID int
+		var tag string
+		if err := rows.Scan(&todoID, &tag); err != nil {
+			return nil, err
+		}
+		todoTags[todoID] = append(todoTags[todoID], tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return todoTags, nil
+}
+
+// SaveTags replaces the tags of the todo
+func (this *TodoSqlModel) SaveTags(todoID int64, tags []string) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM todo_tag WHERE todo_id=?", todoID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, tag := range NormalizeTags(tags) {
+		if _, err := tx.Exec("INSERT INTO todo_tag ( todo_id, tag ) VALUES( ?, ? )", todoID, tag); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
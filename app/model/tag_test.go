@@ -0,0 +1,52 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	cases := []struct {
+		tags []string
+		want string
+	}{
+		{nil, "[]"},
+		{[]string{"#Work"}, "[work]"},
+		{[]string{"work", "#home", "#WORK", " ", "#"}, "[home work]"},
+		{[]string{"งาน", "##urgent"}, "[urgent งาน]"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprint(NormalizeTags(c.tags)); got != c.want {
+			t.Errorf("NormalizeTags(%q) == %v, want %v", c.tags, got, c.want)
+		}
+	}
+	if got := NormalizeTags(nil); got != nil {
+		t.Errorf("NormalizeTags(nil) == %#v, want nil", got)
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	todos := []Todo{
+		{ID: 1, Tags: []string{"home"}},
+		{ID: 2, Tags: []string{"home", "work"}},
+		{ID: 3},
+	}
+	cases := []struct {
+		tags []string
+		want []int
+	}{
+		{nil, []int{1, 2, 3}},
+		{[]string{"home"}, []int{1, 2}},
+		{[]string{"#Home", "work"}, []int{2}},
+		{[]string{"shopping"}, nil},
+	}
+	for _, c := range cases {
+		var got []int
+		for _, todo := range FilterByTags(todos, c.tags) {
+			got = append(got, todo.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("FilterByTags(%q) == %v, want %v", c.tags, got, c.want)
+		}
+	}
+}
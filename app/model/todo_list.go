@@ -0,0 +1,173 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var ErrListName = errors.New("Wrong list name")
+
+// DefaultListName is the list of the todos with ListID 0, including the ones created before lists existed
+const DefaultListName = "Inbox"
+
+// TodoList is owned by UserID, Joined lists are shared with the user by their owner
+type TodoList struct {
+	ID         int
+	UserID     string
+	Name       string
+	InviteCode string
+	Joined     bool
+}
+
+// FilterByList returns the todos in the list, 0 is the default list
+func FilterByList(todos []Todo, listID int) []Todo {
+	var filtered []Todo
+	for _, todo := range todos {
+		if todo.ListID == listID {
+			filtered = append(filtered, todo)
+		}
+	}
+	return filtered
+}
+
+func (this *TodoSqlModel) CheckListOwner(userID string, id int) error {
+	var owner string
+	err := this.db.QueryRow("SELECT user_id FROM todo_list WHERE id=?", id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if owner != userID {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// scanList hides the invite code from the members of the list
+func scanList(userID string, scanner interface {
+	Scan(dest ...interface{}) error
+}) (TodoList, error) {
+	list := TodoList{}
+	if err := scanner.Scan(&list.ID, &list.UserID, &list.Name, &list.InviteCode); err != nil {
+		return TodoList{}, err
+	}
+	if list.UserID != userID {
+		list.Joined = true
+		list.InviteCode = ""
+	}
+	return list, nil
+}
+
+// Lists returns the lists of the user and the lists the user joined
+func (this *TodoSqlModel) Lists(userID string) ([]TodoList, error) {
+	var lists []TodoList
+	rows, err := this.db.Query("SELECT id, user_id, name, invite_code FROM todo_list WHERE user_id=? OR id IN ( SELECT list_id FROM list_member WHERE user_id=? ) ORDER BY name, id", userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		list, err := scanList(userID, rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// FindList finds the list of the user by name ignoring case, the own list comes before a joined one
+func (this *TodoSqlModel) FindList(userID string, name string) (TodoList, error) {
+	row := this.db.QueryRow("SELECT id, user_id, name, invite_code FROM todo_list WHERE LOWER(name)=? AND ( user_id=? OR id IN ( SELECT list_id FROM list_member WHERE user_id=? ) ) ORDER BY CASE WHEN user_id=? THEN 0 ELSE 1 END, id", strings.ToLower(strings.TrimSpace(name)), userID, userID, userID)
+	list, err := scanList(userID, row)
+	if err == sql.ErrNoRows {
+		return TodoList{}, ErrNotFound
+	}
+	if err != nil {
+		return TodoList{}, err
+	}
+	return list, nil
+}
+
+// checkListName rejects blank names and names used by another list of the user
+func (this *TodoSqlModel) checkListName(list TodoList) error {
+	name := strings.TrimSpace(list.Name)
+	if name == "" || strings.EqualFold(name, DefaultListName) {
+		return ErrListName
+	}
+	found, err := this.FindList(list.UserID, name)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if found.ID != list.ID {
+		return ErrListName
+	}
+	return nil
+}
+
+func (this *TodoSqlModel) CreateList(list TodoList) error {
+	if err := this.checkListName(list); err != nil {
+		return err
+	}
+	sql := `INSERT INTO todo_list ( user_id, name ) VALUES( ?, ? )`
+	result, err := this.db.Exec(sql, list.UserID, strings.TrimSpace(list.Name))
+	if err != nil {
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num != 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (this *TodoSqlModel) RenameList(userID string, list TodoList) error {
+	if err := this.CheckListOwner(userID, list.ID); err != nil {
+		return err
+	}
+	list.UserID = userID
+	if err := this.checkListName(list); err != nil {
+		return err
+	}
+	_, err := this.db.Exec("UPDATE todo_list SET name=? WHERE id=? AND user_id=?", strings.TrimSpace(list.Name), list.ID, userID)
+	return err
+}
+
+// DeleteList moves the todos of the list to the default list of their owners and stops sharing it
+func (this *TodoSqlModel) DeleteList(userID string, list TodoList) error {
+	if err := this.CheckListOwner(userID, list.ID); err != nil {
+		return err
+	}
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE todo SET list_id=0 WHERE list_id=?", list.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM list_member WHERE list_id=?", list.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM todo_list WHERE id=? AND user_id=?", list.ID, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
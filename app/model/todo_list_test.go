@@ -0,0 +1,123 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTodoSqlModelCreateList(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	list := TodoList{
+		UserID: "dummy user",
+		Name:   " Shopping ",
+	}
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT id, user_id, name, invite_code FROM todo_list WHERE LOWER\\(name\\)=?").WithArgs("shopping", "dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "name", "invite_code"}))
+	mock.ExpectExec("INSERT INTO todo_list").WithArgs("dummy user", "Shopping").WillReturnResult(sqlmock.NewResult(1, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.CreateList(list)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.CreateList(%#v) == %#v, want %#v", list, err, nil)
+	}
+	// Name taken
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT id, user_id, name, invite_code FROM todo_list WHERE LOWER\\(name\\)=?").WithArgs("shopping", "dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "name", "invite_code"}).AddRow(1, "dummy user", "shopping", ""))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.CreateList(list)
+	if err != ErrListName {
+		t.Errorf("Result TodoSqlModel.CreateList(%#v) == %#v, want %#v", list, err, ErrListName)
+	}
+	// Error when insert row
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT id, user_id, name, invite_code FROM todo_list WHERE LOWER\\(name\\)=?").WithArgs("shopping", "dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id", "name", "invite_code"}))
+	mock.ExpectExec("INSERT INTO todo_list").WithArgs("dummy user", "Shopping").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.CreateList(list)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.CreateList(%#v) == %#v, want %#v", list, err, wantErr)
+	}
+}
+
+func TestTodoSqlModelDeleteList(t *testing.T) {
+	wantErr := errors.New("Dummy error")
+	list := TodoList{
+		ID: 1,
+	}
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id FROM todo_list WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}).AddRow("dummy user"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE todo SET list_id=0").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM list_member").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM todo_list").WithArgs(1, "dummy user").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.DeleteList("dummy user", list)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.DeleteList(%q, %#v) == %#v, want %#v", "dummy user", list, err, nil)
+	}
+	// Error when moving the todos
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id FROM todo_list WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}).AddRow("dummy user"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE todo SET list_id=0").WithArgs(1).WillReturnError(wantErr)
+	mock.ExpectRollback()
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.DeleteList("dummy user", list)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.DeleteList(%q, %#v) == %#v, want %#v", "dummy user", list, err, wantErr)
+	}
+	// Owned by another user
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id FROM todo_list WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}).AddRow("another user"))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.DeleteList("dummy user", list)
+	if err != ErrForbidden {
+		t.Errorf("Result TodoSqlModel.DeleteList(%q, %#v) == %#v, want %#v", "dummy user", list, err, ErrForbidden)
+	}
+}
@@ -0,0 +1,702 @@
+package model
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TodoMemoryModel struct {
+	mutex      sync.Mutex
+	nextID     int
+	todos      []Todo
+	nextStepID int
+	steps      []Step
+	nextListID int
+	lists      []TodoList
+	members    []listMember
+	settings   map[string]Setting
+	inactive   map[string]time.Time
+}
+
+type listMember struct {
+	ListID int
+	UserID string
+}
+
+func NewTodoMemoryModel() *TodoMemoryModel {
+	return &TodoMemoryModel{
+		nextID:     1,
+		nextStepID: 1,
+		nextListID: 1,
+		settings:   map[string]Setting{},
+		inactive:   map[string]time.Time{},
+	}
+}
+
+// find returns the index of the todo, ownership is checked like TodoSqlModel.CheckOwner
+func (this *TodoMemoryModel) find(userID string, id int) (int, error) {
+	for i, todo := range this.todos {
+		if todo.ID == id {
+			if !this.canAccess(userID, todo) {
+				return -1, ErrForbidden
+			}
+			return i, nil
+		}
+	}
+	return -1, ErrNotFound
+}
+
+// canAccess is todoAccess
+func (this *TodoMemoryModel) canAccess(userID string, todo Todo) bool {
+	return todo.UserID == userID || (todo.ListID != 0 && this.checkListMember(userID, todo.ListID) == nil)
+}
+
+func (this *TodoMemoryModel) output(todo Todo) Todo {
+	todo.Due = todo.Due.UTC()
+	todo.Steps = nil
+	for _, step := range this.steps {
+		if step.TodoID == todo.ID {
+			todo.Steps = append(todo.Steps, step)
+		}
+	}
+	sort.SliceStable(todo.Steps, func(i, j int) bool {
+		return todo.Steps[i].Position < todo.Steps[j].Position
+	})
+	return todo
+}
+
+func (this *TodoMemoryModel) List(userID string) ([]Todo, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var todos []Todo
+	for _, todo := range this.todos {
+		if this.canAccess(userID, todo) {
+			todos = append(todos, this.output(todo))
+		}
+	}
+	return todos, nil
+}
+
+func (this *TodoMemoryModel) Create(todo Todo) error {
+	if todo.Repeat != "" {
+		if _, err := ParseRecurrence(todo.Repeat); err != nil {
+			return err
+		}
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if todo.ListID != 0 {
+		if err := this.checkListMember(todo.UserID, todo.ListID); err != nil {
+			return err
+		}
+	}
+	todo.ID = this.nextID
+	todo.Done = false
+	todo.Due = todo.Due.UTC()
+	todo.Snoozed = time.Time{}
+	todo.Steps = nil
+	todo.Tags = NormalizeTags(todo.Tags)
+	this.nextID++
+	this.todos = append(this.todos, todo)
+	return nil
+}
+
+func (this *TodoMemoryModel) Snooze(userID string, todo Todo) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.find(userID, todo.ID)
+	if err != nil {
+		return err
+	}
+	this.todos[i].Snoozed = todo.Snoozed.UTC()
+	if todo.Snoozed.IsZero() {
+		this.todos[i].Snoozed = time.Time{}
+	}
+	return nil
+}
+
+func (this *TodoMemoryModel) Pin(userID string, todo Todo) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.find(userID, todo.ID)
+	if err != nil {
+		return err
+	}
+	this.todos[i].Pin = todo.Pin
+	return nil
+}
+
+func (this *TodoMemoryModel) Done(userID string, todo Todo) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.find(userID, todo.ID)
+	if err != nil {
+		return err
+	}
+	this.todos[i].Done = todo.Done
+	if todo.Done && this.todos[i].Repeat != "" {
+		return this.recur(i)
+	}
+	return nil
+}
+
+// recur creates the next occurrence like TodoSqlModel.Recur
+func (this *TodoMemoryModel) recur(i int) error {
+	recurrence, err := ParseRecurrence(this.todos[i].Repeat)
+	if err != nil {
+		return err
+	}
+	next := this.todos[i]
+	next.ID = this.nextID
+	next.Done = false
+	next.Snoozed = time.Time{}
+	next.Due = recurrence.NextAfter(next.Due.In(this.settings[next.UserID].Location()), time.Now()).UTC()
+	this.nextID++
+	this.todos[i].Repeat = ""
+	this.todos = append(this.todos, next)
+	// The checklist starts over on the next occurrence
+	for _, step := range this.steps {
+		if step.TodoID == this.todos[i].ID {
+			step.ID = this.nextStepID
+			step.TodoID = next.ID
+			step.Done = false
+			this.nextStepID++
+			this.steps = append(this.steps, step)
+		}
+	}
+	return nil
+}
+
+func (this *TodoMemoryModel) RemindUsers() ([]string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	todos := make([]Todo, len(this.todos))
+	copy(todos, this.todos)
+	userIDs := []string{}
+	for userID := range this.remind(todos) {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+func (this *TodoMemoryModel) Remind(userIDs []string) (map[string][]Todo, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	todos := make([]Todo, len(this.todos))
+	copy(todos, this.todos)
+	userTodos := this.remind(todos)
+	reminded := map[string][]Todo{}
+	for _, userID := range userIDs {
+		if todos, ok := userTodos[userID]; ok {
+			reminded[userID] = todos
+		}
+	}
+	return reminded, nil
+}
+
+func (this *TodoMemoryModel) DueTodos(from time.Time, to time.Time) (map[string][]Todo, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	todos := []Todo{}
+	for _, todo := range this.todos {
+		if !todo.Done && !todo.Due.Before(from) && !todo.Due.After(to) {
+			todos = append(todos, todo)
+		}
+	}
+	return this.remind(todos), nil
+}
+
+// remind is Remind of some todos without locking
+func (this *TodoMemoryModel) remind(todos []Todo) map[string][]Todo {
+	// Same order as ORDER BY user_id, done, pin DESC, due
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := todos[i], todos[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		if a.Done != b.Done {
+			return !a.Done
+		}
+		if a.Pin != b.Pin {
+			return a.Pin
+		}
+		return a.Due.Before(b.Due)
+	})
+	userTodos := map[string][]Todo{}
+	for _, todo := range todos {
+		userTodos[todo.UserID] = append(userTodos[todo.UserID], this.output(todo))
+	}
+	members := map[int][]string{}
+	for _, list := range this.lists {
+		for _, member := range this.members {
+			if member.ListID == list.ID {
+				if len(members[list.ID]) == 0 {
+					members[list.ID] = []string{list.UserID}
+				}
+				members[list.ID] = append(members[list.ID], member.UserID)
+			}
+		}
+	}
+	userTodos = ShareTodos(userTodos, members)
+	for userID := range this.inactive {
+		delete(userTodos, userID)
+	}
+	return userTodos
+}
+
+func (this *TodoMemoryModel) Edit(userID string, todo Todo) error {
+	if todo.Repeat != "" {
+		if _, err := ParseRecurrence(todo.Repeat); err != nil {
+			return err
+		}
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if todo.ListID != 0 {
+		if err := this.checkListMember(userID, todo.ListID); err != nil {
+			return err
+		}
+	}
+	i, err := this.find(userID, todo.ID)
+	if err != nil {
+		return err
+	}
+	this.todos[i].Task = todo.Task
+	this.todos[i].Due = todo.Due.UTC()
+	this.todos[i].Repeat = todo.Repeat
+	this.todos[i].Tags = NormalizeTags(todo.Tags)
+	this.todos[i].ListID = todo.ListID
+	return nil
+}
+
+func (this *TodoMemoryModel) Delete(userID string, todo Todo) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.find(userID, todo.ID)
+	if err != nil {
+		return err
+	}
+	this.todos = append(this.todos[:i], this.todos[i+1:]...)
+	steps := []Step{}
+	for _, step := range this.steps {
+		if step.TodoID != todo.ID {
+			steps = append(steps, step)
+		}
+	}
+	this.steps = steps
+	return nil
+}
+
+// findStep returns the index of the step, ownership is checked through its todo
+func (this *TodoMemoryModel) findStep(userID string, id int) (int, error) {
+	for i, step := range this.steps {
+		if step.ID == id {
+			if _, err := this.find(userID, step.TodoID); err != nil {
+				return -1, err
+			}
+			return i, nil
+		}
+	}
+	return -1, ErrNotFound
+}
+
+func (this *TodoMemoryModel) AddStep(userID string, step Step) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if _, err := this.find(userID, step.TodoID); err != nil {
+		return err
+	}
+	step.ID = this.nextStepID
+	step.Done = false
+	step.Position = 1
+	for _, other := range this.steps {
+		if other.TodoID == step.TodoID && other.Position >= step.Position {
+			step.Position = other.Position + 1
+		}
+	}
+	this.nextStepID++
+	this.steps = append(this.steps, step)
+	return nil
+}
+
+func (this *TodoMemoryModel) DoneStep(userID string, step Step) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.findStep(userID, step.ID)
+	if err != nil {
+		return err
+	}
+	this.steps[i].Done = step.Done
+	return nil
+}
+
+func (this *TodoMemoryModel) ReorderSteps(userID string, todoID int, stepIDs []int) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if _, err := this.find(userID, todoID); err != nil {
+		return err
+	}
+	for position, id := range stepIDs {
+		for i, step := range this.steps {
+			if step.ID == id && step.TodoID == todoID {
+				this.steps[i].Position = position + 1
+			}
+		}
+	}
+	return nil
+}
+
+func (this *TodoMemoryModel) DeleteStep(userID string, step Step) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.findStep(userID, step.ID)
+	if err != nil {
+		return err
+	}
+	this.steps = append(this.steps[:i], this.steps[i+1:]...)
+	return nil
+}
+
+func (this *TodoMemoryModel) findList(userID string, id int) (int, error) {
+	for i, list := range this.lists {
+		if list.ID == id {
+			if list.UserID != userID {
+				return -1, ErrForbidden
+			}
+			return i, nil
+		}
+	}
+	return -1, ErrNotFound
+}
+
+// checkListMember is TodoSqlModel.CheckListMember
+func (this *TodoMemoryModel) checkListMember(userID string, id int) error {
+	if _, err := this.findList(userID, id); err != ErrForbidden {
+		return err
+	}
+	for _, member := range this.members {
+		if member.ListID == id && member.UserID == userID {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+// outputList hides the invite code from the members of the list like scanList
+func (this *TodoMemoryModel) outputList(userID string, list TodoList) TodoList {
+	if list.UserID != userID {
+		list.Joined = true
+		list.InviteCode = ""
+	}
+	return list
+}
+
+// findListByName is TodoSqlModel.FindList without locking
+func (this *TodoMemoryModel) findListByName(userID string, name string) (TodoList, error) {
+	var joined []TodoList
+	for _, list := range this.lists {
+		if !strings.EqualFold(list.Name, strings.TrimSpace(name)) {
+			continue
+		}
+		if list.UserID == userID {
+			return list, nil
+		}
+		if this.checkListMember(userID, list.ID) == nil {
+			joined = append(joined, this.outputList(userID, list))
+		}
+	}
+	if len(joined) > 0 {
+		return joined[0], nil
+	}
+	return TodoList{}, ErrNotFound
+}
+
+// checkListName is TodoSqlModel.checkListName
+func (this *TodoMemoryModel) checkListName(list TodoList) error {
+	name := strings.TrimSpace(list.Name)
+	if name == "" || strings.EqualFold(name, DefaultListName) {
+		return ErrListName
+	}
+	found, err := this.findListByName(list.UserID, name)
+	if err == nil && found.ID != list.ID {
+		return ErrListName
+	}
+	return nil
+}
+
+func (this *TodoMemoryModel) Lists(userID string) ([]TodoList, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	var lists []TodoList
+	for _, list := range this.lists {
+		if this.checkListMember(userID, list.ID) == nil {
+			lists = append(lists, this.outputList(userID, list))
+		}
+	}
+	sort.SliceStable(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+	return lists, nil
+}
+
+func (this *TodoMemoryModel) FindList(userID string, name string) (TodoList, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.findListByName(userID, name)
+}
+
+func (this *TodoMemoryModel) CreateList(list TodoList) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if err := this.checkListName(list); err != nil {
+		return err
+	}
+	list.ID = this.nextListID
+	list.Name = strings.TrimSpace(list.Name)
+	this.nextListID++
+	this.lists = append(this.lists, list)
+	return nil
+}
+
+func (this *TodoMemoryModel) RenameList(userID string, list TodoList) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.findList(userID, list.ID)
+	if err != nil {
+		return err
+	}
+	list.UserID = userID
+	if err := this.checkListName(list); err != nil {
+		return err
+	}
+	this.lists[i].Name = strings.TrimSpace(list.Name)
+	return nil
+}
+
+func (this *TodoMemoryModel) DeleteList(userID string, list TodoList) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.findList(userID, list.ID)
+	if err != nil {
+		return err
+	}
+	for j := range this.todos {
+		if this.todos[j].ListID == list.ID {
+			this.todos[j].ListID = 0
+		}
+	}
+	this.lists = append(this.lists[:i], this.lists[i+1:]...)
+	members := []listMember{}
+	for _, member := range this.members {
+		if member.ListID != list.ID {
+			members = append(members, member)
+		}
+	}
+	this.members = members
+	return nil
+}
+
+func (this *TodoMemoryModel) ShareList(userID string, list TodoList) (string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	i, err := this.findList(userID, list.ID)
+	if err != nil {
+		return "", err
+	}
+	if this.lists[i].InviteCode == "" {
+		this.lists[i].InviteCode = NewInviteCode()
+	}
+	return this.lists[i].InviteCode, nil
+}
+
+func (this *TodoMemoryModel) JoinList(userID string, code string) (TodoList, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	code = NormalizeInviteCode(code)
+	if code == "" {
+		return TodoList{}, ErrNotFound
+	}
+	for _, list := range this.lists {
+		if list.InviteCode != code {
+			continue
+		}
+		if this.checkListMember(userID, list.ID) != nil {
+			this.members = append(this.members, listMember{ListID: list.ID, UserID: userID})
+		}
+		list = this.outputList(userID, list)
+		if !list.Joined {
+			list.InviteCode = code
+		}
+		return list, nil
+	}
+	return TodoList{}, ErrNotFound
+}
+
+func (this *TodoMemoryModel) LeaveList(userID string, list TodoList) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for i, member := range this.members {
+		if member.ListID == list.ID && member.UserID == userID {
+			this.members = append(this.members[:i], this.members[i+1:]...)
+			for j := range this.todos {
+				if this.todos[j].ListID == list.ID && this.todos[j].UserID == userID {
+					this.todos[j].ListID = 0
+				}
+			}
+			return nil
+		}
+	}
+	if _, err := this.findList(userID, list.ID); err != nil {
+		return err
+	}
+	return ErrForbidden
+}
+
+func (this *TodoMemoryModel) ListMembers(listID int) ([]string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for _, list := range this.lists {
+		if list.ID == listID {
+			members := []string{list.UserID}
+			others := []string{}
+			for _, member := range this.members {
+				if member.ListID == listID {
+					others = append(others, member.UserID)
+				}
+			}
+			sort.Strings(others)
+			return append(members, others...), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (this *TodoMemoryModel) Setting(userID string) (Setting, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	setting, ok := this.settings[userID]
+	if !ok {
+		return Setting{UserID: userID}, nil
+	}
+	return setting, nil
+}
+
+func (this *TodoMemoryModel) Settings(userIDs []string) (map[string]Setting, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	settings := map[string]Setting{}
+	for _, userID := range userIDs {
+		setting, ok := this.settings[userID]
+		if !ok {
+			setting = Setting{UserID: userID}
+		}
+		settings[userID] = setting
+	}
+	return settings, nil
+}
+
+func (this *TodoMemoryModel) SaveSetting(setting Setting) error {
+	if err := CheckTimeZone(setting.TimeZone); err != nil {
+		return err
+	}
+	if err := CheckReminder(setting); err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.settings[setting.UserID] = setting
+	return nil
+}
+
+func (this *TodoMemoryModel) BuddyOwner(code string) (string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	code = NormalizeInviteCode(code)
+	if code == "" {
+		return "", ErrNotFound
+	}
+	for userID, setting := range this.settings {
+		if setting.BuddyCode == code {
+			return userID, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+func (this *TodoMemoryModel) Deactivate(userID string, since time.Time) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.inactive[userID] = since.UTC().Truncate(time.Second)
+	return nil
+}
+
+func (this *TodoMemoryModel) Activate(userID string) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	_, ok := this.inactive[userID]
+	delete(this.inactive, userID)
+	return ok, nil
+}
+
+func (this *TodoMemoryModel) InactiveUsers(before time.Time) ([]string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	userIDs := []string{}
+	for userID, since := range this.inactive {
+		if since.Before(before) {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+func (this *TodoMemoryModel) Purge(userID string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	purged := map[int]bool{}
+	todos := []Todo{}
+	for _, todo := range this.todos {
+		if todo.UserID == userID {
+			purged[todo.ID] = true
+		} else {
+			todos = append(todos, todo)
+		}
+	}
+	this.todos = todos
+	steps := []Step{}
+	for _, step := range this.steps {
+		if !purged[step.TodoID] {
+			steps = append(steps, step)
+		}
+	}
+	this.steps = steps
+	owned := map[int]bool{}
+	lists := []TodoList{}
+	for _, list := range this.lists {
+		if list.UserID == userID {
+			owned[list.ID] = true
+		} else {
+			lists = append(lists, list)
+		}
+	}
+	this.lists = lists
+	for i := range this.todos {
+		if owned[this.todos[i].ListID] {
+			this.todos[i].ListID = 0
+		}
+	}
+	members := []listMember{}
+	for _, member := range this.members {
+		if member.UserID != userID && !owned[member.ListID] {
+			members = append(members, member)
+		}
+	}
+	this.members = members
+	delete(this.settings, userID)
+	delete(this.inactive, userID)
+	return nil
+}
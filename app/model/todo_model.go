@@ -5,9 +5,16 @@ import (
 	"errors"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	ErrNotFound  = errors.New("No record")
+	ErrForbidden = errors.New("Forbidden")
 )
 
 type Todo struct {
@@ -17,24 +24,109 @@ type Todo struct {
 	Done   bool
 	Pin    bool
 	Due    time.Time
+	Repeat string
+	Steps  []Step
+	Tags   []string
+	ListID int
+	// Snoozed hides the todo from the digests and the alerts until then, zero when it is not snoozed
+	Snoozed time.Time
 }
 
 type TodoModel interface {
 	List(userID string) ([]Todo, error)
 	Create(todo Todo) error
-	Pin(todo Todo) error
-	Done(todo Todo) error
-	Remind() (map[string][]Todo, error)
-	Edit(todo Todo) error
-	Delete(todo Todo) error
+	Pin(userID string, todo Todo) error
+	Done(userID string, todo Todo) error
+	Snooze(userID string, todo Todo) error
+	RemindUsers() ([]string, error)
+	Remind(userIDs []string) (map[string][]Todo, error)
+	DueTodos(from time.Time, to time.Time) (map[string][]Todo, error)
+	Edit(userID string, todo Todo) error
+	Delete(userID string, todo Todo) error
+	AddStep(userID string, step Step) error
+	DoneStep(userID string, step Step) error
+	ReorderSteps(userID string, todoID int, stepIDs []int) error
+	DeleteStep(userID string, step Step) error
+	Lists(userID string) ([]TodoList, error)
+	FindList(userID string, name string) (TodoList, error)
+	CreateList(list TodoList) error
+	RenameList(userID string, list TodoList) error
+	DeleteList(userID string, list TodoList) error
+	ShareList(userID string, list TodoList) (string, error)
+	JoinList(userID string, code string) (TodoList, error)
+	LeaveList(userID string, list TodoList) error
+	ListMembers(listID int) ([]string, error)
+	Setting(userID string) (Setting, error)
+	Settings(userIDs []string) (map[string]Setting, error)
+	SaveSetting(setting Setting) error
+	BuddyOwner(code string) (string, error)
+	Deactivate(userID string, since time.Time) error
+	Activate(userID string) (bool, error)
+	InactiveUsers(before time.Time) ([]string, error)
+	Purge(userID string) error
+}
+
+type TodoSqlModel struct {
+	db      *sql.DB
+	dialect string
+}
+
+// Models are the TodoModel and the stores of the bot and the scheduler, on the data source of DATA_SOURCE_NAME
+type Models struct {
+	TodoModel    TodoModel
+	ListingModel ListingModel
+	DraftModel   DraftModel
+	DialogModel  DialogModel
+	AlertModel   AlertModel
+	JobRunModel  JobRunModel
 }
 
-type TodoMySqlModel struct {
-	db *sql.DB
+func NewModels() Models {
+	dataSourceName := os.Getenv("DATA_SOURCE_NAME")
+	if strings.HasPrefix(dataSourceName, "memory://") {
+		// Nothing is persisted, for demos only
+		return NewMemoryModels()
+	}
+	for _, scheme := range []string{"sqlite3://", "sqlite://"} {
+		if strings.HasPrefix(dataSourceName, scheme) {
+			todoModel := NewTodoSqliteModel(strings.TrimPrefix(dataSourceName, scheme))
+			return todoModel.Models()
+		}
+	}
+	todoModel := NewTodoMySqlModel()
+	return todoModel.Models()
 }
 
-func (this *TodoMySqlModel) SetTimeZone() error {
-	sql := `SET time_zone = 'Asia/Bangkok'`
+func NewMemoryModels() Models {
+	return Models{
+		TodoModel:    NewTodoMemoryModel(),
+		ListingModel: NewListingMemoryModel(),
+		DraftModel:   NewDraftMemoryModel(),
+		DialogModel:  NewDialogMemoryModel(),
+		AlertModel:   NewAlertMemoryModel(),
+		JobRunModel:  NewJobRunMemoryModel(),
+	}
+}
+
+// Models are the stores sharing the database of the todo model
+func (this *TodoSqlModel) Models() Models {
+	return Models{
+		TodoModel:    this,
+		ListingModel: &ListingSqlModel{db: this.db},
+		DraftModel:   &DraftSqlModel{db: this.db},
+		DialogModel:  &DialogSqlModel{db: this.db},
+		AlertModel:   &AlertSqlModel{db: this.db, dialect: this.dialect},
+		JobRunModel:  &JobRunSqlModel{db: this.db, dialect: this.dialect},
+	}
+}
+
+func (this *TodoSqlModel) SetTimeZone() error {
+	if this.dialect == "sqlite3" {
+		// SQLite has no session time zone, due dates are stored in UTC
+		return nil
+	}
+	// Due dates are stored in UTC, each user sees them in the time zone of the setting
+	sql := `SET time_zone = '+00:00'`
 	_, err := this.db.Exec(sql)
 	if err != nil {
 		return err
@@ -43,14 +135,10 @@ func (this *TodoMySqlModel) SetTimeZone() error {
 	return nil
 }
 
-func (this *TodoMySqlModel) List(userID string) ([]Todo, error) {
+func (this *TodoSqlModel) List(userID string) ([]Todo, error) {
 	this.SetTimeZone()
-	err := this.CreateTablesIfNotExist()
-	if err != nil {
-		return nil, err
-	}
 	var todos []Todo
-	rows, err := this.db.Query("SELECT id, task, done, pin, due FROM todo WHERE user_id=?", userID)
+	rows, err := this.db.Query("SELECT id, user_id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE "+todoAccess, userID, userID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -58,69 +146,133 @@ func (this *TodoMySqlModel) List(userID string) ([]Todo, error) {
 
 	for rows.Next() {
 		var id int
+		var owner string
 		var task string
 		var done bool
 		var pin bool
 		var due time.Time
-		if err := rows.Scan(&id, &task, &done, &pin, &due); err != nil {
+		var repeat string
+		var listID int
+		var snoozed *time.Time
+		if err := rows.Scan(&id, &owner, &task, &done, &pin, &due, &repeat, &listID, &snoozed); err != nil {
 			return nil, err
 		}
-		loc, _ := time.LoadLocation("Asia/Bangkok")
-		due = due.In(loc)
 		todo := Todo{
 			ID:     id,
-			UserID: userID,
+			UserID: owner,
 			Task:   task,
 			Pin:    pin,
 			Done:   done,
-			Due:    due,
+			Due:    due.UTC(),
+			Repeat: repeat,
+			ListID: listID,
+		}
+		if snoozed != nil {
+			todo.Snoozed = snoozed.UTC()
 		}
 		todos = append(todos, todo)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	todoSteps, err := this.ListSteps("SELECT step.id, step.todo_id, step.task, step.done, step.position FROM step WHERE step.todo_id IN ( SELECT id FROM todo WHERE "+todoAccess+" ) ORDER BY step.todo_id, step.position, step.id", userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	todoTags, err := this.ListTags("SELECT todo_tag.todo_id, todo_tag.tag FROM todo_tag WHERE todo_tag.todo_id IN ( SELECT id FROM todo WHERE "+todoAccess+" ) ORDER BY todo_tag.todo_id, todo_tag.tag", userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range todos {
+		todos[i].Steps = todoSteps[todos[i].ID]
+		todos[i].Tags = todoTags[todos[i].ID]
+	}
 	return todos, nil
 }
 
-func NewTodoMySqlModel() TodoMySqlModel {
+func NewTodoMySqlModel() TodoSqlModel {
 	db, _ := sql.Open("mysql", os.Getenv("DATA_SOURCE_NAME"))
-	return TodoMySqlModel{
-		db: db,
+	return TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
 }
 
-func (this *TodoMySqlModel) CreateTablesIfNotExist() error {
-	sql := "SELECT 1 FROM todo LIMIT 1"
-	_, err := this.db.Query(sql)
-	if err != nil {
-		sql = `
-		CREATE TABLE todo (
-			id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
-			user_id VARCHAR(255) NOT NULL,
-			task TEXT NOT NULL,
-			done BOOL NOT NULL DEFAULT FALSE,
-			pin BOOL NOT NULL DEFAULT FALSE,
-			due DATETIME NOT NULL
-		) CHARACTER SET utf8 COLLATE utf8_general_ci`
+func NewTodoSqliteModel(path string) TodoSqlModel {
+	db, _ := sql.Open("sqlite3", path)
+	// SQLite allows a single writer, sharing one connection also keeps ":memory:" databases alive
+	db.SetMaxOpenConns(1)
+	return TodoSqlModel{
+		db:      db,
+		dialect: "sqlite3",
+	}
+}
 
-		_, err = this.db.Exec(sql)
+func (this *TodoSqlModel) Create(todo Todo) error {
+	this.SetTimeZone()
+	if todo.Repeat != "" {
+		if _, err := ParseRecurrence(todo.Repeat); err != nil {
+			return err
+		}
+	}
+	if todo.ListID != 0 {
+		if err := this.CheckListMember(todo.UserID, todo.ListID); err != nil {
+			return err
+		}
+	}
+	sql := `INSERT INTO todo ( user_id, task, pin, due, repeat_rule, list_id ) VALUES( ?, ?, ?, ?, ?, ?)`
+	result, err := this.db.Exec(sql, todo.UserID, todo.Task, todo.Pin, todo.Due.UTC(), todo.Repeat, todo.ListID)
+	if err != nil {
+		return err
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if num != 1 {
+		return ErrNotFound
+	}
+	if len(todo.Tags) > 0 {
+		id, err := result.LastInsertId()
 		if err != nil {
 			return err
 		}
+		return this.SaveTags(id, todo.Tags)
 	}
 
 	return nil
 }
 
-func (this *TodoMySqlModel) Create(todo Todo) error {
-	this.SetTimeZone()
-	err := this.CreateTablesIfNotExist()
+// CheckOwner checks the user owns the todo or is a member of its list
+func (this *TodoSqlModel) CheckOwner(userID string, id int) error {
+	var owner string
+	var listID int
+	err := this.db.QueryRow("SELECT user_id, list_id FROM todo WHERE id=?", id).Scan(&owner, &listID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
 	if err != nil {
 		return err
 	}
-	sql := `INSERT INTO todo ( user_id, task, due ) VALUES( ?, ?, ?)`
-	result, err := this.db.Exec(sql, todo.UserID, todo.Task, todo.Due)
+	if owner == userID {
+		return nil
+	}
+	if listID == 0 {
+		return ErrForbidden
+	}
+	if err := this.CheckListMember(userID, listID); err != nil {
+		if err == ErrNotFound {
+			return ErrForbidden
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (this *TodoSqlModel) Pin(userID string, todo Todo) error {
+	sql := `UPDATE todo SET pin=? WHERE id=? AND ` + todoAccess
+	result, err := this.db.Exec(sql, todo.Pin, todo.ID, userID, userID, userID)
 	if err != nil {
 		return err
 	}
@@ -129,14 +281,15 @@ func (this *TodoMySqlModel) Create(todo Todo) error {
 		return err
 	}
 	if num != 1 {
-		return errors.New("No record")
+		return this.CheckOwner(userID, todo.ID)
 	}
 
 	return nil
 }
-func (this *TodoMySqlModel) Pin(todo Todo) error {
-	sql := `UPDATE todo SET pin=? WHERE id=?`
-	result, err := this.db.Exec(sql, todo.Pin, todo.ID)
+
+func (this *TodoSqlModel) Done(userID string, todo Todo) error {
+	sql := `UPDATE todo SET done=? WHERE id=? AND ` + todoAccess
+	result, err := this.db.Exec(sql, todo.Done, todo.ID, userID, userID, userID)
 	if err != nil {
 		return err
 	}
@@ -145,42 +298,168 @@ func (this *TodoMySqlModel) Pin(todo Todo) error {
 		return err
 	}
 	if num != 1 {
-		return errors.New("No record")
+		if err := this.CheckOwner(userID, todo.ID); err != nil {
+			return err
+		}
+	}
+	if todo.Done {
+		return this.Recur(userID, todo.ID)
 	}
 
 	return nil
 }
 
-func (this *TodoMySqlModel) Done(todo Todo) error {
-	sql := `UPDATE todo SET done=? WHERE id=?`
-	result, err := this.db.Exec(sql, todo.Done, todo.ID)
+// Recur creates the next occurrence of a completed repeating todo, the rule moves to the new todo so it is spawned once
+func (this *TodoSqlModel) Recur(userID string, id int) error {
+	var owner string
+	var task string
+	var pin bool
+	var due time.Time
+	var repeat string
+	var listID int
+	err := this.db.QueryRow("SELECT user_id, task, pin, due, repeat_rule, list_id FROM todo WHERE id=? AND "+todoAccess, id, userID, userID, userID).Scan(&owner, &task, &pin, &due, &repeat, &listID)
+	if err != nil {
+		return err
+	}
+	if repeat == "" {
+		return nil
+	}
+	recurrence, err := ParseRecurrence(repeat)
+	if err != nil {
+		return err
+	}
+	// Repeat at the same clock time of the owner across daylight saving changes
+	next := recurrence.NextAfter(due.In(this.location(owner)), time.Now())
+
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec("UPDATE todo SET repeat_rule='' WHERE id=? AND repeat_rule=?", id, repeat)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
 	num, err := result.RowsAffected()
+	if err != nil || num != 1 {
+		// Spawned by someone else meanwhile
+		tx.Rollback()
+		return err
+	}
+	result, err = tx.Exec("INSERT INTO todo ( user_id, task, pin, due, repeat_rule, list_id ) VALUES( ?, ?, ?, ?, ?, ?)", owner, task, pin, next.UTC(), repeat, listID)
 	if err != nil {
+		tx.Rollback()
 		return err
 	}
-	if num != 1 {
-		return errors.New("No record")
+	nextID, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return err
 	}
+	// The checklist starts over on the next occurrence
+	_, err = tx.Exec("INSERT INTO step ( todo_id, task, position ) SELECT ?, task, position FROM step WHERE todo_id=?", nextID, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO todo_tag ( todo_id, tag ) SELECT ?, tag FROM todo_tag WHERE todo_id=?", nextID, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
 
-	return nil
+// RemindUsers are the active users who may have todos to remind, their own ones or the ones of a shared list, see Remind
+func (this *TodoSqlModel) RemindUsers() ([]string, error) {
+	rows, err := this.db.Query("SELECT user_id FROM ( SELECT user_id FROM todo UNION SELECT user_id FROM list_member UNION SELECT user_id FROM todo_list WHERE id IN ( SELECT list_id FROM list_member ) ) AS reminded WHERE user_id NOT IN ( SELECT user_id FROM inactive_user ) ORDER BY user_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
 }
 
-func (this *TodoMySqlModel) Remind() (map[string][]Todo, error) {
+// Remind are the todos of the users for their digests with their steps and tags, the ones of the lists shared with them too
+func (this *TodoSqlModel) Remind(userIDs []string) (map[string][]Todo, error) {
+	reminded := map[string][]Todo{}
+	if len(userIDs) == 0 {
+		return reminded, nil
+	}
 	this.SetTimeZone()
-	err := this.CreateTablesIfNotExist()
+	in := "( ?" + strings.Repeat(", ?", len(userIDs)-1) + " )"
+	// Their own todos and the ones of the lists they own or joined
+	where := "user_id IN " + in + " OR list_id IN ( SELECT id FROM todo_list WHERE user_id IN " + in + " UNION SELECT list_id FROM list_member WHERE user_id IN " + in + " )"
+	args := []interface{}{}
+	for i := 0; i < 3; i++ {
+		for _, userID := range userIDs {
+			args = append(args, userID)
+		}
+	}
+	rows, err := this.db.Query("SELECT user_id, id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE "+where+" ORDER BY user_id, done, pin DESC, due", args...)
 	if err != nil {
 		return nil, err
 	}
-	userTodos := map[string][]Todo{}
-	rows, err := this.db.Query("SELECT user_id, id, task, done, pin, due FROM todo ORDER BY user_id, done, pin DESC, due")
+	userTodos, err := scanUserTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+	todoSteps, err := this.ListSteps("SELECT id, todo_id, task, done, position FROM step WHERE todo_id IN ( SELECT id FROM todo WHERE "+where+" ) ORDER BY todo_id, position, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	todoTags, err := this.ListTags("SELECT todo_id, tag FROM todo_tag WHERE todo_id IN ( SELECT id FROM todo WHERE "+where+" ) ORDER BY todo_id, tag", args...)
 	if err != nil {
 		return nil, err
 	}
+	for _, todos := range userTodos {
+		for i := range todos {
+			todos[i].Steps = todoSteps[todos[i].ID]
+			todos[i].Tags = todoTags[todos[i].ID]
+		}
+	}
+	userTodos, err = this.shareTodos(userTodos)
+	if err != nil {
+		return nil, err
+	}
+	// The other members of their shared lists are left out
+	for _, userID := range userIDs {
+		if todos, ok := userTodos[userID]; ok {
+			reminded[userID] = todos
+		}
+	}
+	return reminded, nil
+}
+
+// DueTodos are the remaining todos due between from and to by user like Remind, without their steps and tags, for the alerts
+func (this *TodoSqlModel) DueTodos(from time.Time, to time.Time) (map[string][]Todo, error) {
+	this.SetTimeZone()
+	rows, err := this.db.Query("SELECT user_id, id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE done=? AND due>=? AND due<=? ORDER BY user_id, done, pin DESC, due",
+		false, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	userTodos, err := scanUserTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+	return this.shareTodos(userTodos)
+}
+
+// scanUserTodos reads the todos of Remind and DueTodos by user and closes the rows
+func scanUserTodos(rows *sql.Rows) (map[string][]Todo, error) {
 	defer rows.Close()
 
+	userTodos := map[string][]Todo{}
 	for rows.Next() {
 		var userID string
 		var id int
@@ -188,18 +467,24 @@ func (this *TodoMySqlModel) Remind() (map[string][]Todo, error) {
 		var done bool
 		var pin bool
 		var due time.Time
-		if err := rows.Scan(&userID, &id, &task, &done, &pin, &due); err != nil {
+		var repeat string
+		var listID int
+		var snoozed *time.Time
+		if err := rows.Scan(&userID, &id, &task, &done, &pin, &due, &repeat, &listID, &snoozed); err != nil {
 			return nil, err
 		}
-		loc, _ := time.LoadLocation("Asia/Bangkok")
-		due = due.In(loc)
 		todo := Todo{
 			ID:     id,
 			UserID: userID,
 			Task:   task,
 			Pin:    pin,
 			Done:   done,
-			Due:    due,
+			Due:    due.UTC(),
+			Repeat: repeat,
+			ListID: listID,
+		}
+		if snoozed != nil {
+			todo.Snoozed = snoozed.UTC()
 		}
 		log.Println(due)
 		//Add to map
@@ -207,17 +492,32 @@ func (this *TodoMySqlModel) Remind() (map[string][]Todo, error) {
 		todos = append(todos, todo)
 		userTodos[userID] = todos
 	}
-	if err = rows.Err(); err != nil {
+	return userTodos, rows.Err()
+}
+
+// shareTodos adds the todos of the shared lists to their members and removes the inactive users
+func (this *TodoSqlModel) shareTodos(userTodos map[string][]Todo) (map[string][]Todo, error) {
+	members, err := this.SharedLists()
+	if err != nil {
 		return nil, err
 	}
-
-	return userTodos, nil
+	return this.withoutInactive(ShareTodos(userTodos, members))
 }
 
-func (this *TodoMySqlModel) Edit(todo Todo) error {
+func (this *TodoSqlModel) Edit(userID string, todo Todo) error {
 	log.Println(todo)
-	sql := `UPDATE todo SET task=?, due=? WHERE id=?`
-	result, err := this.db.Exec(sql, todo.Task, todo.Due, todo.ID)
+	if todo.Repeat != "" {
+		if _, err := ParseRecurrence(todo.Repeat); err != nil {
+			return err
+		}
+	}
+	if todo.ListID != 0 {
+		if err := this.CheckListMember(userID, todo.ListID); err != nil {
+			return err
+		}
+	}
+	sql := `UPDATE todo SET task=?, due=?, repeat_rule=?, list_id=? WHERE id=? AND ` + todoAccess
+	result, err := this.db.Exec(sql, todo.Task, todo.Due.UTC(), todo.Repeat, todo.ListID, todo.ID, userID, userID, userID)
 	if err != nil {
 		return err
 	}
@@ -226,16 +526,18 @@ func (this *TodoMySqlModel) Edit(todo Todo) error {
 		return err
 	}
 	if num != 1 {
-		return errors.New("No record")
+		if err := this.CheckOwner(userID, todo.ID); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return this.SaveTags(int64(todo.ID), todo.Tags)
 }
 
-func (this *TodoMySqlModel) Delete(todo Todo) error {
+func (this *TodoSqlModel) Delete(userID string, todo Todo) error {
 	log.Println(todo)
-	sql := `DELETE FROM todo WHERE id=?`
-	result, err := this.db.Exec(sql, todo.ID)
+	sql := `DELETE FROM todo WHERE id=? AND ` + todoAccess
+	result, err := this.db.Exec(sql, todo.ID, userID, userID, userID)
 	if err != nil {
 		return err
 	}
@@ -244,7 +546,15 @@ func (this *TodoMySqlModel) Delete(todo Todo) error {
 		return err
 	}
 	if num != 1 {
-		return errors.New("No record")
+		return this.CheckOwner(userID, todo.ID)
+	}
+	_, err = this.db.Exec("DELETE FROM step WHERE todo_id=?", todo.ID)
+	if err != nil {
+		return err
+	}
+	_, err = this.db.Exec("DELETE FROM todo_tag WHERE todo_id=?", todo.ID)
+	if err != nil {
+		return err
 	}
 
 	return nil
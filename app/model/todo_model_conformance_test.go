@@ -0,0 +1,1145 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func findTodo(todos []Todo, task string) (Todo, bool) {
+	for _, todo := range todos {
+		if todo.Task == task {
+			return todo, true
+		}
+	}
+	return Todo{}, false
+}
+
+// testTodoModelConformance checks the behaviour every TodoModel implementation and its stores must pass
+func testTodoModelConformance(t *testing.T, models Models) {
+	todoModel := models.TodoModel
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	userID := fmt.Sprintf("suite user %d", time.Now().UnixNano())
+	anotherUserID := userID + " another"
+	due, _ := time.ParseInLocation("2006-01-02 15:04", "2018-11-15 15:04", loc)
+
+	// Create
+	for i, task := range []string{"task 1", "task 2", "task 3"} {
+		todo := Todo{
+			UserID: userID,
+			Task:   task,
+			Due:    due.AddDate(0, 0, -i),
+		}
+		if err := todoModel.Create(todo); err != nil {
+			t.Fatalf("TodoModel.Create(%#v) == %v, want %v", todo, err, nil)
+		}
+	}
+	if err := todoModel.Create(Todo{UserID: anotherUserID, Task: "another task", Due: due}); err != nil {
+		t.Fatalf("TodoModel.Create() == %v, want %v", err, nil)
+	}
+
+	// List
+	todos, err := todoModel.List(userID)
+	if err != nil || len(todos) != 3 {
+		t.Fatalf("TodoModel.List(%q) == %d todos, %v, want %d todos, %v", userID, len(todos), err, 3, nil)
+	}
+	todo, _ := findTodo(todos, "task 1")
+	if todo.UserID != userID || todo.Done || todo.Pin || !todo.Due.Equal(due) || todo.Due.Location() != time.UTC {
+		t.Errorf("TodoModel.List(%q) == %#v", userID, todo)
+	}
+
+	// Pin, Done and Edit by the owner
+	todo.Pin = true
+	if err := todoModel.Pin(userID, todo); err != nil {
+		t.Errorf("TodoModel.Pin(%q, %#v) == %v, want %v", userID, todo, err, nil)
+	}
+	task2, _ := findTodo(todos, "task 2")
+	task2.Done = true
+	if err := todoModel.Done(userID, task2); err != nil {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", userID, task2, err, nil)
+	}
+	task3, _ := findTodo(todos, "task 3")
+	task3.Task = "task 3 edited"
+	task3.Due = due.AddDate(0, 0, 1).UTC()
+	if err := todoModel.Edit(userID, task3); err != nil {
+		t.Errorf("TodoModel.Edit(%q, %#v) == %v, want %v", userID, task3, err, nil)
+	}
+	// Setting the same value again is not an error
+	if err := todoModel.Pin(userID, todo); err != nil {
+		t.Errorf("TodoModel.Pin(%q, %#v) == %v, want %v", userID, todo, err, nil)
+	}
+	todos, _ = todoModel.List(userID)
+	if got, _ := findTodo(todos, "task 1"); !got.Pin {
+		t.Errorf("TodoModel.Pin() did not pin %#v", got)
+	}
+	if got, _ := findTodo(todos, "task 2"); !got.Done {
+		t.Errorf("TodoModel.Done() did not complete %#v", got)
+	}
+	if got, ok := findTodo(todos, "task 3 edited"); !ok || !got.Due.Equal(task3.Due) || got.Due.Location() != time.UTC {
+		t.Errorf("TodoModel.Edit() did not edit %#v", got)
+	}
+
+	// Mutations by another user
+	if err := todoModel.Pin(anotherUserID, todo); err != ErrForbidden {
+		t.Errorf("TodoModel.Pin(%q, %#v) == %v, want %v", anotherUserID, todo, err, ErrForbidden)
+	}
+	if err := todoModel.Done(anotherUserID, todo); err != ErrForbidden {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", anotherUserID, todo, err, ErrForbidden)
+	}
+	if err := todoModel.Edit(anotherUserID, todo); err != ErrForbidden {
+		t.Errorf("TodoModel.Edit(%q, %#v) == %v, want %v", anotherUserID, todo, err, ErrForbidden)
+	}
+	if err := todoModel.Delete(anotherUserID, todo); err != ErrForbidden {
+		t.Errorf("TodoModel.Delete(%q, %#v) == %v, want %v", anotherUserID, todo, err, ErrForbidden)
+	}
+
+	// Unknown todo
+	unknown := Todo{ID: 2147483647}
+	if err := todoModel.Done(userID, unknown); err != ErrNotFound {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", userID, unknown, err, ErrNotFound)
+	}
+	if err := todoModel.Delete(userID, unknown); err != ErrNotFound {
+		t.Errorf("TodoModel.Delete(%q, %#v) == %v, want %v", userID, unknown, err, ErrNotFound)
+	}
+
+	// Remind orders by done, pin then due
+	userTodos, err := remindAll(todoModel)
+	if err != nil {
+		t.Fatalf("TodoModel.Remind() == %v, want %v", err, nil)
+	}
+	want := []string{"task 1", "task 3 edited", "task 2"}
+	got := []string{}
+	for _, todo := range userTodos[userID] {
+		got = append(got, todo.Task)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("TodoModel.Remind()[%q] == %v, want %v", userID, got, want)
+	}
+	if len(userTodos[anotherUserID]) != 1 {
+		t.Errorf("TodoModel.Remind()[%q] == %v, want %d todo", anotherUserID, userTodos[anotherUserID], 1)
+	}
+
+	// DueTodos are the remaining ones due in the range, both ends included
+	userTodos, err = todoModel.DueTodos(due.AddDate(0, 0, -1), due.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("TodoModel.DueTodos() == %v, want %v", err, nil)
+	}
+	got = []string{}
+	for _, todo := range userTodos[userID] {
+		got = append(got, todo.Task)
+	}
+	if fmt.Sprint(got) != "[task 1 task 3 edited]" || len(userTodos[anotherUserID]) != 1 {
+		t.Errorf("TodoModel.DueTodos() == %v, want %v and %d todo of %q", userTodos, "[task 1 task 3 edited]", 1, anotherUserID)
+	}
+	if userTodos, err := todoModel.DueTodos(due.Add(time.Hour), due.Add(2*time.Hour)); err != nil || len(userTodos[userID]) != 0 {
+		t.Errorf("TodoModel.DueTodos() of an empty range == %v, %v, want none", userTodos, err)
+	}
+
+	// Repeating todo
+	repeating := Todo{
+		UserID: userID,
+		Task:   "repeating task",
+		Due:    time.Now().In(loc).Add(time.Hour).Truncate(time.Second),
+		Repeat: "FREQ=DAILY",
+	}
+	if err := todoModel.Create(repeating); err != nil {
+		t.Errorf("TodoModel.Create(%#v) == %v, want %v", repeating, err, nil)
+	}
+	invalid := Todo{UserID: userID, Task: "invalid", Due: due, Repeat: "FREQ=SOMETIMES"}
+	if err := todoModel.Create(invalid); err != ErrRecurrence {
+		t.Errorf("TodoModel.Create(%#v) == %v, want %v", invalid, err, ErrRecurrence)
+	}
+	todos, _ = todoModel.List(userID)
+	repeating, _ = findTodo(todos, "repeating task")
+	if repeating.Repeat != "FREQ=DAILY" {
+		t.Errorf("TodoModel.List() == %#v, want repeat rule", repeating)
+	}
+	repeating.Done = true
+	for i := 0; i < 2; i++ {
+		// Completing twice spawns once
+		if err := todoModel.Done(userID, repeating); err != nil {
+			t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", userID, repeating, err, nil)
+		}
+	}
+	todos, _ = todoModel.List(userID)
+	occurrences := []Todo{}
+	for _, todo := range todos {
+		if todo.Task == "repeating task" {
+			occurrences = append(occurrences, todo)
+		}
+	}
+	if len(occurrences) != 2 {
+		t.Fatalf("TodoModel.Done() spawned %d occurrences, want %d", len(occurrences)-1, 1)
+	}
+	for _, occurrence := range occurrences {
+		if occurrence.ID == repeating.ID && (!occurrence.Done || occurrence.Repeat != "") {
+			t.Errorf("TodoModel.Done() left %#v", occurrence)
+		}
+		if occurrence.ID != repeating.ID && (occurrence.Done || occurrence.Repeat != "FREQ=DAILY" || !occurrence.Due.Equal(repeating.Due.AddDate(0, 0, 1))) {
+			t.Errorf("TodoModel.Done() spawned %#v", occurrence)
+		}
+	}
+	for _, occurrence := range occurrences {
+		todoModel.Delete(userID, occurrence)
+	}
+
+	// Delete
+	if err := todoModel.Delete(userID, todo); err != nil {
+		t.Errorf("TodoModel.Delete(%q, %#v) == %v, want %v", userID, todo, err, nil)
+	}
+	todos, _ = todoModel.List(userID)
+	if _, ok := findTodo(todos, "task 1"); ok || len(todos) != 2 {
+		t.Errorf("TodoModel.Delete() did not delete %#v", todo)
+	}
+
+	testStepConformance(t, todoModel, userID)
+	testTagConformance(t, todoModel, userID)
+	testListConformance(t, todoModel, userID)
+	testShareConformance(t, todoModel, userID)
+	testSettingConformance(t, todoModel, userID)
+	testListingConformance(t, models.ListingModel, userID)
+	testDraftConformance(t, models.DraftModel, userID)
+	testDialogConformance(t, models, userID)
+	testInactiveConformance(t, todoModel, userID)
+	testJobRunConformance(t, models.JobRunModel, userID)
+	testAlertConformance(t, models.AlertModel, userID)
+	testSnoozeConformance(t, todoModel, userID)
+}
+
+func stepTasks(todo Todo) string {
+	tasks := []string{}
+	for _, step := range todo.Steps {
+		tasks = append(tasks, step.Task)
+	}
+	return fmt.Sprint(tasks)
+}
+
+// testStepConformance checks the checklist of a todo
+func testStepConformance(t *testing.T, todoModel TodoModel, userID string) {
+	anotherUserID := userID + " another"
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	if err := todoModel.Create(Todo{UserID: userID, Task: "checklist", Due: time.Now().In(loc).Add(time.Hour), Repeat: "FREQ=WEEKLY"}); err != nil {
+		t.Fatalf("TodoModel.Create() == %v, want %v", err, nil)
+	}
+	todos, _ := todoModel.List(userID)
+	todo, _ := findTodo(todos, "checklist")
+	if todo.Steps != nil {
+		t.Errorf("TodoModel.List() == %#v, want no steps", todo)
+	}
+
+	// Add
+	for _, task := range []string{"step 1", "step 2", "step 3"} {
+		step := Step{TodoID: todo.ID, Task: task}
+		if err := todoModel.AddStep(userID, step); err != nil {
+			t.Fatalf("TodoModel.AddStep(%q, %#v) == %v, want %v", userID, step, err, nil)
+		}
+	}
+	if err := todoModel.AddStep(anotherUserID, Step{TodoID: todo.ID, Task: "step 4"}); err != ErrForbidden {
+		t.Errorf("TodoModel.AddStep(%q) == %v, want %v", anotherUserID, err, ErrForbidden)
+	}
+	if err := todoModel.AddStep(userID, Step{TodoID: 2147483647, Task: "step 4"}); err != ErrNotFound {
+		t.Errorf("TodoModel.AddStep(%q) == %v, want %v", userID, err, ErrNotFound)
+	}
+	todos, _ = todoModel.List(userID)
+	todo, _ = findTodo(todos, "checklist")
+	if got := stepTasks(todo); got != "[step 1 step 2 step 3]" {
+		t.Fatalf("TodoModel.List() steps == %v, want %v", got, "[step 1 step 2 step 3]")
+	}
+	steps := todo.Steps
+
+	// Done
+	steps[1].Done = true
+	if err := todoModel.DoneStep(userID, steps[1]); err != nil {
+		t.Errorf("TodoModel.DoneStep(%q, %#v) == %v, want %v", userID, steps[1], err, nil)
+	}
+	if err := todoModel.DoneStep(anotherUserID, steps[1]); err != ErrForbidden {
+		t.Errorf("TodoModel.DoneStep(%q, %#v) == %v, want %v", anotherUserID, steps[1], err, ErrForbidden)
+	}
+	if err := todoModel.DoneStep(userID, Step{ID: 2147483647, Done: true}); err != ErrNotFound {
+		t.Errorf("TodoModel.DoneStep(%q) == %v, want %v", userID, err, ErrNotFound)
+	}
+
+	// Reorder
+	stepIDs := []int{steps[2].ID, steps[0].ID, steps[1].ID}
+	if err := todoModel.ReorderSteps(userID, todo.ID, stepIDs); err != nil {
+		t.Errorf("TodoModel.ReorderSteps(%q, %d, %v) == %v, want %v", userID, todo.ID, stepIDs, err, nil)
+	}
+	if err := todoModel.ReorderSteps(anotherUserID, todo.ID, stepIDs); err != ErrForbidden {
+		t.Errorf("TodoModel.ReorderSteps(%q, %d, %v) == %v, want %v", anotherUserID, todo.ID, stepIDs, err, ErrForbidden)
+	}
+	userTodos, _ := remindAll(todoModel)
+	todo, _ = findTodo(userTodos[userID], "checklist")
+	if got := stepTasks(todo); got != "[step 3 step 1 step 2]" {
+		t.Errorf("TodoModel.Remind() steps == %v, want %v", got, "[step 3 step 1 step 2]")
+	}
+	if done, total := todo.Progress(); done != 1 || total != 3 {
+		t.Errorf("Todo.Progress() == %d, %d, want %d, %d", done, total, 1, 3)
+	}
+
+	// Delete
+	if err := todoModel.DeleteStep(anotherUserID, steps[0]); err != ErrForbidden {
+		t.Errorf("TodoModel.DeleteStep(%q, %#v) == %v, want %v", anotherUserID, steps[0], err, ErrForbidden)
+	}
+	if err := todoModel.DeleteStep(userID, steps[0]); err != nil {
+		t.Errorf("TodoModel.DeleteStep(%q, %#v) == %v, want %v", userID, steps[0], err, nil)
+	}
+	if err := todoModel.DeleteStep(userID, steps[0]); err != ErrNotFound {
+		t.Errorf("TodoModel.DeleteStep(%q, %#v) == %v, want %v", userID, steps[0], err, ErrNotFound)
+	}
+
+	// The next occurrence starts the checklist over
+	todo.Done = true
+	if err := todoModel.Done(userID, todo); err != nil {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", userID, todo, err, nil)
+	}
+	todos, _ = todoModel.List(userID)
+	for _, occurrence := range todos {
+		if occurrence.Task != "checklist" {
+			continue
+		}
+		done, total := occurrence.Progress()
+		if stepTasks(occurrence) != "[step 3 step 2]" || (occurrence.ID == todo.ID && done != 1) || (occurrence.ID != todo.ID && done != 0) || total != 2 {
+			t.Errorf("TodoModel.Done() left %#v", occurrence)
+		}
+		if err := todoModel.Delete(userID, occurrence); err != nil {
+			t.Errorf("TodoModel.Delete(%q, %#v) == %v, want %v", userID, occurrence, err, nil)
+		}
+	}
+	// Steps go with their todo
+	if err := todoModel.DoneStep(userID, steps[2]); err != ErrNotFound {
+		t.Errorf("TodoModel.DoneStep(%q, %#v) == %v, want %v", userID, steps[2], err, ErrNotFound)
+	}
+}
+
+// remindAll is the digest of every user, see TodoModel.RemindUsers
+func remindAll(todoModel TodoModel) (map[string][]Todo, error) {
+	userIDs, err := todoModel.RemindUsers()
+	if err != nil {
+		return nil, err
+	}
+	return todoModel.Remind(userIDs)
+}
+
+func TestTodoMemoryModel(t *testing.T) {
+	testTodoModelConformance(t, NewMemoryModels())
+
+	// Concurrent use
+	todoModel := NewTodoMemoryModel()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			todoModel.Create(Todo{UserID: "dummy user", Task: fmt.Sprint(i), Due: time.Now()})
+			remindAll(todoModel)
+		}(i)
+	}
+	wg.Wait()
+	todos, _ := todoModel.List("dummy user")
+	ids := map[int]bool{}
+	for _, todo := range todos {
+		ids[todo.ID] = true
+	}
+	if len(todos) != 100 || len(ids) != 100 {
+		t.Errorf("TodoMemoryModel.List() == %d todos with %d IDs, want %d", len(todos), len(ids), 100)
+	}
+}
+
+func TestTodoSqliteModel(t *testing.T) {
+	todoModel := NewTodoSqliteModel(":memory:")
+	if err := todoModel.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	testTodoModelConformance(t, todoModel.Models())
+}
+
+func TestTodoMySqlModel(t *testing.T) {
+	dataSourceName := os.Getenv("TEST_DATA_SOURCE_NAME")
+	if dataSourceName == "" {
+		t.Skip("TEST_DATA_SOURCE_NAME is not set")
+	}
+	defer os.Setenv("DATA_SOURCE_NAME", os.Getenv("DATA_SOURCE_NAME"))
+	os.Setenv("DATA_SOURCE_NAME", dataSourceName)
+	todoModel := NewTodoMySqlModel()
+	if err := todoModel.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	testTodoModelConformance(t, todoModel.Models())
+}
+
+func TestNewModels(t *testing.T) {
+	dataSourceName := os.Getenv("DATA_SOURCE_NAME")
+	defer os.Setenv("DATA_SOURCE_NAME", dataSourceName)
+
+	os.Setenv("DATA_SOURCE_NAME", "sqlite3://:memory:")
+	models := NewModels()
+	if todoModel, ok := models.TodoModel.(*TodoSqlModel); !ok || todoModel.dialect != "sqlite3" {
+		t.Errorf("NewModels().TodoModel == %#v, want sqlite3 model", todoModel)
+	}
+	if jobRunModel, ok := models.JobRunModel.(*JobRunSqlModel); !ok || jobRunModel.dialect != "sqlite3" || jobRunModel.db != models.TodoModel.(*TodoSqlModel).db {
+		t.Errorf("NewModels().JobRunModel == %#v, want sqlite3 model on the same database", jobRunModel)
+	}
+	os.Setenv("DATA_SOURCE_NAME", "sqlite://todo.db")
+	if todoModel, ok := NewModels().TodoModel.(*TodoSqlModel); !ok || todoModel.dialect != "sqlite3" {
+		t.Errorf("NewModels().TodoModel == %#v, want sqlite3 model", todoModel)
+	}
+	os.Setenv("DATA_SOURCE_NAME", "memory://")
+	models = NewModels()
+	if todoModel, ok := models.TodoModel.(*TodoMemoryModel); !ok {
+		t.Errorf("NewModels().TodoModel == %#v, want memory model", todoModel)
+	}
+	if alertModel, ok := models.AlertModel.(*AlertMemoryModel); !ok {
+		t.Errorf("NewModels().AlertModel == %#v, want memory model", alertModel)
+	}
+	os.Setenv("DATA_SOURCE_NAME", "user:password@tcp(localhost:3306)/todo?parseTime=true")
+	if todoModel, ok := NewModels().TodoModel.(*TodoSqlModel); !ok || todoModel.dialect != "mysql" {
+		t.Errorf("NewModels().TodoModel == %#v, want mysql model", todoModel)
+	}
+}
+
+// testTagConformance checks the tags of a todo
+func testTagConformance(t *testing.T, todoModel TodoModel, userID string) {
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	tagged := Todo{
+		UserID: userID,
+		Task:   "tagged",
+		Due:    time.Now().In(loc).Add(time.Hour).Truncate(time.Second),
+		Repeat: "FREQ=DAILY",
+		Tags:   []string{"#Work", "home", "work"},
+	}
+	if err := todoModel.Create(tagged); err != nil {
+		t.Fatalf("TodoModel.Create(%#v) == %v, want %v", tagged, err, nil)
+	}
+	todos, _ := todoModel.List(userID)
+	tagged, _ = findTodo(todos, "tagged")
+	if fmt.Sprint(tagged.Tags) != "[home work]" {
+		t.Errorf("TodoModel.List() tags == %v, want %v", tagged.Tags, "[home work]")
+	}
+	if other, _ := findTodo(todos, "task 2"); other.Tags != nil {
+		t.Errorf("TodoModel.List() tags == %#v, want none", other.Tags)
+	}
+
+	// Edit replaces the tags
+	tagged.Tags = []string{"errand"}
+	if err := todoModel.Edit(userID, tagged); err != nil {
+		t.Errorf("TodoModel.Edit(%q, %#v) == %v, want %v", userID, tagged, err, nil)
+	}
+	userTodos, _ := remindAll(todoModel)
+	if got, _ := findTodo(userTodos[userID], "tagged"); fmt.Sprint(got.Tags) != "[errand]" {
+		t.Errorf("TodoModel.Remind() tags == %v, want %v", got.Tags, "[errand]")
+	}
+
+	// The next occurrence keeps the tags
+	tagged.Done = true
+	if err := todoModel.Done(userID, tagged); err != nil {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", userID, tagged, err, nil)
+	}
+	todos, _ = todoModel.List(userID)
+	occurrences := FilterByTags(todos, []string{"errand"})
+	if len(occurrences) != 2 {
+		t.Errorf("FilterByTags(%q) == %d todos, want %d", "errand", len(occurrences), 2)
+	}
+	for _, occurrence := range occurrences {
+		if err := todoModel.Delete(userID, occurrence); err != nil {
+			t.Errorf("TodoModel.Delete(%q, %#v) == %v, want %v", userID, occurrence, err, nil)
+		}
+	}
+}
+
+// testListConformance checks the named lists of a user
+func testListConformance(t *testing.T, todoModel TodoModel, userID string) {
+	anotherUserID := userID + " another"
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	due := time.Now().In(loc).Add(time.Hour).Truncate(time.Second)
+
+	// Create
+	for _, name := range []string{"Work", "Shopping"} {
+		if err := todoModel.CreateList(TodoList{UserID: userID, Name: name}); err != nil {
+			t.Fatalf("TodoModel.CreateList(%q) == %v, want %v", name, err, nil)
+		}
+	}
+	for _, name := range []string{"", " ", "work", DefaultListName} {
+		if err := todoModel.CreateList(TodoList{UserID: userID, Name: name}); err != ErrListName {
+			t.Errorf("TodoModel.CreateList(%q) == %v, want %v", name, err, ErrListName)
+		}
+	}
+	if err := todoModel.CreateList(TodoList{UserID: anotherUserID, Name: "Work"}); err != nil {
+		t.Errorf("TodoModel.CreateList(%q) by %q == %v, want %v", "Work", anotherUserID, err, nil)
+	}
+	lists, err := todoModel.Lists(userID)
+	if err != nil || len(lists) != 2 || lists[0].Name != "Shopping" || lists[1].Name != "Work" {
+		t.Fatalf("TodoModel.Lists(%q) == %v, %v, want Shopping and Work", userID, lists, err)
+	}
+	shopping := lists[0]
+	work, err := todoModel.FindList(userID, "WORK")
+	if err != nil || work.ID != lists[1].ID {
+		t.Errorf("TodoModel.FindList(%q) == %v, %v, want %v", "WORK", work, err, lists[1])
+	}
+	if _, err := todoModel.FindList(userID, "Personal"); err != ErrNotFound {
+		t.Errorf("TodoModel.FindList(%q) == %v, want %v", "Personal", err, ErrNotFound)
+	}
+	anotherWork, _ := todoModel.FindList(anotherUserID, "Work")
+
+	// Todos in lists
+	milk := Todo{UserID: userID, Task: "milk", Due: due, ListID: shopping.ID, Repeat: "FREQ=WEEKLY"}
+	if err := todoModel.Create(milk); err != nil {
+		t.Errorf("TodoModel.Create(%#v) == %v, want %v", milk, err, nil)
+	}
+	if err := todoModel.Create(Todo{UserID: userID, Task: "report", Due: due, ListID: anotherWork.ID}); err != ErrForbidden {
+		t.Errorf("TodoModel.Create() in a list of %q == %v, want %v", anotherUserID, err, ErrForbidden)
+	}
+	if err := todoModel.Create(Todo{UserID: userID, Task: "report", Due: due, ListID: 2147483647}); err != ErrNotFound {
+		t.Errorf("TodoModel.Create() in an unknown list == %v, want %v", err, ErrNotFound)
+	}
+	todos, _ := todoModel.List(userID)
+	milk, _ = findTodo(todos, "milk")
+	if milk.ListID != shopping.ID {
+		t.Errorf("TodoModel.List() == %#v, want list %d", milk, shopping.ID)
+	}
+	if other, _ := findTodo(todos, "task 2"); other.ListID != 0 {
+		t.Errorf("TodoModel.List() == %#v, want the default list", other)
+	}
+
+	// Move to another list
+	milk.ListID = work.ID
+	if err := todoModel.Edit(userID, milk); err != nil {
+		t.Errorf("TodoModel.Edit(%q, %#v) == %v, want %v", userID, milk, err, nil)
+	}
+	milk.ListID = anotherWork.ID
+	if err := todoModel.Edit(userID, milk); err != ErrForbidden {
+		t.Errorf("TodoModel.Edit(%q, %#v) == %v, want %v", userID, milk, err, ErrForbidden)
+	}
+
+	// The next occurrence stays in the list
+	milk.ListID = work.ID
+	milk.Done = true
+	if err := todoModel.Done(userID, milk); err != nil {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", userID, milk, err, nil)
+	}
+	userTodos, _ := remindAll(todoModel)
+	inWork := 0
+	for _, todo := range userTodos[userID] {
+		if todo.ListID == work.ID {
+			inWork++
+		}
+	}
+	if inWork != 2 {
+		t.Errorf("TodoModel.Remind() == %d todos in %q, want %d", inWork, "Work", 2)
+	}
+
+	// Rename
+	work.Name = "Office"
+	if err := todoModel.RenameList(userID, work); err != nil {
+		t.Errorf("TodoModel.RenameList(%q, %#v) == %v, want %v", userID, work, err, nil)
+	}
+	if err := todoModel.RenameList(anotherUserID, work); err != ErrForbidden {
+		t.Errorf("TodoModel.RenameList(%q, %#v) == %v, want %v", anotherUserID, work, err, ErrForbidden)
+	}
+	work.Name = "shopping"
+	if err := todoModel.RenameList(userID, work); err != ErrListName {
+		t.Errorf("TodoModel.RenameList(%q, %#v) == %v, want %v", userID, work, err, ErrListName)
+	}
+	if found, err := todoModel.FindList(userID, "office"); err != nil || found.ID != work.ID {
+		t.Errorf("TodoModel.FindList(%q) == %v, %v, want %d", "office", found, err, work.ID)
+	}
+
+	// Delete moves the todos to the default list
+	if err := todoModel.DeleteList(anotherUserID, work); err != ErrForbidden {
+		t.Errorf("TodoModel.DeleteList(%q, %#v) == %v, want %v", anotherUserID, work, err, ErrForbidden)
+	}
+	if err := todoModel.DeleteList(userID, work); err != nil {
+		t.Errorf("TodoModel.DeleteList(%q, %#v) == %v, want %v", userID, work, err, nil)
+	}
+	if err := todoModel.DeleteList(userID, work); err != ErrNotFound {
+		t.Errorf("TodoModel.DeleteList(%q, %#v) == %v, want %v", userID, work, err, ErrNotFound)
+	}
+	todos, _ = todoModel.List(userID)
+	for _, todo := range todos {
+		if todo.Task == "milk" {
+			if todo.ListID != 0 {
+				t.Errorf("TodoModel.DeleteList() left %#v", todo)
+			}
+			todoModel.Delete(userID, todo)
+		}
+	}
+	if lists, _ := todoModel.Lists(anotherUserID); len(lists) != 1 {
+		t.Errorf("TodoModel.Lists(%q) == %v, want %d list", anotherUserID, lists, 1)
+	}
+}
+
+// testShareConformance checks a list shared by its owner with a member
+func testShareConformance(t *testing.T, todoModel TodoModel, userID string) {
+	ownerID := userID + " owner"
+	memberID := userID + " member"
+	strangerID := userID + " stranger"
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	due := time.Now().In(loc).Add(time.Hour).Truncate(time.Second)
+
+	if err := todoModel.CreateList(TodoList{UserID: ownerID, Name: "Family"}); err != nil {
+		t.Fatalf("TodoModel.CreateList() == %v, want %v", err, nil)
+	}
+	family, _ := todoModel.FindList(ownerID, "Family")
+	if err := todoModel.Create(Todo{UserID: ownerID, Task: "eggs", Due: due, ListID: family.ID}); err != nil {
+		t.Fatalf("TodoModel.Create() == %v, want %v", err, nil)
+	}
+	if err := todoModel.Create(Todo{UserID: ownerID, Task: "diary", Due: due}); err != nil {
+		t.Fatalf("TodoModel.Create() == %v, want %v", err, nil)
+	}
+
+	// Share
+	if _, err := todoModel.ShareList(memberID, family); err != ErrForbidden {
+		t.Errorf("TodoModel.ShareList(%q) == %v, want %v", memberID, err, ErrForbidden)
+	}
+	code, err := todoModel.ShareList(ownerID, family)
+	if err != nil || code == "" {
+		t.Fatalf("TodoModel.ShareList(%q) == %q, %v, want a code", ownerID, code, err)
+	}
+	if again, _ := todoModel.ShareList(ownerID, family); again != code {
+		t.Errorf("TodoModel.ShareList(%q) again == %q, want %q", ownerID, again, code)
+	}
+
+	// Join
+	if _, err := todoModel.JoinList(memberID, "WRONG"); err != ErrNotFound {
+		t.Errorf("TodoModel.JoinList(%q) == %v, want %v", "WRONG", err, ErrNotFound)
+	}
+	joined, err := todoModel.JoinList(memberID, " "+strings.ToLower(code)+" ")
+	if err != nil || joined.ID != family.ID || !joined.Joined {
+		t.Fatalf("TodoModel.JoinList(%q) == %#v, %v, want %#v", code, joined, err, family)
+	}
+	if _, err := todoModel.JoinList(memberID, code); err != nil {
+		t.Errorf("TodoModel.JoinList(%q) again == %v, want %v", code, err, nil)
+	}
+	if own, err := todoModel.JoinList(ownerID, code); err != nil || own.Joined {
+		t.Errorf("TodoModel.JoinList(%q) by the owner == %#v, %v, want not joined", code, own, err)
+	}
+	members, err := todoModel.ListMembers(family.ID)
+	if err != nil || len(members) != 2 || members[0] != ownerID || members[1] != memberID {
+		t.Errorf("TodoModel.ListMembers(%d) == %v, %v, want %q and %q", family.ID, members, err, ownerID, memberID)
+	}
+	lists, _ := todoModel.Lists(memberID)
+	if len(lists) != 1 || !lists[0].Joined || lists[0].InviteCode != "" || lists[0].UserID != ownerID {
+		t.Errorf("TodoModel.Lists(%q) == %#v, want %q joined", memberID, lists, "Family")
+	}
+	if found, err := todoModel.FindList(memberID, "family"); err != nil || found.ID != family.ID {
+		t.Errorf("TodoModel.FindList(%q, %q) == %#v, %v, want %d", memberID, "family", found, err, family.ID)
+	}
+
+	// Members see and change the shared todos only
+	todos, _ := todoModel.List(memberID)
+	eggs, ok := findTodo(todos, "eggs")
+	if !ok || len(todos) != 1 || eggs.UserID != ownerID {
+		t.Fatalf("TodoModel.List(%q) == %#v, want %q only", memberID, todos, "eggs")
+	}
+	eggs.Done = true
+	if err := todoModel.Done(memberID, eggs); err != nil {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", memberID, eggs, err, nil)
+	}
+	if err := todoModel.Done(strangerID, eggs); err != ErrForbidden {
+		t.Errorf("TodoModel.Done(%q, %#v) == %v, want %v", strangerID, eggs, err, ErrForbidden)
+	}
+	milk := Todo{UserID: memberID, Task: "milk", Due: due, ListID: family.ID}
+	if err := todoModel.Create(milk); err != nil {
+		t.Errorf("TodoModel.Create(%#v) == %v, want %v", milk, err, nil)
+	}
+	if err := todoModel.Create(Todo{UserID: strangerID, Task: "milk", Due: due, ListID: family.ID}); err != ErrForbidden {
+		t.Errorf("TodoModel.Create() by %q == %v, want %v", strangerID, err, ErrForbidden)
+	}
+	todos, _ = todoModel.List(ownerID)
+	milk, ok = findTodo(todos, "milk")
+	if !ok || len(todos) != 3 {
+		t.Errorf("TodoModel.List(%q) == %#v, want %q of %q", ownerID, todos, "milk", memberID)
+	}
+	milk.Pin = true
+	if err := todoModel.Pin(ownerID, milk); err != nil {
+		t.Errorf("TodoModel.Pin(%q, %#v) == %v, want %v", ownerID, milk, err, nil)
+	}
+	userTodos, _ := remindAll(todoModel)
+	if len(userTodos[memberID]) != 2 || len(userTodos[ownerID]) != 3 || userTodos[memberID][0].Task != "milk" {
+		t.Errorf("TodoModel.Remind() == %#v for %q, want the shared todos", userTodos[memberID], memberID)
+	}
+	if len(userTodos[strangerID]) != 0 {
+		t.Errorf("TodoModel.Remind() == %#v for %q, want nothing", userTodos[strangerID], strangerID)
+	}
+	// Only the users asked for, with the shared todos
+	if userTodos, err := todoModel.Remind([]string{memberID, strangerID}); err != nil || len(userTodos) != 1 || len(userTodos[memberID]) != 2 {
+		t.Errorf("TodoModel.Remind(%q, %q) == %#v, %v, want the shared todos of %q only", memberID, strangerID, userTodos, err, memberID)
+	}
+	if userTodos, err := todoModel.Remind([]string{}); err != nil || len(userTodos) != 0 {
+		t.Errorf("TodoModel.Remind() of nobody == %#v, %v, want none", userTodos, err)
+	}
+
+	// Leave
+	if err := todoModel.LeaveList(ownerID, family); err != ErrForbidden {
+		t.Errorf("TodoModel.LeaveList(%q) == %v, want %v", ownerID, err, ErrForbidden)
+	}
+	if err := todoModel.LeaveList(memberID, family); err != nil {
+		t.Errorf("TodoModel.LeaveList(%q) == %v, want %v", memberID, err, nil)
+	}
+	if err := todoModel.LeaveList(memberID, family); err != ErrForbidden {
+		t.Errorf("TodoModel.LeaveList(%q) again == %v, want %v", memberID, err, ErrForbidden)
+	}
+	if err := todoModel.Pin(memberID, eggs); err != ErrForbidden {
+		t.Errorf("TodoModel.Pin(%q, %#v) after leaving == %v, want %v", memberID, eggs, err, ErrForbidden)
+	}
+	todos, _ = todoModel.List(memberID)
+	if len(todos) != 1 || todos[0].Task != "milk" || todos[0].ListID != 0 {
+		t.Errorf("TodoModel.List(%q) after leaving == %#v, want own %q only in the default list", memberID, todos, "milk")
+	}
+	// The member still edits the todos added to the list and they are not shared anymore
+	if len(todos) == 1 {
+		milk = todos[0]
+		milk.Task = "oat milk"
+		if err := todoModel.Edit(memberID, milk); err != nil {
+			t.Errorf("TodoModel.Edit(%q, %#v) after leaving == %v, want %v", memberID, milk, err, nil)
+		}
+	}
+	if todos, _ := todoModel.List(ownerID); len(todos) != 2 {
+		t.Errorf("TodoModel.List(%q) after %q left == %#v, want without %q", ownerID, memberID, todos, "milk")
+	}
+
+	// Delete stops sharing
+	todoModel.JoinList(memberID, code)
+	if err := todoModel.DeleteList(ownerID, family); err != nil {
+		t.Errorf("TodoModel.DeleteList(%q) == %v, want %v", ownerID, err, nil)
+	}
+	if lists, _ := todoModel.Lists(memberID); len(lists) != 0 {
+		t.Errorf("TodoModel.Lists(%q) after delete == %#v, want none", memberID, lists)
+	}
+	todos, _ = todoModel.List(memberID)
+	if len(todos) != 1 || todos[0].ListID != 0 {
+		t.Errorf("TodoModel.List(%q) after delete == %#v, want %q in the default list", memberID, todos, "milk")
+	}
+}
+
+// testSettingConformance checks the defaults and the overwrite of the settings
+func testSettingConformance(t *testing.T, todoModel TodoModel, userID string) {
+	if setting, err := todoModel.Setting(userID); err != nil || setting != (Setting{UserID: userID}) {
+		t.Errorf("TodoModel.Setting(%q) == %#v, %v, want the defaults", userID, setting, err)
+	}
+	for _, want := range []Setting{{UserID: userID, Language: "th"}, {UserID: userID, Language: "en"}} {
+		if err := todoModel.SaveSetting(want); err != nil {
+			t.Errorf("TodoModel.SaveSetting(%#v) == %v, want %v", want, err, nil)
+		}
+		if setting, err := todoModel.Setting(userID); err != nil || setting != want {
+			t.Errorf("TodoModel.Setting(%q) == %#v, %v, want %#v", userID, setting, err, want)
+		}
+	}
+	reminder := Setting{UserID: userID, Language: "en", RemindTimes: "07:30,19:00", RemindDays: "0,6", QuietStart: "23:00", QuietEnd: "06:30", HideCompleted: true, OnlyOverdue: true,
+		OverdueIntervals: "60,4320", Buddy: "C1", BuddyDays: 3, BuddyCode: NewInviteCode()}
+	if err := todoModel.SaveSetting(reminder); err != nil {
+		t.Errorf("TodoModel.SaveSetting(%#v) == %v, want %v", reminder, err, nil)
+	}
+	if setting, err := todoModel.Setting(userID); err != nil || setting != reminder {
+		t.Errorf("TodoModel.Setting(%q) == %#v, %v, want %#v", userID, setting, err, reminder)
+	}
+	if owner, err := todoModel.BuddyOwner(" " + strings.ToLower(reminder.BuddyCode) + " "); err != nil || owner != userID {
+		t.Errorf("TodoModel.BuddyOwner(%q) == %q, %v, want %q", reminder.BuddyCode, owner, err, userID)
+	}
+	for _, code := range []string{"", NewInviteCode()} {
+		if _, err := todoModel.BuddyOwner(code); err != ErrNotFound {
+			t.Errorf("TodoModel.BuddyOwner(%q) == %v, want %v", code, err, ErrNotFound)
+		}
+	}
+	settings, err := todoModel.Settings([]string{userID, userID + " another"})
+	if err != nil || len(settings) != 2 || settings[userID] != reminder || settings[userID+" another"] != (Setting{UserID: userID + " another"}) {
+		t.Errorf("TodoModel.Settings() == %#v, %v, want the saved one and the defaults", settings, err)
+	}
+	if settings, err := todoModel.Settings(nil); err != nil || len(settings) != 0 {
+		t.Errorf("TodoModel.Settings(nil) == %#v, %v, want none", settings, err)
+	}
+	if err := todoModel.SaveSetting(Setting{UserID: userID, QuietStart: "23:00"}); err != ErrReminder {
+		t.Errorf("TodoModel.SaveSetting() of half the quiet hours == %v, want %v", err, ErrReminder)
+	}
+	if setting, _ := todoModel.Setting(userID + " another"); setting.Language != "" {
+		t.Errorf("TodoModel.Setting(%q) == %#v, want the defaults", userID+" another", setting)
+	}
+
+	// Time zones
+	for _, timeZone := range []string{"Mars/Olympus", "Local", "/etc/localtime"} {
+		if err := todoModel.SaveSetting(Setting{UserID: userID, TimeZone: timeZone}); err != ErrTimeZone {
+			t.Errorf("TodoModel.SaveSetting(%q) == %v, want %v", timeZone, err, ErrTimeZone)
+		}
+	}
+	berlin := Setting{UserID: userID, Language: "en", TimeZone: "Europe/Berlin"}
+	if err := todoModel.SaveSetting(berlin); err != nil {
+		t.Errorf("TodoModel.SaveSetting(%#v) == %v, want %v", berlin, err, nil)
+	}
+	if setting, _ := todoModel.Setting(userID); setting != berlin || setting.Location().String() != "Europe/Berlin" {
+		t.Errorf("TodoModel.Setting(%q) == %#v, want %#v", userID, setting, berlin)
+	}
+	// A daily todo at 09:00 in Berlin before daylight saving ends stays at 09:00 after
+	loc := berlin.Location()
+	due := time.Date(2018, 10, 27, 9, 0, 0, 0, loc)
+	if err := todoModel.Create(Todo{UserID: userID, Task: "berlin", Due: due, Repeat: "FREQ=DAILY"}); err != nil {
+		t.Fatalf("TodoModel.Create() == %v, want %v", err, nil)
+	}
+	todos, _ := todoModel.List(userID)
+	todo, _ := findTodo(todos, "berlin")
+	if !todo.Due.Equal(due) || todo.Due.Location() != time.UTC {
+		t.Errorf("TodoModel.List() due == %v, want %v in UTC", todo.Due, due)
+	}
+	todo.Done = true
+	if err := todoModel.Done(userID, todo); err != nil {
+		t.Errorf("TodoModel.Done() == %v, want %v", err, nil)
+	}
+	todos, _ = todoModel.List(userID)
+	for _, next := range todos {
+		if next.Task == "berlin" && !next.Done {
+			if got := next.Due.In(loc).Format("15:04"); got != "09:00" {
+				t.Errorf("TodoModel.Done() next due at %v in Berlin, want %v", got, "09:00")
+			}
+			todoModel.Delete(userID, next)
+		}
+	}
+	todoModel.Delete(userID, todo)
+}
+
+// testListingConformance checks that a listing replaces the previous one of the user only
+func testListingConformance(t *testing.T, listingModel ListingModel, userID string) {
+	if todoIDs, err := listingModel.Listing(userID); err != nil || len(todoIDs) != 0 {
+		t.Errorf("ListingModel.Listing(%q) == %v, %v, want none", userID, todoIDs, err)
+	}
+	for _, want := range [][]int{{3, 1, 2}, {5}, {}} {
+		if err := listingModel.SaveListing(userID, want); err != nil {
+			t.Errorf("ListingModel.SaveListing(%v) == %v, want %v", want, err, nil)
+		}
+		if todoIDs, err := listingModel.Listing(userID); err != nil || fmt.Sprint(todoIDs) != fmt.Sprint(want) {
+			t.Errorf("ListingModel.Listing(%q) == %v, %v, want %v", userID, todoIDs, err, want)
+		}
+	}
+	listingModel.SaveListing(userID, []int{7, 8})
+	listingModel.SaveListing(userID+" another", []int{9})
+	if todoIDs, _ := listingModel.Listing(userID); fmt.Sprint(todoIDs) != "[7 8]" {
+		t.Errorf("ListingModel.Listing(%q) == %v, want %v", userID, todoIDs, []int{7, 8})
+	}
+	if err := listingModel.Purge(userID); err != nil {
+		t.Errorf("ListingModel.Purge(%q) == %v, want %v", userID, err, nil)
+	}
+	if todoIDs, _ := listingModel.Listing(userID); len(todoIDs) != 0 {
+		t.Errorf("ListingModel.Listing(%q) after Purge() == %v, want none", userID, todoIDs)
+	}
+	if todoIDs, _ := listingModel.Listing(userID + " another"); fmt.Sprint(todoIDs) != "[9]" {
+		t.Errorf("ListingModel.Purge(%q) purged the listing of another user", userID)
+	}
+}
+
+// testDraftConformance checks that a user has one draft at most
+func testDraftConformance(t *testing.T, draftModel DraftModel, userID string) {
+	if _, err := draftModel.Draft(userID); err != ErrNotFound {
+		t.Errorf("DraftModel.Draft(%q) == %v, want %v", userID, err, ErrNotFound)
+	}
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	created := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	for _, text := range []string{"Buy milk", "@Shopping Buy bread #home"} {
+		if err := draftModel.SaveDraft(Draft{UserID: userID, Text: text, Created: created}); err != nil {
+			t.Errorf("DraftModel.SaveDraft(%q) == %v, want %v", text, err, nil)
+		}
+	}
+	draftModel.SaveDraft(Draft{UserID: userID + " another", Text: "Not mine", Created: created})
+	draft, err := draftModel.Draft(userID)
+	if err != nil || draft.Text != "@Shopping Buy bread #home" || !draft.Created.Equal(created) || draft.Created.Location() != time.UTC {
+		t.Errorf("DraftModel.Draft(%q) == %#v, %v", userID, draft, err)
+	}
+	// Replaced by a newer draft
+	if deleted, err := draftModel.DeleteDraft(Draft{UserID: userID, Created: created.Add(-time.Minute)}); deleted || err != nil {
+		t.Errorf("DraftModel.DeleteDraft() of an older draft == %v, %v, want %v", deleted, err, false)
+	}
+	if deleted, err := draftModel.DeleteDraft(draft); !deleted || err != nil {
+		t.Errorf("DraftModel.DeleteDraft(%#v) == %v, %v, want %v", draft, deleted, err, true)
+	}
+	if deleted, err := draftModel.DeleteDraft(draft); deleted || err != nil {
+		t.Errorf("DraftModel.DeleteDraft(%#v) again == %v, %v, want %v", draft, deleted, err, false)
+	}
+	if _, err := draftModel.Draft(userID); err != ErrNotFound {
+		t.Errorf("DraftModel.Draft(%q) after DeleteDraft() == %v, want %v", userID, err, ErrNotFound)
+	}
+	if draft, _ := draftModel.Draft(userID + " another"); draft.Text != "Not mine" {
+		t.Errorf("DraftModel.DeleteDraft(%q) deleted the draft of another user", userID)
+	}
+	if err := draftModel.Purge(userID + " another"); err != nil {
+		t.Errorf("DraftModel.Purge() == %v, want %v", err, nil)
+	}
+	if _, err := draftModel.Draft(userID + " another"); err != ErrNotFound {
+		t.Errorf("DraftModel.Draft() after Purge() == %v, want %v", err, ErrNotFound)
+	}
+}
+
+// testDialogConformance checks that a dialog keeps its answers and that a todo is created pinned when asked
+func testDialogConformance(t *testing.T, models Models, userID string) {
+	todoModel, dialogModel := models.TodoModel, models.DialogModel
+	if _, err := dialogModel.Dialog(userID); err != ErrNotFound {
+		t.Errorf("DialogModel.Dialog(%q) == %v, want %v", userID, err, ErrNotFound)
+	}
+	loc, _ := time.LoadLocation("Asia/Bangkok")
+	updated := time.Date(2018, 11, 15, 10, 0, 0, 0, loc)
+	due := time.Date(2018, 11, 16, 17, 0, 0, 0, loc)
+	want := Dialog{UserID: userID, State: "task", Updated: updated}
+	if err := dialogModel.SaveDialog(want); err != nil {
+		t.Errorf("DialogModel.SaveDialog(%#v) == %v, want %v", want, err, nil)
+	}
+	if dialog, err := dialogModel.Dialog(userID); err != nil || dialog.State != "task" || !dialog.Due.IsZero() || !dialog.Updated.Equal(updated) {
+		t.Errorf("DialogModel.Dialog(%q) == %#v, %v", userID, dialog, err)
+	}
+	want = Dialog{UserID: userID, State: "pin", Text: "@Shopping Buy milk #home", Due: due, Repeat: "FREQ=WEEKLY;BYDAY=FR", Updated: updated.Add(time.Minute)}
+	dialogModel.SaveDialog(want)
+	dialog, err := dialogModel.Dialog(userID)
+	if err != nil || dialog.State != "pin" || dialog.Text != want.Text || !dialog.Due.Equal(due) || dialog.Due.Location() != time.UTC || dialog.Repeat != want.Repeat || !dialog.Updated.Equal(want.Updated) {
+		t.Errorf("DialogModel.Dialog(%q) == %#v, %v, want %#v", userID, dialog, err, want)
+	}
+	dialogModel.DeleteDialog(userID)
+	if _, err := dialogModel.Dialog(userID); err != ErrNotFound {
+		t.Errorf("DialogModel.Dialog(%q) after DeleteDialog() == %v, want %v", userID, err, ErrNotFound)
+	}
+	dialogModel.SaveDialog(want)
+	if err := dialogModel.Purge(userID); err != nil {
+		t.Errorf("DialogModel.Purge(%q) == %v, want %v", userID, err, nil)
+	}
+	if _, err := dialogModel.Dialog(userID); err != ErrNotFound {
+		t.Errorf("DialogModel.Dialog(%q) after Purge() == %v, want %v", userID, err, ErrNotFound)
+	}
+
+	if err := todoModel.Create(Todo{UserID: userID, Task: "pinned task", Pin: true, Due: due}); err != nil {
+		t.Fatalf("TodoModel.Create() == %v, want %v", err, nil)
+	}
+	todos, _ := todoModel.List(userID)
+	if todo, ok := findTodo(todos, "pinned task"); !ok || !todo.Pin {
+		t.Errorf("TodoModel.Create() of a pinned todo == %#v", todo)
+	}
+}
+
+// testInactiveConformance checks that inactive users get no reminders and that a purge leaves nothing of them
+func testInactiveConformance(t *testing.T, todoModel TodoModel, userID string) {
+	ownerID := userID + " inactive owner"
+	memberID := userID + " inactive member"
+	due := time.Now().Add(time.Hour).Truncate(time.Second)
+	todoModel.CreateList(TodoList{UserID: ownerID, Name: "Chores"})
+	chores, _ := todoModel.FindList(ownerID, "Chores")
+	code, _ := todoModel.ShareList(ownerID, chores)
+	todoModel.JoinList(memberID, code)
+	todoModel.Create(Todo{UserID: ownerID, Task: "laundry", Due: due, ListID: chores.ID, Tags: []string{"home"}})
+	todoModel.Create(Todo{UserID: memberID, Task: "dishes", Due: due, ListID: chores.ID})
+	todos, _ := todoModel.List(ownerID)
+	laundry, _ := findTodo(todos, "laundry")
+	todoModel.AddStep(ownerID, Step{TodoID: laundry.ID, Task: "fold"})
+	todoModel.SaveSetting(Setting{UserID: ownerID, Language: "th"})
+
+	// Deactivate
+	since := time.Date(2018, 11, 15, 10, 0, 0, 0, time.UTC)
+	if err := todoModel.Deactivate(memberID, since); err != nil {
+		t.Errorf("TodoModel.Deactivate(%q) == %v, want %v", memberID, err, nil)
+	}
+	userTodos, _ := remindAll(todoModel)
+	if _, ok := userTodos[memberID]; ok || len(userTodos[ownerID]) != 2 {
+		t.Errorf("TodoModel.Remind() with %q inactive == %v", memberID, userTodos)
+	}
+
+	// Activate
+	if back, err := todoModel.Activate(memberID); !back || err != nil {
+		t.Errorf("TodoModel.Activate(%q) == %v, %v, want %v", memberID, back, err, true)
+	}
+	if back, err := todoModel.Activate(memberID); back || err != nil {
+		t.Errorf("TodoModel.Activate(%q) again == %v, %v, want %v", memberID, back, err, false)
+	}
+	if userTodos, _ := remindAll(todoModel); len(userTodos[memberID]) != 2 {
+		t.Errorf("TodoModel.Remind() with %q active again == %v", memberID, userTodos[memberID])
+	}
+
+	// Retention
+	todoModel.Deactivate(ownerID, since)
+	for _, c := range []struct {
+		before time.Time
+		want   bool
+	}{
+		{since, false},
+		{since.Add(time.Second), true},
+	} {
+		userIDs, err := todoModel.InactiveUsers(c.before)
+		found := false
+		for _, id := range userIDs {
+			found = found || id == ownerID
+		}
+		if err != nil || found != c.want {
+			t.Errorf("TodoModel.InactiveUsers(%v) == %v, %v, want %q %v", c.before, userIDs, err, ownerID, c.want)
+		}
+	}
+
+	// Purge
+	if err := todoModel.Purge(ownerID); err != nil {
+		t.Errorf("TodoModel.Purge(%q) == %v, want %v", ownerID, err, nil)
+	}
+	if todos, _ := todoModel.List(ownerID); len(todos) != 0 {
+		t.Errorf("TodoModel.List(%q) after Purge() == %#v", ownerID, todos)
+	}
+	if lists, _ := todoModel.Lists(memberID); len(lists) != 0 {
+		t.Errorf("TodoModel.Lists(%q) after Purge() == %#v", memberID, lists)
+	}
+	todos, _ = todoModel.List(memberID)
+	if dishes, ok := findTodo(todos, "dishes"); !ok || dishes.ListID != 0 || len(todos) != 1 {
+		t.Errorf("TodoModel.List(%q) after Purge() == %#v", memberID, todos)
+	}
+	if setting, _ := todoModel.Setting(ownerID); setting.Language != "" {
+		t.Errorf("TodoModel.Setting(%q) after Purge() == %#v", ownerID, setting)
+	}
+	if userIDs, _ := todoModel.InactiveUsers(time.Now()); len(userIDs) != 0 {
+		t.Errorf("TodoModel.InactiveUsers() after Purge() == %v", userIDs)
+	}
+}
+
+// testJobRunConformance checks that only one instance claims each run of a job
+func testJobRunConformance(t *testing.T, jobRunModel JobRunModel, userID string) {
+	name := "job " + userID
+	if last, err := jobRunModel.LastRun(name); err != nil || !last.IsZero() {
+		t.Errorf("JobRunModel.LastRun(%q) == %v, %v, want zero", name, last, err)
+	}
+	first := time.Date(2018, 11, 15, 1, 0, 0, 0, time.UTC)
+	second := first.Add(15 * time.Minute)
+	for _, c := range []struct {
+		last time.Time
+		run  time.Time
+		want bool
+	}{
+		{time.Time{}, first, true},
+		// Another instance started at the same time
+		{time.Time{}, first, false},
+		{first, second, true},
+		{first, second.Add(time.Second), false},
+	} {
+		if claimed, err := jobRunModel.ClaimRun(name, c.last, c.run); claimed != c.want || err != nil {
+			t.Errorf("JobRunModel.ClaimRun(%q, %v, %v) == %v, %v, want %v", name, c.last, c.run, claimed, err, c.want)
+		}
+	}
+	if last, err := jobRunModel.LastRun(name); err != nil || !last.Equal(second) || last.Location() != time.UTC {
+		t.Errorf("JobRunModel.LastRun(%q) == %v, %v, want %v", name, last, err, second)
+	}
+}
+
+// testAlertConformance checks the lead times and that each alert is claimed by one instance until it is sent or released
+func testAlertConformance(t *testing.T, alertModel AlertModel, userID string) {
+	if todoLeads, err := alertModel.AlertLeads(userID); err != nil || len(todoLeads) != 0 {
+		t.Errorf("AlertModel.AlertLeads(%q) == %v, %v, want none", userID, todoLeads, err)
+	}
+	alertModel.SaveAlertLeads(userID, 0, []time.Duration{})
+	alertModel.SaveAlertLeads(userID, 7, []time.Duration{time.Hour, 24 * time.Hour})
+	alertModel.SaveAlertLeads(userID, 8, []time.Duration{time.Hour})
+	alertModel.DeleteAlertLeads(userID, 8)
+	if todoLeads, err := alertModel.AlertLeads(userID); err != nil || fmt.Sprint(todoLeads) != "map[0:[] 7:[1h0m0s 24h0m0s]]" {
+		t.Errorf("AlertModel.AlertLeads(%q) == %v, %v", userID, todoLeads, err)
+	}
+	userLeads, err := alertModel.AlertLeadsByUser([]string{userID, userID + " another"})
+	if err != nil || len(userLeads) != 1 || fmt.Sprint(userLeads[userID]) != "map[0:[] 7:[1h0m0s 24h0m0s]]" {
+		t.Errorf("AlertModel.AlertLeadsByUser() == %v, %v, want the ones of %q only", userLeads, err, userID)
+	}
+
+	due := time.Date(2018, 11, 15, 9, 0, 0, 0, time.UTC)
+	alerts := []Alert{
+		{UserID: userID, TodoID: 7, Due: due, Lead: time.Hour},
+		{UserID: userID, TodoID: 7, Due: due, Lead: 15 * time.Minute},
+	}
+	for i := 0; i < 2; i++ {
+		// Scheduled again by the next run
+		if err := alertModel.ScheduleAlerts(alerts); err != nil {
+			t.Fatalf("AlertModel.ScheduleAlerts() == %v, want %v", err, nil)
+		}
+	}
+	pending, err := alertModel.PendingAlerts(due.Add(-30 * time.Minute))
+	if err != nil || len(pending) != 1 || pending[0].Lead != time.Hour || !pending[0].At.Equal(due.Add(-time.Hour)) || pending[0].At.Location() != time.UTC {
+		t.Fatalf("AlertModel.PendingAlerts() == %#v, %v, want the alert an hour before", pending, err)
+	}
+	claimedAt := due.Add(-30 * time.Minute)
+	if claimed, err := alertModel.ClaimAlert(pending[0].ID, claimedAt); !claimed || err != nil {
+		t.Errorf("AlertModel.ClaimAlert() == %v, %v, want %v", claimed, err, true)
+	}
+	if claimed, err := alertModel.ClaimAlert(pending[0].ID, claimedAt); claimed || err != nil {
+		t.Errorf("AlertModel.ClaimAlert() again == %v, %v, want %v", claimed, err, false)
+	}
+	if pending, err := alertModel.PendingAlerts(claimedAt); err != nil || len(pending) != 0 {
+		t.Errorf("AlertModel.PendingAlerts() of a claimed alert == %#v, %v, want none", pending, err)
+	}
+	// A failed push
+	if err := alertModel.ReleaseAlert(pending[0].ID); err != nil {
+		t.Errorf("AlertModel.ReleaseAlert() == %v, want %v", err, nil)
+	}
+	if pending, err := alertModel.PendingAlerts(claimedAt); err != nil || len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Errorf("AlertModel.PendingAlerts() of a released alert == %#v, %v, want it with an attempt", pending, err)
+	}
+	// An instance stopped while pushing
+	alertModel.ClaimAlert(pending[0].ID, claimedAt)
+	if claimed, err := alertModel.ClaimAlert(pending[0].ID, claimedAt.Add(alertClaimTimeout+time.Second)); !claimed || err != nil {
+		t.Errorf("AlertModel.ClaimAlert() after the claim timeout == %v, %v, want %v", claimed, err, true)
+	}
+	if err := alertModel.SendAlert(pending[0].ID, claimedAt); err != nil {
+		t.Errorf("AlertModel.SendAlert() == %v, want %v", err, nil)
+	}
+	if claimed, err := alertModel.ClaimAlert(pending[0].ID, due); claimed || err != nil {
+		t.Errorf("AlertModel.ClaimAlert() of a sent alert == %v, %v, want %v", claimed, err, false)
+	}
+	alertModel.ScheduleAlerts(alerts)
+	if pending, err := alertModel.PendingAlerts(due); err != nil || len(pending) != 1 || pending[0].Lead != 15*time.Minute {
+		t.Errorf("AlertModel.PendingAlerts() after sending == %#v, %v, want the alert 15 minutes before", pending, err)
+	}
+
+	alertModel.DeleteAlerts(due.Add(-20 * time.Minute))
+	if pending, err := alertModel.PendingAlerts(due); err != nil || len(pending) != 1 {
+		t.Errorf("AlertModel.PendingAlerts() after DeleteAlerts() == %#v, %v", pending, err)
+	}
+	alertModel.DeleteAlerts(due)
+	if pending, err := alertModel.PendingAlerts(due); err != nil || len(pending) != 0 {
+		t.Errorf("AlertModel.PendingAlerts() after DeleteAlerts() == %#v, %v, want none", pending, err)
+	}
+	alertModel.ScheduleAlerts(alerts)
+	alertModel.ScheduleAlerts([]Alert{{UserID: userID + " another", TodoID: 8, Due: due, Lead: time.Hour}})
+	if err := alertModel.Purge(userID); err != nil {
+		t.Errorf("AlertModel.Purge(%q) == %v, want %v", userID, err, nil)
+	}
+	if todoLeads, err := alertModel.AlertLeads(userID); err != nil || len(todoLeads) != 0 {
+		t.Errorf("AlertModel.AlertLeads(%q) after Purge() == %v, %v, want none", userID, todoLeads, err)
+	}
+	if pending, err := alertModel.PendingAlerts(due); err != nil || len(pending) != 1 || pending[0].UserID != userID+" another" {
+		t.Errorf("AlertModel.PendingAlerts() after Purge() == %#v, %v, want the alert of another user", pending, err)
+	}
+}
+
+func testSnoozeConformance(t *testing.T, todoModel TodoModel, userID string) {
+	todos, _ := todoModel.List(userID)
+	todo := todos[0]
+	if !todo.Snoozed.IsZero() {
+		t.Errorf("TodoModel.List() snoozed == %v, want zero", todo.Snoozed)
+	}
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	until := time.Date(2018, 11, 16, 9, 0, 0, 0, bangkok)
+	todo.Snoozed = until
+	if err := todoModel.Snooze(userID, todo); err != nil {
+		t.Errorf("TodoModel.Snooze() == %v, want %v", err, nil)
+	}
+	// The due date and the snooze are kept by an edit
+	todo.Snoozed = time.Time{}
+	todo.Task = "snoozed task"
+	todoModel.Edit(userID, todo)
+	todos, _ = todoModel.List(userID)
+	snoozed, _ := findTodo(todos, "snoozed task")
+	if !snoozed.Snoozed.Equal(until) || snoozed.Snoozed.Location() != time.UTC || !snoozed.Due.Equal(todo.Due) {
+		t.Errorf("TodoModel.Snooze() snoozed == %v, due == %v, want %v, %v", snoozed.Snoozed, snoozed.Due, until, todo.Due)
+	}
+	if !snoozed.IsSnoozed(until.Add(-time.Minute)) || snoozed.IsSnoozed(until) {
+		t.Errorf("Todo.IsSnoozed() is not until %v", until)
+	}
+	userTodos, _ := remindAll(todoModel)
+	if reminded, _ := findTodo(userTodos[userID], "snoozed task"); !reminded.Snoozed.Equal(until) {
+		t.Errorf("TodoModel.Remind() snoozed == %v, want %v", reminded.Snoozed, until)
+	}
+
+	// Woken up
+	if err := todoModel.Snooze(userID, todo); err != nil {
+		t.Errorf("TodoModel.Snooze() with zero == %v, want %v", err, nil)
+	}
+	todos, _ = todoModel.List(userID)
+	if woken, _ := findTodo(todos, "snoozed task"); !woken.Snoozed.IsZero() {
+		t.Errorf("TodoModel.Snooze() with zero snoozed == %v, want zero", woken.Snoozed)
+	}
+	if err := todoModel.Snooze(userID, Todo{ID: 9999, Snoozed: until}); err != ErrNotFound {
+		t.Errorf("TodoModel.Snooze() of an unknown todo == %v, want %v", err, ErrNotFound)
+	}
+}
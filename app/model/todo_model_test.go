@@ -16,88 +16,78 @@ func (a AnyTime) Match(v driver.Value) bool {
 	return ok
 }
 
-func TestTodoMySqlModelCreate(t *testing.T) {
+func TestTodoSqlModelCreate(t *testing.T) {
 	wantErr := errors.New("Dummy error")
-	// No table
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatal(err)
-	}
-
 	todo := Todo{
 		UserID: "dummy user",
 		Task:   "dummy task",
 		Due:    time.Now(),
 	}
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnError(wantErr)
-	mock.ExpectExec("CREATE TABLE todo").WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", AnyTime{}).WillReturnResult(sqlmock.NewResult(1, 1))
-	model := TodoMySqlModel{
-		db: db,
+	// Success
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", false, AnyTime{}, "", 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
 	err = model.Create(todo)
 	if err != nil {
-		t.Errorf("Result TodoMySqlModel.Create(%#v) == %#v, want %#v", todo, err, nil)
+		t.Errorf("Result TodoSqlModel.Create(%#v) == %#v, want %#v", todo, err, nil)
 	}
-	// No table but error when create table
+	// Error when insert row
 	db, mock, err = sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnError(wantErr)
-	mock.ExpectExec("CREATE TABLE todo").WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", false, AnyTime{}, "", 0).WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
 	err = model.Create(todo)
 	if err == nil {
-		t.Errorf("Result TodoMySqlModel.Create(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Create(%#v) == %#v, want %#v", todo, err, wantErr)
 	}
-	// Table already exist
+	// No row affected
 	db, mock, err = sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", AnyTime{}).WillReturnResult(sqlmock.NewResult(1, 1))
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", false, AnyTime{}, "", 0).WillReturnResult(sqlmock.NewResult(1, 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
 	err = model.Create(todo)
-	if err != nil {
-		t.Errorf("Result TodoMySqlModel.Create(%#v) == %#v, want %#v", todo, err, nil)
+	wantErr = errors.New("No record")
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.Create(%#v) == %#v, want %#v", todo, err, nil)
 	}
-	// Table already exist but error when insert row
+	// With tags
+	todo.Tags = []string{"#Home", "work"}
 	db, mock, err = sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", AnyTime{}).WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", false, AnyTime{}, "", 0).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM todo_tag").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO todo_tag").WithArgs(7, "home").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO todo_tag").WithArgs(7, "work").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
 	err = model.Create(todo)
-	if err == nil {
-		t.Errorf("Result TodoMySqlModel.Create(%#v) == %#v, want %#v", todo, err, wantErr)
-	}
-	// Table already exist but no row affected
-	db, mock, err = sqlmock.New()
 	if err != nil {
-		t.Fatal(err)
+		t.Errorf("Result TodoSqlModel.Create(%#v) == %#v, want %#v", todo, err, nil)
 	}
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "dummy task", AnyTime{}).WillReturnResult(sqlmock.NewResult(1, 0))
-	model = TodoMySqlModel{
-		db: db,
-	}
-	err = model.Create(todo)
-	wantErr = errors.New("No record")
-	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Create(%#v) == %#v, want %#v", todo, err, nil)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Result TodoSqlModel.Create(%#v) %v", todo, err)
 	}
 }
 
@@ -109,7 +99,7 @@ func TestNewTodoMySqlModel(t *testing.T) {
 
 }
 
-func TestTodoMySqlModelList(t *testing.T) {
+func TestTodoSqlModelList(t *testing.T) {
 	wantErr := errors.New("Dummy error")
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -117,85 +107,97 @@ func TestTodoMySqlModelList(t *testing.T) {
 	}
 
 	//Success
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectQuery("SELECT id, task, done, pin, due FROM todo WHERE user_id=?").WithArgs("dummy user").WillReturnRows(
+	snoozed := time.Date(2018, 11, 15, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, user_id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
 		sqlmock.NewRows([]string{
 			"id",
+			"user_id",
 			"task",
 			"done",
 			"pin",
 			"due",
+			"repeat_rule",
+			"list_id",
+			"snoozed",
 		}).AddRow(
 			1,
+			"dummy user",
 			"task",
 			false,
 			true,
 			time.Now(),
+			"",
+			0,
+			snoozed,
 		))
-	model := TodoMySqlModel{
-		db: db,
-	}
-	_, err = model.List("dummy user")
+	mock.ExpectQuery("SELECT step.id, step.todo_id, step.task, step.done, step.position FROM step").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "todo_id", "task", "done", "position"}).AddRow(1, 1, "step", true, 1).AddRow(2, 1, "step", false, 2))
+	mock.ExpectQuery("SELECT todo_tag.todo_id, todo_tag.tag FROM todo_tag").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"todo_id", "tag"}).AddRow(1, "home").AddRow(1, "work"))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	todos, err := model.List("dummy user")
 	if err != nil {
-		t.Errorf("Result TodoMySqlModel.List(%q) == %v, want %v", "dummy user", err, nil)
+		t.Errorf("Result TodoSqlModel.List(%q) == %v, want %v", "dummy user", err, nil)
 	}
-
-	//No Table
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectQuery("SELECT id, task, done, pin, due FROM todo WHERE user_id=?").WithArgs("dummy user").WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+	if done, total := todos[0].Progress(); done != 1 || total != 2 {
+		t.Errorf("Result TodoSqlModel.List(%q) progress == %d/%d, want %d/%d", "dummy user", done, total, 1, 2)
 	}
-	_, err = model.List("dummy user")
-	if err == nil {
-		t.Errorf("Result TodoMySqlModel.List(%q) == %v, want %v", "dummy user", err, wantErr)
+	if len(todos[0].Tags) != 2 || !todos[0].HasTag("work") {
+		t.Errorf("Result TodoSqlModel.List(%q) tags == %v, want %v", "dummy user", todos[0].Tags, []string{"home", "work"})
 	}
-
-	// No table but error when create table
-	db, mock, err = sqlmock.New()
-	if err != nil {
-		t.Fatal(err)
+	if !todos[0].Snoozed.Equal(snoozed) {
+		t.Errorf("Result TodoSqlModel.List(%q) snoozed == %v, want %v", "dummy user", todos[0].Snoozed, snoozed)
 	}
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnError(wantErr)
-	mock.ExpectExec("CREATE TABLE todo").WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+
+	// Error from query
+	mock.ExpectQuery("SELECT id, user_id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE").WithArgs("dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
 	_, err = model.List("dummy user")
 	if err == nil {
-		t.Errorf("Result TodoMySqlModel.List(%q) == %#v, want %#v", "dummy user", err, wantErr)
+		t.Errorf("Result TodoSqlModel.List(%q) == %v, want %v", "dummy user", err, wantErr)
 	}
 
 	//Wrong col type
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectQuery("SELECT id, task, done, pin, due FROM todo WHERE user_id=?").WithArgs("dummy user").WillReturnRows(
+	mock.ExpectQuery("SELECT id, user_id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
 		sqlmock.NewRows([]string{
 			"id",
+			"user_id",
 			"task",
 			"done",
 			"pin",
 			"due",
+			"repeat_rule",
+			"list_id",
+			"snoozed",
 		}).AddRow(
 			1,
+			"dummy user",
 			"task",
 			false,
 			true,
 			"wrong date",
+			"",
+			0,
+			nil,
 		))
-	model = TodoMySqlModel{
-		db: db,
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
 	_, err = model.List("dummy user")
-	wantErr = errors.New(`sql: Scan error on column index 4, name "due": unsupported Scan, storing driver.Value type string into type *time.Time`)
+	wantErr = errors.New(`sql: Scan error on column index 5, name "due": unsupported Scan, storing driver.Value type string into type *time.Time`)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.List(%q) == %v, want %v", "dummy user", err, wantErr)
+		t.Errorf("Result TodoSqlModel.List(%q) == %v, want %v", "dummy user", err, wantErr)
 	}
 }
 
-func TestTodoMySqlModelPin(t *testing.T) {
+func TestTodoSqlModelPin(t *testing.T) {
 	wantErr := errors.New("dummy error")
 	// Success
 	todo := Todo{
@@ -206,26 +208,28 @@ func TestTodoMySqlModelPin(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1).WillReturnResult(sqlmock.NewResult(1, 1))
-	model := TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Pin(todo)
+	err = model.Pin("dummy user", todo)
 	if err != nil {
-		t.Errorf("Result TodoMySqlModel.Pin(%#v) == %#v, want %#v", todo, err, nil)
+		t.Errorf("Result TodoSqlModel.Pin(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
 	}
 	// Error when insert row
 	db, mock, err = sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1).WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Pin(todo)
+	err = model.Pin("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Pin(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Pin(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
 	}
 	// No row affected
 	wantErr = errors.New("No record")
@@ -233,17 +237,53 @@ func TestTodoMySqlModelPin(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1).WillReturnResult(sqlmock.NewResult(1, 0))
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Pin("dummy user", todo)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.Pin(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
+	}
+	// Owned by another user
+	wantErr = errors.New("Forbidden")
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("another user", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Pin(todo)
+	err = model.Pin("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Pin(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Pin(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
+	}
+	// Owned but nothing changed
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo SET pin=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("dummy user", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Pin("dummy user", todo)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.Pin(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
 	}
 }
 
-func TestTodoMySqlModelDone(t *testing.T) {
+func TestTodoSqlModelDone(t *testing.T) {
 	wantErr := errors.New("dummy error")
 	// Success
 	todo := Todo{
@@ -254,26 +294,30 @@ func TestTodoMySqlModelDone(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1).WillReturnResult(sqlmock.NewResult(1, 1))
-	model := TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT user_id, task, pin, due, repeat_rule, list_id FROM todo WHERE id=?").WithArgs(1, "dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "task", "pin", "due", "repeat_rule", "list_id"}).AddRow("dummy user", "task", false, time.Now(), "", 0))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Done(todo)
+	err = model.Done("dummy user", todo)
 	if err != nil {
-		t.Errorf("Result TodoMySqlModel.Done(%#v) == %#v, want %#v", todo, err, nil)
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
 	}
 	// Error when insert row
 	db, mock, err = sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1).WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Done(todo)
+	err = model.Done("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Done(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
 	}
 	// No row affected
 	wantErr = errors.New("No record")
@@ -281,17 +325,106 @@ func TestTodoMySqlModelDone(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1).WillReturnResult(sqlmock.NewResult(1, 0))
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Done(todo)
+	err = model.Done("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Done(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
+	}
+	// Owned by another user
+	wantErr = errors.New("Forbidden")
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("another user", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Done("dummy user", todo)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
+	}
+	// Owned but nothing changed
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("dummy user", 0))
+	mock.ExpectQuery("SELECT user_id, task, pin, due, repeat_rule, list_id FROM todo WHERE id=?").WithArgs(1, "dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "task", "pin", "due", "repeat_rule", "list_id"}).AddRow("dummy user", "task", false, time.Now(), "", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Done("dummy user", todo)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
+	}
+	// Repeating todo spawns the next occurrence
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT user_id, task, pin, due, repeat_rule, list_id FROM todo WHERE id=?").WithArgs(1, "dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "task", "pin", "due", "repeat_rule", "list_id"}).AddRow("dummy user", "task", true, time.Now(), "FREQ=DAILY", 3))
+	mock.ExpectQuery("SELECT language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id=?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"language", "time_zone"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE todo SET repeat_rule=''").WithArgs(1, "FREQ=DAILY").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO todo").WithArgs("dummy user", "task", true, AnyTime{}, "FREQ=DAILY", 3).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO step").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(3, 2))
+	mock.ExpectExec("INSERT INTO todo_tag").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Done("dummy user", todo)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) %v", "dummy user", todo, err)
+	}
+	// Already spawned by someone else
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo SET done=?").WithArgs(true, 1, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT user_id, task, pin, due, repeat_rule, list_id FROM todo WHERE id=?").WithArgs(1, "dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "task", "pin", "due", "repeat_rule", "list_id"}).AddRow("dummy user", "task", true, time.Now(), "FREQ=DAILY", 3))
+	mock.ExpectQuery("SELECT language, time_zone, remind_times, remind_days, quiet_start, quiet_end, hide_completed, only_overdue, overdue_intervals, buddy, buddy_days, buddy_code FROM user_setting WHERE user_id=?").WithArgs("dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"language", "time_zone"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE todo SET repeat_rule=''").WithArgs(1, "FREQ=DAILY").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectRollback()
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Done("dummy user", todo)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Result TodoSqlModel.Done(%q, %#v) %v", "dummy user", todo, err)
 	}
 }
 
-func TestTodoMySqlModelRemind(t *testing.T) {
+func TestTodoSqlModelRemind(t *testing.T) {
 	wantErr := errors.New("Dummy error")
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -299,9 +432,7 @@ func TestTodoMySqlModelRemind(t *testing.T) {
 	}
 
 	//Success
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectQuery("SELECT user_id, id, task, done, pin, due FROM todo ORDER BY user_id, done, pin DESC, due").WillReturnRows(
+	mock.ExpectQuery("SELECT user_id, id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE user_id IN").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
 		sqlmock.NewRows([]string{
 			"user_id",
 			"id",
@@ -309,6 +440,9 @@ func TestTodoMySqlModelRemind(t *testing.T) {
 			"done",
 			"pin",
 			"due",
+			"repeat_rule",
+			"list_id",
+			"snoozed",
 		}).AddRow(
 			"dummy user",
 			1,
@@ -316,45 +450,40 @@ func TestTodoMySqlModelRemind(t *testing.T) {
 			false,
 			true,
 			time.Now(),
+			"",
+			0,
+			nil,
 		))
-	model := TodoMySqlModel{
-		db: db,
-	}
-	_, err = model.Remind()
-	if err != nil {
-		t.Errorf("Result TodoMySqlModel.Remind() == %v, want %v", err, nil)
+	mock.ExpectQuery("SELECT id, todo_id, task, done, position FROM step WHERE todo_id IN").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "todo_id", "task", "done", "position"}).AddRow(1, 1, "step", false, 1))
+	mock.ExpectQuery("SELECT todo_id, tag FROM todo_tag WHERE todo_id IN").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
+		sqlmock.NewRows([]string{"todo_id", "tag"}).AddRow(1, "work"))
+	mock.ExpectQuery("SELECT todo_list.id, todo_list.user_id FROM todo_list").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "user_id"}))
+	mock.ExpectQuery("SELECT user_id FROM inactive_user").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	userTodos, err := model.Remind([]string{"dummy user"})
+	if err != nil || len(userTodos["dummy user"]) != 1 || len(userTodos["dummy user"][0].Steps) != 1 {
+		t.Errorf("Result TodoSqlModel.Remind(%q) == %v, want %v", "dummy user", err, nil)
 	}
 
-	//No Table
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectQuery("SELECT user_id, id, task, done, pin, due FROM todo ORDER BY user_id, done, pin DESC, due").WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
-	}
-	_, err = model.Remind()
-	if err == nil {
-		t.Errorf("Result TodoMySqlModel.Remind() == %v, want %v", err, wantErr)
+	// Error from query
+	mock.ExpectQuery("SELECT user_id, id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE user_id IN").WithArgs("dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-
-	// No table but error when create table
-	db, mock, err = sqlmock.New()
-	if err != nil {
-		t.Fatal(err)
-	}
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnError(wantErr)
-	mock.ExpectExec("CREATE TABLE todo").WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
-	}
-	_, err = model.Remind()
+	_, err = model.Remind([]string{"dummy user"})
 	if err == nil {
-		t.Errorf("Result TodoMySqlModel.Remind() == %#v, want %#v", err, wantErr)
+		t.Errorf("Result TodoSqlModel.Remind(%q) == %v, want %v", "dummy user", err, wantErr)
 	}
+
 	//Wrong col type
-	mock.ExpectQuery("SELECT 1 FROM todo LIMIT 1").WillReturnRows(
-		sqlmock.NewRows([]string{"dummy_col"}).AddRow("1"))
-	mock.ExpectQuery("SELECT user_id, id, task, done, pin, due FROM todo ORDER BY user_id, done, pin DESC, due").WillReturnRows(
+	mock.ExpectQuery("SELECT user_id, id, task, done, pin, due, repeat_rule, list_id, snoozed FROM todo WHERE user_id IN").WithArgs("dummy user", "dummy user", "dummy user").WillReturnRows(
 		sqlmock.NewRows([]string{
 			"user_id",
 			"id",
@@ -362,6 +491,9 @@ func TestTodoMySqlModelRemind(t *testing.T) {
 			"done",
 			"pin",
 			"due",
+			"repeat_rule",
+			"list_id",
+			"snoozed",
 		}).AddRow(
 			"dummy user",
 			1,
@@ -369,49 +501,83 @@ func TestTodoMySqlModelRemind(t *testing.T) {
 			false,
 			true,
 			"wrong date",
+			"",
+			0,
+			nil,
 		))
-	model = TodoMySqlModel{
-		db: db,
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	_, err = model.Remind()
+	_, err = model.Remind([]string{"dummy user"})
 	wantErr = errors.New(`sql: Scan error on column index 5, name "due": unsupported Scan, storing driver.Value type string into type *time.Time`)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Remind() == %v, want %v", err, wantErr)
+		t.Errorf("Result TodoSqlModel.Remind(%q) == %v, want %v", "dummy user", err, wantErr)
 	}
 }
 
-func TestTodoMySqlModelEdit(t *testing.T) {
+func TestTodoSqlModelRemindUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT user_id FROM \\( SELECT user_id FROM todo UNION").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}).AddRow("dummy user").AddRow("dummy member"))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	if userIDs, err := model.RemindUsers(); err != nil || len(userIDs) != 2 {
+		t.Errorf("Result TodoSqlModel.RemindUsers() == %v, %v, want %d users", userIDs, err, 2)
+	}
+	// Nobody to remind
+	if userTodos, err := model.Remind([]string{}); err != nil || len(userTodos) != 0 {
+		t.Errorf("Result TodoSqlModel.Remind() == %v, %v, want none", userTodos, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Result TodoSqlModel.RemindUsers() %v", err)
+	}
+}
+
+func TestTodoSqlModelEdit(t *testing.T) {
 	wantErr := errors.New("dummy error")
 	// Success
 	todo := Todo{
 		ID:   1,
 		Task: "dummy",
 		Due:  time.Now(),
+		Tags: []string{"#Work"},
 	}
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due, todo.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-	model := TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due.UTC(), todo.Repeat, todo.ListID, todo.ID, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM todo_tag").WithArgs(todo.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO todo_tag").WithArgs(todo.ID, "work").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Edit(todo)
+	err = model.Edit("dummy user", todo)
 	if err != nil {
-		t.Errorf("Result TodoMySqlModel.Edit(%#v) == %#v, want %#v", todo, err, nil)
+		t.Errorf("Result TodoSqlModel.Edit(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
 	}
 	// Error when insert row
 	db, mock, err = sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due, todo.ID).WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due.UTC(), todo.Repeat, todo.ListID, todo.ID, "dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Edit(todo)
+	err = model.Edit("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Edit(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Edit(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
 	}
 	// No row affected
 	wantErr = errors.New("No record")
@@ -419,17 +585,57 @@ func TestTodoMySqlModelEdit(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due, todo.ID).WillReturnResult(sqlmock.NewResult(1, 0))
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due.UTC(), todo.Repeat, todo.ListID, todo.ID, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Edit("dummy user", todo)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.Edit(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
+	}
+	// Owned by another user
+	wantErr = errors.New("Forbidden")
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due.UTC(), todo.Repeat, todo.ListID, todo.ID, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("another user", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Edit(todo)
+	err = model.Edit("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Edit(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Edit(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
+	}
+	// Owned but nothing changed
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("UPDATE todo").WithArgs(todo.Task, todo.Due.UTC(), todo.Repeat, todo.ListID, todo.ID, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("dummy user", 0))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM todo_tag").WithArgs(todo.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO todo_tag").WithArgs(todo.ID, "work").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Edit("dummy user", todo)
+	if err != nil {
+		t.Errorf("Result TodoSqlModel.Edit(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
 	}
 }
 
-func TestTodoMySqlModelDelete(t *testing.T) {
+func TestTodoSqlModelDelete(t *testing.T) {
 	wantErr := errors.New("dummy error")
 	// Success
 	todo := Todo{
@@ -439,26 +645,30 @@ func TestTodoMySqlModelDelete(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("DELETE FROM todo").WithArgs(todo.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-	model := TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("DELETE FROM todo").WithArgs(todo.ID, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM step").WithArgs(todo.ID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM todo_tag").WithArgs(todo.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	model := TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Delete(todo)
+	err = model.Delete("dummy user", todo)
 	if err != nil {
-		t.Errorf("Result TodoMySqlModel.Delete(%#v) == %#v, want %#v", todo, err, nil)
+		t.Errorf("Result TodoSqlModel.Delete(%q, %#v) == %#v, want %#v", "dummy user", todo, err, nil)
 	}
 	// Error when insert row
 	db, mock, err = sqlmock.New()
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("DELETE FROM todo").WithArgs(todo.ID).WillReturnError(wantErr)
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("DELETE FROM todo").WithArgs(todo.ID, "dummy user", "dummy user", "dummy user").WillReturnError(wantErr)
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Delete(todo)
+	err = model.Delete("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Delete(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Delete(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
 	}
 	// No row affected
 	wantErr = errors.New("No record")
@@ -466,12 +676,32 @@ func TestTodoMySqlModelDelete(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	mock.ExpectExec("DELETE FROM todo").WithArgs(todo.ID).WillReturnResult(sqlmock.NewResult(1, 0))
-	model = TodoMySqlModel{
-		db: db,
+	mock.ExpectExec("DELETE FROM todo").WithArgs(todo.ID, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id"}))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
+	}
+	err = model.Delete("dummy user", todo)
+	if err == nil || err.Error() != wantErr.Error() {
+		t.Errorf("Result TodoSqlModel.Delete(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
+	}
+	// Owned by another user
+	wantErr = errors.New("Forbidden")
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec("DELETE FROM todo").WithArgs(todo.ID, "dummy user", "dummy user", "dummy user").WillReturnResult(sqlmock.NewResult(1, 0))
+	mock.ExpectQuery("SELECT user_id, list_id FROM todo WHERE id=?").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "list_id"}).AddRow("another user", 0))
+	model = TodoSqlModel{
+		db:      db,
+		dialect: "mysql",
 	}
-	err = model.Delete(todo)
+	err = model.Delete("dummy user", todo)
 	if err == nil || err.Error() != wantErr.Error() {
-		t.Errorf("Result TodoMySqlModel.Delete(%#v) == %#v, want %#v", todo, err, wantErr)
+		t.Errorf("Result TodoSqlModel.Delete(%q, %#v) == %#v, want %#v", "dummy user", todo, err, wantErr)
 	}
 }
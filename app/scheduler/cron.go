@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron expression, "minute hour day-of-month month day-of-week" like "0 8,18 * * 1-5",
+// it is read in the time zone of the times it is given so one schedule fits every user
+type Schedule struct {
+	Expression string
+	minutes    uint64
+	hours      uint64
+	days       uint64
+	months     uint64
+	weekdays   uint64
+	// Like cron, a day matches either the day of month or the day of week when both are restricted
+	anyDay bool
+}
+
+var scheduleMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+var cronMonths = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronWeekdays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// maxScheduleYears stops Next for the dates that never come like "0 0 30 2 *"
+const maxScheduleYears = 5
+
+// ParseSchedule understands *, lists, ranges, steps, the names of months and weekdays and @hourly, @daily, @weekly, @monthly
+func ParseSchedule(expression string) (*Schedule, error) {
+	fields := strings.Fields(strings.ToLower(expression))
+	if len(fields) == 1 {
+		if macro, ok := scheduleMacros[fields[0]]; ok {
+			fields = strings.Fields(macro)
+		}
+	}
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Wrong schedule %q, want 5 fields like \"0 8 * * *\"", expression)
+	}
+	schedule := &Schedule{Expression: expression}
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("Wrong minute in schedule %q: %v", expression, err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("Wrong hour in schedule %q: %v", expression, err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("Wrong day of month in schedule %q: %v", expression, err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return nil, fmt.Errorf("Wrong month in schedule %q: %v", expression, err)
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdays); err != nil {
+		return nil, fmt.Errorf("Wrong day of week in schedule %q: %v", expression, err)
+	}
+	// 7 is Sunday too
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	// Like cron, a field starting with * is not restricted, */2 included
+	schedule.anyDay = !strings.HasPrefix(fields[2], "*") && !strings.HasPrefix(fields[4], "*")
+	return schedule, nil
+}
+
+// parseCronField is the bits of the values of "1,5-10,*/15"
+func parseCronField(field string, min int, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("wrong step %q", item[i+1:])
+			}
+			item = item[:i]
+		}
+		first, last := min, max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if first, err = cronValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			last = first
+			if len(bounds) == 2 {
+				if last, err = cronValue(bounds[1], min, max, names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// 5/15 is 5-59/15
+				last = max
+			}
+			if last < first {
+				return 0, fmt.Errorf("wrong range %q", item)
+			}
+		}
+		for value := first; value <= last; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(text string, min int, max int, names map[string]int) (int, error) {
+	if value, ok := names[text]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("wrong value %q, want %d to %d", text, min, max)
+	}
+	return value, nil
+}
+
+func (this *Schedule) matchDay(t time.Time) bool {
+	day := this.days&(1<<uint(t.Day())) != 0
+	weekday := this.weekdays&(1<<uint(t.Weekday())) != 0
+	if this.anyDay {
+		return day || weekday
+	}
+	return day && weekday
+}
+
+// Next is the first time of the schedule after the time, in its time zone, zero when there is none
+func (this *Schedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxScheduleYears, 0, 0)
+	for t.Before(limit) {
+		if this.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !this.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if this.hours&(1<<uint(t.Hour())) == 0 {
+			// Add an hour rather than time.Date the next one, it may not exist on a DST change.
+			// Truncate would not do, the hours of India start at :30 in UTC
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Add(time.Hour)
+			continue
+		}
+		if this.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Due is whether the schedule has a time after from until to, in the time zone of from
+func (this *Schedule) Due(from time.Time, to time.Time) bool {
+	next := this.Next(from)
+	return !next.IsZero() && !next.After(to)
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleError(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"0 8 * *",
+		"0 8 * * * *",
+		"60 8 * * *",
+		"0 24 * * *",
+		"0 8 0 * *",
+		"0 8 * 13 *",
+		"0 8 * * 8",
+		"0 8 * foo *",
+		"0 18-8 * * *",
+		"*/0 * * * *",
+		"@yearly",
+	} {
+		if _, err := ParseSchedule(expression); err == nil {
+			t.Errorf("ParseSchedule(%q) == nil error", expression)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	kolkata, _ := time.LoadLocation("Asia/Kolkata")
+	cases := []struct {
+		expression string
+		after      time.Time
+		want       time.Time
+	}{
+		{"0 8 * * *", time.Date(2018, 11, 15, 7, 59, 30, 0, time.UTC), time.Date(2018, 11, 15, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * *", time.Date(2018, 11, 15, 8, 0, 0, 0, time.UTC), time.Date(2018, 11, 16, 8, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2018, 11, 15, 8, 1, 0, 0, time.UTC), time.Date(2018, 11, 15, 8, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2018, 11, 15, 8, 30, 0, 0, time.UTC), time.Date(2018, 11, 15, 8, 45, 0, 0, time.UTC)},
+		{"0 8,18 * * mon-fri", time.Date(2018, 11, 16, 19, 0, 0, 0, time.UTC), time.Date(2018, 11, 19, 8, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2018, 11, 18, 9, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Either the day of month or the day of week
+		{"0 0 1 * fri", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2018, 11, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */2 * mon", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2018, 11, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * */2", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2018, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Date(2018, 11, 15, 0, 0, 0, 0, time.UTC), time.Time{}},
+		// In the time zone of the time
+		{"0 8 * * *", time.Date(2018, 11, 15, 9, 0, 0, 0, berlin), time.Date(2018, 11, 16, 8, 0, 0, 0, berlin)},
+		{"0 8 * * *", time.Date(2018, 11, 15, 7, 0, 0, 0, kolkata), time.Date(2018, 11, 15, 8, 0, 0, 0, kolkata)},
+		// 2:30 does not exist on the DST change in Berlin
+		{"30 * * * *", time.Date(2019, 3, 31, 1, 45, 0, 0, berlin), time.Date(2019, 3, 31, 3, 30, 0, 0, berlin)},
+		{"0 8 * * *", time.Date(2019, 3, 30, 9, 0, 0, 0, berlin), time.Date(2019, 3, 31, 8, 0, 0, 0, berlin)},
+	}
+	for _, c := range cases {
+		schedule, err := ParseSchedule(c.expression)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) == %v", c.expression, err)
+			continue
+		}
+		if got := schedule.Next(c.after); !got.Equal(c.want) {
+			t.Errorf("Schedule(%q).Next(%v) == %v, want %v", c.expression, c.after, got, c.want)
+		}
+	}
+}
+
+func TestScheduleDue(t *testing.T) {
+	schedule, _ := ParseSchedule("0 8 * * *")
+	from := time.Date(2018, 11, 15, 7, 45, 0, 0, time.UTC)
+	if !schedule.Due(from, from.Add(15*time.Minute)) {
+		t.Errorf("Schedule.Due() until 8:00 == false")
+	}
+	if schedule.Due(from, from.Add(14*time.Minute)) {
+		t.Errorf("Schedule.Due() until 7:59 == true")
+	}
+	if schedule.Due(from.Add(15*time.Minute), from.Add(30*time.Minute)) {
+		t.Errorf("Schedule.Due() from 8:00 == true")
+	}
+}
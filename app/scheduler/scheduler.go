@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"log"
+	"math/rand"
+	"time"
+)
+
+// Job runs at the times of its schedule, from is its previous run so it catches up on the times missed while no instance was running
+type Job struct {
+	Name     string
+	Schedule *Schedule
+	Run      func(from time.Time, to time.Time) error
+}
+
+// Store keeps the last run of each job, see model.JobRunModel
+type Store interface {
+	// LastRun is zero when the job never ran
+	LastRun(name string) (time.Time, error)
+	// ClaimRun moves the last run from last to run, it is false when another instance did it first
+	ClaimRun(name string, last time.Time, run time.Time) (bool, error)
+}
+
+// Scheduler runs the jobs in the process, in UTC
+type Scheduler struct {
+	Store Store
+	Jobs  []Job
+	// Jitter delays each run by up to this much, so the instances and the LINE API are not all busy at the same second
+	Jitter time.Duration
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// RunDue runs the jobs whose time has come since their last run, the first call of a new job only starts counting
+func (this *Scheduler) RunDue(now time.Time) {
+	now = now.UTC()
+	for _, job := range this.Jobs {
+		last, err := this.Store.LastRun(job.Name)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if !last.IsZero() && !job.Schedule.Due(last, now) {
+			continue
+		}
+		claimed, err := this.Store.ClaimRun(job.Name, last, now)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if !claimed || last.IsZero() {
+			continue
+		}
+		if err := job.Run(last, now); err != nil {
+			log.Printf("Job %v: %v", job.Name, err)
+		}
+	}
+}
+
+// Next is the earliest time of the jobs after now
+func (this *Scheduler) Next(now time.Time) time.Time {
+	next := time.Time{}
+	for _, job := range this.Jobs {
+		if t := job.Schedule.Next(now.UTC()); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// Start catches up on the missed runs, then runs the jobs in the background until Stop
+func (this *Scheduler) Start() {
+	this.stop = make(chan struct{})
+	this.done = make(chan struct{})
+	go func() {
+		defer close(this.done)
+		this.RunDue(time.Now())
+		for {
+			next := this.Next(time.Now())
+			if next.IsZero() {
+				return
+			}
+			wait := time.Until(next)
+			if this.Jitter > 0 {
+				wait += time.Duration(rand.Int63n(int64(this.Jitter)))
+			}
+			select {
+			case <-time.After(wait):
+				this.RunDue(time.Now())
+			case <-this.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the running jobs to finish, no job runs after it
+func (this *Scheduler) Stop() {
+	close(this.stop)
+	<-this.done
+}
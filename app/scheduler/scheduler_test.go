@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockStore struct {
+	runs map[string]time.Time
+	err  error
+}
+
+func (this *mockStore) LastRun(name string) (time.Time, error) {
+	return this.runs[name], this.err
+}
+
+func (this *mockStore) ClaimRun(name string, last time.Time, run time.Time) (bool, error) {
+	if this.err != nil {
+		return false, this.err
+	}
+	if !this.runs[name].Equal(last) {
+		return false, nil
+	}
+	this.runs[name] = run
+	return true, nil
+}
+
+type run struct {
+	from time.Time
+	to   time.Time
+}
+
+func newTestScheduler(store Store, runs *[]run) *Scheduler {
+	schedule, _ := ParseSchedule("0 8 * * *")
+	return &Scheduler{
+		Store: store,
+		Jobs: []Job{{
+			Name:     "remind",
+			Schedule: schedule,
+			Run: func(from time.Time, to time.Time) error {
+				*runs = append(*runs, run{from, to})
+				return nil
+			},
+		}},
+	}
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	store := &mockStore{runs: map[string]time.Time{}}
+	runs := []run{}
+	scheduler := newTestScheduler(store, &runs)
+	now := time.Date(2018, 11, 15, 7, 50, 0, 0, time.UTC)
+
+	// The first run only starts counting
+	scheduler.RunDue(now)
+	if len(runs) != 0 || !store.runs["remind"].Equal(now) {
+		t.Errorf("Scheduler.RunDue() first == %v, last run %v", runs, store.runs["remind"])
+	}
+
+	scheduler.RunDue(now.Add(5 * time.Minute))
+	if len(runs) != 0 {
+		t.Errorf("Scheduler.RunDue() before 8:00 == %v", runs)
+	}
+
+	scheduler.RunDue(now.Add(10 * time.Minute))
+	if len(runs) != 1 || !runs[0].from.Equal(now) || !runs[0].to.Equal(now.Add(10*time.Minute)) {
+		t.Errorf("Scheduler.RunDue() at 8:00 == %v", runs)
+	}
+
+	// Down for three days, it catches up once
+	scheduler.RunDue(now.AddDate(0, 0, 3))
+	if len(runs) != 2 || !runs[1].from.Equal(now.Add(10*time.Minute)) {
+		t.Errorf("Scheduler.RunDue() after a gap == %v", runs)
+	}
+	scheduler.RunDue(now.AddDate(0, 0, 3).Add(time.Minute))
+	if len(runs) != 2 {
+		t.Errorf("Scheduler.RunDue() after the catch up == %v", runs)
+	}
+}
+
+func TestSchedulerRunDueClaimed(t *testing.T) {
+	now := time.Date(2018, 11, 15, 7, 50, 0, 0, time.UTC)
+	store := &mockStore{runs: map[string]time.Time{"remind": now}}
+	runs := []run{}
+	instances := []*Scheduler{newTestScheduler(store, &runs), newTestScheduler(store, &runs)}
+	for _, scheduler := range instances {
+		scheduler.RunDue(now.Add(time.Hour))
+	}
+	if len(runs) != 1 {
+		t.Errorf("Scheduler.RunDue() of two instances == %v, want 1 run", runs)
+	}
+
+	// Another instance claimed it between LastRun and ClaimRun
+	if claimed, _ := store.ClaimRun("remind", now, now.AddDate(0, 0, 1)); claimed {
+		t.Errorf("mockStore.ClaimRun() of a stale last run == true")
+	}
+
+	store.err = errors.New("Database is down")
+	instances[0].RunDue(now.AddDate(0, 0, 2))
+	if len(runs) != 1 {
+		t.Errorf("Scheduler.RunDue() with a store error == %v", runs)
+	}
+}
+
+func TestSchedulerNext(t *testing.T) {
+	hourly, _ := ParseSchedule("@hourly")
+	daily, _ := ParseSchedule("0 8 * * *")
+	scheduler := &Scheduler{Jobs: []Job{{Name: "daily", Schedule: daily}, {Name: "hourly", Schedule: hourly}}}
+	now := time.Date(2018, 11, 15, 7, 50, 0, 0, time.UTC)
+	if next := scheduler.Next(now); !next.Equal(time.Date(2018, 11, 15, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Scheduler.Next(%v) == %v", now, next)
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	store := &mockStore{runs: map[string]time.Time{"remind": time.Now().AddDate(0, 0, -2)}}
+	finished := false
+	schedule, _ := ParseSchedule("0 8 * * *")
+	scheduler := &Scheduler{
+		Store: store,
+		Jobs: []Job{{
+			Name:     "remind",
+			Schedule: schedule,
+			Run: func(from time.Time, to time.Time) error {
+				time.Sleep(50 * time.Millisecond)
+				finished = true
+				return nil
+			},
+		}},
+	}
+	scheduler.Start()
+	scheduler.Stop()
+	if !finished {
+		t.Errorf("Scheduler.Stop() before the running job finished")
+	}
+}
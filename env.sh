@@ -6,6 +6,13 @@ export LINE_LOGIN_ID=
 export LINE_LOGIN_SECRET=
 export LINE_LOGIN_REDIRECT_URL=https://choo-todo-bot.serveo.net/auth
 export EDIT_URL=https://choo-todo-bot.serveo.net/
+export REMIND_GROUP_BY_TAG=false
+export REMIND_SCHEDULE="0 8 * * *"
+export REMIND_JITTER=1m
+export REMIND_TOKEN=
+export INACTIVE_RETENTION_DAYS=
+export MESSAGE_FORMAT=flex
+export LINE_BOT_ID=@gpd2291p
 export MYSQL_USER=todo_user
 export MYSQL_PASSWORD=todo_pass
 export MYSQL_DATABASE=todo_db